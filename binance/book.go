@@ -0,0 +1,282 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/simonks2016/dex_plus/binance/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/httpClient"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/orderbook"
+	"github.com/simonks2016/dex_plus/ratelimit"
+)
+
+const (
+	// DefaultBookDepth 快照回调的默认档位数
+	DefaultBookDepth = 20
+	// DefaultBookLimit 同步时从 /api/v3/depth 获取的档位数，权重 50
+	DefaultBookLimit = 1000
+	// maxBufferedDeltas 等待 REST 快照期间每个品种最多缓存的增量，超出时丢弃最早的
+	maxBufferedDeltas = 1000
+	// bookRetryDelay 获取快照失败或快照早于缓存的增量时，重新获取前等待的时间
+	bookRetryDelay = time.Second
+)
+
+// BookOption 本地盘口的选项
+type BookOption func(*bookConfig)
+
+type bookConfig struct {
+	depth   int
+	limit   int
+	restURL string
+}
+
+// WithBookDepth 快照回调的档位数
+func WithBookDepth(n int) BookOption {
+	return func(c *bookConfig) {
+		if n > 0 {
+			c.depth = n
+		}
+	}
+}
+
+// WithBookLimit 同步时从 REST 获取的档位数，最大 5000；档位越多权重越高
+func WithBookLimit(n int) BookOption {
+	return func(c *bookConfig) {
+		if n > 0 {
+			c.limit = n
+		}
+	}
+}
+
+// WithBookRestURL 替换获取快照的 REST 地址，默认使用 WithRestURL 的地址
+func WithBookRestURL(url string) BookOption {
+	return func(c *bookConfig) { c.restURL = url }
+}
+
+// bookState 一个品种的同步状态
+type bookState struct {
+	// book 为空表示未同步
+	book *orderbook.Book
+	// buffer 等待快照期间收到的增量
+	buffer []payload.OrderBookDelta
+	// syncing 正在获取快照
+	syncing bool
+}
+
+// LocalBooks 按币安文档的步骤在本地维护盘口：先缓存增量，再获取 REST 快照，丢弃 u <= lastUpdateId 的增量，
+// 之后每条增量要求 U <= 本地 updateId + 1 <= u；出现缺口或买卖盘交叉时重新获取快照，并发安全
+type LocalBooks struct {
+	p    *Public
+	cfg  bookConfig
+	rest *httpClient.Client
+
+	mu      sync.Mutex
+	books   map[string]*bookState
+	resyncs atomic.Uint64
+}
+
+// SubscribeLocalBook 订阅 depth 频道并在本地维护盘口，每隔 interval 回调一次已同步品种的前 N 档快照
+// interval 为 0 或 callback 为空时不回调，通过 LocalBooks.Snapshot 查询
+func (p *Public) SubscribeLocalBook(interval time.Duration, callback func([]market.BookSnapshot) error, opts ...BookOption) *LocalBooks {
+	cfg := bookConfig{depth: DefaultBookDepth, limit: DefaultBookLimit, restURL: p.restURL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	lb := &LocalBooks{
+		p:   p,
+		cfg: cfg,
+		rest: httpClient.NewClient(httpClient.Config{
+			WorkerSize:  1,
+			Exchange:    common.Binance,
+			RateLimiter: ratelimit.DefaultRegistry.Get(common.Binance),
+		}),
+		books: make(map[string]*bookState),
+	}
+	// 增量必须按顺序应用
+	p.SubscribeOrderBookDelta(lb.handle, options.WithOrdered())
+	if interval > 0 && callback != nil {
+		go lb.run(p.ctx, interval, callback)
+	}
+	return lb
+}
+
+// Snapshot 品种前 depth 档的快照，depth <= 0 时使用 WithBookDepth 的档位数；盘口未同步时返回 false
+func (lb *LocalBooks) Snapshot(symbol string, depth int) (market.BookSnapshot, bool) {
+	if depth <= 0 {
+		depth = lb.cfg.depth
+	}
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	s, ok := lb.books[symbol]
+	if !ok || s.book == nil {
+		return market.BookSnapshot{}, false
+	}
+	return s.book.Snapshot(depth), true
+}
+
+// Snapshots 全部已同步品种的快照，按品种排序
+func (lb *LocalBooks) Snapshots(depth int) []market.BookSnapshot {
+	if depth <= 0 {
+		depth = lb.cfg.depth
+	}
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	ret := make([]market.BookSnapshot, 0, len(lb.books))
+	for _, s := range lb.books {
+		if s.book != nil {
+			ret = append(ret, s.book.Snapshot(depth))
+		}
+	}
+	slices.SortFunc(ret, func(x, y market.BookSnapshot) int { return strings.Compare(x.Symbol, y.Symbol) })
+	return ret
+}
+
+// Synced 品种的盘口是否已经同步
+func (lb *LocalBooks) Synced(symbol string) bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	s, ok := lb.books[symbol]
+	return ok && s.book != nil
+}
+
+// Resyncs 出现缺口后重新同步的次数
+func (lb *LocalBooks) Resyncs() uint64 {
+	return lb.resyncs.Load()
+}
+
+func (lb *LocalBooks) handle(symbol string, delta payload.OrderBookDelta) error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	s, ok := lb.books[symbol]
+	if !ok {
+		s = &bookState{}
+		lb.books[symbol] = s
+	}
+	if s.book != nil {
+		err := applyDelta(s.book, delta)
+		if err == nil {
+			return nil
+		}
+		lb.resyncs.Add(1)
+		lb.p.logger.Warn("order book out of sync, fetching snapshot", "channel", "depth", "symbol", symbol, "reason", err)
+		s.book = nil
+	}
+
+	s.buffer = append(s.buffer, delta)
+	if len(s.buffer) > maxBufferedDeltas {
+		s.buffer = slices.Delete(s.buffer, 0, len(s.buffer)-maxBufferedDeltas)
+	}
+	if !s.syncing {
+		s.syncing = true
+		go lb.sync(symbol, s)
+	}
+	return nil
+}
+
+// applyDelta 应用一条增量，早于本地 updateId 的增量被忽略
+func applyDelta(book *orderbook.Book, delta payload.OrderBookDelta) error {
+	first, last := int64(delta.UpdateId), int64(delta.LastUpdateId)
+	if last <= book.Seq {
+		return nil
+	}
+	if first > book.Seq+1 {
+		return fmt.Errorf("update gap: U %d, local updateId %d", first, book.Seq)
+	}
+	book.Update(delta.BidLevels(), delta.AskLevels())
+	book.Seq = last
+	book.Updated = time.UnixMilli(delta.EventTime)
+	if book.Crossed() {
+		return errors.New("book crossed")
+	}
+	return nil
+}
+
+// sync 获取 REST 快照并重放缓存的增量，直到同步成功或 ctx 结束
+func (lb *LocalBooks) sync(symbol string, s *bookState) {
+	ctx := lb.p.ctx
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				lb.mu.Lock()
+				s.syncing = false
+				lb.mu.Unlock()
+				return
+			case <-time.After(bookRetryDelay):
+			}
+		}
+
+		snapshot, err := lb.fetch(ctx, symbol)
+		if err != nil {
+			lb.p.logger.Warn("failed to fetch order book snapshot", "symbol", symbol, "error", err)
+			continue
+		}
+
+		lb.mu.Lock()
+		err = lb.seed(s, symbol, snapshot)
+		lb.mu.Unlock()
+		if err == nil {
+			return
+		}
+		lb.p.logger.Debug("order book snapshot rejected, retrying", "symbol", symbol, "reason", err)
+	}
+}
+
+// seed 用快照与缓存的增量重建盘口；调用方持有 lb.mu
+func (lb *LocalBooks) seed(s *bookState, symbol string, snapshot payload.DepthSnapshot) error {
+	if len(s.buffer) > 0 && snapshot.LastUpdateId < int64(s.buffer[0].UpdateId) {
+		return fmt.Errorf("snapshot lastUpdateId %d is older than buffered U %d", snapshot.LastUpdateId, s.buffer[0].UpdateId)
+	}
+
+	book := orderbook.New(common.Binance, symbol)
+	book.Reset(snapshot.BidLevels(), snapshot.AskLevels())
+	book.Seq = snapshot.LastUpdateId
+	book.Updated = time.Now()
+	for i, delta := range s.buffer {
+		if err := applyDelta(book, delta); err != nil {
+			// 缓存中间缺了增量，保留之后的部分等待更新的快照
+			s.buffer = s.buffer[i:]
+			return err
+		}
+	}
+	s.book, s.buffer, s.syncing = book, nil, false
+	return nil
+}
+
+func (lb *LocalBooks) fetch(ctx context.Context, symbol string) (payload.DepthSnapshot, error) {
+	var snapshot payload.DepthSnapshot
+	url := lb.cfg.restURL + "/api/v3/depth?symbol=" + strings.ToUpper(symbol) + "&limit=" + strconv.Itoa(lb.cfg.limit)
+	err := lb.rest.GetJSON(ctx, url, nil, &snapshot)
+	return snapshot, err
+}
+
+// run 定时回调快照，回调返回的错误记录到日志
+func (lb *LocalBooks) run(ctx context.Context, interval time.Duration, callback func([]market.BookSnapshot) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshots := lb.Snapshots(0)
+			if len(snapshots) == 0 {
+				continue
+			}
+			if err := callback(snapshots); err != nil {
+				lb.p.logger.Error("failed to handle book snapshot", "channel", "depth", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestLocalBookOffline(t *testing.T) {
+	srv := dextest.NewBinanceServer()
+	defer srv.Close()
+
+	var lastUpdateId atomic.Int64
+	lastUpdateId.Store(100)
+	srv.Handle("/api/v3/depth", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("symbol") != "BTCUSDT" {
+			http.Error(w, "bad symbol", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"lastUpdateId": lastUpdateId.Load(),
+			"bids":         [][]string{{"100.00", "1"}, {"99.00", "2"}},
+			"asks":         [][]string{{"101.00", "1"}},
+		})
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := New(ctx,
+		options.WithURL(srv.URL()),
+		options.WithSymbols("btcusdt"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	books := p.SubscribeLocalBook(0, nil, WithBookRestURL(srv.HTTPURL()))
+	p.Connect()
+	defer p.Close()
+
+	if _, ok := srv.WaitRequest("subscribe", "depth", 5*time.Second); !ok {
+		t.Fatal("no subscribe request")
+	}
+	delta := func(first, last int, bids [][]string) map[string]any {
+		return map[string]any{"e": "depthUpdate", "E": 1700000000000, "s": "BTCUSDT", "U": first, "u": last, "b": bids, "a": [][]string{}}
+	}
+	// 第一条早于快照，被丢弃；第二条跨过快照的 lastUpdateId
+	_ = srv.Push("btcusdt@depth", delta(90, 100, [][]string{{"100.00", "5"}}))
+	_ = srv.Push("btcusdt@depth", delta(99, 102, [][]string{{"100.00", "0"}}))
+
+	waitFor := func(cond func() bool) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	if !waitFor(func() bool { return books.Synced("btcusdt") }) {
+		t.Fatal("book not synced")
+	}
+	s, _ := books.Snapshot("btcusdt", 5)
+	if len(s.Bids) != 1 || s.Bids[0].Price.String() != "99.00" {
+		t.Fatalf("bids = %v", s.Bids)
+	}
+
+	// 出现缺口后重新获取快照
+	lastUpdateId.Store(120)
+	_ = srv.Push("btcusdt@depth", delta(110, 111, nil))
+	if !waitFor(func() bool { return books.Resyncs() == 1 && books.Synced("btcusdt") }) {
+		t.Fatalf("resyncs = %d, synced = %v", books.Resyncs(), books.Synced("btcusdt"))
+	}
+	if s, _ := books.Snapshot("btcusdt", 5); len(s.Bids) != 2 {
+		t.Fatalf("bids after resync = %v", s.Bids)
+	}
+}
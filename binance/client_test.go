@@ -1,24 +1,16 @@
 package binance
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 	"testing"
 
 	"github.com/simonks2016/dex_plus/binance/payload"
 	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
 )
 
 func TestClient(t *testing.T) {
-
-	ctx, stop := signal.NotifyContext(
-		context.Background(),
-		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
-	// 释放资源
-	defer stop()
+	ctx := dextest.LiveContext(t)
 
 	cli := NewPublic(ctx,
 		common.BinanceSymbol(common.BTC, common.USDT),
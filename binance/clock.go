@@ -0,0 +1,39 @@
+package binance
+
+import (
+	"context"
+	"time"
+
+	"github.com/simonks2016/dex_plus/binance/internal"
+	"github.com/simonks2016/dex_plus/binance/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/httpClient"
+)
+
+// TimeSource 通过 /api/v3/time 获取币安服务器时间，用于 clocksync.New
+type TimeSource struct {
+	BaseURL string
+	client  *httpClient.Client
+}
+
+func NewTimeSource() *TimeSource {
+	return &TimeSource{
+		BaseURL: internal.RestURL,
+		client:  httpClient.NewClient(httpClient.Config{WorkerSize: 1, Exchange: common.Binance}),
+	}
+}
+
+// Close 释放 HTTP 连接，时钟 Stop 之后调用
+func (s *TimeSource) Close() {
+	s.client.Close()
+}
+
+func (s *TimeSource) Exchange() string { return common.Binance }
+
+func (s *TimeSource) ServerTime(ctx context.Context) (time.Time, error) {
+	var out payload.ServerTime
+	if err := s.client.GetJSON(ctx, s.BaseURL+"/api/v3/time", nil, &out); err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(out.ServerTime), nil
+}
@@ -0,0 +1,63 @@
+package binance
+
+import (
+	"context"
+	"strings"
+
+	"github.com/simonks2016/dex_plus/binance/internal"
+	"github.com/simonks2016/dex_plus/binance/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/httpClient"
+	"github.com/simonks2016/dex_plus/ratelimit"
+)
+
+// InstrumentLoader 通过 /api/v3/exchangeInfo 加载币安现货产品信息
+type InstrumentLoader struct {
+	BaseURL string
+	client  *httpClient.Client
+}
+
+func NewInstrumentLoader() *InstrumentLoader {
+	return &InstrumentLoader{
+		BaseURL: internal.RestURL,
+		client: httpClient.NewClient(httpClient.Config{
+			WorkerSize:  1,
+			Exchange:    common.Binance,
+			RateLimiter: ratelimit.DefaultRegistry.Get(common.Binance),
+		}),
+	}
+}
+
+func (l *InstrumentLoader) Exchange() string { return common.Binance }
+
+func (l *InstrumentLoader) LoadInstruments(ctx context.Context) ([]common.Instrument, error) {
+	var info payload.ExchangeInfo
+	if err := l.client.GetJSON(ctx, l.BaseURL+"/api/v3/exchangeInfo", nil, &info); err != nil {
+		return nil, err
+	}
+
+	ret := make([]common.Instrument, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		inst := common.Instrument{
+			Exchange: common.Binance,
+			// 推送频道使用小写代码
+			Symbol: strings.ToLower(s.Symbol),
+			Type:   common.InstrumentSpot,
+			Base:   s.BaseAsset,
+			Quote:  s.QuoteAsset,
+		}
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "PRICE_FILTER":
+				inst.TickSize, _ = common.ParseDecimal(f.TickSize)
+			case "LOT_SIZE":
+				inst.LotSize, _ = common.ParseDecimal(f.StepSize)
+				inst.MinSize, _ = common.ParseDecimal(f.MinQty)
+			case "NOTIONAL", "MIN_NOTIONAL":
+				inst.MinNotional, _ = common.ParseDecimal(f.MinNotional)
+			}
+		}
+		ret = append(ret, inst)
+	}
+	return ret, nil
+}
@@ -3,12 +3,18 @@ package internal
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/watchdog"
 )
 
 type SubscribeParams struct {
@@ -16,12 +22,14 @@ type SubscribeParams struct {
 	Is100Ms           bool   `json:"is100ms"`
 	Symbol            string `json:"symbol"`
 	ReturnChannelName string `json:"return_channel_name"`
+	// Ordered 同一个 stream 的推送按到达顺序串行回调
+	Ordered bool `json:"ordered"`
 }
 
 type BinanceClient struct {
 	ctx              context.Context
-	client           *client.WsClient
-	logger           *log.Logger
+	client           client.Client
+	logger           *slog.Logger
 	pool             *ants.Pool
 	cfg              *client.Config
 	auth             *Auth
@@ -30,81 +38,241 @@ type BinanceClient struct {
 	authDone         atomic.Bool
 	isConnected      atomic.Bool
 	handlerMap       map[string][]Caller
-	subscribedParams *BinanceParams
-	deadQueue        [][]byte
+	orderedHandler   map[string][]Caller // 保序订阅的回调，在读协程里直接执行
+	returnNames      map[string]string   // stream 中的频道名 → 推送中的频道名，取消订阅时据此移除回调
+	handlerMu        sync.RWMutex        // 运行时订阅、取消订阅与读协程并发访问回调表
+	subMu            sync.Mutex
+	subscribedParams *BinanceParams        // 已订阅的 stream，重连后重放
+	tracker          *subscription.Tracker // 订阅状态机，按请求 id 关联确认
+	watchdog         *watchdog.Watchdog    // 静默检测，未配置时为 nil
+	onReconnected    func()                // 自动重连成功后调用，连接池据此判断是否需要重新分配
 }
 
 func NewBinanceClient(ctx context.Context, auth *Auth, cfg *client.Config) *BinanceClient {
+	cfg.SetDispatchKey(MessageKey)
 
 	pool, _ := ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
 
 	cli := &BinanceClient{
-		client:           client.NewWsClient(ctx, cfg),
+		client:           client.NewClient(ctx, cfg),
 		auth:             auth,
 		ctx:              ctx,
 		handlerMap:       make(map[string][]Caller),
+		orderedHandler:   make(map[string][]Caller),
+		returnNames:      make(map[string]string),
 		IsRequireAuth:    cfg.IsNeedAuth,
-		logger:           cfg.Logger,
+		logger:           client.OrDefaultLogger(cfg.Logger).With("exchange", "binance"),
 		url:              cfg.URL,
 		cfg:              cfg,
 		pool:             pool,
-		deadQueue:        make([][]byte, 0),
 		subscribedParams: NewBinanceParams(SubscribeMethod),
 	}
+	cli.tracker = subscription.New(cfg.Subscription(), cli.sendRequest, cli.logger)
 	cli.client.SetObserver(cli)
+	if cfg.Watchdog != nil {
+		cli.watchdog = watchdog.New(*cfg.Watchdog, cli, cli.logger)
+	}
 
 	return cli
 }
 
+func (b *BinanceClient) Logger() *slog.Logger {
+	return b.logger
+}
+
 func (b *BinanceClient) Connect() {
+	b.watchdog.Start(b.ctx)
 	b.client.Start()
 }
 
 func (b *BinanceClient) Close() {
+	b.watchdog.Stop()
 	b.client.Close()
 }
+
+// Shutdown 取消订阅并停止接收，等待已收到的消息回调完成后释放协程池
+func (b *BinanceClient) Shutdown(ctx context.Context) error {
+	b.watchdog.Stop()
+	err := b.client.Shutdown(ctx)
+	return errors.Join(err, client.ReleasePool(ctx, b.pool))
+}
+
+func (b *BinanceClient) Reconnect(reason string) {
+	b.client.Reconnect(reason)
+}
 func (b *BinanceClient) Subscribe(params *SubscribeParams, caller ...Caller) {
 
-	if b.handlerMap == nil {
-		b.handlerMap = make(map[string][]Caller)
-	}
 	// 添加处理函数
-	b.handlerMap[params.ReturnChannelName] = append(b.handlerMap[params.ReturnChannelName], caller...)
-	// 创建订阅参数
+	b.AddHandler(params.ReturnChannelName, params.Ordered, caller...)
+	b.handlerMu.Lock()
+	b.returnNames[params.Channel] = params.ReturnChannelName
+	b.handlerMu.Unlock()
+	// stream 名称中的品种是小写的
+	symbol := strings.ToLower(params.Symbol)
+	b.watchdog.Watch(params.Channel, symbol)
 
-	if b.isConnected.Load() {
-		// 添加到已有订阅参数
-		b.subscribedParams.Add(params.Symbol, params.Channel, params.Is100Ms)
-		// 创建一个新的立刻发送给币安
-		dataBytes := NewBinanceParams(SubscribeMethod, params.Channel).Add(
-			params.Symbol,
-			params.Channel,
-			params.Is100Ms).Json()
-		// 发送订阅信息
-		if err := b.Send(dataBytes); err != nil {
-			b.deadQueue = append(b.deadQueue, dataBytes)
-			return
+	// 添加到已有订阅参数，重连后重放
+	stream := StreamName(symbol, params.Channel, params.Is100Ms)
+	b.subMu.Lock()
+	if slices.Contains(b.subscribedParams.Params, stream) {
+		b.subMu.Unlock()
+		return
+	}
+	b.subscribedParams.Params = append(b.subscribedParams.Params, stream)
+	b.subMu.Unlock()
+
+	// 已连接时立刻发送给币安，否则在 OnConnected 中重放
+	if err := b.tracker.Subscribe(subscription.Feed{Channel: params.Channel, Symbol: symbol}); err != nil {
+		b.logger.Error("failed to subscribe channel", "conn_id", b.client.ConnID(), "stream", stream, "error", err)
+	}
+}
+
+// sendRequest 发送订阅请求，请求 id 使用状态机分配的 id；发送失败的请求由状态机超时后重发
+func (b *BinanceClient) sendRequest(req subscription.Request) error {
+	streams := b.streams(req.Feeds...)
+	if len(streams) == 0 {
+		return nil
+	}
+	dataBytes := NewBinanceParams(SubscribeMethod, streams...).WithId(req.ID).Json()
+	return b.Send(dataBytes)
+}
+
+// streams 订阅对应的 stream 名称，不传订阅时返回全部
+func (b *BinanceClient) streams(feeds ...subscription.Feed) []string {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	if len(feeds) == 0 {
+		return slices.Clone(b.subscribedParams.Params)
+	}
+	var ret []string
+	for _, stream := range b.subscribedParams.Params {
+		s := ParseStreamName(stream)
+		if len(s) > 1 && slices.Contains(feeds, subscription.Feed{Channel: s[1], Symbol: s[0]}) {
+			ret = append(ret, stream)
+		}
+	}
+	return ret
+}
+
+// feeds 全部已订阅的 (channel, symbol)
+func (b *BinanceClient) feeds() []subscription.Feed {
+	var ret []subscription.Feed
+	for _, stream := range b.streams() {
+		if s := ParseStreamName(stream); len(s) > 1 {
+			ret = append(ret, subscription.Feed{Channel: s[1], Symbol: s[0]})
 		}
+	}
+	return ret
+}
+
+// SubscriptionState 订阅的当前状态，symbol 不区分大小写
+func (b *BinanceClient) SubscriptionState(channel, symbol string) subscription.State {
+	return b.tracker.State(channel, strings.ToLower(symbol))
+}
+
+// SetOnSubscriptionChange 设置订阅状态变化回调
+func (b *BinanceClient) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	b.tracker.SetOnChange(fn)
+}
+
+// SetOnReconnected 设置自动重连成功后的回调，首次连接不调用；需在 Connect 之前设置
+func (b *BinanceClient) SetOnReconnected(fn func()) {
+	b.onReconnected = fn
+}
+
+// AddHandler 只注册回调，不发送订阅；ordered 的回调在读协程里按到达顺序直接执行
+func (b *BinanceClient) AddHandler(name string, ordered bool, caller ...Caller) {
+	if len(caller) == 0 {
+		return
+	}
+	b.handlerMu.Lock()
+	defer b.handlerMu.Unlock()
+	if ordered {
+		b.orderedHandler[name] = append(slices.Clip(b.orderedHandler[name]), caller...)
 	} else {
-		b.subscribedParams.Add(params.Symbol, params.Channel, params.Is100Ms)
+		b.handlerMap[name] = append(slices.Clip(b.handlerMap[name]), caller...)
+	}
+}
+
+// HasChannel 频道中是否还有订阅的 stream
+func (b *BinanceClient) HasChannel(channel string) bool {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	return slices.ContainsFunc(b.subscribedParams.Params, func(stream string) bool {
+		s := ParseStreamName(stream)
+		return len(s) > 1 && s[1] == channel
+	})
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有 stream 时一并移除回调
+func (b *BinanceClient) Unsubscribe(channel string, symbols ...string) error {
+	lower := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		lower[i] = strings.ToLower(symbol)
+	}
+
+	var removed []string
+	b.subMu.Lock()
+	kept := b.subscribedParams.Params[:0]
+	for _, stream := range b.subscribedParams.Params {
+		s := ParseStreamName(stream)
+		if len(s) > 1 && s[1] == channel && (len(lower) == 0 || slices.Contains(lower, s[0])) {
+			removed = append(removed, stream)
+			b.watchdog.Forget(channel, s[0])
+			b.tracker.Remove(subscription.Feed{Channel: channel, Symbol: s[0]})
+			continue
+		}
+		kept = append(kept, stream)
+	}
+	b.subscribedParams.Params = kept
+	b.subMu.Unlock()
+
+	if !b.HasChannel(channel) {
+		b.handlerMu.Lock()
+		if name, ok := b.returnNames[channel]; ok {
+			delete(b.handlerMap, name)
+			delete(b.orderedHandler, name)
+			delete(b.returnNames, channel)
+		}
+		b.handlerMu.Unlock()
 	}
+
+	if len(removed) == 0 || !b.isConnected.Load() {
+		return nil
+	}
+	return b.Send(NewBinanceParams(UnsubscribeMethod, removed...).Json())
 }
 
-func (b *BinanceClient) Unsubscribe() {
+// UnsubscribeAll 取消订阅全部 stream，保留重放列表
+func (b *BinanceClient) UnsubscribeAll() {
 
 	if b.subscribedParams != nil {
 		// 复制一个新的
+		b.subMu.Lock()
 		p1 := b.subscribedParams.CopyNew(UnsubscribeMethod)
+		p1.Params = slices.Clone(p1.Params)
+		b.subMu.Unlock()
 		// 发送取消订阅信息
 		if err := b.Send(p1.Json()); err != nil {
-			if b.logger != nil {
-				b.logger.Println("Failed to Unsubscribe Channel:", err)
-			}
+			b.logger.Error("failed to unsubscribe channels", "conn_id", b.client.ConnID(), "error", err)
 			return
 		}
 	}
 }
 
+// ResubscribeFeed 先取消再重新订阅一个 (channel, symbol) 对应的 stream
+func (b *BinanceClient) ResubscribeFeed(channel, symbol string) error {
+	feed := subscription.Feed{Channel: channel, Symbol: symbol}
+	streams := b.streams(feed)
+	if len(streams) == 0 {
+		return nil
+	}
+	if err := b.Send(NewBinanceParams(UnsubscribeMethod, streams...).Json()); err != nil {
+		return err
+	}
+	return b.tracker.Subscribe(feed)
+}
+
 func (b *BinanceClient) Send(dataBytes []byte) error {
 	if b.isConnected.Load() {
 		if b.authDone.Load() {
@@ -114,48 +282,7 @@ func (b *BinanceClient) Send(dataBytes []byte) error {
 			return b.client.Send(ctx, dataBytes)
 		}
 	} else {
-		return errors.New("the client has been disconnected")
-	}
-	return nil
-}
-
-func (b *BinanceClient) clearDeadMessage() {
-
-	t1 := time.NewTicker(time.Second * time.Duration(20))
-	defer t1.Stop()
-
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		case <-t1.C:
-			if err := b.replySendDeadMessage(); err != nil {
-				if b.logger != nil {
-					b.logger.Println("failed to reply send dead message error:", err)
-				}
-				continue
-			}
-		}
-	}
-}
-
-func (b *BinanceClient) replySendDeadMessage() error {
-
-	if len(b.deadQueue) > 0 {
-		// 1. 暂存旧切片并立即重置原队列，避免逻辑处理期间原队列被修改
-		queueToProcess := b.deadQueue
-		b.deadQueue = nil // 使用 nil 比 [][]byte{} 更节省一次分配
-
-		for i, data := range queueToProcess {
-			if err := b.Send(data); err != nil {
-				if b.logger != nil {
-					b.logger.Println("[error] Failed to Subscribe Binance Channel:", err.Error())
-				}
-				// 失败处理：如果发送失败，建议将剩余未发送的重新放回队列，防止数据丢失
-				b.deadQueue = append(queueToProcess[i:], b.deadQueue...)
-				return err
-			}
-		}
+		return common.ErrNotConnected
 	}
 	return nil
 }
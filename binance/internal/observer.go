@@ -9,67 +9,79 @@ import (
 )
 
 func (b *BinanceClient) OnConnecting(reason string) {
-	//TODO implement me
-	if b.logger != nil {
-		b.logger.Println("Binance client OnConnecting:", reason)
-	}
+	b.logger.Info("connecting", "conn_id", b.client.ConnID(), "reason", reason)
+}
+
+// OnEndpoint 连接上的地址变化
+func (b *BinanceClient) OnEndpoint(url string) {
+	b.logger.Info("endpoint active", "url", url)
 }
 
 func (b *BinanceClient) OnConnected() {
 	//TODO implement me
 	b.isConnected.Store(true)
+	b.logger.Info("connected", "conn_id", b.client.ConnID())
+	b.watchdog.Resume()
+	if b.onReconnected != nil && b.client.ConnID() > 1 {
+		b.onReconnected()
+	}
 
 	if !b.IsRequireAuth {
 		// 设置已验证
 		b.authDone.Store(true)
+		b.tracker.Resume()
 
-		if b.subscribedParams != nil {
-			if err := b.Send(b.subscribedParams.Json()); err != nil {
-				if b.logger != nil {
-					b.logger.Println(err)
-				}
-			}
+		// 重放全部订阅，等待确认
+		if err := b.tracker.Subscribe(b.feeds()...); err != nil {
+			b.logger.Error("failed to subscribe channels", "conn_id", b.client.ConnID(), "error", err)
 		}
-		// 定时处理死信队列
-		go b.clearDeadMessage()
 	}
 
 }
 
 func (b *BinanceClient) OnDisconnecting() {
-	//TODO implement me
+	// 先取消订阅，之后不再发送
+	b.UnsubscribeAll()
 	b.isConnected.Store(false)
 	b.authDone.Store(false)
-
-	if b.subscribedParams != nil {
-		b.Unsubscribe()
-	}
 }
 
 func (b *BinanceClient) OnDisconnected() {
 	//TODO implement me
 	b.isConnected.Store(false)
-	if b.logger != nil {
-		b.logger.Printf("Binance client OnDisconnected")
-	}
+	b.authDone.Store(false)
+	b.tracker.Pause()
+	b.watchdog.Pause()
+	b.logger.Info("disconnected", "conn_id", b.client.ConnID())
 }
 
 func (b *BinanceClient) OnMessage(data []byte) error {
+	return b.OnTimedMessage(data, time.Time{})
+}
+
+// OnTimedMessage received 为收到该帧的时间，配置了延迟统计时按推送中的时间戳记录线路延迟
+func (b *BinanceClient) OnTimedMessage(data []byte, received time.Time) error {
 	//TODO implement me
 
 	var streams payload.Stream
 
 	if err := json.Unmarshal(data, &streams); err != nil {
-		if b.logger != nil {
-			b.logger.Printf("[error] unmarshal json:%v", err)
-		}
+		b.logger.Error("failed to decode message", "conn_id", b.client.ConnID(), "error", err)
 		return err
 	}
+	if streams.Stream != nil {
+		b.cfg.Latency.ObserveWire(streams.EventTime(), received)
+	}
 
 	if streams.Id != nil {
-		if b.logger != nil {
-			b.logger.Printf("[success] Successfuly Subscribe Channel")
+		if streams.Error != nil {
+			err := streams.Error.Err()
+			b.logger.Error("request rejected", "conn_id", b.client.ConnID(), "id", *streams.Id, "error", err)
+			b.tracker.Ack(*streams.Id, err)
+			return nil
 		}
+		b.logger.Info("subscribed", "conn_id", b.client.ConnID(), "id", *streams.Id)
+		b.tracker.Ack(*streams.Id, nil)
 		return nil
 	}
 
@@ -79,9 +91,7 @@ func (b *BinanceClient) OnMessage(data []byte) error {
 
 		// 假如服务器即将关闭
 		if strings.EqualFold(streamName, "serverShutdown") {
-			if b.logger != nil {
-				b.logger.Printf("[error] Binance server shutdown,date_time=%s", time.Now().Format("2006-01-02 15:04:05"))
-			}
+			b.logger.Warn("server is shutting down, reconnecting", "conn_id", b.client.ConnID())
 			b.client.Reconnect("The server is shutting down")
 			return nil
 		}
@@ -90,15 +100,26 @@ func (b *BinanceClient) OnMessage(data []byte) error {
 		// 分析出ChannelName
 		channelName := s[1]
 		symbol := s[0]
+		b.watchdog.Touch(channelName, symbol)
+
+		b.handlerMu.RLock()
+		ordered := b.orderedHandler[channelName]
+		callers := b.handlerMap[channelName]
+		b.handlerMu.RUnlock()
+
+		// 保序回调，读协程已按 stream 分区，直接执行
+		for _, callback := range ordered {
+			if err := callback(symbol, streams.Data); err != nil {
+				b.logger.Error("failed to handle message", "channel", channelName, "symbol", symbol, "error", err)
+			}
+		}
 
 		// 查看一下处理函数
-		if callers, ex := b.handlerMap[channelName]; ex {
+		if len(callers) > 0 {
 			for _, callback := range callers {
 				if err := b.pool.Submit(func() {
 					if err := callback(symbol, streams.Data); err != nil {
-						if b.logger != nil {
-							b.logger.Printf("[error]Failed to read message:%s,%s,%s", err.Error(), channelName, *streams.Stream)
-						}
+						b.logger.Error("failed to handle message", "channel", channelName, "symbol", symbol, "error", err)
 						return
 					}
 				}); err != nil {
@@ -111,8 +132,14 @@ func (b *BinanceClient) OnMessage(data []byte) error {
 }
 
 func (b *BinanceClient) OnError(err error) {
-	//TODO implement me
-	if b.logger != nil {
-		b.logger.Printf("Binance client OnError:%v", err.Error())
+	b.logger.Error("client error", "conn_id", b.client.ConnID(), "error", err)
+}
+
+// MessageKey 消息的 (channel, symbol) 键，用于按键合并与按键顺序分发
+func MessageKey(data []byte) string {
+	var v struct {
+		Stream string `json:"stream"`
 	}
+	_ = json.Unmarshal(data, &v)
+	return v.Stream
 }
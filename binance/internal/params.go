@@ -12,15 +12,22 @@ type BinanceParams struct {
 	Id     string   `json:"id"`
 }
 
+// NewBinanceParams 创建请求参数；订阅状态机分配的请求 id 从 1 开始，这里默认使用 0，其确认不会被当作订阅确认
 func NewBinanceParams(Method string, params ...string) *BinanceParams {
 
 	return &BinanceParams{
 		Method: Method,
 		Params: params,
-		Id:     "1",
+		Id:     "0",
 	}
 }
 
+// WithId 设置请求 id，交易所在确认中原样返回
+func (p *BinanceParams) WithId(id string) *BinanceParams {
+	p.Id = id
+	return p
+}
+
 const (
 	SubscribeMethod   = "SUBSCRIBE"
 	UnsubscribeMethod = "UNSUBSCRIBE"
@@ -37,19 +44,24 @@ func (p *BinanceParams) Json() []byte {
 
 func (p *BinanceParams) Add(symbol, channel string, Is100Ms bool) *BinanceParams {
 
-	p1 := symbol + "@" + channel
+	p.Params = append(p.Params, StreamName(symbol, channel, Is100Ms))
+	return p
+}
+
+// StreamName 订阅参数对应的 stream 名称
+func StreamName(symbol, channel string, Is100Ms bool) string {
+	name := symbol + "@" + channel
 	if !Is100Ms {
-		p1 = p1 + "@100ms"
+		name = name + "@100ms"
 	}
-	p.Params = append(p.Params, p1)
-	return p
+	return name
 }
 
 func (p *BinanceParams) CopyNew(method string) *BinanceParams {
 	return &BinanceParams{
 		Method: method,
 		Params: p.Params,
-		Id:     "2",
+		Id:     "0",
 	}
 }
 
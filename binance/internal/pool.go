@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/shard"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+)
+
+// MaxStreamsPerConn Binance 单个连接最多订阅 1024 个 stream
+const MaxStreamsPerConn = 1024
+
+// BinancePool 按 stream 数量把订阅分散到多个 BinanceClient，所有连接的推送回调到同一组处理函数
+type BinancePool struct {
+	logger   *slog.Logger
+	pool     *shard.Pool[*BinanceClient]
+	channels *shard.Channels[*BinanceClient, Caller] // 按频道记录的回调
+	onChange func(common.SubscriptionChange)         // 订阅状态变化回调，新建的连接同样使用
+
+	// templates 按频道记录的订阅模板，stream 名称取决于模板中的 Is100Ms
+	mu        sync.Mutex
+	templates map[string]SubscribeParams
+}
+
+func NewBinancePool(ctx context.Context, auth *Auth, cfg *client.Config) *BinancePool {
+	p := &BinancePool{
+		logger:    client.OrDefaultLogger(cfg.Logger).With("exchange", "binance"),
+		templates: make(map[string]SubscribeParams),
+	}
+	p.pool = shard.NewPool(cfg.SubscriptionLimit(MaxStreamsPerConn), func(index int) *BinanceClient {
+		conn := NewBinanceClient(ctx, auth, cfg.ForShard(index))
+		conn.SetOnSubscriptionChange(p.onChange)
+		conn.SetOnReconnected(func() { p.channels.Reconnected() })
+		return conn
+	})
+	p.channels = shard.NewChannels[*BinanceClient, Caller](p.pool, streamKeys{p})
+
+	return p
+}
+
+// streamKeys 以 stream 名称为订阅键
+type streamKeys struct{ p *BinancePool }
+
+func (k streamKeys) Key(channel, symbol string) string {
+	k.p.mu.Lock()
+	template := k.p.templates[channel]
+	k.p.mu.Unlock()
+	return StreamName(strings.ToLower(symbol), channel, template.Is100Ms)
+}
+
+func (k streamKeys) Split(key string) (string, string) {
+	s := ParseStreamName(key)
+	if len(s) < 2 {
+		return "", key
+	}
+	return s[1], s[0]
+}
+
+func (p *BinancePool) Logger() *slog.Logger {
+	return p.logger
+}
+
+// SetOnSubscriptionChange 设置所有连接的订阅状态变化回调
+func (p *BinancePool) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.onChange = fn
+	for _, conn := range p.pool.Conns() {
+		conn.SetOnSubscriptionChange(fn)
+	}
+}
+
+// SubscriptionState 在 stream 所在的连接上查询订阅状态
+func (p *BinancePool) SubscriptionState(channel, symbol string) subscription.State {
+	if !p.channels.Has(channel) {
+		return subscription.Unsubscribed
+	}
+	conn, ok := p.pool.Owner(streamKeys{p}.Key(channel, symbol))
+	if !ok {
+		return subscription.Unsubscribed
+	}
+	return conn.SubscriptionState(channel, symbol)
+}
+
+func (p *BinancePool) Connect() {
+	p.pool.Connect()
+}
+
+func (p *BinancePool) Close() {
+	p.pool.Close()
+}
+
+// Shutdown 并发关闭全部连接，等待已收到的消息回调完成
+func (p *BinancePool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(func(conn *BinanceClient) error {
+		return conn.Shutdown(ctx)
+	})
+}
+
+// Reconnect 重新连接，并按 stream 数量重新平均分配连接
+func (p *BinancePool) Reconnect() {
+	p.channels.Rebalance()
+}
+
+// Subscribe 按模板订阅频道中的品种，当前连接已满时新建连接
+// 同一频道的 stream 名称沿用第一次订阅的模板，回调按本次的 Ordered 注册；caller 为空时沿用该频道已经注册的回调，用于运行时追加品种
+func (p *BinancePool) Subscribe(template SubscribeParams, symbols []string, caller ...Caller) {
+	ordered := template.Ordered
+	p.mu.Lock()
+	if t, ok := p.templates[template.Channel]; ok {
+		template = t
+	} else {
+		p.templates[template.Channel] = template
+	}
+	p.mu.Unlock()
+
+	p.channels.Subscribe(template.Channel, symbols, ordered, caller, func(conn *BinanceClient, symbols []string, batches []shard.Batch[Caller]) {
+		for _, b := range batches {
+			conn.AddHandler(template.ReturnChannelName, b.Ordered, b.Callers...)
+		}
+		for _, symbol := range symbols {
+			params := template
+			params.Symbol = symbol
+			conn.Subscribe(&params)
+		}
+	})
+}
+
+// HasChannel 频道是否注册过回调
+func (p *BinancePool) HasChannel(channel string) bool {
+	return p.channels.Has(channel)
+}
+
+// Unsubscribe 在品种所在的连接上取消订阅，不传品种时取消整个频道；频道中已经没有订阅时一并移除回调
+func (p *BinancePool) Unsubscribe(channel string, symbols ...string) error {
+	if !p.channels.Has(channel) {
+		return nil
+	}
+	err := p.channels.Unsubscribe(channel, symbols, func(conn *BinanceClient, symbols []string) (bool, error) {
+		err := conn.Unsubscribe(channel, symbols...)
+		return conn.HasChannel(channel), err
+	})
+	if !p.channels.Has(channel) {
+		p.mu.Lock()
+		delete(p.templates, channel)
+		p.mu.Unlock()
+	}
+	return err
+}
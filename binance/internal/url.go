@@ -1,5 +1,13 @@
 package internal
 
 const (
-	WsURL = "wss://stream.binance.com:9443/stream"
+	WsURL   = "wss://stream.binance.com:9443/stream"
+	RestURL = "https://api.binance.com"
 )
+
+// WsEndpoints 按优先级排列的行情地址，WsURL 不可用时依次切换
+var WsEndpoints = []string{
+	WsURL,
+	"wss://stream.binance.com:443/stream",
+	"wss://data-stream.binance.vision/stream",
+}
@@ -0,0 +1,81 @@
+package binance
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/simonks2016/dex_plus/binance/payload"
+	"github.com/simonks2016/dex_plus/candles"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+var _ market.MarketDataSource = (*Public)(nil)
+
+// defaultSnapshotInterval OnBookSnapshot 默认的回调间隔，与 depth20@100ms 的推送频率一致
+const defaultSnapshotInterval = 100 * time.Millisecond
+
+// OnTrade 订阅统一格式的成交数据（归集交易）
+func (p *Public) OnTrade(handler market.TradeHandler) error {
+	p.SubscribeAggTrade(func(symbol string, t payload.AggTrade) error {
+		side := market.Buy
+		// m=true 表示买方是挂单方，即主动卖出
+		if t.IsMarket {
+			side = market.Sell
+		}
+		return handler(market.Trade{
+			Exchange:  common.Binance,
+			Symbol:    symbol,
+			TradeId:   strconv.Itoa(t.TradeId),
+			Price:     t.PriceDecimal(),
+			Size:      t.QuantityDecimal(),
+			Side:      side,
+			Timestamp: time.UnixMilli(t.TradeTime),
+		})
+	})
+	return nil
+}
+
+// OnBookSnapshot 在本地维护 depth 增量盘口，每隔 interval 回调已同步品种的前20档快照，interval <= 0 时为 100ms
+// depth20 推送不带事件时间，快照的时间取最近一条增量的事件时间 E
+func (p *Public) OnBookSnapshot(interval time.Duration, handler market.BookSnapshotHandler) error {
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	p.SubscribeLocalBook(interval, func(snapshots []market.BookSnapshot) error {
+		for _, s := range snapshots {
+			if err := handler(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnBookDelta 订阅增量盘口
+func (p *Public) OnBookDelta(handler market.BookDeltaHandler) error {
+	p.SubscribeOrderBookDelta(func(symbol string, delta payload.OrderBookDelta) error {
+		return handler(market.BookDelta{
+			Exchange:  common.Binance,
+			Symbol:    symbol,
+			Bids:      delta.BidLevels(),
+			Asks:      delta.AskLevels(),
+			Timestamp: time.UnixMilli(delta.EventTime),
+		})
+	})
+	return nil
+}
+
+func (p *Public) OnTicker(market.TickerHandler) error { return market.ErrNotSupported }
+
+// OnCandle 由成交在本地合成统一格式的K线，未收盘的K线随每笔成交回调，下一个周期的第一笔成交到达时收盘
+func (p *Public) OnCandle(interval time.Duration, handler market.CandleHandler) error {
+	builder, err := candles.New(candles.Every(interval), candles.Config{Partial: true}, func(bar candles.Bar) error {
+		return handler(bar.Candle)
+	})
+	if err != nil {
+		return err
+	}
+	return p.OnTrade(builder.OnTrade)
+}
@@ -0,0 +1,100 @@
+package binance
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestOnTradeOffline(t *testing.T) {
+	srv := dextest.NewBinanceServer()
+	defer srv.Close()
+
+	p := New(t.Context(),
+		options.WithURL(srv.URL()),
+		options.WithSymbols("btcusdt"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	defer p.Close()
+
+	// m 为 true 表示买方挂单、主动卖出，时间取成交时间 T 而不是事件时间 E
+	dextest.ExpectOfflineTrade(t, srv.Server, p, "aggTrade", func() error {
+		return srv.Push("btcusdt@aggTrade", map[string]any{
+			"e": "aggTrade", "E": 1700000000001, "s": "BTCUSDT", "a": 7,
+			"p": "42000.10", "q": "0.5", "f": 1, "l": 2, "T": 1700000000000, "m": true,
+		})
+	}, market.Trade{
+		Exchange: common.Binance, Symbol: "btcusdt", TradeId: "7",
+		Price: common.MustDecimal("42000.10"), Size: common.MustDecimal("0.5"),
+		Side: market.Sell, Timestamp: time.UnixMilli(1700000000000),
+	})
+
+	// 服务端关闭通知应当触发重连并重新订阅
+	_ = srv.ServerShutdown()
+	if !srv.WaitRequestCount("subscribe", "aggTrade", 2, 10*time.Second) {
+		t.Fatal("did not resubscribe after serverShutdown")
+	}
+}
+
+// TestOnBookSnapshotOffline 快照的时间取增量的事件时间，而不是本地时间
+func TestOnBookSnapshotOffline(t *testing.T) {
+	srv := dextest.NewBinanceServer()
+	defer srv.Close()
+	srv.HandleJSON("/api/v3/depth", map[string]any{
+		"lastUpdateId": 100,
+		"bids":         [][]string{{"100.00", "1"}},
+		"asks":         [][]string{{"101.00", "1"}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := New(ctx,
+		options.WithURL(srv.URL()),
+		options.WithRestURL(srv.HTTPURL()),
+		options.WithSymbols("btcusdt"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+
+	snapshots := make(chan market.BookSnapshot, 16)
+	_ = p.OnBookSnapshot(10*time.Millisecond, func(s market.BookSnapshot) error {
+		select {
+		case snapshots <- s:
+		default:
+		}
+		return nil
+	})
+	p.Connect()
+	defer p.Close()
+
+	if _, ok := srv.WaitRequest("subscribe", "depth", 5*time.Second); !ok {
+		t.Fatal("no subscribe request")
+	}
+	_ = srv.Push("btcusdt@depth", map[string]any{
+		"e": "depthUpdate", "E": 1700000000123, "s": "BTCUSDT", "U": 99, "u": 101,
+		"b": [][]string{{"100.00", "2"}}, "a": [][]string{},
+	})
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-snapshots:
+			if len(s.Bids) == 0 || s.Bids[0].Size.String() != "2" {
+				continue
+			}
+			if s.Symbol != "btcusdt" || !s.Timestamp.Equal(time.UnixMilli(1700000000123)) {
+				t.Fatalf("unexpected snapshot: %+v", s)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no snapshot received")
+		}
+	}
+}
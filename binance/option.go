@@ -0,0 +1,12 @@
+package binance
+
+import (
+	"github.com/simonks2016/dex_plus/options"
+)
+
+// Option 构造选项，见 options 包；静默检测的频道名为 stream 中 @ 之后的部分，如 "trade"、"depth"，
+// WithMaxSubscriptionsPerConn 按 stream 计数
+type Option = options.Option
+
+// SubscribeOption 订阅选项，WithOrdered 时同一个 stream（即同一品种的同一频道）的推送按到达顺序串行回调
+type SubscribeOption = options.SubscribeOption
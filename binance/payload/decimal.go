@@ -0,0 +1,59 @@
+package payload
+
+import (
+	"fmt"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// Decimal 精确小数，币安的价格、数量都以字符串下发，可以无损转换
+type Decimal = common.Decimal
+
+func decimal(s string) Decimal {
+	d, _ := common.ParseDecimal(s)
+	return d
+}
+
+func (t AggTrade) PriceDecimal() Decimal    { return decimal(t.Price) }
+func (t AggTrade) QuantityDecimal() Decimal { return decimal(t.Quantity) }
+
+func (t Trade) PriceDecimal() Decimal    { return decimal(t.Price) }
+func (t Trade) QuantityDecimal() Decimal { return decimal(t.Quantity) }
+
+// BidLevels 买盘档位（精确小数）
+func (o OrderBookDelta) BidLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Bids)
+	return levels
+}
+
+// AskLevels 卖盘档位（精确小数）
+func (o OrderBookDelta) AskLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Asks)
+	return levels
+}
+
+func (o DepthSnapshot) BidLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Bids)
+	return levels
+}
+
+func (o DepthSnapshot) AskLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Asks)
+	return levels
+}
+
+func (o OrderBookSnapshot) BidLevels() []common.PriceLevel { return anyLevels(o.Bids) }
+func (o OrderBookSnapshot) AskLevels() []common.PriceLevel { return anyLevels(o.Asks) }
+
+// anyLevels 快照档位是 [][]any，价格和数量仍是字符串
+func anyLevels(items [][]any) []common.PriceLevel {
+	raw := make([][]string, 0, len(items))
+	for _, item := range items {
+		if len(item) < 2 {
+			continue
+		}
+		raw = append(raw, []string{fmt.Sprint(item[0]), fmt.Sprint(item[1])})
+	}
+	levels, _ := common.ParseLevels(raw)
+	return levels
+}
@@ -0,0 +1,35 @@
+package payload
+
+// ExchangeInfo GET /api/v3/exchangeInfo
+type ExchangeInfo struct {
+	Timezone   string       `json:"timezone"`
+	ServerTime int64        `json:"serverTime"`
+	Symbols    []SymbolInfo `json:"symbols"`
+}
+
+type SymbolInfo struct {
+	Symbol              string         `json:"symbol"`
+	Status              string         `json:"status"`
+	BaseAsset           string         `json:"baseAsset"`
+	BaseAssetPrecision  int            `json:"baseAssetPrecision"`
+	QuoteAsset          string         `json:"quoteAsset"`
+	QuoteAssetPrecision int            `json:"quoteAssetPrecision"`
+	Filters             []SymbolFilter `json:"filters"`
+}
+
+// SymbolFilter 交易规则，不同 filterType 使用不同字段
+type SymbolFilter struct {
+	FilterType  string `json:"filterType"`
+	MinPrice    string `json:"minPrice"`
+	MaxPrice    string `json:"maxPrice"`
+	TickSize    string `json:"tickSize"`
+	MinQty      string `json:"minQty"`
+	MaxQty      string `json:"maxQty"`
+	StepSize    string `json:"stepSize"`
+	MinNotional string `json:"minNotional"`
+}
+
+// ServerTime GET /api/v3/time
+type ServerTime struct {
+	ServerTime int64 `json:"serverTime"`
+}
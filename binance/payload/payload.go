@@ -1,6 +1,12 @@
 package payload
 
-import "github.com/goccy/go-json"
+import (
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/common"
+)
 
 type AggTrade struct {
 	EventType    string `json:"e" binance:"e"`
@@ -32,6 +38,13 @@ type OrderBookSnapshot struct {
 	Asks         [][]any `json:"asks" binance:"asks"`
 }
 
+// DepthSnapshot REST /api/v3/depth 返回的盘口快照
+type DepthSnapshot struct {
+	LastUpdateId int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+}
+
 type OrderBookDelta struct {
 	EventType    string     `json:"e" binance:"e"`
 	EventTime    int64      `json:"E" binance:"E"`
@@ -47,4 +60,31 @@ type Stream struct {
 	Data   json.RawMessage `json:"data"`
 	Id     *string         `json:"id"`
 	Result json.RawMessage `json:"result"`
+	Error  *StreamError    `json:"error"`
+}
+
+// StreamError 订阅请求被拒绝时返回的错误
+type StreamError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// Err 转换为 common 中的错误类型，-1003 为请求过多
+func (e *StreamError) Err() error {
+	code := strconv.Itoa(e.Code)
+	if e.Code == -1003 {
+		return &common.RateLimitError{Exchange: common.Binance, Code: code, Msg: e.Msg}
+	}
+	return &common.ExchangeError{Exchange: common.Binance, Code: code, Msg: e.Msg}
+}
+
+// EventTime 推送中的事件时间 E，没有时返回零值（如 depth20 快照）
+func (s *Stream) EventTime() time.Time {
+	var v struct {
+		E int64 `json:"E"`
+	}
+	if len(s.Data) == 0 || json.Unmarshal(s.Data, &v) != nil || v.E <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(v.E)
 }
@@ -2,25 +2,39 @@ package binance
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/simonks2016/dex_plus/binance/internal"
 	"github.com/simonks2016/dex_plus/binance/payload"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/ratelimit"
+	"github.com/simonks2016/dex_plus/stream"
 )
 
 type Public struct {
-	client  *internal.BinanceClient
-	logger  *log.Logger
+	ctx     context.Context
+	client  *internal.BinancePool
+	cfg     *client.Config
+	logger  *slog.Logger
 	symbols []string
+	streams stream.Group
+	restURL string // 获取盘口快照的 REST 地址
 }
 
 func NewPublic(ctx context.Context, symbol ...string) *Public {
+	return New(ctx, options.WithSymbols(symbol...))
+}
+
+func New(ctx context.Context, opts ...Option) *Public {
 
 	cfg := client.NewConfig()
-	cfg.WithURL(internal.WsURL)
+	cfg.WithEndpoints(internal.WsEndpoints...)
+	cfg.WithRateLimit(ratelimit.DefaultRegistry.Get(common.Binance), ratelimit.Wait)
 	cfg.SetReadTimeout(time.Minute)
 	cfg.SetReadWorkerNum(10)
 	cfg.SetWriteTimeout(time.Minute)
@@ -30,13 +44,22 @@ func NewPublic(ctx context.Context, symbol ...string) *Public {
 	// 每5秒就发送ping
 	cfg.SetPingInterval(time.Duration(5) * time.Second)
 	cfg.ForbidIPV6()
-
-	cli := internal.NewBinanceClient(ctx, nil, cfg)
-
-	return &Public{
-		client:  cli,
-		symbols: symbol,
+	// BackpressureConflate 时按 stream 名（已包含品种）合并
+	cfg.SetConflateKey(internal.MessageKey)
+
+	p := &Public{
+		ctx:     ctx,
+		cfg:     cfg,
+		symbols: []string{},
+		restURL: internal.RestURL,
 	}
+	o := options.New(cfg, opts...)
+	p.symbols = append(p.symbols, o.Symbols()...)
+	p.restURL = o.RestURL(p.restURL)
+
+	p.client = internal.NewBinancePool(ctx, nil, cfg)
+	p.logger = p.client.Logger()
+	return p
 }
 
 func subscribeChannel[T payload.BinancePayloadType](p *Public, channel string, callback func(string, T) error, opts ...SubscribeOption) {
@@ -44,61 +67,42 @@ func subscribeChannel[T payload.BinancePayloadType](p *Public, channel string, c
 	caller := func(symbol string, data json.RawMessage) error {
 
 		if d, err := payload.ParseData[T](data); err != nil {
-			if p.logger != nil {
-				p.logger.Printf("[error] failed to decode agg trade data: %v", err.Error())
-			}
+			p.logger.Error("failed to decode message", "channel", channel, "symbol", symbol, "error", err)
 			return err
 		} else {
 			return callback(symbol, d)
 		}
 	}
 
-	for _, symbol := range p.symbols {
-		pa := internal.SubscribeParams{
-			Channel:           channel,
-			Is100Ms:           false,
-			Symbol:            symbol,
-			ReturnChannelName: channel,
-		}
-		for _, opt := range opts {
-			opt(&pa)
-		}
-		p.client.Subscribe(&pa, caller)
+	pa := internal.SubscribeParams{
+		Channel:           channel,
+		Is100Ms:           true,
+		ReturnChannelName: channel,
+		Ordered:           options.NewSubscribeOptions(opts...).Ordered,
 	}
+	p.client.Subscribe(pa, p.symbols, caller)
 }
 
 // SubscribeTradeRaw 订阅逐笔交易
-func (p *Public) SubscribeTradeRaw(callback func(string, payload.Trade) error) {
-	subscribeChannel[payload.Trade](p, "trade", callback,
-		WithReturnChannelName("trade"),
-		WithIs100Ms(),
-	)
+func (p *Public) SubscribeTradeRaw(callback func(string, payload.Trade) error, opts ...SubscribeOption) {
+	subscribeChannel[payload.Trade](p, "trade", callback, opts...)
 }
 
 // SubscribeAggTrade 订阅归集交易
-func (p *Public) SubscribeAggTrade(callback func(string, payload.AggTrade) error) {
-	subscribeChannel[payload.AggTrade](p, "aggTrade", callback,
-		WithReturnChannelName("aggTrade"),
-		WithIs100Ms(),
-	)
+func (p *Public) SubscribeAggTrade(callback func(string, payload.AggTrade) error, opts ...SubscribeOption) {
+	subscribeChannel[payload.AggTrade](p, "aggTrade", callback, opts...)
 }
 
 // SubscribeOrderBookDelta 订阅增量盘口深度数据
-func (p *Public) SubscribeOrderBookDelta(callback func(string, payload.OrderBookDelta) error) {
+func (p *Public) SubscribeOrderBookDelta(callback func(string, payload.OrderBookDelta) error, opts ...SubscribeOption) {
 	//
-	subscribeChannel[payload.OrderBookDelta](p, "depth", callback,
-		WithReturnChannelName("depthUpdate"),
-		WithIs100Ms(),
-	)
+	subscribeChannel[payload.OrderBookDelta](p, "depth", callback, opts...)
 }
 
 // SubscribeOrderBook 订阅盘口快照数据
-func (p *Public) SubscribeOrderBook(callback func(string, payload.OrderBookSnapshot) error) {
+func (p *Public) SubscribeOrderBook(callback func(string, payload.OrderBookSnapshot) error, opts ...SubscribeOption) {
 
-	subscribeChannel[payload.OrderBookSnapshot](p, "depth20", callback,
-		WithReturnChannelName("depth20"),
-		WithIs100Ms(),
-	)
+	subscribeChannel[payload.OrderBookSnapshot](p, "depth20", callback, opts...)
 
 }
 
@@ -108,21 +112,46 @@ func (p *Public) Connect() {
 
 func (p *Public) Close() {
 	p.client.Close()
+	p.streams.Close()
 }
 
-type SubscribeOption func(params *internal.SubscribeParams)
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Public) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	// 回调处理完之后再关闭 Stream，阻塞在 Stream 上的回调随之退出
+	p.streams.Close()
+	return err
+}
 
-func WithReturnChannelName(name string) SubscribeOption {
+// Reconnect 重新连接，订阅超过单连接上限时按 stream 数量重新分配连接
+func (p *Public) Reconnect() {
+	p.client.Reconnect()
+}
 
-	return func(params *internal.SubscribeParams) {
-		params.ReturnChannelName = name
+// Subscribe 在已经订阅过的频道中追加品种，连接上立即生效并在重连后重放
+// channel 为 stream 中的频道名，如 "trade"、"aggTrade"、"depth"、"depth20"，需先通过 Subscribe* 注册回调
+func (p *Public) Subscribe(channel string, symbols ...string) error {
+	if !p.client.HasChannel(channel) {
+		return fmt.Errorf("channel %s has no handler, subscribe it with a Subscribe* method first", channel)
 	}
+	p.client.Subscribe(internal.SubscribeParams{Channel: channel}, symbols)
+	return nil
 }
 
-func WithIs100Ms() SubscribeOption {
-	return func(params *internal.SubscribeParams) {
-		params.Is100Ms = true
-	}
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有品种时回调一并移除
+func (p *Public) Unsubscribe(channel string, symbols ...string) error {
+	return p.client.Unsubscribe(channel, symbols...)
+}
+
+// SubscriptionState 订阅的当前状态，channel 为 stream 中的频道名；没有订阅过的为 common.SubscriptionUnsubscribed
+func (p *Public) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认、被拒绝或确认超时；回调不能阻塞
+func (p *Public) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
 }
 
 // ExchangeName 返回交易所名字
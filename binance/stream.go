@@ -0,0 +1,67 @@
+package binance
+
+import (
+	"context"
+	"iter"
+
+	"github.com/simonks2016/dex_plus/binance/payload"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
+)
+
+// openStream 按 stream 保序订阅并写入 Stream，ctx 结束或客户端关闭时 channel 关闭
+// ctx 结束后订阅仍然保留，不再需要时调用 Unsubscribe
+func openStream[T payload.BinancePayloadType](ctx context.Context, p *Public, subscribe func(func(string, T) error, ...SubscribeOption), opts []stream.Option) (<-chan stream.Item[T], error) {
+	return stream.SubscribeItems(ctx, &p.streams, func(push func(string, T) error) error {
+		subscribe(push, options.WithOrdered())
+		return nil
+	}, opts...)
+}
+
+// TradesStream 以 channel 的形式订阅逐笔交易
+func (p *Public) TradesStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.Trade], error) {
+	return openStream(ctx, p, p.SubscribeTradeRaw, opts)
+}
+
+// TradesSeq 以迭代器的形式订阅逐笔交易，退出循环时关闭
+func (p *Public) TradesSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.Trade], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.Trade], error) {
+		return p.TradesStream(ctx, opts...)
+	})
+}
+
+// AggTradesStream 以 channel 的形式订阅归集交易
+func (p *Public) AggTradesStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.AggTrade], error) {
+	return openStream(ctx, p, p.SubscribeAggTrade, opts)
+}
+
+// AggTradesSeq 以迭代器的形式订阅归集交易，退出循环时关闭
+func (p *Public) AggTradesSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.AggTrade], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.AggTrade], error) {
+		return p.AggTradesStream(ctx, opts...)
+	})
+}
+
+// BookDeltasStream 以 channel 的形式订阅增量盘口深度数据
+func (p *Public) BookDeltasStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.OrderBookDelta], error) {
+	return openStream(ctx, p, p.SubscribeOrderBookDelta, opts)
+}
+
+// BookDeltasSeq 以迭代器的形式订阅增量盘口深度数据，退出循环时关闭
+func (p *Public) BookDeltasSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.OrderBookDelta], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.OrderBookDelta], error) {
+		return p.BookDeltasStream(ctx, opts...)
+	})
+}
+
+// BooksStream 以 channel 的形式订阅盘口快照数据
+func (p *Public) BooksStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.OrderBookSnapshot], error) {
+	return openStream(ctx, p, p.SubscribeOrderBook, opts)
+}
+
+// BooksSeq 以迭代器的形式订阅盘口快照数据，退出循环时关闭
+func (p *Public) BooksSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.OrderBookSnapshot], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.OrderBookSnapshot], error) {
+		return p.BooksStream(ctx, opts...)
+	})
+}
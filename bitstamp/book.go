@@ -0,0 +1,237 @@
+package bitstamp
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/simonks2016/book_manager"
+	"github.com/simonks2016/dex_plus/bitstamp/internal"
+	"github.com/simonks2016/dex_plus/bitstamp/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+const (
+	// DefaultBookDepth 快照回调的档位数
+	DefaultBookDepth = 20
+	// maxPendingDiffs 收到快照之前每个品种最多缓存的增量，超出时丢弃最早的
+	maxPendingDiffs = 1000
+)
+
+// sequence 一个品种的播种状态
+type sequence struct {
+	// seeded 已经用 order_book 快照播种
+	seeded bool
+	// last 最近一次应用的 microtimestamp
+	last int64
+	// pending 播种之前收到的增量
+	pending []diff
+}
+
+type diff struct {
+	micro int64
+	book  payload.OrderBook
+}
+
+// bookSequencer 按 microtimestamp 排列 order_book 快照与 diff_order_book 增量
+// Bitstamp 的增量没有序号，两个频道之间也不保证顺序：以第一条快照播种，丢弃不晚于已应用时间的增量
+// 不是并发安全的，由 Public.bookMu 保护
+type bookSequencer struct {
+	books map[string]*sequence
+}
+
+func newBookSequencer() *bookSequencer {
+	return &bookSequencer{books: make(map[string]*sequence)}
+}
+
+func (s *bookSequencer) get(symbol string) *sequence {
+	seq, ok := s.books[symbol]
+	if !ok {
+		seq = &sequence{}
+		s.books[symbol] = seq
+	}
+	return seq
+}
+
+// snapshot 用快照播种，返回需要在快照之后重放的增量；已经播种时忽略快照，返回 false
+func (s *bookSequencer) snapshot(symbol string, micro int64) ([]payload.OrderBook, bool) {
+	seq := s.get(symbol)
+	if seq.seeded {
+		return nil, false
+	}
+	seq.seeded, seq.last = true, micro
+
+	slices.SortStableFunc(seq.pending, func(x, y diff) int { return cmp.Compare(x.micro, y.micro) })
+	var replay []payload.OrderBook
+	for _, d := range seq.pending {
+		if d.micro > seq.last {
+			replay = append(replay, d.book)
+			seq.last = d.micro
+		}
+	}
+	seq.pending = nil
+	return replay, true
+}
+
+// diff 是否应用增量；播种之前缓存增量，返回 false
+func (s *bookSequencer) diff(symbol string, micro int64, book payload.OrderBook) bool {
+	seq := s.get(symbol)
+	if !seq.seeded {
+		seq.pending = append(seq.pending, diff{micro: micro, book: book})
+		if len(seq.pending) > maxPendingDiffs {
+			seq.pending = slices.Delete(seq.pending, 0, len(seq.pending)-maxPendingDiffs)
+		}
+		return false
+	}
+	if micro <= seq.last {
+		return false
+	}
+	seq.last = micro
+	return true
+}
+
+// reset 丢弃品种的播种状态，等待下一条快照重新播种；不传品种时丢弃全部
+func (s *bookSequencer) reset(symbols ...string) {
+	if len(symbols) == 0 {
+		clear(s.books)
+		return
+	}
+	for _, symbol := range symbols {
+		delete(s.books, symbol)
+	}
+}
+
+// SubscribeLocalBook 同时订阅 order_book 与 diff_order_book，在 bookManager 中维护本地盘口，
+// 每隔 interval 回调一次前 DefaultBookDepth 档的快照
+// 以收到的第一条 order_book 快照（前 100 档）播种，之后按 microtimestamp 应用增量，数量为 0 的档位被删除；
+// 重连、提交队列已满或 bookManager 判定盘口异常时丢弃该品种的盘口，用下一条快照重新播种
+// 价格与数量按 /api/v2/trading-pairs-info/ 的 counter_decimals 与 base_decimals 换算，加载之前使用 8 位小数
+func (p *Public) SubscribeLocalBook(interval time.Duration, callback func([]market.BookSnapshot) error) {
+	p.client.SetOnConnected(func() {
+		p.bookMu.Lock()
+		p.books.reset()
+		p.bookMu.Unlock()
+	})
+
+	ordered := options.SubscribeOptions{Ordered: true}
+	p.subscribe(ordered, "order_book", p.handlingBookSnapshot, p.symbols...)
+	p.subscribe(ordered, "diff_order_book", p.handlingBookDiff, p.symbols...)
+	p.setSnapshotTimer(p.ctx, interval, callback)
+	go p.scales.Load(p.ctx, p.products, p.logger)
+}
+
+func (p *Public) handlingBookSnapshot(env *internal.Envelope) error {
+	book, err := payload.ParseData[payload.OrderBook](env)
+	if err != nil {
+		return err
+	}
+	symbol := env.GetSymbol()
+	micro, err := strconv.ParseInt(book.MicroTimestamp, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	p.bookMu.Lock()
+	defer p.bookMu.Unlock()
+	replay, ok := p.books.snapshot(symbol, micro)
+	if !ok {
+		return nil
+	}
+	// 播种时确定精度，缓存的增量与之后的增量沿用同一个精度
+	prec, _ := p.scales.ForSnapshot(symbol)
+	if !p.submitBook(symbol, bookManager.EventSnapshot, book, prec) {
+		return nil
+	}
+	for _, d := range replay {
+		if !p.submitBook(symbol, bookManager.EventUpdate, d, prec) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *Public) handlingBookDiff(env *internal.Envelope) error {
+	book, err := payload.ParseData[payload.OrderBook](env)
+	if err != nil {
+		return err
+	}
+	symbol := env.GetSymbol()
+	micro, err := strconv.ParseInt(book.MicroTimestamp, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	p.bookMu.Lock()
+	defer p.bookMu.Unlock()
+	if p.books.diff(symbol, micro, book) {
+		p.submitBook(symbol, bookManager.EventUpdate, book, p.scales.ForBook(symbol))
+	}
+	return nil
+}
+
+// submitBook 提交到 bookManager，队列已满时丢弃盘口等待重新播种；调用方持有 p.bookMu
+func (p *Public) submitBook(symbol string, typ bookManager.BookEventType, book payload.OrderBook, prec bookscale.Precision) bool {
+	levels := make([]bookManager.Level, 0, len(book.Bids)+len(book.Asks))
+	levels = appendLevels(levels, book.Bids, true, prec)
+	levels = appendLevels(levels, book.Asks, false, prec)
+
+	if !p.bookManager.Submit(bookManager.BookEvent{
+		Symbol: symbol,
+		Type:   typ,
+		Ts:     parseMicro(book.MicroTimestamp),
+		Levels: levels,
+	}) {
+		p.logger.Warn("failed to submit order book event, the queue is full", "channel", "diff_order_book", "symbol", symbol)
+		p.books.reset(symbol)
+		return false
+	}
+	return true
+}
+
+// appendLevels 按精度解析 [价格, 数量] 档位，数量为 0 表示删除该价位
+func appendLevels(levels []bookManager.Level, items [][]string, isBid bool, prec bookscale.Precision) []bookManager.Level {
+	for _, item := range items {
+		if len(item) < 2 {
+			continue
+		}
+		level, err := prec.ParseLevel(item[0], item[1], isBid)
+		if err != nil {
+			continue
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// setSnapshotTimer 定时回调 bookManager 中的前 N 档快照
+func (p *Public) setSnapshotTimer(ctx context.Context, interval time.Duration, callback func([]market.BookSnapshot) error) {
+	p.bookManager.StartSnapshotTimerAsync(ctx, interval, DefaultBookDepth, func(snapshots []bookManager.TopNSnapshot) {
+		if len(snapshots) == 0 {
+			return
+		}
+
+		resp := make([]market.BookSnapshot, len(snapshots))
+		for i, snapshot := range snapshots {
+			prec := p.scales.ForBook(snapshot.ProductID)
+			resp[i] = market.BookSnapshot{
+				Exchange:  common.Bitstamp,
+				Symbol:    snapshot.ProductID,
+				Bids:      prec.Levels(snapshot.Bids),
+				Asks:      prec.Levels(snapshot.Asks),
+				Timestamp: time.UnixMilli(snapshot.Ts),
+			}
+		}
+
+		// 异步执行回调，防止阻塞管理器
+		go func(d []market.BookSnapshot) {
+			if err := callback(d); err != nil {
+				p.logger.Error("failed to handle book snapshot", "channel", "diff_order_book", "error", err)
+			}
+		}(resp)
+	})
+}
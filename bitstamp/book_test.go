@@ -0,0 +1,86 @@
+package bitstamp
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/bitstamp/payload"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestBookSequencer(t *testing.T) {
+	s := newBookSequencer()
+	book := func(micro string) payload.OrderBook { return payload.OrderBook{MicroTimestamp: micro} }
+
+	// 播种之前的增量被缓存，乱序到达
+	for _, micro := range []int64{30, 10, 20} {
+		if s.diff("btcusd", micro, book("")) {
+			t.Fatalf("diff %d applied before snapshot", micro)
+		}
+	}
+	replay, ok := s.snapshot("btcusd", 15)
+	if !ok || len(replay) != 2 {
+		t.Fatalf("replay %d diffs, ok = %v; want 2", len(replay), ok)
+	}
+	if _, ok := s.snapshot("btcusd", 40); ok {
+		t.Fatal("seeded book accepted another snapshot")
+	}
+
+	if s.diff("btcusd", 30, book("")) {
+		t.Fatal("stale diff applied")
+	}
+	if !s.diff("btcusd", 31, book("")) {
+		t.Fatal("newer diff not applied")
+	}
+
+	s.reset("btcusd")
+	if s.diff("btcusd", 50, book("")) {
+		t.Fatal("diff applied after reset")
+	}
+	if _, ok := s.snapshot("btcusd", 45); !ok {
+		t.Fatal("snapshot after reset not accepted")
+	}
+}
+
+func TestLocalBookPrecisionOffline(t *testing.T) {
+	srv := dextest.NewBitstampServer()
+	defer srv.Close()
+	srv.HandleJSON("/api/v2/trading-pairs-info/", []payload.TradingPair{
+		{Name: "BTC/USD", UrlSymbol: "btcusd", CounterDecimals: 0, BaseDecimals: 8},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := New(ctx,
+		options.WithURL(srv.URL()),
+		options.WithRestURL(srv.HTTPURL()),
+		options.WithSymbols("btcusd"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	p.SubscribeLocalBook(time.Hour, func([]market.BookSnapshot) error { return nil })
+
+	want := bookscale.Precision{PriceDecimals: 0, SizeDecimals: 8}
+	deadline := time.Now().Add(5 * time.Second)
+	for p.scales.ForBook("btcusd") != want {
+		if time.Now().After(deadline) {
+			t.Fatal("book precision not loaded from counter_decimals and base_decimals")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 价格按 counter_decimals 换算成整数，精度之外的价格被丢弃
+	levels := appendLevels(nil, [][]string{{"42000", "0.5"}, {"42000.5", "1"}}, true, want)
+	if len(levels) != 1 || levels[0].PriceTicks != 42000 {
+		t.Fatalf("levels = %+v, want one level at 42000", levels)
+	}
+	if got := want.Size(levels[0].Size).String(); got != "0.50000000" {
+		t.Fatalf("size = %s, want 0.50000000", got)
+	}
+}
@@ -0,0 +1,57 @@
+package bitstamp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/simonks2016/dex_plus/bitstamp/internal"
+	"github.com/simonks2016/dex_plus/bitstamp/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/httpClient"
+)
+
+// InstrumentLoader 通过 /api/v2/trading-pairs-info/ 加载 Bitstamp 产品信息
+type InstrumentLoader struct {
+	BaseURL string
+	client  *httpClient.Client
+}
+
+func NewInstrumentLoader() *InstrumentLoader {
+	return &InstrumentLoader{
+		BaseURL: internal.RestURL,
+		client:  httpClient.NewClient(httpClient.Config{WorkerSize: 1, Exchange: common.Bitstamp}),
+	}
+}
+
+func (l *InstrumentLoader) Exchange() string { return common.Bitstamp }
+
+func (l *InstrumentLoader) LoadInstruments(ctx context.Context) ([]common.Instrument, error) {
+	var pairs []payload.TradingPair
+	if err := l.client.GetJSON(ctx, l.BaseURL+"/api/v2/trading-pairs-info/", nil, &pairs); err != nil {
+		return nil, err
+	}
+
+	ret := make([]common.Instrument, 0, len(pairs))
+	for _, p := range pairs {
+		// name 形如 "BTC/USD"
+		base, quote, ok := strings.Cut(p.Name, "/")
+		if !ok {
+			continue
+		}
+		inst := common.Instrument{
+			Exchange: common.Bitstamp,
+			Symbol:   p.UrlSymbol,
+			Type:     common.InstrumentSpot,
+			Base:     base,
+			Quote:    quote,
+			TickSize: common.NewDecimal(1, int32(p.CounterDecimals)),
+			LotSize:  common.NewDecimal(1, int32(p.BaseDecimals)),
+		}
+		// minimum_order 形如 "10.0 USD"，单位是计价货币
+		if amount, _, _ := strings.Cut(p.MinimumOrder, " "); amount != "" {
+			inst.MinNotional, _ = common.ParseDecimal(amount)
+		}
+		ret = append(ret, inst)
+	}
+	return ret, nil
+}
@@ -3,59 +3,106 @@ package internal
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
 	"github.com/simonks2016/dex_plus/bitstamp/params"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/watchdog"
 )
 
 type BitstampClient struct {
-	ctx           context.Context
-	client        *client.WsClient
-	logger        *log.Logger
-	pool          *ants.Pool
-	cfg           *client.Config
-	authDone      atomic.Bool
-	isConnected   atomic.Bool
-	isRequireAuth bool
-	handler       map[string][]Caller
+	ctx            context.Context
+	client         client.Client
+	logger         *slog.Logger
+	pool           *ants.Pool
+	cfg            *client.Config
+	authDone       atomic.Bool
+	isConnected    atomic.Bool
+	isRequireAuth  bool
+	handler        map[string][]Caller
+	orderedHandler map[string][]Caller    // 保序订阅的回调，在读协程里直接执行
+	feeds          map[string]*feed       // 按频道记录的回调与品种，运行时追加品种时沿用
+	handlerMu      sync.RWMutex           // 运行时订阅、取消订阅与读协程并发访问回调表
+	watchdog       *watchdog.Watchdog     // 静默检测，未配置时为 nil
+	tracker        *subscription.Tracker  // 订阅状态机，按 bts:subscription_succeeded 确认
+	onConnected    atomic.Pointer[func()] // 每次连接建立后回调，用于重建本地状态
+}
+
+type feed struct {
+	callers []Caller // 普通回调
+	ordered []Caller // 保序回调
+	symbols []string
 }
 
 func NewBitstampClient(ctx context.Context, cfg *client.Config) *BitstampClient {
+	cfg.SetDispatchKey(MessageKey)
 
 	pool, _ := ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
 
 	cli := BitstampClient{
-		ctx:           ctx,
-		client:        client.NewWsClient(ctx, cfg),
-		logger:        cfg.Logger,
-		pool:          pool,
-		cfg:           cfg,
-		isRequireAuth: cfg.IsNeedAuth,
-		handler:       make(map[string][]Caller),
+		ctx:            ctx,
+		client:         client.NewClient(ctx, cfg),
+		logger:         client.OrDefaultLogger(cfg.Logger).With("exchange", "bitstamp"),
+		pool:           pool,
+		cfg:            cfg,
+		isRequireAuth:  cfg.IsNeedAuth,
+		handler:        make(map[string][]Caller),
+		orderedHandler: make(map[string][]Caller),
+		feeds:          make(map[string]*feed),
 	}
+	cli.tracker = subscription.New(cfg.Subscription(), cli.sendRequest, cli.logger)
 	cli.client.SetObserver(&cli)
+	if cfg.Watchdog != nil {
+		cli.watchdog = watchdog.New(*cfg.Watchdog, &cli, cli.logger)
+	}
 	return &cli
 }
 
+// SetOnConnected 每次连接建立（含重连）后、重放订阅之前回调；回调不能阻塞
+func (cli *BitstampClient) SetOnConnected(fn func()) {
+	cli.onConnected.Store(&fn)
+}
+
+func (cli *BitstampClient) Logger() *slog.Logger {
+	return cli.logger
+}
+
 // Connect 连接
 func (cli *BitstampClient) Connect() {
+	cli.watchdog.Start(cli.ctx)
 	cli.client.Start()
 }
 
 // Close 关闭连接
 func (cli *BitstampClient) Close() {
+	cli.watchdog.Stop()
 	cli.client.Close()
 }
 
+// Shutdown 取消订阅并停止接收，等待已收到的消息回调完成后释放协程池
+func (cli *BitstampClient) Shutdown(ctx context.Context) error {
+	cli.watchdog.Stop()
+	err := cli.client.Shutdown(ctx)
+	return errors.Join(err, client.ReleasePool(ctx, cli.pool))
+}
+
+// Reconnect 重连
+func (cli *BitstampClient) Reconnect(reason string) {
+	cli.client.Reconnect(reason)
+}
+
 // Send 发送信息
 func (cli *BitstampClient) Send(dataBytes []byte) error {
 
 	if !cli.isConnected.Load() {
-		return errors.New("the client has been disconnected")
+		return common.ErrNotConnected
 	}
 	ctx, cancel := context.WithTimeout(cli.ctx, time.Second*time.Duration(5))
 	defer cancel()
@@ -65,26 +112,190 @@ func (cli *BitstampClient) Send(dataBytes []byte) error {
 
 // Subscribe 订阅
 func (cli *BitstampClient) Subscribe(channel string, call func(*Envelope) error, symbols ...string) {
+	cli.subscribe(channel, false, []Caller{call}, symbols...)
+}
 
-	channels := make([]string, 0, len(symbols))
+// SubscribeOrdered 同 Subscribe，但同一个 channel 的消息按到达顺序串行回调，需要在 Connect 之前调用
+func (cli *BitstampClient) SubscribeOrdered(channel string, call func(*Envelope) error, symbols ...string) {
+	cli.subscribe(channel, true, []Caller{call}, symbols...)
+}
+
+// SubscribeSymbols 在已经订阅过的频道中追加品种，沿用该频道的回调
+func (cli *BitstampClient) SubscribeSymbols(channel string, symbols ...string) {
+	cli.subscribe(channel, false, nil, symbols...)
+}
 
+// subscribe 注册回调并订阅频道中的品种，连接已建立时立即发送新增的品种
+// 新增的品种注册该频道全部的回调，已订阅的品种只按本次的 ordered 追加本次的回调
+func (cli *BitstampClient) subscribe(channel string, ordered bool, calls []Caller, symbols ...string) {
+	var fresh []string
+	cli.handlerMu.Lock()
+	f, ok := cli.feeds[channel]
+	if !ok {
+		f = &feed{}
+		cli.feeds[channel] = f
+	}
+	handlers := cli.handler
+	if ordered {
+		f.ordered = append(f.ordered, calls...)
+		handlers = cli.orderedHandler
+	} else {
+		f.callers = append(f.callers, calls...)
+	}
 	for _, symbol := range symbols {
 		ch := channel + "_" + symbol
-		channels = append(channels, ch)
-		cli.handler[ch] = append(cli.handler[ch], call)
+		if slices.Contains(f.symbols, symbol) {
+			if len(calls) > 0 {
+				handlers[ch] = append(slices.Clip(handlers[ch]), calls...)
+			}
+			continue
+		}
+		f.symbols = append(f.symbols, symbol)
+		if len(f.callers) > 0 {
+			cli.handler[ch] = slices.Clone(f.callers)
+		}
+		if len(f.ordered) > 0 {
+			cli.orderedHandler[ch] = slices.Clone(f.ordered)
+		}
+		fresh = append(fresh, symbol)
+	}
+	cli.handlerMu.Unlock()
+
+	for _, symbol := range fresh {
+		cli.watchdog.Watch(channel, symbol)
+	}
+	// 连接建立前只记录状态，连接后在 OnConnected 中重放
+	if err := cli.tracker.Subscribe(feeds(channel, fresh...)...); err != nil {
+		cli.logger.Error("failed to subscribe channel", "conn_id", cli.client.ConnID(), "channel", channel, "error", err)
+	}
+}
+
+// sendRequest 按 channel_symbol 逐个发送订阅；Bitstamp 不回传请求 id，按确认消息中的频道确认
+func (cli *BitstampClient) sendRequest(req subscription.Request) error {
+	for i, f := range req.Feeds {
+		if i > 0 {
+			time.Sleep(time.Millisecond * time.Duration(5))
+		}
+		p1 := params.NewSubscribeParams(f.Channel + "_" + f.Symbol)
+		if err := cli.Send(p1.Json()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscriptions 全部已订阅的 (channel, symbol)
+func (cli *BitstampClient) subscriptions() []subscription.Feed {
+	cli.handlerMu.RLock()
+	defer cli.handlerMu.RUnlock()
+	var ret []subscription.Feed
+	for channel, f := range cli.feeds {
+		ret = append(ret, feeds(channel, f.symbols...)...)
+	}
+	return ret
+}
+
+// SubscriptionState 订阅的当前状态
+func (cli *BitstampClient) SubscriptionState(channel, symbol string) subscription.State {
+	return cli.tracker.State(channel, symbol)
+}
+
+// SetOnSubscriptionChange 设置订阅状态变化回调
+func (cli *BitstampClient) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	cli.tracker.SetOnChange(fn)
+}
+
+func feeds(channel string, symbols ...string) []subscription.Feed {
+	ret := make([]subscription.Feed, 0, len(symbols))
+	for _, symbol := range symbols {
+		ret = append(ret, subscription.Feed{Channel: channel, Symbol: symbol})
+	}
+	return ret
+}
+
+// HasChannel 频道是否注册过回调
+func (cli *BitstampClient) HasChannel(channel string) bool {
+	cli.handlerMu.RLock()
+	defer cli.handlerMu.RUnlock()
+	_, ok := cli.feeds[channel]
+	return ok
+}
+
+// Unsubscribe 取消订阅频道中的品种并移除对应的回调，不传品种时取消整个频道
+func (cli *BitstampClient) Unsubscribe(channel string, symbols ...string) error {
+	var removed []string
+	cli.handlerMu.Lock()
+	f, ok := cli.feeds[channel]
+	if !ok {
+		cli.handlerMu.Unlock()
+		return nil
+	}
+	kept := make([]string, 0, len(f.symbols))
+	for _, symbol := range f.symbols {
+		if len(symbols) == 0 || slices.Contains(symbols, symbol) {
+			removed = append(removed, symbol)
+			delete(cli.handler, channel+"_"+symbol)
+			delete(cli.orderedHandler, channel+"_"+symbol)
+			continue
+		}
+		kept = append(kept, symbol)
 	}
+	f.symbols = kept
+	if len(kept) == 0 {
+		delete(cli.feeds, channel)
+	}
+	cli.handlerMu.Unlock()
+
+	for _, symbol := range removed {
+		cli.watchdog.Forget(channel, symbol)
+	}
+	cli.tracker.Remove(feeds(channel, removed...)...)
+	if !cli.authDone.Load() {
+		return nil
+	}
+	for _, symbol := range removed {
+		p1 := params.NewUnsubscribeParams(channel + "_" + symbol)
+		if err := cli.Send(p1.Json()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResubscribeFeed 先取消再重新订阅一个 (channel, symbol)
+func (cli *BitstampClient) ResubscribeFeed(channel, symbol string) error {
+	ch := channel + "_" + symbol
+	p1 := params.NewUnsubscribeParams(ch)
+	if err := cli.Send(p1.Json()); err != nil {
+		return err
+	}
+	return cli.tracker.Subscribe(feeds(channel, symbol)...)
+}
+
+// channels 全部已订阅的频道
+func (cli *BitstampClient) channels() []string {
+	cli.handlerMu.RLock()
+	defer cli.handlerMu.RUnlock()
+	ret := make([]string, 0, len(cli.handler)+len(cli.orderedHandler))
+	for ch := range cli.handler {
+		ret = append(ret, ch)
+	}
+	for ch := range cli.orderedHandler {
+		if _, ok := cli.handler[ch]; !ok {
+			ret = append(ret, ch)
+		}
+	}
+	return ret
 }
 
-// Unsubscribe 取消订阅
-func (cli *BitstampClient) Unsubscribe() {
+// UnsubscribeAll 取消全部订阅，保留回调以便重连后重新订阅
+func (cli *BitstampClient) UnsubscribeAll() {
 
-	for channelName, _ := range cli.handler {
+	for _, channelName := range cli.channels() {
 		p1 := params.NewUnsubscribeParams(channelName)
 		// 发送信息
 		if err := cli.Send(p1.Json()); err != nil {
-			if cli.logger != nil {
-				cli.logger.Printf("[error] failed to unsubscribe channel,%s", err.Error())
-			}
+			cli.logger.Error("failed to unsubscribe channel", "conn_id", cli.client.ConnID(), "channel", channelName, "error", err)
 			return
 		}
 	}
@@ -1,5 +1,6 @@
 package internal
 
 const (
-	WsURL = "wss://ws.bitstamp.net/"
+	WsURL   = "wss://ws.bitstamp.net/"
+	RestURL = "https://www.bitstamp.net"
 )
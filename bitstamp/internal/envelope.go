@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-json"
 )
@@ -22,3 +24,27 @@ func (e *Envelope) GetSymbol() string {
 	}
 	return e.Channel[idx+1:]
 }
+
+// MessageKey 消息的 (channel, symbol) 键，用于按键合并与按键顺序分发
+func MessageKey(data []byte) string {
+	var v struct {
+		Channel string `json:"channel"`
+	}
+	_ = json.Unmarshal(data, &v)
+	return v.Channel
+}
+
+// Timestamp 推送中的 microtimestamp，没有时返回零值
+func (e *Envelope) Timestamp() time.Time {
+	var v struct {
+		MicroTimestamp string `json:"microtimestamp"`
+	}
+	if len(e.Data) == 0 || json.Unmarshal(e.Data, &v) != nil {
+		return time.Time{}
+	}
+	us, err := strconv.ParseInt(v.MicroTimestamp, 10, 64)
+	if err != nil || us <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us)
+}
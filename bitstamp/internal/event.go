@@ -1,62 +1,69 @@
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/goccy/go-json"
 
-	"github.com/simonks2016/dex_plus/bitstamp/params"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/subscription"
 )
 
 func (b *BitstampClient) OnConnecting(reason string) {
-	//TODO implement me
-	fmt.Println(reason)
+	b.logger.Info("connecting", "conn_id", b.client.ConnID(), "reason", reason)
+}
+
+// OnEndpoint 连接上的地址变化
+func (b *BitstampClient) OnEndpoint(url string) {
+	b.logger.Info("endpoint active", "url", url)
 }
 
 func (b *BitstampClient) OnConnected() {
-	//TODO implement me
-	fmt.Println("On Connected")
+	b.logger.Info("connected", "conn_id", b.client.ConnID())
+	b.watchdog.Resume()
 	b.isConnected.Store(true)
+	if fn := b.onConnected.Load(); fn != nil && *fn != nil {
+		(*fn)()
+	}
 
 	if !b.isRequireAuth {
 		b.authDone.Store(true)
-		// 循环订阅频道
-		for channel, _ := range b.handler {
-			p1 := params.NewSubscribeParams(channel)
-			// 发送订阅信息
-			if err := b.Send(p1.Json()); err != nil {
-				if b.logger != nil {
-					b.logger.Printf("[error]failed to subscribe channel,%s,error:%s", channel, err.Error())
-				}
-				return
-			}
-			time.Sleep(time.Millisecond * time.Duration(5))
+		b.tracker.Resume()
+		// 重放全部订阅，等待 bts:subscription_succeeded 确认
+		if err := b.tracker.Subscribe(b.subscriptions()...); err != nil {
+			b.logger.Error("failed to subscribe channels", "conn_id", b.client.ConnID(), "error", err)
 		}
 	}
 
 }
 
 func (b *BitstampClient) OnDisconnecting() {
-	//TODO implement me
-	fmt.Println("On Disconnecting")
+	b.logger.Info("disconnecting", "conn_id", b.client.ConnID())
 	// 取消全部订阅
-	b.Unsubscribe()
+	b.UnsubscribeAll()
 }
 
 func (b *BitstampClient) OnDisconnected() {
-	//TODO implement me
-	fmt.Println("On Disconnected")
+	b.isConnected.Store(false)
+	b.authDone.Store(false)
+	b.tracker.Pause()
+	b.watchdog.Pause()
+	b.logger.Info("disconnected", "conn_id", b.client.ConnID())
 }
 
 func (b *BitstampClient) OnMessage(data []byte) error {
+	return b.OnTimedMessage(data, time.Time{})
+}
+
+// OnTimedMessage received 为收到该帧的时间，配置了延迟统计时按推送中的时间戳记录线路延迟
+func (b *BitstampClient) OnTimedMessage(data []byte, received time.Time) error {
 	var result Envelope
 	if err := json.Unmarshal(data, &result); err != nil {
-		// 建议使用 logger 而不是 fmt.Println，保持日志一致性
 		return fmt.Errorf("unmarshal envelope: %w", err)
 	}
+	b.cfg.Latency.ObserveWire(result.Timestamp(), received)
 
 	// 1. 预处理 Event 字符串，避免多次调用 ToLower
 	event := strings.ToLower(result.Event)
@@ -64,19 +71,39 @@ func (b *BitstampClient) OnMessage(data []byte) error {
 
 	// 2. 使用 switch 替代多个 if，逻辑更清晰且性能稍好
 	switch event {
-	case "bts:subscription_succeeded", "bts:unsubscription_succeeded":
-		if b.logger != nil {
-			b.logger.Printf("[success] %s channel: %s", event, channel)
+	case "bts:subscription_succeeded":
+		b.logger.Info("subscription_succeeded", "conn_id", b.client.ConnID(), "channel", channel)
+		if symbol := result.GetSymbol(); symbol != "" {
+			b.tracker.AckFeed("", subscription.Feed{Channel: strings.TrimSuffix(channel, "_"+symbol), Symbol: symbol}, nil)
 		}
 		return nil
 
+	case "bts:unsubscription_succeeded":
+		b.logger.Info("unsubscription_succeeded", "conn_id", b.client.ConnID(), "channel", channel)
+		return nil
+
 	case "bts:error":
 		return b.handleErrorMessage(result.Data)
 	}
 
-	// 3. 处理业务消息
-	callers, exists := b.handler[channel]
-	if !exists {
+	if symbol := result.GetSymbol(); symbol != "" {
+		b.watchdog.Touch(strings.TrimSuffix(channel, "_"+symbol), symbol)
+	}
+
+	b.handlerMu.RLock()
+	ordered := b.orderedHandler[channel]
+	callers := b.handler[channel]
+	b.handlerMu.RUnlock()
+
+	// 3. 保序回调，读协程已按 channel 分区，直接执行
+	for _, caller := range ordered {
+		if err := caller(&result); err != nil {
+			b.logger.Error("failed to handle message", "channel", channel, "event", event, "error", err)
+		}
+	}
+
+	// 4. 处理业务消息
+	if len(callers) == 0 {
 		return nil
 	}
 
@@ -88,9 +115,7 @@ func (b *BitstampClient) OnMessage(data []byte) error {
 
 		err := b.pool.Submit(func() {
 			if err := currentCaller(&currentResult); err != nil {
-				if b.logger != nil {
-					b.logger.Printf("[error] failed to parse message of %s, error: %v", event, err)
-				}
+				b.logger.Error("failed to handle message", "channel", channel, "event", event, "error", err)
 			}
 		})
 		if err != nil {
@@ -112,12 +137,15 @@ func (b *BitstampClient) handleErrorMessage(rawData json.RawMessage) error {
 	if msg == "" {
 		msg = "unknown bitstamp error"
 	}
+	code := ""
+	if c, ok := d1["code"]; ok && c != nil {
+		code = fmt.Sprint(c)
+	}
 
-	b.OnError(errors.New(msg))
+	b.OnError(&common.ExchangeError{Exchange: common.Bitstamp, Code: code, Msg: msg})
 	return nil
 }
 
 func (b *BitstampClient) OnError(err error) {
-	//TODO implement me
-	fmt.Println(err.Error())
+	b.logger.Error("client error", "conn_id", b.client.ConnID(), "error", err)
 }
@@ -0,0 +1,86 @@
+package bitstamp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/simonks2016/dex_plus/bitstamp/payload"
+	"github.com/simonks2016/dex_plus/candles"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+var _ market.MarketDataSource = (*Public)(nil)
+
+// OnTrade 订阅统一格式的成交数据
+func (p *Public) OnTrade(handler market.TradeHandler) error {
+	p.SubscribeTrades(func(symbol string, t payload.Trade) error {
+		// type: 0 买入，1 卖出
+		side := market.Buy
+		if t.Type == 1 {
+			side = market.Sell
+		}
+		return handler(market.Trade{
+			Exchange:  common.Bitstamp,
+			Symbol:    symbol,
+			TradeId:   t.IdStr,
+			Price:     t.PriceDecimal(),
+			Size:      t.AmountDecimal(),
+			Side:      side,
+			Timestamp: parseMicro(t.MicroTimestamp),
+		})
+	})
+	return nil
+}
+
+// OnBookSnapshot 订阅盘口快照，按推送频率回调
+func (p *Public) OnBookSnapshot(_ time.Duration, handler market.BookSnapshotHandler) error {
+	p.SubscribeOrderBook(func(symbol string, book payload.OrderBook) error {
+		return handler(market.BookSnapshot{
+			Exchange:  common.Bitstamp,
+			Symbol:    symbol,
+			Bids:      book.BidLevels(),
+			Asks:      book.AskLevels(),
+			Timestamp: parseMicro(book.MicroTimestamp),
+		})
+	})
+	return nil
+}
+
+// OnBookDelta 订阅盘口增量
+func (p *Public) OnBookDelta(handler market.BookDeltaHandler) error {
+	p.SubscribeOrderBookDelta(func(symbol string, book payload.OrderBook) error {
+		return handler(market.BookDelta{
+			Exchange:  common.Bitstamp,
+			Symbol:    symbol,
+			Bids:      book.BidLevels(),
+			Asks:      book.AskLevels(),
+			Timestamp: parseMicro(book.MicroTimestamp),
+		})
+	})
+	return nil
+}
+
+func (p *Public) OnTicker(market.TickerHandler) error { return market.ErrNotSupported }
+
+// OnCandle 由成交在本地合成统一格式的K线，未收盘的K线随每笔成交回调，下一个周期的第一笔成交到达时收盘
+func (p *Public) OnCandle(interval time.Duration, handler market.CandleHandler) error {
+	builder, err := candles.New(candles.Every(interval), candles.Config{Partial: true}, func(bar candles.Bar) error {
+		return handler(bar.Candle)
+	})
+	if err != nil {
+		return err
+	}
+	return p.OnTrade(builder.OnTrade)
+}
+
+// ExchangeName 交易所名字
+func (p *Public) ExchangeName() string { return "bitstamp" }
+
+func parseMicro(s string) time.Time {
+	us, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMicro(us)
+}
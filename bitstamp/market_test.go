@@ -0,0 +1,38 @@
+package bitstamp
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestOnTradeOffline(t *testing.T) {
+	srv := dextest.NewBitstampServer()
+	defer srv.Close()
+
+	p := New(t.Context(),
+		options.WithURL(srv.URL()),
+		options.WithSymbols("btcusd"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	defer p.Close()
+
+	// type 1 为卖单，时间取微秒时间戳，价格保留推送的字符串
+	dextest.ExpectOfflineTrade(t, srv.Server, p, "live_trades", func() error {
+		return srv.Push("live_trades_btcusd", "trade", map[string]any{
+			"id": 1, "id_str": "1", "amount": 0.25, "amount_str": "0.25000000",
+			"price": 42000.1, "price_str": "42000.10", "type": 1,
+			"timestamp": "1700000000", "microtimestamp": "1700000000000000",
+		})
+	}, market.Trade{
+		Exchange: common.Bitstamp, Symbol: "btcusd", TradeId: "1",
+		Price: common.MustDecimal("42000.10"), Size: common.MustDecimal("0.25000000"),
+		Side: market.Sell, Timestamp: time.UnixMicro(1700000000000000),
+	})
+}
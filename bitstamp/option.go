@@ -0,0 +1,11 @@
+package bitstamp
+
+import (
+	"github.com/simonks2016/dex_plus/options"
+)
+
+// Option 构造选项，见 options 包；静默检测的频道名不含品种后缀，如 "live_trades"、"diff_order_book"
+type Option = options.Option
+
+// SubscribeOption 订阅选项，WithOrdered 时同一个 channel（已包含品种）的消息按到达顺序串行回调
+type SubscribeOption = options.SubscribeOption
@@ -0,0 +1,26 @@
+package payload
+
+import "github.com/simonks2016/dex_plus/common"
+
+// Decimal 精确小数，使用 *_str 字段和盘口的字符串转换，不经过 float64
+type Decimal = common.Decimal
+
+func decimal(s string) Decimal {
+	d, _ := common.ParseDecimal(s)
+	return d
+}
+
+func (t Trade) PriceDecimal() Decimal  { return decimal(t.PriceStr) }
+func (t Trade) AmountDecimal() Decimal { return decimal(t.AmountStr) }
+
+// BidLevels 买盘档位（精确小数）
+func (o OrderBook) BidLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Bids)
+	return levels
+}
+
+// AskLevels 卖盘档位（精确小数）
+func (o OrderBook) AskLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Asks)
+	return levels
+}
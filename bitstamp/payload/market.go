@@ -0,0 +1,14 @@
+package payload
+
+// TradingPair GET /api/v2/trading-pairs-info/
+type TradingPair struct {
+	Name                        string `json:"name"`
+	UrlSymbol                   string `json:"url_symbol"`
+	BaseDecimals                int    `json:"base_decimals"`
+	CounterDecimals             int    `json:"counter_decimals"`
+	InstantOrderCounterDecimals int    `json:"instant_order_counter_decimals"`
+	MinimumOrder                string `json:"minimum_order"`
+	Trading                     string `json:"trading"`
+	InstantAndMarketOrders      string `json:"instant_and_market_orders"`
+	Description                 string `json:"description"`
+}
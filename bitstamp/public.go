@@ -2,40 +2,90 @@ package bitstamp
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"sync"
 
+	"github.com/simonks2016/book_manager"
 	"github.com/simonks2016/dex_plus/bitstamp/internal"
 	"github.com/simonks2016/dex_plus/bitstamp/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
 )
 
 type Public struct {
-	client  *internal.BitstampClient
-	logger  *log.Logger
-	ctx     context.Context
-	symbols []string
+	client      *internal.BitstampClient
+	cfg         *client.Config
+	logger      *slog.Logger
+	ctx         context.Context
+	symbols     []string
+	streams     stream.Group
+	bookManager *bookManager.BookManager
+	books       *bookSequencer
+	bookMu      sync.Mutex        // 保证快照、增量按播种顺序提交到 bookManager
+	products    *InstrumentLoader // 加载 counter_decimals 与 base_decimals，确定盘口价格与数量的小数位数
+	scales      *bookscale.Scales
 }
 
 func NewPublic(ctx context.Context, symbols ...string) *Public {
+	return New(ctx, options.WithSymbols(symbols...))
+}
+
+func New(ctx context.Context, opts ...Option) *Public {
 
 	cfg := client.NewConfig()
 	cfg.WithURL(internal.WsURL)
 	cfg.IsNeedAuth = false
+	// BackpressureConflate 时按 channel（已包含品种）合并
+	cfg.SetConflateKey(internal.MessageKey)
 
-	return &Public{
-		client:  internal.NewBitstampClient(ctx, cfg),
-		logger:  cfg.Logger,
+	p := &Public{
+		cfg:     cfg,
 		ctx:     ctx,
-		symbols: symbols,
+		symbols: []string{},
+		bookManager: bookManager.NewBookManagerWithWorkers(10, 4000,
+			bookManager.WithCrossedThreshold(10)),
+		books:    newBookSequencer(),
+		products: NewInstrumentLoader(),
+		scales:   bookscale.NewScales(),
 	}
+	o := options.New(cfg, opts...)
+	p.symbols = append(p.symbols, o.Symbols()...)
+	p.products.BaseURL = o.RestURL(p.products.BaseURL)
+
+	p.client = internal.NewBitstampClient(ctx, cfg)
+	p.logger = p.client.Logger()
+	p.bookManager.OnMarkDirty(func(symbol string, reason string, ev *bookManager.BookEvent, book *bookManager.OrderBook) {
+		// 用下一条 order_book 快照重新播种
+		p.logger.Warn("order book marked dirty, waiting for snapshot", "channel", "diff_order_book", "symbol", symbol, "reason", reason)
+		p.bookMu.Lock()
+		p.books.reset(symbol)
+		p.bookMu.Unlock()
+	})
+	return p
 }
 
 func (p *Public) Connect() { p.client.Connect() }
-func (p *Public) Close()   { p.client.Close() }
+func (p *Public) Close() {
+	p.client.Close()
+	p.streams.Close()
+}
+
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Public) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	// 回调处理完之后再关闭 Stream，阻塞在 Stream 上的回调随之退出
+	p.streams.Close()
+	return err
+}
 
 // SubscribeTrades 订阅成交数据
-func (p *Public) SubscribeTrades(callback func(string, payload.Trade) error) {
-	p.client.Subscribe("live_trades", func(env *internal.Envelope) error {
+func (p *Public) SubscribeTrades(callback func(string, payload.Trade) error, opts ...SubscribeOption) {
+	p.subscribe(options.NewSubscribeOptions(opts...), "live_trades", func(env *internal.Envelope) error {
 		data, err := payload.ParseData[payload.Trade](env)
 		if err != nil {
 			return err
@@ -45,8 +95,8 @@ func (p *Public) SubscribeTrades(callback func(string, payload.Trade) error) {
 }
 
 // SubscribeOrderBookDelta 订阅盘口增量数据
-func (p *Public) SubscribeOrderBookDelta(callback func(string, payload.OrderBook) error) {
-	p.client.Subscribe("diff_order_book", func(env *internal.Envelope) error {
+func (p *Public) SubscribeOrderBookDelta(callback func(string, payload.OrderBook) error, opts ...SubscribeOption) {
+	p.subscribe(options.NewSubscribeOptions(opts...), "diff_order_book", func(env *internal.Envelope) error {
 		data, err := payload.ParseData[payload.OrderBook](env)
 		if err != nil {
 			return err
@@ -56,9 +106,9 @@ func (p *Public) SubscribeOrderBookDelta(callback func(string, payload.OrderBook
 }
 
 // SubscribeOrderBook 订阅盘口快照数据
-func (p *Public) SubscribeOrderBook(callback func(string, payload.OrderBook) error) {
+func (p *Public) SubscribeOrderBook(callback func(string, payload.OrderBook) error, opts ...SubscribeOption) {
 
-	p.client.Subscribe("order_book", func(env *internal.Envelope) error {
+	p.subscribe(options.NewSubscribeOptions(opts...), "order_book", func(env *internal.Envelope) error {
 		data, err := payload.ParseData[payload.OrderBook](env)
 		if err != nil {
 			return err
@@ -67,6 +117,40 @@ func (p *Public) SubscribeOrderBook(callback func(string, payload.OrderBook) err
 	}, p.symbols...)
 }
 
+func (p *Public) subscribe(o options.SubscribeOptions, channel string, call func(*internal.Envelope) error, symbols ...string) {
+	if o.Ordered {
+		p.client.SubscribeOrdered(channel, call, symbols...)
+		return
+	}
+	p.client.Subscribe(channel, call, symbols...)
+}
+
+// Subscribe 在已经订阅过的频道中追加品种，沿用该频道的回调；连接上立即生效并在重连后重放
+// channel 为订阅时的频道名，如 "live_trades"、"diff_order_book"、"order_book"
+func (p *Public) Subscribe(channel string, symbols ...string) error {
+	if !p.client.HasChannel(channel) {
+		return fmt.Errorf("channel %s has no handler, subscribe it with a Subscribe* method first", channel)
+	}
+	p.client.SubscribeSymbols(channel, symbols...)
+	return nil
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；回调一并移除
+func (p *Public) Unsubscribe(channel string, symbols ...string) error {
+	return p.client.Unsubscribe(channel, symbols...)
+}
+
+// SubscriptionState 订阅的当前状态，没有订阅过的为 common.SubscriptionUnsubscribed
+// channel 不含品种后缀，如 "live_trades"
+func (p *Public) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认或确认超时；回调不能阻塞
+func (p *Public) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
+}
+
 // SetSymbols 设置品种
 func (p *Public) SetSymbols(symbols ...string) {
 	p.symbols = symbols
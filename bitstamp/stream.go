@@ -0,0 +1,55 @@
+package bitstamp
+
+import (
+	"context"
+	"iter"
+
+	"github.com/simonks2016/dex_plus/bitstamp/payload"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
+)
+
+// openStream 按 channel 保序订阅并写入 Stream，ctx 结束或客户端关闭时 channel 关闭
+// ctx 结束后订阅仍然保留，不再需要时调用 Unsubscribe
+func openStream[T any](ctx context.Context, p *Public, subscribe func(func(string, T) error, ...SubscribeOption), opts []stream.Option) (<-chan stream.Item[T], error) {
+	return stream.SubscribeItems(ctx, &p.streams, func(push func(string, T) error) error {
+		subscribe(push, options.WithOrdered())
+		return nil
+	}, opts...)
+}
+
+// TradesStream 以 channel 的形式订阅成交数据
+func (p *Public) TradesStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.Trade], error) {
+	return openStream(ctx, p, p.SubscribeTrades, opts)
+}
+
+// TradesSeq 以迭代器的形式订阅成交数据，退出循环时关闭
+func (p *Public) TradesSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.Trade], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.Trade], error) {
+		return p.TradesStream(ctx, opts...)
+	})
+}
+
+// BookDeltasStream 以 channel 的形式订阅盘口增量数据
+func (p *Public) BookDeltasStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.OrderBook], error) {
+	return openStream(ctx, p, p.SubscribeOrderBookDelta, opts)
+}
+
+// BookDeltasSeq 以迭代器的形式订阅盘口增量数据，退出循环时关闭
+func (p *Public) BookDeltasSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.OrderBook], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.OrderBook], error) {
+		return p.BookDeltasStream(ctx, opts...)
+	})
+}
+
+// BooksStream 以 channel 的形式订阅盘口快照数据
+func (p *Public) BooksStream(ctx context.Context, opts ...stream.Option) (<-chan stream.Item[payload.OrderBook], error) {
+	return openStream(ctx, p, p.SubscribeOrderBook, opts)
+}
+
+// BooksSeq 以迭代器的形式订阅盘口快照数据，退出循环时关闭
+func (p *Public) BooksSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[stream.Item[payload.OrderBook], error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan stream.Item[payload.OrderBook], error) {
+		return p.BooksStream(ctx, opts...)
+	})
+}
@@ -0,0 +1,399 @@
+// Package candles 由成交在本地合成K线，用于不提供K线推送的交易所，或需要成交笔数、成交量、成交额K线的策略
+// 时间K线按 UTC 对齐（1 分钟K线从整分开始，1 天K线从 UTC 0 点开始，周K线从周一开始），
+// 没有成交的周期不产生K线；K线内的开盘价与收盘价按成交时间确定，乱序到达的成交不会改变开收盘的顺序
+package candles
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simonks2016/dex_plus/clocksync"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+// Kind K线的类型
+type Kind int
+
+const (
+	// TimeBar 固定时间周期
+	TimeBar Kind = iota
+	// TickBar 固定成交笔数
+	TickBar
+	// VolumeBar 成交量达到阈值
+	VolumeBar
+	// DollarBar 成交额（价格 × 数量）达到阈值
+	DollarBar
+)
+
+func (k Kind) String() string {
+	switch k {
+	case TickBar:
+		return "tick"
+	case VolumeBar:
+		return "volume"
+	case DollarBar:
+		return "dollar"
+	default:
+		return "time"
+	}
+}
+
+// Spec K线的划分方式，使用 Every、Ticks、Volume、Dollars 创建
+type Spec struct {
+	Kind Kind
+	// Interval 时间K线的周期
+	Interval time.Duration
+	// Count 成交笔数K线的笔数
+	Count int
+	// Threshold 成交量K线的成交量或成交额K线的成交额，达到后收盘；最后一笔成交不拆分，收盘时可能超过阈值
+	Threshold common.Decimal
+}
+
+// Every 按时间周期划分
+func Every(interval time.Duration) Spec {
+	return Spec{Kind: TimeBar, Interval: interval}
+}
+
+// Ticks 每 n 笔成交一根K线
+func Ticks(n int) Spec {
+	return Spec{Kind: TickBar, Count: n}
+}
+
+// Volume 成交量达到 v 时收盘
+func Volume(v common.Decimal) Spec {
+	return Spec{Kind: VolumeBar, Threshold: v}
+}
+
+// Dollars 成交额达到 v 时收盘
+func Dollars(v common.Decimal) Spec {
+	return Spec{Kind: DollarBar, Threshold: v}
+}
+
+func (s Spec) validate() error {
+	switch s.Kind {
+	case TimeBar:
+		if s.Interval <= 0 {
+			return fmt.Errorf("%w: candle interval must be positive", common.ErrInvalidParams)
+		}
+	case TickBar:
+		if s.Count <= 0 {
+			return fmt.Errorf("%w: tick count must be positive", common.ErrInvalidParams)
+		}
+	default:
+		if s.Threshold.Sign() <= 0 {
+			return fmt.Errorf("%w: %s threshold must be positive", common.ErrInvalidParams, s.Kind)
+		}
+	}
+	return nil
+}
+
+// Bar 合成的K线，Candle.Interval 只对时间K线有效
+type Bar struct {
+	market.Candle
+	Kind Kind
+	// End 时间K线为 Start+Interval，其他K线为最后一笔成交的时间
+	End time.Time
+	// Trades 成交笔数
+	Trades int
+	// QuoteVolume 成交额
+	QuoteVolume common.Decimal
+	// BuyVolume 主动买入的成交量
+	BuyVolume common.Decimal
+}
+
+// Config 合成配置
+type Config struct {
+	// Partial 每笔成交后回调未收盘的K线（Closed 为 false），收盘时再回调一次 Closed 为 true 的K线
+	Partial bool
+	// Lateness 时间K线到期后继续等待迟到成交的时间；超过后收盘，之后到达的成交被丢弃
+	// 按收到的最新成交时间或 Advance 的时间计算
+	Lateness time.Duration
+	// OnLate 迟到的成交被丢弃时回调
+	OnLate func(trade market.Trade)
+	// Clock Run 用来判断时间K线是否到期的时钟，为空时使用本地时间
+	Clock  *clocksync.Clock
+	Logger *slog.Logger
+}
+
+// bar 带开收盘成交时间的K线
+type bar struct {
+	Bar
+	openAt, closeAt time.Time
+}
+
+// series 一个品种的K线状态
+type series struct {
+	exchange, symbol string
+	// open 未收盘的时间K线，按 Start 升序；其他类型最多一根
+	open []*bar
+	// watermark 收到的最新成交时间
+	watermark time.Time
+	// closedUntil 时间K线已经收盘到的时间，更早的成交为迟到成交
+	closedUntil time.Time
+}
+
+type seriesKey struct {
+	exchange, symbol string
+}
+
+// Builder 按品种合成K线，并发安全
+// 回调在调用 OnTrade、Advance、Flush 的协程中执行；同一个品种的成交应当保序传入，例如订阅时使用 WithOrdered
+type Builder struct {
+	spec    Spec
+	cfg     Config
+	handler func(Bar) error
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	series map[seriesKey]*series
+}
+
+// New 创建K线合成器，handler 接收未收盘与已收盘的K线
+func New(spec Spec, cfg Config, handler func(Bar) error) (*Builder, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Builder{
+		spec:    spec,
+		cfg:     cfg,
+		handler: handler,
+		logger:  cfg.Logger.With("candle", spec.Kind.String()),
+		series:  make(map[seriesKey]*series),
+	}, nil
+}
+
+// OnTrade 传入一笔成交，签名与 market.TradeHandler 一致，可以直接传给 OnTrade 订阅
+func (b *Builder) OnTrade(trade market.Trade) error {
+	b.mu.Lock()
+	s := b.seriesOf(trade)
+	var out []Bar
+	late := false
+	if b.spec.Kind == TimeBar {
+		out, late = b.addTimed(s, trade)
+	} else {
+		out = b.addCounted(s, trade)
+	}
+	b.mu.Unlock()
+
+	if late {
+		if b.cfg.OnLate != nil {
+			b.cfg.OnLate(trade)
+		}
+		return nil
+	}
+	return b.emit(out)
+}
+
+// Advance 收盘 now - Lateness 之前到期的时间K线，用于没有新成交时按时钟收盘
+func (b *Builder) Advance(now time.Time) error {
+	if b.spec.Kind != TimeBar {
+		return nil
+	}
+	b.mu.Lock()
+	var out []Bar
+	for _, s := range b.sortedSeries() {
+		out = append(out, b.closeUntil(s, now.Add(-b.cfg.Lateness))...)
+	}
+	b.mu.Unlock()
+	return b.emit(out)
+}
+
+// Flush 收盘全部未收盘的K线，通常在退出前调用
+func (b *Builder) Flush() error {
+	b.mu.Lock()
+	var out []Bar
+	for _, s := range b.sortedSeries() {
+		for _, open := range s.open {
+			if b.spec.Kind == TimeBar {
+				s.closedUntil = open.End
+			}
+			out = append(out, open.snapshot(true))
+		}
+		s.open = nil
+	}
+	b.mu.Unlock()
+	return b.emit(out)
+}
+
+// Run 每秒（周期小于 1 秒时按周期）调用一次 Advance，直到 ctx 结束；回调返回的错误记录到日志
+func (b *Builder) Run(ctx context.Context) {
+	if b.spec.Kind != TimeBar {
+		return
+	}
+	ticker := time.NewTicker(min(time.Second, b.spec.Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Advance(b.cfg.Clock.Now()); err != nil {
+				b.logger.Warn("candle handler failed", "error", err)
+			}
+		}
+	}
+}
+
+func (b *Builder) seriesOf(trade market.Trade) *series {
+	key := seriesKey{exchange: trade.Exchange, symbol: trade.Symbol}
+	s, ok := b.series[key]
+	if !ok {
+		s = &series{exchange: trade.Exchange, symbol: trade.Symbol}
+		b.series[key] = s
+	}
+	return s
+}
+
+// sortedSeries 按交易所与品种排序，回调顺序固定；调用方持有 b.mu
+func (b *Builder) sortedSeries() []*series {
+	ret := make([]*series, 0, len(b.series))
+	for _, s := range b.series {
+		ret = append(ret, s)
+	}
+	slices.SortFunc(ret, func(x, y *series) int {
+		return cmp.Or(strings.Compare(x.exchange, y.exchange), strings.Compare(x.symbol, y.symbol))
+	})
+	return ret
+}
+
+// addTimed 把成交加入所在周期的K线，返回需要回调的K线与成交是否迟到；调用方持有 b.mu
+func (b *Builder) addTimed(s *series, trade market.Trade) ([]Bar, bool) {
+	start := trade.Timestamp.UTC().Truncate(b.spec.Interval)
+	if start.Before(s.closedUntil) {
+		return nil, true
+	}
+
+	i, found := slices.BinarySearchFunc(s.open, start, func(o *bar, t time.Time) int {
+		return o.Start.Compare(t)
+	})
+	if !found {
+		s.open = slices.Insert(s.open, i, b.newBar(s, start))
+	}
+	target := s.open[i]
+	target.add(trade)
+
+	var out []Bar
+	if trade.Timestamp.After(s.watermark) {
+		s.watermark = trade.Timestamp
+		out = b.closeUntil(s, s.watermark.Add(-b.cfg.Lateness))
+	}
+	// 成交所在的K线可能刚好被收盘
+	if b.cfg.Partial && !target.Start.Before(s.closedUntil) {
+		out = append(out, target.snapshot(false))
+	}
+	return out, false
+}
+
+// closeUntil 收盘 End 不晚于 limit 的时间K线；调用方持有 b.mu
+func (b *Builder) closeUntil(s *series, limit time.Time) []Bar {
+	var out []Bar
+	n := 0
+	for _, open := range s.open {
+		if open.End.After(limit) {
+			break
+		}
+		out = append(out, open.snapshot(true))
+		s.closedUntil = open.End
+		n++
+	}
+	s.open = s.open[n:]
+	// 没有成交的周期也不再接收迟到成交
+	if limit.After(s.closedUntil) {
+		s.closedUntil = limit.UTC().Truncate(b.spec.Interval)
+	}
+	return out
+}
+
+// addCounted 按笔数、成交量或成交额划分；调用方持有 b.mu
+func (b *Builder) addCounted(s *series, trade market.Trade) []Bar {
+	if len(s.open) == 0 {
+		s.open = []*bar{b.newBar(s, trade.Timestamp)}
+	}
+	cur := s.open[0]
+	cur.add(trade)
+	cur.End = cur.closeAt
+
+	var full bool
+	switch b.spec.Kind {
+	case TickBar:
+		full = cur.Trades >= b.spec.Count
+	case VolumeBar:
+		full = cur.Volume.Cmp(b.spec.Threshold) >= 0
+	default:
+		full = cur.QuoteVolume.Cmp(b.spec.Threshold) >= 0
+	}
+	if full {
+		s.open = nil
+		return []Bar{cur.snapshot(true)}
+	}
+	if b.cfg.Partial {
+		return []Bar{cur.snapshot(false)}
+	}
+	return nil
+}
+
+func (b *Builder) newBar(s *series, start time.Time) *bar {
+	ret := &bar{Bar: Bar{
+		Candle: market.Candle{Exchange: s.exchange, Symbol: s.symbol, Start: start},
+		Kind:   b.spec.Kind,
+	}}
+	if b.spec.Kind == TimeBar {
+		ret.Interval = b.spec.Interval
+		ret.End = start.Add(b.spec.Interval)
+	}
+	return ret
+}
+
+func (b *Builder) emit(bars []Bar) error {
+	var errs []error
+	for _, bar := range bars {
+		if err := b.handler(bar); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o *bar) add(trade market.Trade) {
+	price, size, ts := trade.Price, trade.Size, trade.Timestamp
+	if o.Trades == 0 {
+		o.Open, o.High, o.Low, o.Close = price, price, price, price
+		o.openAt, o.closeAt = ts, ts
+	} else {
+		if price.Cmp(o.High) > 0 {
+			o.High = price
+		}
+		if price.Cmp(o.Low) < 0 {
+			o.Low = price
+		}
+		if ts.Before(o.openAt) {
+			o.Open, o.openAt = price, ts
+		}
+		if !ts.Before(o.closeAt) {
+			o.Close, o.closeAt = price, ts
+		}
+	}
+	o.Trades++
+	o.Volume = o.Volume.Add(size)
+	o.QuoteVolume = o.QuoteVolume.Add(price.Mul(size))
+	if trade.Side == market.Buy {
+		o.BuyVolume = o.BuyVolume.Add(size)
+	}
+}
+
+func (o *bar) snapshot(closed bool) Bar {
+	ret := o.Bar
+	ret.Closed = closed
+	return ret
+}
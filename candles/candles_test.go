@@ -0,0 +1,103 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+var base = time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC)
+
+func trade(offset time.Duration, price, size string) market.Trade {
+	p, _ := common.ParseDecimal(price)
+	s, _ := common.ParseDecimal(size)
+	return market.Trade{Exchange: "x", Symbol: "BTC-USD", Price: p, Size: s, Side: market.Buy, Timestamp: base.Add(offset)}
+}
+
+func collect(t *testing.T, spec Spec, cfg Config) (*Builder, *[]Bar) {
+	t.Helper()
+	var bars []Bar
+	b, err := New(spec, cfg, func(bar Bar) error {
+		bars = append(bars, bar)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, &bars
+}
+
+func TestTimeBars(t *testing.T) {
+	var late []market.Trade
+	b, bars := collect(t, Every(time.Minute), Config{
+		Lateness: 5 * time.Second,
+		OnLate:   func(tr market.Trade) { late = append(late, tr) },
+	})
+
+	for _, tr := range []market.Trade{
+		trade(10*time.Second, "100", "1"),
+		trade(40*time.Second, "103", "1"),
+		trade(20*time.Second, "98", "2"), // 乱序，不改变收盘价
+		trade(62*time.Second, "104", "1"),
+		trade(50*time.Second, "99", "1"), // 在 Lateness 内，计入第一根
+		trade(70*time.Second, "105", "1"),
+		trade(30*time.Second, "90", "1"), // 第一根已收盘，丢弃
+	} {
+		if err := b.OnTrade(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(*bars) != 1 || len(late) != 1 {
+		t.Fatalf("bars %d late %d, want 1 and 1", len(*bars), len(late))
+	}
+	first := (*bars)[0]
+	if !first.Start.Equal(base) || !first.End.Equal(base.Add(time.Minute)) || !first.Closed {
+		t.Fatalf("first bar %v-%v closed=%v", first.Start, first.End, first.Closed)
+	}
+	if first.Open.String() != "100" || first.High.String() != "103" || first.Low.String() != "98" ||
+		first.Close.String() != "99" || first.Volume.String() != "5" || first.Trades != 4 {
+		t.Fatalf("first bar = %+v", first)
+	}
+
+	if err := b.Advance(base.Add(2*time.Minute + 5*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*bars) != 2 || (*bars)[1].Close.String() != "105" || (*bars)[1].Trades != 2 {
+		t.Fatalf("second bar = %+v", (*bars)[1:])
+	}
+}
+
+func TestCountedBars(t *testing.T) {
+	threshold, _ := common.ParseDecimal("3")
+	b, bars := collect(t, Volume(threshold), Config{Partial: true})
+	for i, size := range []string{"1", "1", "2", "1"} {
+		if err := b.OnTrade(trade(time.Duration(i)*time.Second, "10", size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 两次未收盘、一次收盘（成交量 4，超过阈值不拆分）、新K线一次未收盘
+	if len(*bars) != 4 {
+		t.Fatalf("got %d bars, want 4", len(*bars))
+	}
+	closed := (*bars)[2]
+	if !closed.Closed || closed.Volume.String() != "4" || closed.QuoteVolume.String() != "40" || closed.Trades != 3 {
+		t.Fatalf("closed bar = %+v", closed)
+	}
+	if next := (*bars)[3]; next.Closed || !next.Start.Equal(base.Add(3*time.Second)) {
+		t.Fatalf("next bar = %+v", next)
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if last := (*bars)[len(*bars)-1]; !last.Closed || last.Trades != 1 {
+		t.Fatalf("flushed bar = %+v", last)
+	}
+
+	if _, err := New(Ticks(0), Config{}, nil); err == nil {
+		t.Fatal("invalid spec accepted")
+	}
+}
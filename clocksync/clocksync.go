@@ -0,0 +1,195 @@
+// Package clocksync 定期查询交易所的服务器时间，估计本地时钟与交易所的偏差和往返时间
+// 签名、登录等依赖时间戳的请求使用校正后的时间，本机时钟漂移时请求不会因时间戳过期被拒绝
+package clocksync
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/simonks2016/dex_plus/metrics"
+)
+
+// Source 交易所的服务器时间接口
+type Source interface {
+	Exchange() string
+	ServerTime(ctx context.Context) (time.Time, error)
+}
+
+const (
+	// DefaultInterval 默认的同步间隔
+	DefaultInterval = time.Minute
+	// DefaultSamples 每次同步默认的采样次数
+	DefaultSamples = 5
+)
+
+// Config 同步配置
+type Config struct {
+	// Interval 同步间隔，默认 DefaultInterval
+	Interval time.Duration
+	// Samples 每次同步的采样次数，取往返时间最短的一次，默认 DefaultSamples
+	Samples int
+	Logger  *slog.Logger
+	// Metrics 记录偏差与往返时间，标签为 exchange
+	Metrics metrics.MetricsSink
+}
+
+// Clock 一个交易所的校正时钟，并发安全；nil 的 Clock 返回本地时间
+// 偏差 = 服务器时间 - 请求发出与收到响应的中点，服务器时间只精确到秒的交易所（如 Kraken）误差在 1 秒以内
+type Clock struct {
+	source  Source
+	cfg     Config
+	logger  *slog.Logger
+	metrics metrics.MetricsSink
+
+	offset atomic.Int64 // 纳秒
+	rtt    atomic.Int64 // 纳秒
+	synced atomic.Int64 // 最近一次同步成功的本地时间，UnixNano
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New 创建时钟，Sync 或 Start 之前偏差为 0
+func New(source Source, cfg Config) *Clock {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = DefaultSamples
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Clock{
+		source:  source,
+		cfg:     cfg,
+		logger:  cfg.Logger.With("exchange", source.Exchange()),
+		metrics: metrics.OrDiscard(cfg.Metrics),
+	}
+}
+
+// Exchange 交易所名称
+func (c *Clock) Exchange() string {
+	if c == nil {
+		return ""
+	}
+	return c.source.Exchange()
+}
+
+// Now 校正后的当前时间
+func (c *Clock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return time.Now().Add(c.Offset())
+}
+
+// Offset 交易所时间减去本地时间，本地时钟偏慢时为正
+func (c *Clock) Offset() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.offset.Load())
+}
+
+// RTT 最近一次同步选中的样本的往返时间
+func (c *Clock) RTT() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.rtt.Load())
+}
+
+// LastSync 最近一次同步成功的时间，从未成功时为零值
+func (c *Clock) LastSync() time.Time {
+	if c == nil || c.synced.Load() == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, c.synced.Load())
+}
+
+// Sync 立即同步一次：采样 Samples 次，取往返时间最短的样本；全部失败时返回错误并保留之前的偏差
+func (c *Clock) Sync(ctx context.Context) error {
+	var (
+		best     time.Duration
+		bestRTT  time.Duration = -1
+		failures []error
+	)
+	for i := 0; i < c.cfg.Samples; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sent := time.Now()
+		server, err := c.source.ServerTime(ctx)
+		rtt := time.Since(sent)
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+		if bestRTT < 0 || rtt < bestRTT {
+			bestRTT = rtt
+			best = server.Sub(sent.Add(rtt / 2))
+		}
+	}
+	if bestRTT < 0 {
+		return errors.Join(failures...)
+	}
+
+	c.offset.Store(int64(best))
+	c.rtt.Store(int64(bestRTT))
+	c.synced.Store(time.Now().UnixNano())
+	exchange := c.source.Exchange()
+	c.metrics.Gauge(metrics.ClockOffsetSeconds, best.Seconds(), "exchange", exchange)
+	c.metrics.Gauge(metrics.ClockRTTSeconds, bestRTT.Seconds(), "exchange", exchange)
+	c.logger.Debug("clock synced", "offset", best, "rtt", bestRTT, "failures", len(failures))
+	return nil
+}
+
+// Start 同步一次后按 Interval 在后台定期同步，返回第一次同步的错误；失败时后台同步继续进行
+func (c *Clock) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel, c.done = cancel, make(chan struct{})
+	c.mu.Unlock()
+
+	err := c.Sync(ctx)
+	go c.loop(ctx)
+	return err
+}
+
+func (c *Clock) loop(ctx context.Context) {
+	defer close(c.done)
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Sync(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Warn("clock sync failed", "error", err, "offset", c.Offset())
+			}
+		}
+	}
+}
+
+// Stop 停止后台同步，保留最近一次的偏差
+func (c *Clock) Stop() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,73 @@
+package clocksync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeSource 服务器时钟比本地快 offset，每次请求依次耗时 delays[i]
+type fakeSource struct {
+	offset time.Duration
+	delays []time.Duration
+	err    error
+	calls  int
+}
+
+func (s *fakeSource) Exchange() string { return "fake" }
+
+func (s *fakeSource) ServerTime(ctx context.Context) (time.Time, error) {
+	delay := s.delays[s.calls%len(s.delays)]
+	s.calls++
+	if s.err != nil {
+		return time.Time{}, s.err
+	}
+	// 服务器在往返的中点取时间
+	time.Sleep(delay / 2)
+	now := time.Now().Add(s.offset)
+	time.Sleep(delay / 2)
+	return now, nil
+}
+
+func TestSync(t *testing.T) {
+	src := &fakeSource{offset: 2 * time.Second, delays: []time.Duration{120 * time.Millisecond, 2 * time.Millisecond, 80 * time.Millisecond}}
+	c := New(src, Config{Samples: 3})
+	if err := c.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Offset() - 2*time.Second; d < -10*time.Millisecond || d > 10*time.Millisecond {
+		t.Fatalf("offset = %s, want about 2s", c.Offset())
+	}
+	// 留出调度抖动的余量，只要求选中的不是两个慢样本
+	if c.RTT() >= 60*time.Millisecond {
+		t.Fatalf("rtt = %s, want the fastest sample", c.RTT())
+	}
+	if c.LastSync().IsZero() {
+		t.Fatal("LastSync not set")
+	}
+	if d := c.Now().Sub(time.Now()); d < time.Second {
+		t.Fatalf("Now() ahead by %s, want about 2s", d)
+	}
+
+	// 全部失败时返回错误并保留之前的偏差
+	failed := errors.New("unreachable")
+	src.err = failed
+	before := c.Offset()
+	if err := c.Sync(context.Background()); !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want %v", err, failed)
+	}
+	if c.Offset() != before {
+		t.Fatalf("offset changed to %s after failed sync", c.Offset())
+	}
+}
+
+func TestNilClock(t *testing.T) {
+	var c *Clock
+	if c.Offset() != 0 || c.RTT() != 0 || !c.LastSync().IsZero() {
+		t.Fatal("nil clock should report zero values")
+	}
+	if d := time.Since(c.Now()); d < 0 || d > time.Second {
+		t.Fatalf("nil clock Now() off by %s", d)
+	}
+}
@@ -1,24 +1,17 @@
 package coinbase
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 	"testing"
+	"time"
 
 	"github.com/simonks2016/dex_plus/coinbase/payload"
 	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
 )
 
 func TestCoinbase(t *testing.T) {
-
-	ctx, stop := signal.NotifyContext(
-		context.Background(),
-		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
-	// 释放资源
-	defer stop()
+	ctx := dextest.LiveContext(t)
 
 	cli := NewPublic(ctx, common.CoinBaseSymbol(common.BTC))
 
@@ -29,12 +22,8 @@ func TestCoinbase(t *testing.T) {
 		return nil
 	})
 
-	cli.SubscribeOrderBook(func(snapshot payload.OrderBook) error {
-		fmt.Println(snapshot)
-		return nil
-
-	}, func(update payload.OrderBookUpdate) error {
-		fmt.Println(update)
+	cli.SubscribeOrderBook(time.Second, func(books []payload.OrderBook) error {
+		fmt.Println(books)
 		return nil
 	})
 
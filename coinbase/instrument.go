@@ -0,0 +1,50 @@
+package coinbase
+
+import (
+	"context"
+
+	"github.com/simonks2016/dex_plus/coinbase/internal"
+	"github.com/simonks2016/dex_plus/coinbase/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/httpClient"
+)
+
+// InstrumentLoader 通过 /products 加载 Coinbase 产品信息
+type InstrumentLoader struct {
+	BaseURL string
+	client  *httpClient.Client
+}
+
+func NewInstrumentLoader() *InstrumentLoader {
+	return &InstrumentLoader{
+		BaseURL: internal.RestURL,
+		client:  httpClient.NewClient(httpClient.Config{WorkerSize: 1, Exchange: common.Coinbase}),
+	}
+}
+
+func (l *InstrumentLoader) Exchange() string { return common.Coinbase }
+
+func (l *InstrumentLoader) LoadInstruments(ctx context.Context) ([]common.Instrument, error) {
+	var products []payload.Product
+	// Coinbase 拒绝没有 User-Agent 的请求
+	header := map[string]string{"User-Agent": "dex_plus", "Accept": "application/json"}
+	if err := l.client.GetJSON(ctx, l.BaseURL+"/products", header, &products); err != nil {
+		return nil, err
+	}
+
+	ret := make([]common.Instrument, 0, len(products))
+	for _, p := range products {
+		inst := common.Instrument{
+			Exchange: common.Coinbase,
+			Symbol:   p.Id,
+			Type:     common.InstrumentSpot,
+			Base:     p.BaseCurrency,
+			Quote:    p.QuoteCurrency,
+		}
+		inst.TickSize, _ = common.ParseDecimal(p.QuoteIncrement)
+		inst.LotSize, _ = common.ParseDecimal(p.BaseIncrement)
+		inst.MinNotional, _ = common.ParseDecimal(p.MinMarketFunds)
+		ret = append(ret, inst)
+	}
+	return ret, nil
+}
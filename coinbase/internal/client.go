@@ -2,46 +2,66 @@ package internal
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/coinbase/params"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/watchdog"
 )
 
 type CoinbaseClient struct {
-	ctx           context.Context
-	client        *client.WsClient
-	logger        *log.Logger
-	pool          *ants.Pool
-	cfg           *client.Config
-	authDone      atomic.Bool
-	isConnected   atomic.Bool
-	isRequireAuth bool
-	handler       map[string][]Caller
-	channels      []string
-	symbols       []string
+	ctx            context.Context
+	client         client.Client
+	logger         *slog.Logger
+	pool           *ants.Pool
+	cfg            *client.Config
+	authDone       atomic.Bool
+	isConnected    atomic.Bool
+	resyncAt       atomic.Int64 // 上一次因丢帧重新订阅的时间，避免连续丢帧时反复重订阅
+	isRequireAuth  bool
+	handler        map[string][]Caller
+	orderedHandler map[string][]Caller // 保序订阅的回调，在读协程里直接执行
+	handlerMu      sync.RWMutex        // 运行时订阅、取消订阅与读协程并发访问回调表
+	subMu          sync.Mutex
+	channels       []string              // 按订阅顺序记录的频道
+	subscriptions  map[string][]string   // 按频道记录已订阅的品种，重连后重放
+	symbols        []string              // 默认品种，之后订阅的频道使用这组品种
+	tracker        *subscription.Tracker // 订阅状态机，按 subscriptions 消息确认
+	watchdog       *watchdog.Watchdog    // 静默检测，未配置时为 nil
 }
 
 func NewCoinbaseClient(ctx context.Context, cfg *client.Config) *CoinbaseClient {
+	cfg.SetDispatchKey(MessageKey)
 
 	pool, _ := ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
 
 	cli := CoinbaseClient{
-		ctx:           ctx,
-		client:        client.NewWsClient(ctx, cfg),
-		logger:        cfg.Logger,
-		pool:          pool,
-		cfg:           cfg,
-		handler:       make(map[string][]Caller),
-		channels:      make([]string, 0),
-		symbols:       make([]string, 0),
-		isRequireAuth: cfg.IsNeedAuth,
+		ctx:            ctx,
+		client:         client.NewClient(ctx, cfg),
+		logger:         client.OrDefaultLogger(cfg.Logger).With("exchange", "coinbase"),
+		pool:           pool,
+		cfg:            cfg,
+		handler:        make(map[string][]Caller),
+		orderedHandler: make(map[string][]Caller),
+		channels:       make([]string, 0),
+		subscriptions:  make(map[string][]string),
+		symbols:        make([]string, 0),
+		isRequireAuth:  cfg.IsNeedAuth,
 	}
+	cli.tracker = subscription.New(cfg.Subscription(), cli.sendRequest, cli.logger)
 	cli.client.SetObserver(&cli)
+	if cfg.Watchdog != nil {
+		cli.watchdog = watchdog.New(*cfg.Watchdog, &cli, cli.logger)
+	}
 
 	cli.handler["error"] = append(cli.handler["error"], func(data []byte) error {
 
@@ -50,26 +70,21 @@ func NewCoinbaseClient(ctx context.Context, cfg *client.Config) *CoinbaseClient
 		if err := json.Unmarshal(data, &d); err != nil {
 			return err
 		} else {
-			if cli.logger != nil {
-				cli.logger.Printf("[error]received a error message from Coinbase,%s,%s", d["message"], string(data))
-			}
+			cli.logger.Error("received an error message", "conn_id", cli.client.ConnID(), "reason", d["message"], "data", string(data))
 		}
 
 		return nil
 	})
 	// 添加心跳事件
-	cli.channels = append(cli.channels, "heartbeat")
+	cli.Subscribe("heartbeat")
 	cli.handler["heartbeat"] = append(cli.handler["heartbeat"], func(data []byte) error {
 		var d = make(map[string]any)
 
 		if err := json.Unmarshal(data, &d); err != nil {
 			return err
 		} else {
-			if cli.logger != nil {
-				cli.logger.Printf("[info]received a hearbeat message from Coinbase,%s,time: %s",
-					d["product_id"].(string),
-					d["time"].(string))
-			}
+			cli.logger.Debug("received a heartbeat", "conn_id", cli.client.ConnID(), "channel", "heartbeat",
+				"symbol", d["product_id"], "time", d["time"])
 		}
 		return nil
 	})
@@ -77,18 +92,35 @@ func NewCoinbaseClient(ctx context.Context, cfg *client.Config) *CoinbaseClient
 	return &cli
 }
 
+func (cli *CoinbaseClient) Logger() *slog.Logger {
+	return cli.logger
+}
+
 func (cli *CoinbaseClient) Connect() {
+	cli.watchdog.Start(cli.ctx)
 	cli.client.Start()
 }
 
 func (cli *CoinbaseClient) Close() {
+	cli.watchdog.Stop()
 	cli.client.Close()
 }
 
+// Shutdown 取消订阅并停止接收，等待已收到的消息回调完成后释放协程池
+func (cli *CoinbaseClient) Shutdown(ctx context.Context) error {
+	cli.watchdog.Stop()
+	err := cli.client.Shutdown(ctx)
+	return errors.Join(err, client.ReleasePool(ctx, cli.pool))
+}
+
+func (cli *CoinbaseClient) Reconnect(reason string) {
+	cli.client.Reconnect(reason)
+}
+
 func (cli *CoinbaseClient) Send(dataBytes []byte) error {
 
 	if !cli.isConnected.Load() {
-		return fmt.Errorf("client is not connected")
+		return common.ErrNotConnected
 	}
 	ctx, cancel := context.WithTimeout(cli.ctx, time.Second*time.Duration(5))
 	defer cancel()
@@ -96,41 +128,221 @@ func (cli *CoinbaseClient) Send(dataBytes []byte) error {
 	return cli.client.Send(ctx, dataBytes)
 }
 
+// Subscribe 用默认品种订阅频道，已经订阅过的频道保持不变
 func (cli *CoinbaseClient) Subscribe(channels ...string) {
+	for _, channel := range channels {
+		if cli.HasChannel(channel) {
+			continue
+		}
+		cli.subMu.Lock()
+		symbols := slices.Clone(cli.symbols)
+		cli.subMu.Unlock()
+		cli.SubscribeSymbols(channel, symbols...)
+	}
+}
 
-	seenChannels := make(map[string]bool)
+// SubscribeSymbols 在频道中追加品种，连接已建立时立即发送新增的品种，并记录下来在重连后重放
+func (cli *CoinbaseClient) SubscribeSymbols(channel string, symbols ...string) {
+	var fresh []string
+	cli.subMu.Lock()
+	current, ok := cli.subscriptions[channel]
+	if !ok {
+		cli.channels = append(cli.channels, channel)
+	}
+	for _, symbol := range symbols {
+		if !slices.Contains(current, symbol) && !slices.Contains(fresh, symbol) {
+			fresh = append(fresh, symbol)
+		}
+	}
+	cli.subscriptions[channel] = append(current, fresh...)
+	cli.subMu.Unlock()
 
-	// 记录当前已有的值，防止重复追加（如果 cli.channels 初始不为空）
-	for _, v := range cli.channels {
-		seenChannels[v] = true
+	if channel != "heartbeat" {
+		for _, symbol := range fresh {
+			cli.watchdog.Watch(channel, symbol)
+		}
 	}
 
+	// 连接建立前只记录状态，连接后在 OnConnected 中重放
+	if err := cli.tracker.Subscribe(feeds(channel, fresh...)...); err != nil {
+		cli.logger.Error("failed to subscribe channel", "conn_id", cli.client.ConnID(), "channel", channel, "error", err)
+	}
+}
+
+// sendRequest 发送订阅请求，按频道拆分；Coinbase 不回传请求 id，按 subscriptions 消息中的频道与品种确认
+func (cli *CoinbaseClient) sendRequest(req subscription.Request) error {
+	var channels []string
+	symbols := make(map[string][]string)
+	for _, f := range req.Feeds {
+		if _, ok := symbols[f.Channel]; !ok {
+			channels = append(channels, f.Channel)
+		}
+		symbols[f.Channel] = append(symbols[f.Channel], f.Symbol)
+	}
 	for _, channel := range channels {
-		// 1. Channels 去重追加
-		if !seenChannels[channel] {
-			cli.channels = append(cli.channels, channel)
-			seenChannels[channel] = true
+		p := params.NewSubscribeParams(params.Subscribe, symbols[channel]...)
+		p.AddChannel(channel)
+		if err := cli.Send(p.Json()); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func (cli *CoinbaseClient) SetSymbols(symbols ...string) {
+// SubscriptionState 订阅的当前状态
+func (cli *CoinbaseClient) SubscriptionState(channel, symbol string) subscription.State {
+	return cli.tracker.State(channel, symbol)
+}
+
+// SetOnSubscriptionChange 设置订阅状态变化回调
+func (cli *CoinbaseClient) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	cli.tracker.SetOnChange(fn)
+}
 
-	seenSymbols := make(map[string]bool)
-	for _, v := range cli.symbols {
-		seenSymbols[v] = true
+func feeds(channel string, symbols ...string) []subscription.Feed {
+	ret := make([]subscription.Feed, 0, len(symbols))
+	for _, symbol := range symbols {
+		ret = append(ret, subscription.Feed{Channel: channel, Symbol: symbol})
 	}
+	return ret
+}
+
+// SetSymbols 追加默认品种，已经订阅的频道同样订阅这些品种
+func (cli *CoinbaseClient) SetSymbols(symbols ...string) {
+	cli.subMu.Lock()
 	for _, symbol := range symbols {
-		// 1. Channels 去重追加
-		if !seenSymbols[symbol] {
+		// 去重追加
+		if !slices.Contains(cli.symbols, symbol) {
 			cli.symbols = append(cli.symbols, symbol)
-			seenSymbols[symbol] = true
 		}
 	}
+	channels := slices.Clone(cli.channels)
+	cli.subMu.Unlock()
+
+	for _, channel := range channels {
+		cli.SubscribeSymbols(channel, symbols...)
+	}
+}
+
+// Symbols 频道中已经订阅的产品
+func (cli *CoinbaseClient) Symbols(channel string) []string {
+	cli.subMu.Lock()
+	defer cli.subMu.Unlock()
+	return slices.Clone(cli.subscriptions[channel])
+}
+
+// HasChannel 频道是否已经订阅
+func (cli *CoinbaseClient) HasChannel(channel string) bool {
+	cli.subMu.Lock()
+	defer cli.subMu.Unlock()
+	_, ok := cli.subscriptions[channel]
+	return ok
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有品种时一并移除该频道推送类型的回调
+func (cli *CoinbaseClient) Unsubscribe(channel string, symbols ...string) error {
+	var removed []string
+	cli.subMu.Lock()
+	current, ok := cli.subscriptions[channel]
+	if !ok {
+		cli.subMu.Unlock()
+		return nil
+	}
+	kept := make([]string, 0, len(current))
+	for _, symbol := range current {
+		if len(symbols) == 0 || slices.Contains(symbols, symbol) {
+			removed = append(removed, symbol)
+			continue
+		}
+		kept = append(kept, symbol)
+	}
+	empty := len(kept) == 0
+	if empty {
+		delete(cli.subscriptions, channel)
+		cli.channels = slices.DeleteFunc(cli.channels, func(c string) bool { return c == channel })
+	} else {
+		cli.subscriptions[channel] = kept
+	}
+	cli.subMu.Unlock()
+
+	for _, symbol := range removed {
+		cli.watchdog.Forget(channel, symbol)
+	}
+	cli.tracker.Remove(feeds(channel, removed...)...)
+	if empty {
+		cli.handlerMu.Lock()
+		for _, m := range []map[string][]Caller{cli.handler, cli.orderedHandler} {
+			for name := range m {
+				if FeedChannel(name) == channel {
+					delete(m, name)
+				}
+			}
+		}
+		cli.handlerMu.Unlock()
+	}
+
+	if len(removed) == 0 || !cli.authDone.Load() {
+		return nil
+	}
+	p := params.NewSubscribeParams(params.Unsubscribe, removed...)
+	p.AddChannel(channel)
+	return cli.Send(p.Json())
+}
+
+// subscriptionParams 按频道生成订阅或取消订阅的参数
+func (cli *CoinbaseClient) subscriptionParams(method string, channels ...string) []params.SubscribeParams {
+	cli.subMu.Lock()
+	defer cli.subMu.Unlock()
+	if len(channels) == 0 {
+		channels = cli.channels
+	}
+	ret := make([]params.SubscribeParams, 0, len(channels))
+	for _, channel := range channels {
+		p := params.NewSubscribeParams(method, slices.Clone(cli.subscriptions[channel])...)
+		p.AddChannel(channel)
+		if !p.IsEmpty() {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// Resubscribe 先取消再重新订阅频道，level2 会重新下发 snapshot
+func (cli *CoinbaseClient) Resubscribe(channels ...string) error {
+	for _, p := range cli.subscriptionParams(params.Unsubscribe, channels...) {
+		if err := cli.Send(p.Json()); err != nil {
+			return err
+		}
+	}
+	for _, p := range cli.subscriptionParams(params.Subscribe, channels...) {
+		if err := cli.tracker.Subscribe(feeds(p.Channels[0], p.ProductIDs...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResubscribeFeed 先取消再重新订阅一个 (channel, product_id)
+func (cli *CoinbaseClient) ResubscribeFeed(channel, symbol string) error {
+	unsubscribe := params.NewSubscribeParams(params.Unsubscribe, symbol)
+	unsubscribe.AddChannel(channel)
+	if err := cli.Send(unsubscribe.Json()); err != nil {
+		return err
+	}
+	return cli.tracker.Subscribe(feeds(channel, symbol)...)
 }
 
 func (cli *CoinbaseClient) SetHandler(name string, caller ...Caller) {
-	cli.handler[name] = append(cli.handler[name], caller...)
+	cli.handlerMu.Lock()
+	defer cli.handlerMu.Unlock()
+	cli.handler[name] = append(slices.Clip(cli.handler[name]), caller...)
+}
+
+// SetOrderedHandler 同一个 (type, product_id) 的消息按到达顺序串行回调，Connect 之后也可以调用
+func (cli *CoinbaseClient) SetOrderedHandler(name string, caller ...Caller) {
+	cli.handlerMu.Lock()
+	defer cli.handlerMu.Unlock()
+	cli.orderedHandler[name] = append(slices.Clip(cli.orderedHandler[name]), caller...)
 }
 
 type SubscribeParams struct {
@@ -1,5 +1,6 @@
 package internal
 
 const (
-	WsURL = "wss://ws-feed.exchange.coinbase.com"
+	WsURL   = "wss://ws-feed.exchange.coinbase.com"
+	RestURL = "https://api.exchange.coinbase.com"
 )
@@ -1,5 +1,42 @@
 package internal
 
+import (
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
 type Envelope struct {
-	Type string `json:"type"`
+	Type      string `json:"type"`
+	ProductId string `json:"product_id"`
+	// Time 交易所的时间，subscriptions 等消息没有
+	Time time.Time `json:"time"`
+}
+
+// FeedChannel 推送类型对应的订阅频道，非行情推送返回空
+func FeedChannel(msgType string) string {
+	switch msgType {
+	case "snapshot", "l2update":
+		return "level2"
+	case "match", "last_match":
+		return "matches"
+	case "heartbeat", "error", "subscriptions":
+		return ""
+	default:
+		return msgType
+	}
+}
+
+// MessageKey 消息的 (channel, symbol) 键，用于按键合并与按键顺序分发
+func MessageKey(data []byte) string {
+	var v struct {
+		Type      string `json:"type"`
+		ProductId string `json:"product_id"`
+	}
+	_ = json.Unmarshal(data, &v)
+	// level2 的快照与增量属于同一个频道，必须落在同一个分区
+	if v.Type == "snapshot" || v.Type == "l2update" {
+		v.Type = "level2"
+	}
+	return v.Type + ":" + v.ProductId
 }
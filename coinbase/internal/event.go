@@ -2,32 +2,34 @@ package internal
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/simonks2016/dex_plus/coinbase/params"
 )
 
 func (c *CoinbaseClient) OnConnecting(reason string) {
-	//TODO implement me
-	fmt.Println(reason)
+	c.logger.Info("connecting", "conn_id", c.client.ConnID(), "reason", reason)
+}
+
+// OnEndpoint 连接上的地址变化
+func (c *CoinbaseClient) OnEndpoint(url string) {
+	c.logger.Info("endpoint active", "url", url)
 }
 
 func (c *CoinbaseClient) OnConnected() {
-	//TODO implement me
-	fmt.Println("On Connected")
+	c.logger.Info("connected", "conn_id", c.client.ConnID())
+	c.watchdog.Resume()
 
 	c.isConnected.Store(true)
 	if !c.isRequireAuth {
 		c.authDone.Store(true)
-		// 构建订阅参数
-		p := params.NewSubscribeParams(params.Subscribe, c.symbols...)
-		p.AddChannel(c.channels...)
-		if !p.IsEmpty() {
-			// 发送订阅信息
-			if err := c.Send(p.Json()); err != nil {
-				if c.logger != nil {
-					c.logger.Printf("failed to subscribe channel,%s", err.Error())
-				}
+		c.tracker.Resume()
+		// 按频道重放订阅，等待 subscriptions 消息确认
+		for _, p := range c.subscriptionParams(params.Subscribe) {
+			if err := c.tracker.Subscribe(feeds(p.Channels[0], p.ProductIDs...)...); err != nil {
+				c.logger.Error("failed to subscribe channels", "conn_id", c.client.ConnID(), "error", err)
+				return
 			}
 		}
 
@@ -37,14 +39,10 @@ func (c *CoinbaseClient) OnConnected() {
 
 func (c *CoinbaseClient) OnDisconnecting() {
 	//TODO implement me
-	p := params.NewSubscribeParams(params.Unsubscribe, c.symbols...)
-	p.AddChannel(c.channels...)
-	if !p.IsEmpty() {
+	for _, p := range c.subscriptionParams(params.Unsubscribe) {
 		// 发送取消订阅信息
 		if err := c.Send(p.Json()); err != nil {
-			if c.logger != nil {
-				c.logger.Printf("send subscribe error,%s", err.Error())
-			}
+			c.logger.Error("failed to unsubscribe channels", "conn_id", c.client.ConnID(), "error", err)
 			return
 		}
 	}
@@ -52,11 +50,19 @@ func (c *CoinbaseClient) OnDisconnecting() {
 }
 
 func (c *CoinbaseClient) OnDisconnected() {
-	//TODO implement me
-	fmt.Println("On Disconnected")
+	c.isConnected.Store(false)
+	c.authDone.Store(false)
+	c.tracker.Pause()
+	c.watchdog.Pause()
+	c.logger.Info("disconnected", "conn_id", c.client.ConnID())
 }
 
 func (c *CoinbaseClient) OnMessage(data []byte) error {
+	return c.OnTimedMessage(data, time.Time{})
+}
+
+// OnTimedMessage received 为收到该帧的时间，配置了延迟统计时按推送中的时间戳记录线路延迟
+func (c *CoinbaseClient) OnTimedMessage(data []byte, received time.Time) error {
 	//TODO implement me
 
 	var e Envelope
@@ -64,16 +70,33 @@ func (c *CoinbaseClient) OnMessage(data []byte) error {
 	if err := json.Unmarshal(data, &e); err != nil {
 		return err
 	}
+	c.cfg.Latency.ObserveWire(e.Time, received)
 
 	channelName := e.Type
+	if channelName == "subscriptions" {
+		return c.onSubscriptions(data)
+	}
+	if feed := FeedChannel(e.Type); feed != "" && e.ProductId != "" {
+		c.watchdog.Touch(feed, e.ProductId)
+	}
+
+	c.handlerMu.RLock()
+	ordered := c.orderedHandler[channelName]
+	callers := c.handler[channelName]
+	c.handlerMu.RUnlock()
+
+	// 保序回调，读协程已按 (type, product_id) 分区，直接执行
+	for _, caller := range ordered {
+		if err := caller(data); err != nil {
+			c.logger.Error("failed to handle message", "channel", channelName, "error", err)
+		}
+	}
 
-	if callers, ex := c.handler[channelName]; ex {
+	if len(callers) > 0 {
 		for _, caller := range callers {
 			if err := c.pool.Submit(func() {
 				if err := caller(data); err != nil {
-					if c.logger != nil {
-						c.logger.Printf("[error]failed to handler channel(%s),%s", channelName, err.Error())
-					}
+					c.logger.Error("failed to handle message", "channel", channelName, "error", err)
 					return
 				}
 			}); err != nil {
@@ -84,7 +107,41 @@ func (c *CoinbaseClient) OnMessage(data []byte) error {
 	return nil
 }
 
+// onSubscriptions 订阅或取消订阅后交易所推送当前全部的订阅，据此确认等待中的订阅
+func (c *CoinbaseClient) onSubscriptions(data []byte) error {
+	var v struct {
+		Channels []struct {
+			Name       string   `json:"name"`
+			ProductIds []string `json:"product_ids"`
+		} `json:"channels"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	for _, ch := range v.Channels {
+		for _, f := range feeds(ch.Name, ch.ProductIds...) {
+			c.tracker.AckFeed("", f, nil)
+		}
+	}
+	return nil
+}
+
+// OnDropped 读队列丢帧后 level2 增量已经不连续，重新订阅拿到新的 snapshot
+func (c *CoinbaseClient) OnDropped(count int) {
+	if !c.HasChannel("level2") {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := c.resyncAt.Load()
+	if now-last < int64(time.Second) || !c.resyncAt.CompareAndSwap(last, now) {
+		return
+	}
+	c.logger.Warn("frames dropped, resyncing order books", "conn_id", c.client.ConnID(), "channel", "level2", "count", count)
+	if err := c.Resubscribe("level2"); err != nil {
+		c.logger.Error("failed to resubscribe channel", "conn_id", c.client.ConnID(), "channel", "level2", "error", err)
+	}
+}
+
 func (c *CoinbaseClient) OnError(err error) {
-	//TODO implement me
-	fmt.Println("On Error", err)
+	c.logger.Error("client error", "conn_id", c.client.ConnID(), "error", err)
 }
@@ -0,0 +1,115 @@
+package coinbase
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/candles"
+	"github.com/simonks2016/dex_plus/coinbase/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+var _ market.MarketDataSource = (*Public)(nil)
+
+// OnTrade 订阅统一格式的成交数据
+func (p *Public) OnTrade(handler market.TradeHandler) error {
+	p.SubscribeTrade(func(t payload.MatchedTrade) error {
+		// Coinbase 的 side 是挂单方方向，主动成交方向与之相反
+		side := market.Buy
+		if t.Side == "buy" {
+			side = market.Sell
+		}
+		return handler(market.Trade{
+			Exchange:  common.Coinbase,
+			Symbol:    t.ProductId,
+			TradeId:   strconv.Itoa(t.TradeId),
+			Price:     t.PriceDecimal(),
+			Size:      t.SizeDecimal(),
+			Side:      side,
+			Timestamp: t.Time,
+		})
+	})
+	return nil
+}
+
+// OnBookSnapshot 按 interval 定时回调本地维护的盘口快照
+func (p *Public) OnBookSnapshot(interval time.Duration, handler market.BookSnapshotHandler) error {
+	p.SubscribeOrderBook(interval, func(books []payload.OrderBook) error {
+		for _, book := range books {
+			if err := handler(market.BookSnapshot{
+				Exchange:  common.Coinbase,
+				Symbol:    book.ProductId,
+				Bids:      toLevels(book.Bids),
+				Asks:      toLevels(book.Asks),
+				Timestamp: book.Time,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnBookDelta 订阅 level2 频道的原始快照与增量
+func (p *Public) OnBookDelta(handler market.BookDeltaHandler) error {
+	p.client.Subscribe("level2")
+	p.client.SetHandler("snapshot", func(data []byte) error {
+		var t1 payload.OrderBookSnapshot
+		if err := json.Unmarshal(data, &t1); err != nil {
+			return err
+		}
+		return handler(market.BookDelta{
+			Exchange:  common.Coinbase,
+			Symbol:    t1.ProductId,
+			Bids:      t1.BidLevels(),
+			Asks:      t1.AskLevels(),
+			Snapshot:  true,
+			Timestamp: t1.Time,
+		})
+	})
+	p.client.SetHandler("l2update", func(data []byte) error {
+		var t1 payload.OrderBookUpdate
+		if err := json.Unmarshal(data, &t1); err != nil {
+			return err
+		}
+		delta := market.BookDelta{
+			Exchange:  common.Coinbase,
+			Symbol:    t1.ProductId,
+			Timestamp: t1.Time,
+		}
+		for _, ch := range t1.DecimalChanges() {
+			level := market.Level{Price: ch.Price, Size: ch.Size}
+			if ch.Side == "buy" || ch.Side == "BUY" {
+				delta.Bids = append(delta.Bids, level)
+			} else {
+				delta.Asks = append(delta.Asks, level)
+			}
+		}
+		return handler(delta)
+	})
+	return nil
+}
+
+func (p *Public) OnTicker(market.TickerHandler) error { return market.ErrNotSupported }
+
+// OnCandle 由成交在本地合成统一格式的K线，未收盘的K线随每笔成交回调，下一个周期的第一笔成交到达时收盘
+func (p *Public) OnCandle(interval time.Duration, handler market.CandleHandler) error {
+	builder, err := candles.New(candles.Every(interval), candles.Config{Partial: true}, func(bar candles.Bar) error {
+		return handler(bar.Candle)
+	})
+	if err != nil {
+		return err
+	}
+	return p.OnTrade(builder.OnTrade)
+}
+
+func toLevels(items []payload.Level) []market.Level {
+	ret := make([]market.Level, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, market.Level{Price: item.PriceDecimal, Size: item.SizeDecimal})
+	}
+	return ret
+}
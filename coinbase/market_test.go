@@ -0,0 +1,76 @@
+package coinbase
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestOnTradeOffline(t *testing.T) {
+	srv := dextest.NewCoinbaseServer()
+	defer srv.Close()
+
+	p := New(t.Context(),
+		options.WithURL(srv.URL()),
+		options.WithSymbols("BTC-USD"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	defer p.Close()
+
+	// side 是挂单方向，主动方向相反
+	dextest.ExpectOfflineTrade(t, srv.Server, p, "matches", func() error {
+		return srv.Push(map[string]any{
+			"type": "match", "trade_id": 10, "sequence": 50, "maker_order_id": "a", "taker_order_id": "b",
+			"time": "2023-11-14T22:13:20.000000Z", "product_id": "BTC-USD", "size": "0.010", "price": "42000.10", "side": "buy",
+		})
+	}, market.Trade{
+		Exchange: common.Coinbase, Symbol: "BTC-USD", TradeId: "10",
+		Price: common.MustDecimal("42000.10"), Size: common.MustDecimal("0.010"),
+		Side: market.Sell, Timestamp: time.Unix(1700000000, 0),
+	})
+}
+
+func TestOnBookDeltaSnapshotOffline(t *testing.T) {
+	srv := dextest.NewCoinbaseServer()
+	defer srv.Close()
+
+	p := New(t.Context(),
+		options.WithURL(srv.URL()),
+		options.WithSymbols("BTC-USD"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	defer p.Close()
+
+	deltas := make(chan market.BookDelta, 1)
+	if err := p.OnBookDelta(func(delta market.BookDelta) error {
+		deltas <- delta
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p.Connect()
+
+	if _, ok := srv.WaitRequest("subscribe", "level2", dextest.ExpectTimeout); !ok {
+		t.Fatal("no subscribe request")
+	}
+	_ = srv.Push(map[string]any{
+		"type": "snapshot", "product_id": "BTC-USD", "time": "2023-11-14T22:13:20.000000Z",
+		"bids": [][]string{{"42000.10", "0.5"}}, "asks": [][]string{{"42000.20", "0.25"}},
+	})
+
+	// 快照的时间取推送中的 time
+	select {
+	case got := <-deltas:
+		if !got.Snapshot || !got.Timestamp.Equal(time.Unix(1700000000, 0)) || len(got.Bids) != 1 || len(got.Asks) != 1 {
+			t.Fatalf("delta = %+v", got)
+		}
+	case <-time.After(dextest.ExpectTimeout):
+		t.Fatal("no snapshot received")
+	}
+}
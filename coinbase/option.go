@@ -0,0 +1,11 @@
+package coinbase
+
+import (
+	"github.com/simonks2016/dex_plus/options"
+)
+
+// Option 构造选项，见 options 包；静默检测的频道名为订阅时的频道，如 "level2"、"matches"、"ticker"
+type Option = options.Option
+
+// SubscribeOption 订阅选项，WithOrdered 时同一个 (type, product_id) 的消息按到达顺序串行回调
+type SubscribeOption = options.SubscribeOption
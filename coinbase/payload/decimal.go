@@ -0,0 +1,45 @@
+package payload
+
+import "github.com/simonks2016/dex_plus/common"
+
+// Decimal 精确小数，Coinbase 的价格、数量都以字符串下发，可以无损转换
+type Decimal = common.Decimal
+
+func decimal(s string) Decimal {
+	d, _ := common.ParseDecimal(s)
+	return d
+}
+
+func (t MatchedTrade) PriceDecimal() Decimal { return decimal(t.Price) }
+func (t MatchedTrade) SizeDecimal() Decimal  { return decimal(t.Size) }
+
+// BidLevels 买盘档位（精确小数）
+func (o OrderBookSnapshot) BidLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Bids)
+	return levels
+}
+
+// AskLevels 卖盘档位（精确小数）
+func (o OrderBookSnapshot) AskLevels() []common.PriceLevel {
+	levels, _ := common.ParseLevels(o.Asks)
+	return levels
+}
+
+// Change l2update 中的一条变更
+type Change struct {
+	Side  string  `json:"side"`
+	Price Decimal `json:"price"`
+	Size  Decimal `json:"size"`
+}
+
+// DecimalChanges 以精确小数返回变更，格式为 [side, price, size]
+func (o OrderBookUpdate) DecimalChanges() []Change {
+	changes := make([]Change, 0, len(o.Changes))
+	for _, ch := range o.Changes {
+		if len(ch) < 3 {
+			continue
+		}
+		changes = append(changes, Change{Side: ch[0], Price: decimal(ch[1]), Size: decimal(ch[2])})
+	}
+	return changes
+}
@@ -21,9 +21,14 @@ type OrderBookSnapshot struct {
 	ProductId string     `json:"product_id"`
 	Bids      [][]string `json:"bids"`
 	Asks      [][]string `json:"asks"`
+	Time      time.Time  `json:"time"`
 }
 
 type Level struct {
 	Price float64 `json:"price"`
 	Size  float64 `json:"size"`
+
+	// PriceDecimal 与 SizeDecimal 是按产品精度还原的精确值
+	PriceDecimal Decimal `json:"-"`
+	SizeDecimal  Decimal `json:"-"`
 }
@@ -0,0 +1,18 @@
+package payload
+
+// Product GET /products
+type Product struct {
+	Id              string `json:"id"`
+	BaseCurrency    string `json:"base_currency"`
+	QuoteCurrency   string `json:"quote_currency"`
+	QuoteIncrement  string `json:"quote_increment"`
+	BaseIncrement   string `json:"base_increment"`
+	DisplayName     string `json:"display_name"`
+	MinMarketFunds  string `json:"min_market_funds"`
+	PostOnly        bool   `json:"post_only"`
+	LimitOnly       bool   `json:"limit_only"`
+	CancelOnly      bool   `json:"cancel_only"`
+	Status          string `json:"status"`
+	StatusMessage   string `json:"status_message"`
+	TradingDisabled bool   `json:"trading_disabled"`
+}
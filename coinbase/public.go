@@ -2,25 +2,48 @@ package coinbase
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/simonks2016/book_manager"
 	"github.com/simonks2016/dex_plus/coinbase/internal"
 	"github.com/simonks2016/dex_plus/coinbase/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
 )
 
 type Public struct {
 	client      *internal.CoinbaseClient
 	cfg         *client.Config
-	logger      *log.Logger
+	logger      *slog.Logger
 	ctx         context.Context
+	symbols     []string
 	bookManager *bookManager.BookManager
+	streams     stream.Group
+	products    *InstrumentLoader // 加载 quote_increment 与 base_increment，确定盘口价格与数量的小数位数
+	scales      *bookscale.Scales
+	sequences   *sequenceTracker
+	seqMu       sync.Mutex
+	// tracking 已经注册了 match 的连续性检查，SubscribeOrderBook 重复调用时不再注册
+	tracking atomic.Bool
+	// trades 通过 SubscribeTrade 订阅了 matches，取消 level2 时保留 matches
+	trades atomic.Bool
 }
 
 func NewPublic(ctx context.Context, symbols ...string) *Public {
+	return New(ctx, options.WithSymbols(symbols...))
+}
+
+func New(ctx context.Context, opts ...Option) *Public {
 
 	cfg := client.NewConfig()
 	cfg.WithURL(internal.WsURL)
@@ -31,25 +54,55 @@ func NewPublic(ctx context.Context, symbols ...string) *Public {
 	cfg.SendTimeout = time.Minute
 	cfg.SetReadBufferSize(5000)
 	cfg.ForbidIPV6()
+	// BackpressureConflate 时按 type + product_id 合并
+	cfg.SetConflateKey(internal.MessageKey)
 
-	cli := internal.NewCoinbaseClient(ctx, cfg)
-	cli.SetSymbols(symbols...)
-
-	return &Public{
-		client:      cli,
+	p := &Public{
 		cfg:         cfg,
-		logger:      cfg.Logger,
 		ctx:         ctx,
+		symbols:     []string{},
 		bookManager: bookManager.NewBookManagerWithWorkers(10, 4000),
+		products:    NewInstrumentLoader(),
+		scales:      bookscale.NewScales(),
+		sequences:   newSequenceTracker(),
 	}
+	o := options.New(cfg, opts...)
+	p.symbols = append(p.symbols, o.Symbols()...)
+	p.products.BaseURL = o.RestURL(p.products.BaseURL)
+
+	p.client = internal.NewCoinbaseClient(ctx, cfg)
+	p.logger = p.client.Logger()
+	p.client.SetSymbols(p.symbols...)
+	return p
 }
-func (p *Public) SetSymbols(symbols ...string) { p.SetSymbols(symbols...) }
-func (p *Public) Connect()                     { p.client.Connect() }
-func (p *Public) Close()                       { p.client.Close() }
-func (p *Public) SubscribeTrade(callback func(trades payload.MatchedTrade) error) {
 
+func (p *Public) SetSymbols(symbols ...string) {
+	p.symbols = append(p.symbols, symbols...)
+	p.client.SetSymbols(symbols...)
+}
+
+func (p *Public) Connect()             { p.client.Connect() }
+func (p *Public) ExchangeName() string { return "coinbase" }
+
+func (p *Public) Close() {
+	p.client.Close()
+	p.streams.Close()
+}
+
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Public) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	// 回调处理完之后再关闭 Stream，阻塞在 Stream 上的回调随之退出
+	p.streams.Close()
+	return err
+}
+
+func (p *Public) SubscribeTrade(callback func(trades payload.MatchedTrade) error, opts ...SubscribeOption) {
+
+	p.trades.Store(true)
 	p.client.Subscribe("matches")
-	p.client.SetHandler("match", func(data []byte) error {
+	p.setHandler("match", options.NewSubscribeOptions(opts...), func(data []byte) error {
 		var t1 payload.MatchedTrade
 		if err := json.Unmarshal(data, &t1); err != nil {
 			return err
@@ -58,12 +111,92 @@ func (p *Public) SubscribeTrade(callback func(trades payload.MatchedTrade) error
 	})
 }
 
+// Subscribe 在已经订阅过的频道中追加品种，沿用该频道的回调；连接上立即生效并在重连后重放
+// channel 为订阅时的频道名，如 "matches"、"level2"；追加 level2 的品种时同时追加 matches，用于检查连续性
+func (p *Public) Subscribe(channel string, symbols ...string) error {
+	if !p.client.HasChannel(channel) {
+		return fmt.Errorf("channel %s has no handler, subscribe it with a Subscribe* method first", channel)
+	}
+	p.client.SubscribeSymbols(channel, symbols...)
+	if channel == "level2" && p.tracking.Load() {
+		p.client.SubscribeSymbols("matches", symbols...)
+	}
+	return nil
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有品种时回调一并移除
+// SubscribeOrderBook 依赖 matches 检查 level2 的连续性：仍在订阅 level2 的产品不能单独取消 matches，返回 ErrInvalidParams；
+// 取消 level2 时，如果没有通过 SubscribeTrade 订阅成交，这些产品的 matches 一并取消
+func (p *Public) Unsubscribe(channel string, symbols ...string) error {
+	var matches []string
+	if p.tracking.Load() {
+		switch channel {
+		case "matches":
+			if books := p.subscribed("level2", symbols); len(books) > 0 {
+				return fmt.Errorf("%w: matches of %s is used to check the level2 order book, unsubscribe level2 first",
+					common.ErrInvalidParams, strings.Join(books, ","))
+			}
+		case "level2":
+			if !p.trades.Load() {
+				matches = p.subscribed("matches", symbols)
+			}
+		}
+	}
+
+	err := p.client.Unsubscribe(channel, symbols...)
+	if err == nil && len(matches) > 0 {
+		err = p.client.Unsubscribe("matches", matches...)
+	}
+	// matches 整个取消后连续性检查的回调随之移除，再次 SubscribeOrderBook 时重新注册
+	if !p.client.HasChannel("matches") {
+		p.tracking.Store(false)
+	}
+	return err
+}
+
+// subscribed 频道中已经订阅的产品，symbols 不为空时只保留其中的产品
+func (p *Public) subscribed(channel string, symbols []string) []string {
+	current := p.client.Symbols(channel)
+	if len(symbols) == 0 {
+		return current
+	}
+	return slices.DeleteFunc(current, func(s string) bool { return !slices.Contains(symbols, s) })
+}
+
+// SubscriptionState 订阅的当前状态，没有订阅过的为 common.SubscriptionUnsubscribed
+// Coinbase 不回传请求 id，收到包含该品种的 subscriptions 消息即视为确认
+func (p *Public) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认或确认超时；回调不能阻塞
+func (p *Public) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
+}
+
+func (p *Public) setHandler(name string, o options.SubscribeOptions, caller internal.Caller) {
+	if o.Ordered {
+		p.client.SetOrderedHandler(name, caller)
+		return
+	}
+	p.client.SetHandler(name, caller)
+}
+
 // SubscribeOrderBook 优化：支持流式设置和统一管理
-func (p *Public) SubscribeOrderBook(interval time.Duration, callback func([]payload.OrderBook) error) {
-	p.client.Subscribe("level2")
+// level2 的推送不带序号，SubscribeOrderBook 会额外订阅同一批产品的 matches，按产品检查 sequence 与 trade_id，
+// 漏收消息时重新获取该产品的快照；matches 的推送同样计入订阅数与流量，Unsubscribe 的说明见该方法
+// 价格与数量按产品的 quote_increment 与 base_increment 换算，产品信息在后台从 /products 加载
+func (p *Public) SubscribeOrderBook(interval time.Duration, callback func([]payload.OrderBook) error, opts ...SubscribeOption) {
+	p.client.Subscribe("level2", "matches")
 	// 这里的 Handler 建议在初始化时就设置好，避免重复调用
-	p.client.SetHandler("snapshot", p.handlingOrderBookSnapshot)
-	p.client.SetHandler("l2update", p.handlingOrderBookDelta)
+	o := options.NewSubscribeOptions(opts...)
+	p.setHandler("snapshot", o, p.handlingOrderBookSnapshot)
+	p.setHandler("l2update", o, p.handlingOrderBookDelta)
+	// 连续性检查依赖到达顺序，重复调用时只注册一次
+	if p.tracking.CompareAndSwap(false, true) {
+		p.setHandler("match", options.SubscribeOptions{Ordered: true}, p.trackSequence)
+		go p.scales.Load(p.ctx, p.products, p.logger)
+	}
 
 	// 启动定时器
 	p.setSnapshotTimer(p.ctx, interval, callback)
@@ -77,6 +210,7 @@ func (p *Public) handlingOrderBookSnapshot(data []byte) error {
 	}
 
 	ob := p.bookManager.GetOrCreate(t1.ProductId)
+	prec, _ := p.scales.ForSnapshot(t1.ProductId)
 	// 修正：容量设为总和，长度设为 0
 	levels := make([]bookManager.Level, 0, len(t1.Bids)+len(t1.Asks))
 
@@ -86,22 +220,21 @@ func (p *Public) handlingOrderBookSnapshot(data []byte) error {
 			if len(item) < 2 {
 				continue
 			}
-			px, err1 := bookManager.PriceTicks(item[0], 100)
-			sz, err2 := bookManager.SizeFloat(item[1])
-			if err1 != nil || err2 != nil {
+			level, err := prec.ParseLevel(item[0], item[1], isBid)
+			if err != nil {
 				continue // 实际生产环境建议打一条采样日志
 			}
-			levels = append(levels, bookManager.Level{
-				PriceTicks: px,
-				Size:       sz,
-				IsBids:     isBid,
-			})
+			levels = append(levels, level)
 		}
 	}
 
 	parseAndAppend(t1.Bids, true)
 	parseAndAppend(t1.Asks, false)
 
+	// 新的快照之后重新计算成交的连续性
+	p.seqMu.Lock()
+	p.sequences.reset(t1.ProductId)
+	p.seqMu.Unlock()
 	return ob.ApplySnapshot(time.Now(), levels...)
 }
 
@@ -113,6 +246,7 @@ func (p *Public) handlingOrderBookDelta(data []byte) error {
 	}
 
 	ob := p.bookManager.GetOrCreate(t1.ProductId)
+	prec := p.scales.ForBook(t1.ProductId)
 	levels := make([]bookManager.Level, 0, len(t1.Changes))
 
 	for _, ch := range t1.Changes {
@@ -120,19 +254,13 @@ func (p *Public) handlingOrderBookDelta(data []byte) error {
 			continue
 		}
 
-		px, err1 := bookManager.PriceTicks(ch[1], 100)
-		sz, err2 := bookManager.SizeFloat(ch[2])
-		if err1 != nil || err2 != nil {
-			continue
-		}
-
 		// Coinbase 的 side 通常是 "buy" 或 "sell"
 		isBid := ch[0] == "buy" || ch[0] == "BUY"
-		levels = append(levels, bookManager.Level{
-			PriceTicks: px,
-			Size:       sz,
-			IsBids:     isBid,
-		})
+		level, err := prec.ParseLevel(ch[1], ch[2], isBid)
+		if err != nil {
+			continue
+		}
+		levels = append(levels, level)
 	}
 	return ob.ApplyL2Update(levels, time.Now())
 }
@@ -147,35 +275,35 @@ func (p *Public) setSnapshotTimer(ctx context.Context, interval time.Duration, c
 
 		resp := make([]payload.OrderBook, len(snapshots))
 		for i, snapshot := range snapshots {
-			bids := make([]payload.Level, 0, len(snapshot.Bids))
-			asks := make([]payload.Level, 0, len(snapshot.Asks))
-
-			for _, b := range snapshot.Bids {
-				bids = append(bids, payload.Level{
-					Price: bookManager.PriceTo(b.PriceTicks),
-					Size:  b.Size,
-				})
-			}
-			for _, a := range snapshot.Asks {
-				asks = append(asks, payload.Level{
-					Price: bookManager.PriceTo(a.PriceTicks),
-					Size:  a.Size,
-				})
-			}
-
+			prec := p.scales.ForBook(snapshot.ProductID)
 			resp[i] = payload.OrderBook{
 				ProductId: snapshot.ProductID,
-				Bids:      bids,
-				Asks:      asks,
+				Bids:      bookLevels(prec, snapshot.Bids),
+				Asks:      bookLevels(prec, snapshot.Asks),
 				Time:      time.UnixMilli(snapshot.Ts),
 			}
 		}
 
 		// 异步执行回调，防止阻塞管理器
 		go func(d []payload.OrderBook) {
-			if err := callback(d); err != nil && p.logger != nil {
-				p.logger.Printf("[error] callback failed: %v", err)
+			if err := callback(d); err != nil {
+				p.logger.Error("failed to handle book snapshot", "channel", "level2", "error", err)
 			}
 		}(resp)
 	})
 }
+
+// bookLevels 按盘口的精度还原档位，PriceDecimal 与 SizeDecimal 是精确值
+func bookLevels(prec bookscale.Precision, levels []bookManager.Level) []payload.Level {
+	ret := make([]payload.Level, 0, len(levels))
+	for _, l := range levels {
+		price, size := prec.Price(l.PriceTicks), prec.Size(l.Size)
+		ret = append(ret, payload.Level{
+			Price:        price.Float64(),
+			Size:         size.Float64(),
+			PriceDecimal: price,
+			SizeDecimal:  size,
+		})
+	}
+	return ret
+}
@@ -0,0 +1,92 @@
+package coinbase
+
+import (
+	"slices"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/coinbase/payload"
+)
+
+const (
+	// resyncInterval 同一个产品两次重新获取快照之间的最短间隔
+	resyncInterval = time.Second
+)
+
+// productSequence 一个产品最近一条成交的 sequence 与 trade_id
+type productSequence struct {
+	sequence int64
+	tradeId  int64
+	resyncAt time.Time
+}
+
+// sequenceTracker 按产品跟踪 match 推送的 sequence 与 trade_id；不是并发安全的，由调用方加锁
+// level2 的推送不带序号，同一产品的 trade_id 连续递增，跳号说明连接上漏收了消息，盘口同样不可信
+type sequenceTracker struct {
+	products map[string]*productSequence
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{products: make(map[string]*productSequence)}
+}
+
+// observe 记录一条成交：sequence 不大于上一条时为重复或乱序的消息，stale 为 true；trade_id 跳号时 gap 为 true
+func (t *sequenceTracker) observe(product string, sequence, tradeId int64) (stale, gap bool) {
+	s, ok := t.products[product]
+	if !ok {
+		t.products[product] = &productSequence{sequence: sequence, tradeId: tradeId}
+		return false, false
+	}
+	if sequence <= s.sequence {
+		return true, false
+	}
+	gap = s.tradeId > 0 && tradeId > s.tradeId+1
+	s.sequence, s.tradeId = sequence, tradeId
+	return false, gap
+}
+
+// shouldResync 距离上一次重新获取快照超过 resyncInterval
+func (t *sequenceTracker) shouldResync(product string, now time.Time) bool {
+	s, ok := t.products[product]
+	if !ok || now.Sub(s.resyncAt) < resyncInterval {
+		return false
+	}
+	s.resyncAt = now
+	return true
+}
+
+// reset 收到新的快照后以下一条成交为基准重新计数
+func (t *sequenceTracker) reset(product string) {
+	if s, ok := t.products[product]; ok {
+		s.sequence, s.tradeId = 0, 0
+	}
+}
+
+// trackSequence 检查 match 推送的连续性，漏收消息时重新订阅该产品的 level2 获取新的快照
+func (p *Public) trackSequence(data []byte) error {
+	var t payload.MatchedTrade
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	p.seqMu.Lock()
+	stale, gap := p.sequences.observe(t.ProductId, int64(t.Sequence), int64(t.TradeId))
+	resync := gap && p.sequences.shouldResync(t.ProductId, time.Now())
+	p.seqMu.Unlock()
+	// 只通过 SubscribeTrade 订阅了成交、没有订阅盘口的产品不需要重新获取快照
+	resync = resync && slices.Contains(p.client.Symbols("level2"), t.ProductId)
+
+	if stale {
+		p.logger.Debug("stale match ignored", "channel", "matches", "symbol", t.ProductId, "sequence", t.Sequence)
+	}
+	if resync {
+		p.logger.Warn("sequence gap, resyncing order book", "channel", "level2", "symbol", t.ProductId, "trade_id", t.TradeId)
+		// 回调运行在读协程上，重新订阅需要等待发送，放到单独的协程
+		go func() {
+			if err := p.client.ResubscribeFeed("level2", t.ProductId); err != nil {
+				p.logger.Error("failed to resubscribe channel", "channel", "level2", "symbol", t.ProductId, "error", err)
+			}
+		}()
+	}
+	return nil
+}
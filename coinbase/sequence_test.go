@@ -0,0 +1,126 @@
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/coinbase/payload"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	tr := newSequenceTracker()
+	if stale, gap := tr.observe("BTC-USD", 100, 10); stale || gap {
+		t.Fatal("first match flagged")
+	}
+	if stale, gap := tr.observe("BTC-USD", 105, 11); stale || gap {
+		t.Fatal("contiguous match flagged")
+	}
+	if stale, _ := tr.observe("BTC-USD", 104, 12); !stale {
+		t.Fatal("older sequence not flagged as stale")
+	}
+	if _, gap := tr.observe("BTC-USD", 110, 13); !gap {
+		t.Fatal("trade_id gap not detected")
+	}
+	now := time.Now()
+	if !tr.shouldResync("BTC-USD", now) || tr.shouldResync("BTC-USD", now.Add(time.Millisecond)) {
+		t.Fatal("resync not throttled")
+	}
+
+	tr.reset("BTC-USD")
+	if _, gap := tr.observe("BTC-USD", 200, 20); gap {
+		t.Fatal("gap reported across a snapshot")
+	}
+}
+
+func TestOrderBookResyncOffline(t *testing.T) {
+	srv := dextest.NewCoinbaseServer()
+	defer srv.Close()
+	srv.HandleJSON("/products", []payload.Product{
+		{Id: "BTC-USD", QuoteIncrement: "0.01000000", BaseIncrement: "0.00000001"},
+		{Id: "SHIB-USD", QuoteIncrement: "0.00000001", BaseIncrement: "1"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := New(ctx,
+		options.WithURL(srv.URL()),
+		options.WithRestURL(srv.HTTPURL()),
+		options.WithSymbols("BTC-USD"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	p.SubscribeOrderBook(time.Hour, func([]payload.OrderBook) error { return nil })
+	p.Connect()
+	defer p.Close()
+
+	if _, ok := srv.WaitRequest("subscribe", "matches", 5*time.Second); !ok {
+		t.Fatal("no matches subscription")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for p.scales.ForBook("BTC-USD") != (bookscale.Precision{PriceDecimals: 2, SizeDecimals: 8}) ||
+		p.scales.ForBook("SHIB-USD") != (bookscale.Precision{PriceDecimals: 8, SizeDecimals: 0}) {
+		if time.Now().After(deadline) {
+			t.Fatal("book precision not loaded from quote_increment and base_increment")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	match := func(seq, tradeId int) map[string]any {
+		return map[string]any{
+			"type": "match", "trade_id": tradeId, "sequence": seq, "time": "2023-11-14T22:13:20.000000Z",
+			"product_id": "BTC-USD", "size": "0.01", "price": "42000.10", "side": "buy",
+		}
+	}
+	_ = srv.Push(match(100, 10))
+	_ = srv.Push(match(105, 12))
+	if _, ok := srv.WaitRequest("unsubscribe", "level2", 5*time.Second); !ok {
+		t.Fatal("level2 not resubscribed after trade_id gap")
+	}
+}
+
+func TestUnsubscribeMatchesOffline(t *testing.T) {
+	srv := dextest.NewCoinbaseServer()
+	defer srv.Close()
+	srv.HandleJSON("/products", []payload.Product{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := New(ctx,
+		options.WithURL(srv.URL()),
+		options.WithRestURL(srv.HTTPURL()),
+		options.WithSymbols("BTC-USD"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	// 重复调用只注册一次连续性检查
+	p.SubscribeOrderBook(time.Hour, func([]payload.OrderBook) error { return nil })
+	p.SubscribeOrderBook(time.Hour, func([]payload.OrderBook) error { return nil })
+	p.Connect()
+	defer p.Close()
+
+	if _, ok := srv.WaitRequest("subscribe", "matches", 5*time.Second); !ok {
+		t.Fatal("no matches subscription")
+	}
+	if err := p.Unsubscribe("matches"); !errors.Is(err, common.ErrInvalidParams) {
+		t.Fatalf("Unsubscribe(matches) = %v, want ErrInvalidParams while level2 is subscribed", err)
+	}
+
+	// 没有订阅成交时，matches 随 level2 一并取消
+	if err := p.Unsubscribe("level2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.WaitRequest("unsubscribe", "matches", 5*time.Second); !ok {
+		t.Fatal("matches not unsubscribed together with level2")
+	}
+	if p.tracking.Load() {
+		t.Fatal("sequence tracking still registered after matches was removed")
+	}
+}
@@ -0,0 +1,28 @@
+package common
+
+// BackpressurePolicy WebSocket 读队列写满时的处理策略
+type BackpressurePolicy int
+
+const (
+	// BackpressureDropNewest 丢弃刚读到的帧（默认）
+	BackpressureDropNewest BackpressurePolicy = iota
+	// BackpressureBlock 阻塞 readPump 直到有空位，不丢帧；阻塞期间不会读取控制帧，处理过慢时可能因 PongWait 超时重连
+	BackpressureBlock
+	// BackpressureDropOldest 丢弃队列里最早的帧，保留最新的帧
+	BackpressureDropOldest
+	// BackpressureConflate 队列满时按合并键合并，相同键只保留最新一帧，适合 ticker 等快照类数据
+	BackpressureConflate
+)
+
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureBlock:
+		return "block"
+	case BackpressureDropOldest:
+		return "drop_oldest"
+	case BackpressureConflate:
+		return "conflate"
+	default:
+		return "drop_newest"
+	}
+}
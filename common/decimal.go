@@ -0,0 +1,336 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal 任意精度的定点小数，数值 = value * 10^(-scale)
+// 零值可以直接使用，表示 0；String 会保留原始的小数位数，例如 "0.10" 仍输出 "0.10"
+type Decimal struct {
+	value *big.Int
+	scale int32
+}
+
+const (
+	// maxExponent 科学计数法指数的绝对值上限，防止 "1e999999999" 这样的输入分配巨大的整数
+	maxExponent = 1000
+	// maxScale 解析结果的小数位数上限
+	maxScale = 1000
+)
+
+var (
+	bigTen     = big.NewInt(10)
+	pow10Cache [40]*big.Int
+)
+
+func init() {
+	p := big.NewInt(1)
+	for i := range pow10Cache {
+		pow10Cache[i] = new(big.Int).Set(p)
+		p.Mul(p, bigTen)
+	}
+}
+
+func pow10(n int32) *big.Int {
+	if n >= 0 && int(n) < len(pow10Cache) {
+		return pow10Cache[n]
+	}
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// NewDecimal 由整数系数和小数位数构造，NewDecimal(12345, 2) = 123.45
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	if scale < 0 {
+		v := new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))
+		return Decimal{value: v}
+	}
+	return Decimal{value: big.NewInt(unscaled), scale: scale}
+}
+
+// NewDecimalFromInt 整数转换
+func NewDecimalFromInt(i int64) Decimal {
+	return Decimal{value: big.NewInt(i)}
+}
+
+// NewDecimalFromFloat 按最短可还原的十进制表示转换浮点数
+func NewDecimalFromFloat(f float64) Decimal {
+	d, err := ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return Decimal{}
+	}
+	return d
+}
+
+// ParseDecimal 解析十进制字符串，支持符号、小数点和科学计数法（如 "-1.5e-3"）
+// 指数的绝对值不能超过 1000，结果的小数位数也不能超过 1000
+func ParseDecimal(s string) (Decimal, error) {
+	orig := s
+	if len(s) == 0 {
+		return Decimal{}, fmt.Errorf("decimal: empty string")
+	}
+
+	var exp int64
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("decimal: invalid exponent in %q", orig)
+		}
+		if e > maxExponent || e < -maxExponent {
+			return Decimal{}, fmt.Errorf("decimal: exponent out of range in %q", orig)
+		}
+		exp = e
+		s = s[:i]
+	}
+
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if len(intPart) == 0 && len(fracPart) == 0 {
+		return Decimal{}, fmt.Errorf("decimal: invalid number %q", orig)
+	}
+
+	digits := intPart + fracPart
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return Decimal{}, fmt.Errorf("decimal: invalid number %q", orig)
+		}
+	}
+
+	v, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("decimal: invalid number %q", orig)
+	}
+	if neg {
+		v.Neg(v)
+	}
+
+	scale := int64(len(fracPart)) - exp
+	if scale > maxScale {
+		return Decimal{}, fmt.Errorf("decimal: too many decimal places in %q", orig)
+	}
+	if scale < 0 {
+		v.Mul(v, pow10(int32(-scale)))
+		scale = 0
+	}
+	return Decimal{value: v, scale: int32(scale)}, nil
+}
+
+// MustDecimal 解析失败时 panic，只用于常量
+func MustDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) big() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// rescale 将小数位数扩大到 scale（只扩大不缩小）
+func (d Decimal) rescale(scale int32) *big.Int {
+	v := d.big()
+	if scale <= d.scale {
+		return v
+	}
+	return new(big.Int).Mul(v, pow10(scale-d.scale))
+}
+
+// Scale 小数位数
+func (d Decimal) Scale() int32 { return d.scale }
+
+// Sign 返回 -1、0、1
+func (d Decimal) Sign() int { return d.big().Sign() }
+
+func (d Decimal) IsZero() bool { return d.Sign() == 0 }
+
+// Cmp 比较大小，与小数位数无关：1.0 与 1.00 相等
+func (d Decimal) Cmp(o Decimal) int {
+	scale := max(d.scale, o.scale)
+	return d.rescale(scale).Cmp(o.rescale(scale))
+}
+
+func (d Decimal) Equal(o Decimal) bool { return d.Cmp(o) == 0 }
+
+func (d Decimal) Add(o Decimal) Decimal {
+	scale := max(d.scale, o.scale)
+	return Decimal{value: new(big.Int).Add(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	scale := max(d.scale, o.scale)
+	return Decimal{value: new(big.Int).Sub(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{value: new(big.Int).Mul(d.big(), o.big()), scale: d.scale + o.scale}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{value: new(big.Int).Neg(d.big()), scale: d.scale}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{value: new(big.Int).Abs(d.big()), scale: d.scale}
+}
+
+// Div 除法，结果保留 scale 位小数（四舍五入）；除数为 0 时返回 0 和 false
+func (d Decimal) Div(o Decimal, scale int32) (Decimal, bool) {
+	if o.IsZero() {
+		return Decimal{}, false
+	}
+	// d/o = (dv*10^-ds) / (ov*10^-os)，先放大分子使商带有 scale+1 位小数
+	shift := scale + 1 + o.scale - d.scale
+	num := new(big.Int).Set(d.big())
+	den := new(big.Int).Set(o.big())
+	if shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	q := new(big.Int).Quo(num, den)
+	return Decimal{value: q, scale: scale + 1}.Round(scale), true
+}
+
+// Round 四舍五入（远离零）到 scale 位小数
+func (d Decimal) Round(scale int32) Decimal {
+	if scale >= d.scale {
+		return Decimal{value: d.rescale(scale), scale: scale}
+	}
+	div := pow10(d.scale - scale)
+	q, r := new(big.Int).QuoRem(d.big(), div, new(big.Int))
+	// |r|*2 >= div 时进位
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(div) >= 0 {
+		if d.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{value: q, scale: scale}
+}
+
+// Truncate 直接截断到 scale 位小数
+func (d Decimal) Truncate(scale int32) Decimal {
+	if scale >= d.scale {
+		return Decimal{value: d.rescale(scale), scale: scale}
+	}
+	q := new(big.Int).Quo(d.big(), pow10(d.scale-scale))
+	return Decimal{value: q, scale: scale}
+}
+
+// Normalize 去掉末尾多余的 0，1.2300 -> 1.23
+func (d Decimal) Normalize() Decimal {
+	v := new(big.Int).Set(d.big())
+	scale := d.scale
+	if v.Sign() == 0 {
+		return Decimal{}
+	}
+	r := new(big.Int)
+	for scale > 0 {
+		q, m := new(big.Int).QuoRem(v, bigTen, r)
+		if m.Sign() != 0 {
+			break
+		}
+		v = q
+		scale--
+	}
+	return Decimal{value: v, scale: scale}
+}
+
+// Float64 转换为浮点数，仅用于展示或近似计算
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Int64 返回整数部分
+func (d Decimal) Int64() int64 {
+	return d.Truncate(0).big().Int64()
+}
+
+// String 普通表示法，保留小数位数
+func (d Decimal) String() string {
+	v := d.big()
+	if d.scale <= 0 {
+		return v.String()
+	}
+
+	digits := new(big.Int).Abs(v).String()
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+
+	var sb strings.Builder
+	if v.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(digits[:point])
+	sb.WriteByte('.')
+	sb.WriteString(digits[point:])
+	return sb.String()
+}
+
+// MarshalJSON 输出为带引号的字符串，避免下游按浮点数解析
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON 同时支持 "1.23" 与 1.23 两种写法，null 与 "" 视为 0
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+	if len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		b = b[1 : len(b)-1]
+	}
+	if len(b) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func TestParseDecimal(t *testing.T) {
+	cases := map[string]string{
+		"0":          "0",
+		"0.10":       "0.10",
+		"-1.5":       "-1.5",
+		".5":         "0.5",
+		"12345.6789": "12345.6789",
+		"1e3":        "1000",
+		"1.5e-3":     "0.0015",
+		"-0.000001":  "-0.000001",
+	}
+	for in, want := range cases {
+		d, err := ParseDecimal(in)
+		if err != nil {
+			t.Fatalf("ParseDecimal(%q): %v", in, err)
+		}
+		if got := d.String(); got != want {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "-", "1.2.3", "abc", "1e", "1e999999999", "1e-1001", "0." + strings.Repeat("1", 1001)} {
+		if _, err := ParseDecimal(in); err == nil {
+			t.Errorf("ParseDecimal(%q) expected error", in)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a := MustDecimal("0.1")
+	b := MustDecimal("0.2")
+
+	if got := a.Add(b); !got.Equal(MustDecimal("0.3")) {
+		t.Errorf("0.1+0.2 = %s", got)
+	}
+	if got := a.Sub(b).String(); got != "-0.1" {
+		t.Errorf("0.1-0.2 = %s", got)
+	}
+	if got := MustDecimal("1.5").Mul(MustDecimal("-2.25")).String(); got != "-3.375" {
+		t.Errorf("1.5*-2.25 = %s", got)
+	}
+	if got, _ := MustDecimal("10").Div(MustDecimal("3"), 4); got.String() != "3.3333" {
+		t.Errorf("10/3 = %s", got)
+	}
+	if got, _ := MustDecimal("2").Div(MustDecimal("3"), 2); got.String() != "0.67" {
+		t.Errorf("2/3 = %s", got)
+	}
+	if _, ok := a.Div(Decimal{}, 2); ok {
+		t.Errorf("division by zero should fail")
+	}
+	if got := MustDecimal("-2.345").Round(2).String(); got != "-2.35" {
+		t.Errorf("round(-2.345) = %s", got)
+	}
+	if got := MustDecimal("2.349").Truncate(2).String(); got != "2.34" {
+		t.Errorf("truncate(2.349) = %s", got)
+	}
+	if got := MustDecimal("1.2300").Normalize().String(); got != "1.23" {
+		t.Errorf("normalize(1.2300) = %s", got)
+	}
+	if MustDecimal("1.0").Cmp(MustDecimal("1.00")) != 0 {
+		t.Errorf("1.0 should equal 1.00")
+	}
+	var zero Decimal
+	if !zero.IsZero() || zero.String() != "0" {
+		t.Errorf("zero value = %s", zero)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		Quoted Decimal `json:"quoted"`
+		Bare   Decimal `json:"bare"`
+		Null   Decimal `json:"null"`
+	}
+	if err := json.Unmarshal([]byte(`{"quoted":"34000.10","bare":0.00012,"null":null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Quoted.String() != "34000.10" || v.Bare.String() != "0.00012" || !v.Null.IsZero() {
+		t.Fatalf("unexpected decode: %s %s %s", v.Quoted, v.Bare, v.Null)
+	}
+
+	out, err := json.Marshal(v.Quoted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `"34000.10"` {
+		t.Fatalf("marshal = %s", out)
+	}
+}
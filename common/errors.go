@@ -0,0 +1,77 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotConnected 连接尚未建立或已经断开，重连后可以重试
+	ErrNotConnected = errors.New("not connected")
+	// ErrClosed 客户端已经关闭，不会再恢复
+	ErrClosed = errors.New("client closed")
+	// ErrQueueFull 发送队列已满，稍后可以重试
+	ErrQueueFull = errors.New("queue is full")
+	// ErrTimeout 请求在超时时间内没有返回
+	ErrTimeout = errors.New("request timeout")
+	// ErrAuthRequired 需要登录验证但没有配置密钥
+	ErrAuthRequired = errors.New("authentication required")
+	// ErrInvalidParams 请求参数不合法，没有发送到交易所
+	ErrInvalidParams = errors.New("invalid params")
+	// ErrSubscriptionTimeout 订阅请求在超时与全部重试之后仍然没有收到确认
+	ErrSubscriptionTimeout = errors.New("subscription: ack timed out")
+)
+
+// ExchangeError 交易所返回的业务错误，如下单被拒、订阅被拒或 HTTP 非 2xx 状态码
+type ExchangeError struct {
+	Exchange string
+	// Code 交易所的错误码，HTTP 错误时为状态码
+	Code string
+	Msg  string
+	// Retryable 交易所侧的临时故障（系统繁忙、5xx 等），原样重试可能成功
+	Retryable bool
+}
+
+func (e *ExchangeError) Error() string {
+	if e.Msg == "" {
+		return fmt.Sprintf("%s: code %s", e.Exchange, e.Code)
+	}
+	return fmt.Sprintf("%s: code %s, %s", e.Exchange, e.Code, e.Msg)
+}
+
+// RateLimitError 触发交易所限频，RetryAfter 为交易所建议的等待时间，未知时为 0
+type RateLimitError struct {
+	Exchange   string
+	Code       string
+	Msg        string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("%s: rate limited", e.Exchange)
+	if e.Msg != "" {
+		msg += ", " + e.Msg
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %s", e.RetryAfter)
+	}
+	return msg
+}
+
+// IsRetryable 错误是否为临时故障：断线、队列满、超时、限频以及交易所标记为可重试的错误
+// 订单被拒、参数错误、验证失败等返回 false
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotConnected) || errors.Is(err, ErrQueueFull) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return true
+	}
+	var ex *ExchangeError
+	return errors.As(err, &ex) && ex.Retryable
+}
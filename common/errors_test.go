@@ -0,0 +1,37 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{fmt.Errorf("send: %w", ErrNotConnected), true},
+		{fmt.Errorf("write channel busy: %w", ErrQueueFull), true},
+		{&RateLimitError{Exchange: OKX, Code: "50011"}, true},
+		{fmt.Errorf("GET /x: %w", &ExchangeError{Exchange: Binance, Code: "503", Retryable: true}), true},
+		{&ExchangeError{Exchange: OKX, Code: "51008", Msg: "insufficient balance"}, false},
+		{fmt.Errorf("%w: params is empty", ErrInvalidParams), false},
+		{ErrClosed, false},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+
+	var ex *ExchangeError
+	err := fmt.Errorf("place order: %w", &ExchangeError{Exchange: OKX, Code: "51008", Msg: "insufficient balance"})
+	if !errors.As(err, &ex) || ex.Code != "51008" {
+		t.Fatalf("errors.As = %v", ex)
+	}
+	if got := ex.Error(); got != "okx: code 51008, insufficient balance" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type InstrumentType string
+
+const (
+	InstrumentSpot   InstrumentType = "spot"
+	InstrumentSwap   InstrumentType = "swap"
+	InstrumentFuture InstrumentType = "future"
+	InstrumentOption InstrumentType = "option"
+)
+
+// Instrument 交易产品基础信息
+// Symbol 为交易所原生代码（如 "BTC-USDT-SWAP"、"BTC/USD"、"btcusdt"），Canonical 为跨交易所统一代码
+type Instrument struct {
+	Exchange  string         `json:"exchange"`
+	Symbol    string         `json:"symbol"`
+	Canonical string         `json:"canonical"`
+	Type      InstrumentType `json:"type"`
+
+	Base   string `json:"base"`
+	Quote  string `json:"quote"`
+	Settle string `json:"settle"`
+
+	TickSize      Decimal `json:"tick_size"`
+	LotSize       Decimal `json:"lot_size"`
+	MinSize       Decimal `json:"min_size"`
+	MinNotional   Decimal `json:"min_notional"`
+	ContractValue Decimal `json:"contract_value"`
+
+	// 交割合约、期权才有
+	Expiry     time.Time `json:"expiry"`
+	Strike     Decimal   `json:"strike"`
+	OptionType string    `json:"option_type"`
+}
+
+// CanonicalSymbol 生成统一代码
+// 现货: BTC-USDT，永续: BTC-USDT-SWAP，交割: BTC-USD-FUTURE-20250627，期权: BTC-USD-OPTION-20250627-60000-C
+func (i Instrument) CanonicalSymbol() string {
+	if i.Canonical != "" {
+		return i.Canonical
+	}
+	pair := SymbolInfo{Base: i.Base, Quote: i.Quote}.Standardize().StandardizeString()
+
+	switch i.Type {
+	case InstrumentSwap:
+		return pair + "-SWAP"
+	case InstrumentFuture:
+		return pair + "-FUTURE-" + i.Expiry.UTC().Format("20060102")
+	case InstrumentOption:
+		return fmt.Sprintf("%s-OPTION-%s-%s-%s",
+			pair, i.Expiry.UTC().Format("20060102"), i.Strike.Normalize(), strings.ToUpper(i.OptionType))
+	default:
+		return pair
+	}
+}
+
+// InstrumentLoader 从交易所的 REST 接口或推送频道加载产品信息
+type InstrumentLoader interface {
+	Exchange() string
+	LoadInstruments(ctx context.Context) ([]Instrument, error)
+}
+
+type instrumentKey struct {
+	exchange string
+	symbol   string
+}
+
+func newInstrumentKey(exchange, symbol string) instrumentKey {
+	// 币安、Bitstamp 的推送使用小写代码，REST 使用大写代码，统一按大写索引
+	return instrumentKey{exchange: strings.ToLower(exchange), symbol: strings.ToUpper(symbol)}
+}
+
+// InstrumentRegistry 按 (交易所, 原生代码) 保存产品信息，并提供原生代码与统一代码的双向转换
+type InstrumentRegistry struct {
+	mu          sync.RWMutex
+	byNative    map[instrumentKey]Instrument
+	byCanonical map[instrumentKey]string
+}
+
+// DefaultInstrumentRegistry 默认注册表，ParseSymbol 会优先从这里查询
+var DefaultInstrumentRegistry = NewInstrumentRegistry()
+
+func NewInstrumentRegistry() *InstrumentRegistry {
+	return &InstrumentRegistry{
+		byNative:    make(map[instrumentKey]Instrument),
+		byCanonical: make(map[instrumentKey]string),
+	}
+}
+
+// Add 添加或覆盖产品信息
+func (r *InstrumentRegistry) Add(instruments ...Instrument) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, inst := range instruments {
+		inst.Exchange = strings.ToLower(inst.Exchange)
+		inst.Canonical = inst.CanonicalSymbol()
+
+		r.byNative[newInstrumentKey(inst.Exchange, inst.Symbol)] = inst
+		r.byCanonical[newInstrumentKey(inst.Exchange, inst.Canonical)] = inst.Symbol
+	}
+}
+
+// Load 调用各个 loader 加载产品信息，某个交易所失败不影响其它交易所
+func (r *InstrumentRegistry) Load(ctx context.Context, loaders ...InstrumentLoader) error {
+	var errs []string
+
+	for _, loader := range loaders {
+		instruments, err := loader.LoadInstruments(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", loader.Exchange(), err.Error()))
+			continue
+		}
+		r.Add(instruments...)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to load instruments, %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Get 按原生代码查询
+func (r *InstrumentRegistry) Get(exchange, symbol string) (Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inst, ok := r.byNative[newInstrumentKey(exchange, symbol)]
+	return inst, ok
+}
+
+// GetByCanonical 按统一代码查询
+func (r *InstrumentRegistry) GetByCanonical(exchange, canonical string) (Instrument, bool) {
+	native, ok := r.ToNative(exchange, canonical)
+	if !ok {
+		return Instrument{}, false
+	}
+	return r.Get(exchange, native)
+}
+
+// ToCanonical 原生代码 -> 统一代码
+func (r *InstrumentRegistry) ToCanonical(exchange, symbol string) (string, bool) {
+	inst, ok := r.Get(exchange, symbol)
+	if !ok {
+		return "", false
+	}
+	return inst.Canonical, true
+}
+
+// ToNative 统一代码 -> 原生代码
+func (r *InstrumentRegistry) ToNative(exchange, canonical string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	native, ok := r.byCanonical[newInstrumentKey(exchange, canonical)]
+	return native, ok
+}
+
+// Instruments 返回某个交易所的全部产品，按原生代码排序
+func (r *InstrumentRegistry) Instruments(exchange string) []Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exchange = strings.ToLower(exchange)
+	ret := make([]Instrument, 0)
+	for k, inst := range r.byNative {
+		if k.exchange == exchange {
+			ret = append(ret, inst)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Symbol < ret[j].Symbol })
+	return ret
+}
+
+// Len 产品数量
+func (r *InstrumentRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.byNative)
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type staticLoader struct {
+	exchange    string
+	instruments []Instrument
+}
+
+func (l staticLoader) Exchange() string { return l.exchange }
+func (l staticLoader) LoadInstruments(context.Context) ([]Instrument, error) {
+	return l.instruments, nil
+}
+
+func TestInstrumentRegistry(t *testing.T) {
+	r := NewInstrumentRegistry()
+
+	err := r.Load(context.Background(),
+		staticLoader{exchange: Binance, instruments: []Instrument{
+			{Exchange: Binance, Symbol: "btcusdc", Base: "BTC", Quote: "USDC", Type: InstrumentSpot, TickSize: MustDecimal("0.01")},
+			{Exchange: Binance, Symbol: "usdttry", Base: "USDT", Quote: "TRY", Type: InstrumentSpot},
+		}},
+		staticLoader{exchange: OKX, instruments: []Instrument{
+			{Exchange: OKX, Symbol: "BTC-USDT-SWAP", Base: "BTC", Quote: "USDT", Settle: "USDT", Type: InstrumentSwap},
+			{Exchange: OKX, Symbol: "BTC-USD-250627", Base: "BTC", Quote: "USD", Type: InstrumentFuture,
+				Expiry: time.Date(2025, 6, 27, 8, 0, 0, 0, time.UTC)},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inst, ok := r.Get(Binance, "BTCUSDC")
+	if !ok || inst.Quote != "USDC" || inst.TickSize.String() != "0.01" {
+		t.Fatalf("unexpected instrument: %+v", inst)
+	}
+	if c, _ := r.ToCanonical(Binance, "usdttry"); c != "USDT-TRY" {
+		t.Errorf("canonical = %s", c)
+	}
+	if n, _ := r.ToNative(OKX, "BTC-USDT-SWAP"); n != "BTC-USDT-SWAP" {
+		t.Errorf("native = %s", n)
+	}
+	if n, _ := r.ToNative(OKX, "BTC-USD-FUTURE-20250627"); n != "BTC-USD-250627" {
+		t.Errorf("native = %s", n)
+	}
+	if got := len(r.Instruments(OKX)); got != 2 {
+		t.Errorf("okx instruments = %d", got)
+	}
+}
+
+func TestParseSymbolWithRegistry(t *testing.T) {
+	// ParseSymbol 查询默认注册表，测试期间换成新的注册表，结束后恢复
+	saved := DefaultInstrumentRegistry
+	DefaultInstrumentRegistry = NewInstrumentRegistry()
+	t.Cleanup(func() { DefaultInstrumentRegistry = saved })
+
+	DefaultInstrumentRegistry.Add(Instrument{Exchange: Bitstamp, Symbol: "usdtbrl", Base: "USDT", Quote: "BRL"})
+
+	if got := ParseSymbol(Bitstamp, "usdtbrl"); got.Base != "USDT" || got.Quote != "BRL" {
+		t.Errorf("ParseSymbol = %+v", got)
+	}
+}
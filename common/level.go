@@ -0,0 +1,29 @@
+package common
+
+import "fmt"
+
+// PriceLevel 盘口档位，价格与数量均为精确小数
+type PriceLevel struct {
+	Price Decimal `json:"price"`
+	Size  Decimal `json:"size"`
+}
+
+// ParseLevels 解析 [["price","size",...], ...] 形式的档位，忽略长度不足的项
+func ParseLevels(items [][]string) ([]PriceLevel, error) {
+	levels := make([]PriceLevel, 0, len(items))
+	for _, item := range items {
+		if len(item) < 2 {
+			continue
+		}
+		px, err := ParseDecimal(item[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %q: %w", item[0], err)
+		}
+		sz, err := ParseDecimal(item[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q: %w", item[1], err)
+		}
+		levels = append(levels, PriceLevel{Price: px, Size: sz})
+	}
+	return levels, nil
+}
@@ -26,7 +26,8 @@ func ParseCoinBaseSymbol(symbol string) SymbolInfo {
 }
 
 // ParseNoDelimiterSymbol 处理 "btcusdt" 或 "btcusd" (Binance/Bitstamp)
-// 注意：由于没有分隔符，需要匹配常见的计价货币(Quote)
+// 注意：由于没有分隔符，需要匹配常见的计价货币(Quote)，结果只是猜测；
+// 加载了 InstrumentRegistry 后，ParseSymbol 会优先使用注册表中的信息
 func ParseNoDelimiterSymbol(symbol string) SymbolInfo {
 	symbol = strings.ToUpper(symbol)
 	// 常见的计价币种（按长度倒序排列，防止先匹配到 USD 而错过 USDT）
@@ -60,6 +61,11 @@ func (s SymbolInfo) StandardizeString() string {
 func ParseSymbol(exchange string, symbol string) SymbolInfo {
 	exchange = strings.ToLower(exchange)
 
+	// 优先使用交易所下发的产品信息
+	if inst, ok := DefaultInstrumentRegistry.Get(exchange, symbol); ok && inst.Base != "" {
+		return SymbolInfo{Base: inst.Base, Quote: inst.Quote}.Standardize()
+	}
+
 	switch exchange {
 	case "okx", "coinbase":
 		return ParseOKXSymbol(symbol)
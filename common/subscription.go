@@ -0,0 +1,45 @@
+package common
+
+import "time"
+
+// SubscriptionState 单个 (channel, symbol) 订阅的状态
+type SubscriptionState int
+
+const (
+	// SubscriptionUnsubscribed 未订阅或已取消订阅
+	SubscriptionUnsubscribed SubscriptionState = iota
+	// SubscriptionSubscribing 已发送订阅请求，等待确认
+	SubscriptionSubscribing
+	// SubscriptionSubscribed 交易所已确认订阅
+	SubscriptionSubscribed
+	// SubscriptionResubscribing 重连或重新订阅后等待确认
+	SubscriptionResubscribing
+	// SubscriptionFailed 交易所拒绝订阅，或重试后仍然没有确认
+	SubscriptionFailed
+)
+
+func (s SubscriptionState) String() string {
+	switch s {
+	case SubscriptionSubscribing:
+		return "subscribing"
+	case SubscriptionSubscribed:
+		return "subscribed"
+	case SubscriptionResubscribing:
+		return "resubscribing"
+	case SubscriptionFailed:
+		return "failed"
+	default:
+		return "unsubscribed"
+	}
+}
+
+// SubscriptionChange 订阅状态的一次变化
+type SubscriptionChange struct {
+	Channel string
+	Symbol  string
+	From    SubscriptionState
+	To      SubscriptionState
+	// Err 变为 SubscriptionFailed 的原因：交易所返回的错误或 ErrSubscriptionTimeout
+	Err  error
+	Time time.Time
+}
@@ -1,7 +1,6 @@
 package DexPlus
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"os"
@@ -11,8 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/simonks2016/dex_plus/dextest"
 	"github.com/simonks2016/dex_plus/kraken"
 	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/options"
 )
 
 func NewLogger() *log.Logger {
@@ -24,13 +25,11 @@ func NewLogger() *log.Logger {
 }
 
 func TestNew(t *testing.T) {
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx := dextest.LiveContext(t)
 
 	client := kraken.NewPublic(
 		ctx,
-		kraken.WithLogger(NewLogger()),
+		options.WithLogger(NewLogger()),
 	)
 
 	client.SetSymbols("BTC/USDT")
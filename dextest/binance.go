@@ -0,0 +1,78 @@
+package dextest
+
+import (
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// BinanceServer 模拟币安组合流 /stream
+type BinanceServer struct {
+	*Server
+}
+
+func NewBinanceServer() *BinanceServer {
+	return &BinanceServer{Server: newServer(binanceDialect{})}
+}
+
+// Push 推送 {"stream":..,"data":..}，stream 形如 "btcusdt@aggTrade"
+func (s *BinanceServer) Push(stream string, data any) error {
+	return s.Broadcast(map[string]any{"stream": stream, "data": data})
+}
+
+// ServerShutdown 服务即将关闭，客户端应当重连
+func (s *BinanceServer) ServerShutdown() error {
+	return s.Push("serverShutdown", map[string]any{"e": "serverShutdown", "E": time.Now().UnixMilli()})
+}
+
+type binanceDialect struct{}
+
+type binanceRequest struct {
+	Method string          `json:"method"`
+	Params []string        `json:"params"`
+	Id     json.RawMessage `json:"id"`
+}
+
+func (binanceDialect) parse(msg []byte) []Request {
+	var r binanceRequest
+	if err := json.Unmarshal(msg, &r); err != nil || r.Method == "" {
+		return nil
+	}
+	op := strings.ToLower(r.Method)
+	if len(r.Params) == 0 {
+		return []Request{{Op: op, ID: string(r.Id)}}
+	}
+
+	reqs := make([]Request, 0, len(r.Params))
+	for _, stream := range r.Params {
+		// btcusdt@depth@100ms -> 频道 depth
+		parts := strings.Split(stream, "@")
+		req := Request{Op: op, ID: string(r.Id), Channel: stream}
+		if len(parts) >= 2 {
+			req.Channel = parts[1]
+			req.Symbols = []string{parts[0]}
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// ack 一条请求只回复一次
+func (binanceDialect) ack(reqs []Request) []any {
+	return []any{map[string]any{"result": nil, "id": json.RawMessage(orNull(reqs[0].ID))}}
+}
+
+func (binanceDialect) reject(req Request, code, msg string) any {
+	return map[string]any{
+		"error": map[string]any{"code": json.RawMessage(orNull(code)), "msg": msg},
+		"id":    json.RawMessage(orNull(req.ID)),
+	}
+}
+
+func orNull(s string) string {
+	if s == "" {
+		return "null"
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package dextest
+
+import (
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// BitstampServer 模拟 Bitstamp v2 WebSocket
+type BitstampServer struct {
+	*Server
+}
+
+func NewBitstampServer() *BitstampServer {
+	return &BitstampServer{Server: newServer(bitstampDialect{})}
+}
+
+// Push 推送 {"event":..,"channel":..,"data":..}，channel 形如 "live_trades_btcusd"
+func (s *BitstampServer) Push(channel, event string, data any) error {
+	return s.Broadcast(map[string]any{"event": event, "channel": channel, "data": data})
+}
+
+// RequestReconnect 服务端要求客户端重连
+func (s *BitstampServer) RequestReconnect() error {
+	return s.Push("", "bts:request_reconnect", map[string]any{})
+}
+
+type bitstampDialect struct{}
+
+type bitstampRequest struct {
+	Event string `json:"event"`
+	Data  struct {
+		Channel string `json:"channel"`
+	} `json:"data"`
+}
+
+func (bitstampDialect) parse(msg []byte) []Request {
+	var r bitstampRequest
+	if err := json.Unmarshal(msg, &r); err != nil || r.Event == "" {
+		return nil
+	}
+
+	req := Request{Op: strings.TrimPrefix(r.Event, "bts:"), Channel: r.Data.Channel}
+	// live_trades_btcusd -> 频道 live_trades，品种 btcusd
+	if i := strings.LastIndex(r.Data.Channel, "_"); i > 0 {
+		req.Channel = r.Data.Channel[:i]
+		req.Symbols = []string{r.Data.Channel[i+1:]}
+	}
+	return []Request{req}
+}
+
+func (bitstampDialect) ack(reqs []Request) []any {
+	replies := make([]any, 0, len(reqs))
+	for _, req := range reqs {
+		var event string
+		switch req.Op {
+		case "subscribe":
+			event = "bts:subscription_succeeded"
+		case "unsubscribe":
+			event = "bts:unsubscription_succeeded"
+		case "heartbeat":
+			event = "bts:heartbeat"
+		default:
+			continue
+		}
+		replies = append(replies, map[string]any{"event": event, "channel": bitstampChannel(req), "data": map[string]any{}})
+	}
+	return replies
+}
+
+func (bitstampDialect) reject(req Request, code, msg string) any {
+	return map[string]any{
+		"event":   "bts:error",
+		"channel": bitstampChannel(req),
+		"data":    map[string]any{"code": code, "message": msg},
+	}
+}
+
+func bitstampChannel(req Request) string {
+	if len(req.Symbols) == 0 {
+		return req.Channel
+	}
+	return req.Channel + "_" + req.Symbols[0]
+}
@@ -0,0 +1,79 @@
+package dextest
+
+import (
+	"github.com/goccy/go-json"
+)
+
+// CoinbaseServer 模拟 Coinbase Exchange ws-feed
+type CoinbaseServer struct {
+	*Server
+}
+
+func NewCoinbaseServer() *CoinbaseServer {
+	return &CoinbaseServer{Server: newServer(coinbaseDialect{})}
+}
+
+// Push 推送消息，消息本身需要带 type 字段（match、snapshot、l2update 等）
+func (s *CoinbaseServer) Push(msg any) error {
+	return s.Broadcast(msg)
+}
+
+// Error 推送错误消息
+func (s *CoinbaseServer) Error(message, reason string) error {
+	return s.Broadcast(map[string]string{"type": "error", "message": message, "reason": reason})
+}
+
+type coinbaseDialect struct{}
+
+type coinbaseRequest struct {
+	Type       string            `json:"type"`
+	ProductIds []string          `json:"product_ids"`
+	Channels   []json.RawMessage `json:"channels"`
+}
+
+func (coinbaseDialect) parse(msg []byte) []Request {
+	var r coinbaseRequest
+	if err := json.Unmarshal(msg, &r); err != nil || r.Type == "" {
+		return nil
+	}
+
+	reqs := make([]Request, 0, len(r.Channels))
+	for _, raw := range r.Channels {
+		req := Request{Op: r.Type, Symbols: r.ProductIds}
+		// 频道可以是字符串，也可以是 {"name":..,"product_ids":[..]}
+		var name string
+		if err := json.Unmarshal(raw, &name); err == nil {
+			req.Channel = name
+		} else {
+			var ch struct {
+				Name       string   `json:"name"`
+				ProductIds []string `json:"product_ids"`
+			}
+			if err = json.Unmarshal(raw, &ch); err != nil {
+				continue
+			}
+			req.Channel = ch.Name
+			if len(ch.ProductIds) > 0 {
+				req.Symbols = ch.ProductIds
+			}
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// ack 一条订阅请求回复一次当前的订阅列表
+func (coinbaseDialect) ack(reqs []Request) []any {
+	if reqs[0].Op != "subscribe" && reqs[0].Op != "unsubscribe" {
+		return nil
+	}
+	channels := make([]map[string]any, 0, len(reqs))
+	for _, req := range reqs {
+		channels = append(channels, map[string]any{"name": req.Channel, "product_ids": req.Symbols})
+	}
+	return []any{map[string]any{"type": "subscriptions", "channels": channels}}
+}
+
+func (coinbaseDialect) reject(req Request, code, msg string) any {
+	return map[string]string{"type": "error", "message": msg, "reason": code}
+}
@@ -0,0 +1,80 @@
+package dextest
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/market"
+)
+
+// LiveEnv 设置为非空时才运行连接真实交易所的测试
+const LiveEnv = "DEX_LIVE"
+
+// ExpectTimeout ExpectTrade 等待推送的时间
+const ExpectTimeout = 5 * time.Second
+
+// SkipUnlessLive 连接真实交易所的测试默认跳过，只有设置了 LiveEnv 且没有 -short 时才运行
+func SkipUnlessLive(t testing.TB) {
+	t.Helper()
+	if testing.Short() || os.Getenv(LiveEnv) == "" {
+		t.Skipf("live exchange test, set %s=1 to run", LiveEnv)
+	}
+}
+
+// LiveContext 连接真实交易所的测试使用的 context，收到退出信号时取消，测试结束时释放；
+// 没有设置 LiveEnv 时跳过测试，见 SkipUnlessLive
+func LiveContext(t testing.TB) context.Context {
+	t.Helper()
+	SkipUnlessLive(t)
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	t.Cleanup(stop)
+	return ctx
+}
+
+// TradeSource 可以订阅成交的行情源，各交易所的 Public 都实现
+type TradeSource interface {
+	OnTrade(handler market.TradeHandler) error
+	Connect()
+}
+
+// ExpectOfflineTrade 在模拟服务上订阅成交并连接，收到 channel 的订阅请求后调用 push 推送，再用 ExpectTrade 比较收到的成交；
+// 关闭行情源由调用方负责
+func ExpectOfflineTrade(t testing.TB, srv *Server, source TradeSource, channel string, push func() error, want market.Trade) {
+	t.Helper()
+	trades := make(chan market.Trade, 1)
+	if err := source.OnTrade(func(trade market.Trade) error {
+		trades <- trade
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	source.Connect()
+
+	if _, ok := srv.WaitRequest("subscribe", channel, ExpectTimeout); !ok {
+		t.Fatalf("no subscribe request for %s", channel)
+	}
+	if err := push(); err != nil {
+		t.Fatal(err)
+	}
+	ExpectTrade(t, trades, want)
+}
+
+// ExpectTrade 等待一笔成交并与 want 逐字段比较，价格与数量按字符串比较以检查小数位数
+func ExpectTrade(t testing.TB, trades <-chan market.Trade, want market.Trade) {
+	t.Helper()
+	select {
+	case got := <-trades:
+		if got.Exchange != want.Exchange || got.Symbol != want.Symbol || got.TradeId != want.TradeId ||
+			got.Price.String() != want.Price.String() || got.Size.String() != want.Size.String() ||
+			got.Side != want.Side || !got.Timestamp.Equal(want.Timestamp) {
+			t.Fatalf("trade = %+v\nwant    %+v", got, want)
+		}
+	case <-time.After(ExpectTimeout):
+		t.Fatal("no trade received")
+	}
+}
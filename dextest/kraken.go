@@ -0,0 +1,109 @@
+package dextest
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// KrakenServer 模拟 Kraken v2
+type KrakenServer struct {
+	*Server
+}
+
+func NewKrakenServer() *KrakenServer {
+	return &KrakenServer{Server: newServer(krakenDialect{})}
+}
+
+// Push 推送 {"channel":..,"type":..,"data":..}，type 为 snapshot 或 update
+func (s *KrakenServer) Push(channel, typ string, data any) error {
+	return s.Broadcast(map[string]any{"channel": channel, "type": typ, "data": data})
+}
+
+// Status 推送系统状态，system 为 online、maintenance、cancel_only 等
+func (s *KrakenServer) Status(system string) error {
+	return s.Push("status", "update", []map[string]any{{
+		"api_version":   "v2",
+		"connection_id": 1,
+		"system":        system,
+		"version":       "mock",
+	}})
+}
+
+// Heartbeat 推送心跳
+func (s *KrakenServer) Heartbeat() error {
+	return s.Broadcast(map[string]string{"channel": "heartbeat"})
+}
+
+type krakenDialect struct{}
+
+type krakenRequest struct {
+	Method string `json:"method"`
+	Params struct {
+		Channel string   `json:"channel"`
+		Symbol  []string `json:"symbol"`
+	} `json:"params"`
+	ReqId *int64 `json:"req_id"`
+}
+
+func (krakenDialect) parse(msg []byte) []Request {
+	var r krakenRequest
+	if err := json.Unmarshal(msg, &r); err != nil || r.Method == "" {
+		return nil
+	}
+	req := Request{Op: r.Method, Channel: r.Params.Channel, Symbols: r.Params.Symbol}
+	if r.ReqId != nil {
+		req.ID = strconv.FormatInt(*r.ReqId, 10)
+	}
+	return []Request{req}
+}
+
+// ack Kraken 按品种逐个回复
+func (krakenDialect) ack(reqs []Request) []any {
+	replies := make([]any, 0)
+	for _, req := range reqs {
+		if req.Op == "ping" {
+			replies = append(replies, krakenReply(req, map[string]any{"method": "pong"}))
+			continue
+		}
+		if len(req.Symbols) == 0 {
+			replies = append(replies, krakenReply(req, map[string]any{
+				"method":  req.Op,
+				"result":  map[string]any{"channel": req.Channel},
+				"success": true,
+			}))
+			continue
+		}
+		for _, symbol := range req.Symbols {
+			replies = append(replies, krakenReply(req, map[string]any{
+				"method":  req.Op,
+				"result":  map[string]any{"channel": req.Channel, "symbol": symbol},
+				"success": true,
+			}))
+		}
+	}
+	return replies
+}
+
+func (krakenDialect) reject(req Request, _ string, msg string) any {
+	reply := map[string]any{
+		"method":  req.Op,
+		"error":   msg,
+		"success": false,
+	}
+	if len(req.Symbols) > 0 {
+		reply["symbol"] = req.Symbols[0]
+	}
+	return krakenReply(req, reply)
+}
+
+func krakenReply(req Request, reply map[string]any) map[string]any {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	reply["time_in"] = now
+	reply["time_out"] = now
+	if req.ID != "" {
+		reply["req_id"] = json.RawMessage(req.ID)
+	}
+	return reply
+}
@@ -0,0 +1,103 @@
+package dextest
+
+import (
+	"github.com/goccy/go-json"
+)
+
+// OKXServer 模拟 OKX v5 公共/私有/业务频道
+type OKXServer struct {
+	*Server
+}
+
+func NewOKXServer() *OKXServer {
+	return &OKXServer{Server: newServer(okxDialect{})}
+}
+
+// Push 推送频道数据 {"arg":{"channel":..,"instId":..},"data":..}
+func (s *OKXServer) Push(channel, instId string, data any) error {
+	return s.Broadcast(map[string]any{
+		"arg":  map[string]string{"channel": channel, "instId": instId},
+		"data": data,
+	})
+}
+
+// PushBook 推送带 action（snapshot/update）的盘口数据
+func (s *OKXServer) PushBook(channel, instId, action string, data any) error {
+	return s.Broadcast(map[string]any{
+		"arg":    map[string]string{"channel": channel, "instId": instId},
+		"action": action,
+		"data":   data,
+	})
+}
+
+// Notice 服务升级通知，客户端应当重连
+func (s *OKXServer) Notice(msg string) error {
+	return s.Broadcast(map[string]string{"event": "notice", "code": "64008", "msg": msg, "connId": "mock"})
+}
+
+// Error 推送错误事件
+func (s *OKXServer) Error(code, msg string) error {
+	return s.Broadcast(map[string]string{"event": "error", "code": code, "msg": msg, "connId": "mock"})
+}
+
+type okxDialect struct{}
+
+type okxRequest struct {
+	Id   string           `json:"id"`
+	Op   string           `json:"op"`
+	Args []map[string]any `json:"args"`
+}
+
+func (okxDialect) parse(msg []byte) []Request {
+	if string(msg) == "ping" {
+		return []Request{{Op: "ping"}}
+	}
+
+	var r okxRequest
+	if err := json.Unmarshal(msg, &r); err != nil || r.Op == "" {
+		return nil
+	}
+	if r.Op == "login" || len(r.Args) == 0 {
+		return []Request{{Op: r.Op, ID: r.Id}}
+	}
+
+	reqs := make([]Request, 0, len(r.Args))
+	for _, arg := range r.Args {
+		req := Request{Op: r.Op, ID: r.Id}
+		req.Channel, _ = arg["channel"].(string)
+		if instId, ok := arg["instId"].(string); ok && instId != "" {
+			req.Symbols = []string{instId}
+		} else if family, ok := arg["instFamily"].(string); ok && family != "" {
+			req.Symbols = []string{family}
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+func (okxDialect) ack(reqs []Request) []any {
+	replies := make([]any, 0, len(reqs))
+	for _, req := range reqs {
+		switch req.Op {
+		case "ping":
+			replies = append(replies, "pong")
+		case "login":
+			replies = append(replies, map[string]string{"event": "login", "code": "0", "msg": "", "connId": "mock"})
+		case "subscribe", "unsubscribe":
+			replies = append(replies, map[string]any{"event": req.Op, "arg": okxArg(req), "connId": "mock"})
+		}
+	}
+	return replies
+}
+
+func (okxDialect) reject(req Request, code, msg string) any {
+	return map[string]string{"event": "error", "code": code, "msg": msg, "connId": "mock"}
+}
+
+func okxArg(req Request) map[string]string {
+	arg := map[string]string{"channel": req.Channel}
+	if len(req.Symbols) > 0 {
+		arg["instId"] = req.Symbols[0]
+	}
+	return arg
+}
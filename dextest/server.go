@@ -0,0 +1,353 @@
+// Package dextest 提供本地模拟的交易所 WebSocket/HTTP 服务，用于离线测试各个适配器
+//
+// 每个交易所都有对应的构造函数（NewOKXServer、NewBinanceServer、NewKrakenServer、
+// NewCoinbaseServer、NewBitstampServer），服务会按交易所协议自动回复订阅、取消订阅和登录请求，
+// 测试代码可以通过 OnRequest、Reject 编排回复，通过 Push 系列方法推送数据，
+// 通过 Disconnect 模拟断线。同一个端口上非 WebSocket 请求交给 Handle 注册的 HTTP 处理函数。
+//
+// 连接真实交易所的测试以 LiveContext 开头，默认跳过，设置 DEX_LIVE=1 时运行。
+package dextest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+)
+
+// Request 已按交易所协议解析的客户端请求
+type Request struct {
+	Op      string   // subscribe、unsubscribe、login、ping，无法识别时为原始的 method/op/event
+	Channel string   // 频道名，不含品种
+	Symbols []string // 品种
+	ID      string   // 请求编号（币安 id、Kraken req_id），没有时为空
+	Raw     []byte   // 原始消息
+	ConnID  int
+}
+
+// Handler 自定义请求处理，返回 true 表示已经处理，服务不再自动回复
+type Handler func(conn *Conn, req Request) bool
+
+type rejection struct {
+	code string
+	msg  string
+}
+
+// dialect 交易所协议
+type dialect interface {
+	// parse 解析客户端消息，一条消息可能包含多个请求；无法识别时返回空
+	parse(msg []byte) []Request
+	// ack 生成成功回复
+	ack(reqs []Request) []any
+	// reject 生成失败回复
+	reject(req Request, code, msg string) any
+}
+
+type Server struct {
+	dialect  dialect
+	http     *httptest.Server
+	mux      *http.ServeMux
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	conns    map[*Conn]struct{}
+	nextID   int
+	requests []Request
+	handlers []Handler
+	rejects  map[string]rejection
+	changed  chan struct{}
+}
+
+func newServer(d dialect) *Server {
+	s := &Server{
+		dialect: d,
+		mux:     http.NewServeMux(),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		conns:   make(map[*Conn]struct{}),
+		rejects: make(map[string]rejection),
+		changed: make(chan struct{}),
+	}
+	s.http = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL WebSocket 地址，任意路径都可以连接
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.http.URL, "http")
+}
+
+// HTTPURL REST 地址
+func (s *Server) HTTPURL() string {
+	return s.http.URL
+}
+
+// Handle 注册 REST 处理函数
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleJSON 注册固定返回 v 的 REST 接口
+func (s *Server) HandleJSON(pattern string, v any) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	})
+}
+
+// OnRequest 添加自定义请求处理，按添加顺序调用
+func (s *Server) OnRequest(h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+}
+
+// Reject 之后对该频道的订阅回复错误；key 为频道名，登录请求使用 "login"
+func (s *Server) Reject(key, code, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejects[key] = rejection{code: code, msg: msg}
+}
+
+// Broadcast 向所有连接发送消息，v 为 []byte 或 string 时原样发送，其它类型编码为 JSON
+func (s *Server) Broadcast(v any) error {
+	var firstErr error
+	for _, c := range s.connList() {
+		if err := c.Send(v); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Disconnect 直接断开所有连接（不发送 close 帧），模拟网络中断
+func (s *Server) Disconnect() {
+	for _, c := range s.connList() {
+		c.Close()
+	}
+}
+
+// Conns 当前连接数
+func (s *Server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// WaitConns 等待连接数达到 n
+func (s *Server) WaitConns(n int, timeout time.Duration) bool {
+	return s.wait(timeout, func() bool { return len(s.conns) >= n })
+}
+
+// Requests 已收到的全部请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// WaitRequest 等待指定操作和频道的请求，channel 为空时匹配任意频道
+func (s *Server) WaitRequest(op, channel string, timeout time.Duration) (Request, bool) {
+	var found Request
+	ok := s.wait(timeout, func() bool {
+		for _, r := range s.requests {
+			if r.Op == op && (channel == "" || r.Channel == channel) {
+				found = r
+				return true
+			}
+		}
+		return false
+	})
+	return found, ok
+}
+
+// WaitRequestCount 等待指定操作和频道的请求累计达到 n 个，用于检查重连后是否重新订阅
+func (s *Server) WaitRequestCount(op, channel string, n int, timeout time.Duration) bool {
+	return s.wait(timeout, func() bool {
+		count := 0
+		for _, r := range s.requests {
+			if r.Op == op && (channel == "" || r.Channel == channel) {
+				count++
+			}
+		}
+		return count >= n
+	})
+}
+
+// Close 关闭所有连接和服务
+func (s *Server) Close() {
+	s.Disconnect()
+	s.http.Close()
+}
+
+func (s *Server) connList() []*Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*Conn, 0, len(s.conns))
+	for c := range s.conns {
+		list = append(list, c)
+	}
+	return list
+}
+
+// wait 在持有锁的情况下检查 cond，直到满足或超时
+func (s *Server) wait(timeout time.Duration, cond func() bool) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		if cond() {
+			s.mu.Unlock()
+			return true
+		}
+		ch := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+// notifyLocked 唤醒所有等待者，调用方需持有锁
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	conn := &Conn{id: s.nextID, ws: ws, server: s}
+	s.conns[conn] = struct{}{}
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	go conn.readLoop()
+}
+
+func (s *Server) handle(conn *Conn, msg []byte) {
+	reqs := s.dialect.parse(msg)
+	if len(reqs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	for i := range reqs {
+		reqs[i].Raw = msg
+		reqs[i].ConnID = conn.id
+	}
+	s.requests = append(s.requests, reqs...)
+	handlers := append([]Handler(nil), s.handlers...)
+	rejects := make(map[string]rejection, len(s.rejects))
+	for k, v := range s.rejects {
+		rejects[k] = v
+	}
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	accepted := make([]Request, 0, len(reqs))
+	for _, req := range reqs {
+		handled := false
+		for _, h := range handlers {
+			if h(conn, req) {
+				handled = true
+				break
+			}
+		}
+		if handled {
+			continue
+		}
+
+		key := req.Channel
+		if key == "" {
+			key = req.Op
+		}
+		if rej, ok := rejects[key]; ok {
+			_ = conn.Send(s.dialect.reject(req, rej.code, rej.msg))
+			continue
+		}
+		accepted = append(accepted, req)
+	}
+
+	if len(accepted) == 0 {
+		return
+	}
+	for _, reply := range s.dialect.ack(accepted) {
+		_ = conn.Send(reply)
+	}
+}
+
+// Conn 一个客户端连接
+type Conn struct {
+	id     int
+	ws     *websocket.Conn
+	server *Server
+	mu     sync.Mutex
+	once   sync.Once
+}
+
+func (c *Conn) ID() int { return c.id }
+
+// Send 发送消息，v 为 []byte 或 string 时原样发送，其它类型编码为 JSON
+func (c *Conn) Send(v any) error {
+	var data []byte
+	switch d := v.(type) {
+	case []byte:
+		data = d
+	case string:
+		data = []byte(d)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = b
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close 直接关闭底层连接
+func (c *Conn) Close() {
+	c.once.Do(func() {
+		_ = c.ws.NetConn().Close()
+
+		s := c.server
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.notifyLocked()
+		s.mu.Unlock()
+	})
+}
+
+func (c *Conn) readLoop() {
+	defer c.Close()
+	for {
+		_, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.server.handle(c, msg)
+	}
+}
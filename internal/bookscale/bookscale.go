@@ -0,0 +1,180 @@
+// Package bookscale 在 bookManager 的整数价格、浮点数量与 common.Decimal 之间换算
+//
+// bookManager 以 PriceTicks（价格 * 10^小数位数）保存价格、以 float64 保存数量。
+// 适配器按品种的价格与数量精度换算：价格全程是整数，数量按数量精度四舍五入还原，
+// 回调拿到的档位与交易所推送的数值一致，不依赖浮点数的最短表示
+package bookscale
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/simonks2016/book_manager"
+	"github.com/simonks2016/dex_plus/common"
+)
+
+const (
+	// DefaultDecimals 产品信息加载之前使用的小数位数
+	DefaultDecimals = 8
+	// LoadRetryDelay 加载产品信息失败后重试的间隔
+	LoadRetryDelay = 30 * time.Second
+)
+
+// Precision 一个品种价格与数量的小数位数
+type Precision struct {
+	PriceDecimals int32
+	SizeDecimals  int32
+}
+
+// Default 产品信息加载之前使用的精度
+var Default = Precision{PriceDecimals: DefaultDecimals, SizeDecimals: DefaultDecimals}
+
+// FromInstrument 由产品信息的 TickSize 与 LotSize 得到精度，缺少的一项使用默认值
+func FromInstrument(inst common.Instrument) Precision {
+	p := Default
+	if !inst.TickSize.IsZero() {
+		p.PriceDecimals = max(inst.TickSize.Normalize().Scale(), 0)
+	}
+	if !inst.LotSize.IsZero() {
+		p.SizeDecimals = max(inst.LotSize.Normalize().Scale(), 0)
+	}
+	return p
+}
+
+// Multiplier 价格放大的倍数，即 bookManager.PriceTicks 的 scale 参数
+func (p Precision) Multiplier() int64 {
+	m := int64(1)
+	for range p.PriceDecimals {
+		m *= 10
+	}
+	return m
+}
+
+// Ticks 价格换算成整数，小数位数超过价格精度时返回错误
+func (p Precision) Ticks(price common.Decimal) (int64, error) {
+	ticks := price.Mul(common.NewDecimalFromInt(p.Multiplier())).Normalize()
+	if ticks.Scale() > 0 {
+		return 0, fmt.Errorf("price %s has more than %d decimal places", price, p.PriceDecimals)
+	}
+	return ticks.Int64(), nil
+}
+
+// Level 把一个档位换算成 bookManager.Level
+// bookManager 只能以 float64 保存数量，取出时由 Size 按数量精度还原
+func (p Precision) Level(price, size common.Decimal, isBid bool) (bookManager.Level, error) {
+	ticks, err := p.Ticks(price)
+	if err != nil {
+		return bookManager.Level{}, err
+	}
+	return bookManager.Level{PriceTicks: ticks, Size: size.Float64(), IsBids: isBid}, nil
+}
+
+// ParseLevel 解析字符串档位，换算成 bookManager.Level
+func (p Precision) ParseLevel(price, size string, isBid bool) (bookManager.Level, error) {
+	px, err := common.ParseDecimal(price)
+	if err != nil {
+		return bookManager.Level{}, err
+	}
+	sz, err := common.ParseDecimal(size)
+	if err != nil {
+		return bookManager.Level{}, err
+	}
+	return p.Level(px, sz, isBid)
+}
+
+// Price 由整数价格还原价格，保留价格精度的小数位数
+func (p Precision) Price(ticks int64) common.Decimal {
+	return common.NewDecimal(ticks, p.PriceDecimals)
+}
+
+// Size 按数量精度还原 bookManager 中的数量
+func (p Precision) Size(size float64) common.Decimal {
+	return common.NewDecimalFromFloat(size).Round(p.SizeDecimals)
+}
+
+// Levels 把 bookManager 的档位还原成精确小数
+func (p Precision) Levels(levels []bookManager.Level) []common.PriceLevel {
+	ret := make([]common.PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		ret = append(ret, common.PriceLevel{Price: p.Price(l.PriceTicks), Size: p.Size(l.Size)})
+	}
+	return ret
+}
+
+// Scales 每个品种盘口使用的精度，并发安全
+// 重建盘口时按产品信息选择精度，之后的增量与快照沿用同一个精度，加载产品信息前后不会在同一个盘口里混用
+type Scales struct {
+	mu    sync.Mutex
+	known map[string]Precision // 产品信息中的精度
+	books map[string]Precision // 当前盘口使用的精度
+}
+
+func NewScales() *Scales {
+	return &Scales{known: make(map[string]Precision), books: make(map[string]Precision)}
+}
+
+// Set 记录产品信息中的精度，下一次重建盘口时生效
+func (s *Scales) Set(symbol string, p Precision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.known[symbol] = p
+}
+
+// ForSnapshot 重建盘口时使用的精度，之后 ForBook 返回同一个精度；ok 为 false 表示还没有产品信息，使用的是默认精度
+func (s *Scales) ForSnapshot(symbol string) (p Precision, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok = s.known[symbol]
+	if !ok {
+		p = Default
+	}
+	s.books[symbol] = p
+	return p, ok
+}
+
+// ForBook 当前盘口使用的精度；还没有重建过盘口时与 ForSnapshot 的选择相同
+func (s *Scales) ForBook(symbol string) Precision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.books[symbol]; ok {
+		return p
+	}
+	if p, ok := s.known[symbol]; ok {
+		return p
+	}
+	return Default
+}
+
+// Verified 当前盘口的精度是否来自产品信息，只有这时按精度格式化的校验和才可信
+func (s *Scales) Verified(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.books[symbol]
+	if !ok {
+		return false
+	}
+	known, ok := s.known[symbol]
+	return ok && known == p
+}
+
+// Load 从 loader 加载产品信息中的精度，失败时按 LoadRetryDelay 重试，直到成功或 ctx 结束
+func (s *Scales) Load(ctx context.Context, loader common.InstrumentLoader, logger *slog.Logger) {
+	for {
+		instruments, err := loader.LoadInstruments(ctx)
+		if err == nil {
+			for _, inst := range instruments {
+				s.Set(inst.Symbol, FromInstrument(inst))
+			}
+			return
+		}
+		logger.Warn("failed to load instruments, using default book precision", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(LoadRetryDelay):
+		}
+	}
+}
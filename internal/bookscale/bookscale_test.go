@@ -0,0 +1,57 @@
+package bookscale
+
+import (
+	"testing"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+func TestPrecision(t *testing.T) {
+	p := Precision{PriceDecimals: 2, SizeDecimals: 8}
+	ticks, err := p.Ticks(common.MustDecimal("42000.1"))
+	if err != nil || ticks != 4200010 {
+		t.Fatalf("Ticks = %d, %v, want 4200010", ticks, err)
+	}
+	if _, err := p.Ticks(common.MustDecimal("0.001")); err == nil {
+		t.Fatal("Ticks accepted a price finer than the precision")
+	}
+	if got := p.Price(4200010).String(); got != "42000.10" {
+		t.Fatalf("Price = %s, want 42000.10", got)
+	}
+
+	level, err := p.ParseLevel("0.07", "0.1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Size(level.Size).String(); got != "0.10000000" {
+		t.Fatalf("Size = %s, want 0.10000000", got)
+	}
+}
+
+func TestScales(t *testing.T) {
+	s := NewScales()
+	if p, ok := s.ForSnapshot("BTC-USD"); ok || p != Default {
+		t.Fatalf("ForSnapshot before Set = %v, %v, want default", p, ok)
+	}
+	if s.Verified("BTC-USD") {
+		t.Fatal("book built with the default precision reported as verified")
+	}
+
+	// 产品信息在重建盘口之后到达，当前盘口沿用默认精度
+	usd := Precision{PriceDecimals: 2, SizeDecimals: 8}
+	s.Set("BTC-USD", usd)
+	if p := s.ForBook("BTC-USD"); p != Default {
+		t.Fatalf("ForBook = %v, want default until the next snapshot", p)
+	}
+	if p, ok := s.ForSnapshot("BTC-USD"); !ok || p != usd {
+		t.Fatalf("ForSnapshot = %v, %v, want %v", p, ok, usd)
+	}
+	if p := s.ForBook("BTC-USD"); p != usd || !s.Verified("BTC-USD") {
+		t.Fatalf("ForBook = %v, verified %v", p, s.Verified("BTC-USD"))
+	}
+
+	inst := common.Instrument{TickSize: common.MustDecimal("0.01000000"), LotSize: common.MustDecimal("0.00000001")}
+	if p := FromInstrument(inst); p != usd {
+		t.Fatalf("FromInstrument = %v, want %v", p, usd)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+type BackpressurePolicy = common.BackpressurePolicy
+
+const (
+	BackpressureDropNewest = common.BackpressureDropNewest
+	BackpressureBlock      = common.BackpressureBlock
+	BackpressureDropOldest = common.BackpressureDropOldest
+	BackpressureConflate   = common.BackpressureConflate
+)
+
+// DropObserver ConnectionObserver 可选实现，readPump 丢帧时回调
+// 增量盘口在丢帧后已经不可信，适配器应在这里标记盘口失效并重新同步
+type DropObserver interface {
+	OnDropped(count int)
+}
+
+// conflator 队列写满后暂存的帧，相同键只保留最新一帧，按键第一次出现的顺序回填
+type conflator struct {
+	mu     sync.Mutex
+	keys   []string
+	frames map[string]frame
+}
+
+func newConflator() *conflator {
+	return &conflator{frames: make(map[string]frame)}
+}
+
+// put 暂存一帧，覆盖了同键的旧帧时返回 true
+func (q *conflator) put(key string, data frame) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.frames[key]; ok {
+		q.frames[key] = data
+		return true
+	}
+	q.keys = append(q.keys, key)
+	q.frames[key] = data
+	return false
+}
+
+// flush 把暂存的帧尽量写入 ch，全部写完时返回 true
+func (q *conflator) flush(ch chan<- frame) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.keys) > 0 {
+		key := q.keys[0]
+		select {
+		case ch <- q.frames[key]:
+			delete(q.frames, key)
+			q.keys = q.keys[1:]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (q *conflator) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.keys)
+}
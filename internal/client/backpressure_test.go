@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type dropObserver struct {
+	recordObserver
+	dropped int
+}
+
+func (o *dropObserver) OnDropped(count int) { o.dropped += count }
+
+func newTestWsClient(policy BackpressurePolicy) (*WsClient, *dropObserver) {
+	cfg := NewConfig().SetReadBufferSize(2).SetBackpressure(policy)
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	cfg.ConflateKey = func(data []byte) string {
+		key, _, _ := strings.Cut(string(data), ":")
+		return key
+	}
+	ob := &dropObserver{}
+	c := NewWsClient(context.Background(), cfg)
+	c.SetObserver(ob)
+	return c, ob
+}
+
+func drain(c *WsClient) []string {
+	var ret []string
+	for {
+		select {
+		case f := <-c.readCh:
+			ret = append(ret, string(f.data))
+		default:
+			return ret
+		}
+	}
+}
+
+func TestBackpressurePolicies(t *testing.T) {
+	frames := []string{"a:1", "b:1", "a:2", "c:1", "a:3"}
+
+	cases := []struct {
+		policy  BackpressurePolicy
+		queued  string
+		dropped int
+	}{
+		{BackpressureDropNewest, "a:1,b:1", 3},
+		{BackpressureDropOldest, "c:1,a:3", 3},
+		// a:2 被 a:3 覆盖，c:1 保留在暂存区
+		{BackpressureConflate, "a:1,b:1", 1},
+	}
+	for _, tc := range cases {
+		c, ob := newTestWsClient(tc.policy)
+		for _, f := range frames {
+			c.enqueue(1, []byte(f), time.Now())
+		}
+		if got := strings.Join(drain(c), ","); got != tc.queued {
+			t.Errorf("%s: queued %s, want %s", tc.policy, got, tc.queued)
+		}
+		if ob.dropped != tc.dropped {
+			t.Errorf("%s: dropped %d, want %d", tc.policy, ob.dropped, tc.dropped)
+		}
+		if tc.policy == BackpressureConflate {
+			c.conflate.flush(c.readCh)
+			if got := strings.Join(drain(c), ","); got != "a:3,c:1" {
+				t.Errorf("conflate: flushed %s, want a:3,c:1", got)
+			}
+		}
+	}
+}
@@ -2,11 +2,19 @@ package client
 
 import (
 	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"slices"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/simonks2016/dex_plus/clocksync"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/latency"
+	"github.com/simonks2016/dex_plus/metrics"
+	"github.com/simonks2016/dex_plus/ratelimit"
+	"github.com/simonks2016/dex_plus/recording"
+	"github.com/simonks2016/dex_plus/watchdog"
 )
 
 type PayloadType int
@@ -20,7 +28,24 @@ type Config struct {
 	URL    string
 	Header http.Header
 	Dialer *websocket.Dialer
-	Logger *log.Logger
+
+	// Endpoints 按优先级排列的地址，为空时只使用 URL；连续拨号失败或连接质量下降时按健康度切换
+	Endpoints []string
+	// FailoverAfter 同一个地址连续拨号失败多少次后切换，默认 3
+	FailoverAfter int
+	// StableAfter 连接保持超过该时间才算稳定，更早断开视为连接质量下降并切换地址，默认 30s
+	StableAfter time.Duration
+
+	// Logger 结构化日志，默认 slog.Default()
+	Logger *slog.Logger
+	// Latency 记录排队等待与回调耗时，适配器同时记录线路延迟；为空时不统计
+	Latency *latency.Tracker
+	// Clock 与交易所服务器同步的时钟，登录签名使用校正后的时间；为空时使用本地时间
+	Clock *clocksync.Clock
+	// RateLimiter Send 之前获取令牌，限制发往交易所的消息速率；为空时不限速
+	RateLimiter *ratelimit.Limiter
+	// RateLimitMode 令牌不足时等待或立即返回 *common.RateLimitError
+	RateLimitMode ratelimit.Mode
 
 	// timeouts
 	HandshakeTimeout time.Duration
@@ -42,6 +67,32 @@ type Config struct {
 	ReadWorkerNum   int
 	IsForbidIPV6    bool
 	IsNeedAuth      bool
+
+	// Recorder 不为空时记录每一个收发的原始帧
+	Recorder recording.Recorder
+	// Replay 不为空时 NewClient 返回回放客户端，不再连接交易所
+	Replay *ReplayConfig
+	// Metrics 连接与读写管道的指标，为空时不记录
+	Metrics metrics.MetricsSink
+
+	// Backpressure 读队列写满时的处理策略，默认丢弃新帧
+	Backpressure BackpressurePolicy
+	// ConflateKey BackpressureConflate 下的合并键，如 "ticker:BTC-USDT"；为空时所有帧共用一个键
+	ConflateKey func(data []byte) string
+	// DispatchKey 不为空时读协程按键分区，同一个键（如 channel + symbol）的消息严格按到达顺序交给 OnMessage
+	// 需要在 NewClient 之前设置，适配器在构造客户端时设置
+	DispatchKey func(data []byte) string
+
+	// Watchdog 不为空时适配器按 (channel, symbol) 检测静默的订阅
+	Watchdog *watchdog.Config
+
+	// MaxSubscriptions 每个连接的订阅数上限，超过后自动新建连接；0 使用交易所的默认上限，<0 表示不限
+	MaxSubscriptions int
+
+	// SubscribeAckTimeout 等待订阅确认的时间，默认 10s
+	SubscribeAckTimeout time.Duration
+	// SubscribeRetries 订阅超时未确认时重发的次数，0 使用默认值，<0 表示不重发
+	SubscribeRetries int
 }
 
 type Proxy struct {
@@ -66,22 +117,147 @@ func NewConfig() *Config {
 		ReadWorkerNum:       10,
 		IsForbidIPV6:        false,
 		IsNeedAuth:          false,
-		Logger:              log.New(os.Stdout, "", log.LstdFlags),
+		Logger:              slog.Default(),
 	}
 }
+
+// WithURL 只使用一个地址，清空之前设置的 Endpoints
 func (c *Config) WithURL(url string) *Config {
 	c.URL = url
+	c.Endpoints = nil
 	return c
 }
+
+// WithEndpoints 按优先级设置多个地址，第一个同时作为 URL
+func (c *Config) WithEndpoints(urls ...string) *Config {
+	if len(urls) == 0 {
+		return c
+	}
+	c.URL = urls[0]
+	c.Endpoints = urls
+	return c
+}
+
+// SetFailover 设置切换地址的条件：连续拨号失败 after 次，或连接在 stable 内断开
+func (c *Config) SetFailover(after int, stable time.Duration) *Config {
+	c.FailoverAfter = after
+	c.StableAfter = stable
+	return c
+}
+
+// EndpointList 按优先级排列的全部地址，去掉重复的地址
+func (c *Config) EndpointList() []string {
+	if len(c.Endpoints) == 0 {
+		return []string{c.URL}
+	}
+	ret := make([]string, 0, len(c.Endpoints))
+	for _, u := range c.Endpoints {
+		if u != "" && !slices.Contains(ret, u) {
+			ret = append(ret, u)
+		}
+	}
+	return ret
+}
 func (c *Config) WithHeader(header http.Header) *Config {
 	c.Header = header
 	return c
 }
-func (c *Config) WithLogger(logger *log.Logger) *Config {
+func (c *Config) WithLogger(logger *slog.Logger) *Config {
 	c.Logger = logger
 	return c
 }
 
+// WithStdLogger 兼容 *log.Logger
+func (c *Config) WithStdLogger(logger *log.Logger) *Config {
+	c.Logger = NewStdLogger(logger)
+	return c
+}
+
+func (c *Config) WithRecorder(recorder recording.Recorder) *Config {
+	c.Recorder = recorder
+	return c
+}
+func (c *Config) WithReplay(replay *ReplayConfig) *Config {
+	c.Replay = replay
+	return c
+}
+
+func (c *Config) WithMetrics(sink metrics.MetricsSink) *Config {
+	c.Metrics = sink
+	return c
+}
+
+func (c *Config) WithLatency(tracker *latency.Tracker) *Config {
+	c.Latency = tracker
+	return c
+}
+
+func (c *Config) WithClock(clock *clocksync.Clock) *Config {
+	c.Clock = clock
+	return c
+}
+
+func (c *Config) WithRateLimit(limiter *ratelimit.Limiter, mode ratelimit.Mode) *Config {
+	c.RateLimiter = limiter
+	c.RateLimitMode = mode
+	return c
+}
+
+func (c *Config) WithWatchdog(cfg *watchdog.Config) *Config {
+	c.Watchdog = cfg
+	return c
+}
+
+func (c *Config) SetMaxSubscriptions(n int) *Config {
+	c.MaxSubscriptions = n
+	return c
+}
+
+// SubscriptionLimit 每个连接的订阅数上限，def 为交易所的默认上限；回放时只有一个连接
+func (c *Config) SubscriptionLimit(def int) int {
+	switch {
+	case c.Replay != nil, c.MaxSubscriptions < 0:
+		return 0
+	case c.MaxSubscriptions > 0:
+		return c.MaxSubscriptions
+	default:
+		return def
+	}
+}
+
+// SetSubscribeTimeout 设置订阅确认的超时与重发次数
+func (c *Config) SetSubscribeTimeout(timeout time.Duration, retries int) *Config {
+	c.SubscribeAckTimeout = timeout
+	c.SubscribeRetries = retries
+	return c
+}
+
+// Subscription 订阅状态机的配置
+func (c *Config) Subscription() subscription.Config {
+	return subscription.Config{AckTimeout: c.SubscribeAckTimeout, MaxRetries: c.SubscribeRetries}
+}
+
+// ForShard 第 index 个分片连接使用的配置副本，日志带上 shard 属性
+func (c *Config) ForShard(index int) *Config {
+	cp := *c
+	cp.Logger = OrDefaultLogger(c.Logger).With("shard", index)
+	return &cp
+}
+
+func (c *Config) SetBackpressure(policy BackpressurePolicy) *Config {
+	c.Backpressure = policy
+	return c
+}
+func (c *Config) SetConflateKey(key func(data []byte) string) *Config {
+	c.ConflateKey = key
+	return c
+}
+
+func (c *Config) SetDispatchKey(key func(data []byte) string) *Config {
+	c.DispatchKey = key
+	return c
+}
+
 func (c *Config) SetHandshakeTimeout(timeout time.Duration) *Config {
 	c.HandshakeTimeout = timeout
 	return c
@@ -6,6 +6,20 @@ type Client interface {
 	SetObserver(ob ConnectionObserver) Client
 	Send(context.Context, []byte) error
 	Close()
+	// Shutdown 优雅关闭：取消订阅、停止接收，等待已收到的消息回调完成，ctx 到期时立即关闭
+	Shutdown(ctx context.Context) error
 	Reconnect(reason string)
 	Start()
+	// ConnID 当前连接编号，每次重连递增，用于日志关联
+	ConnID() uint64
+	// Endpoint 当前使用的地址
+	Endpoint() string
+}
+
+// NewClient 根据配置创建客户端，设置了 Replay 时返回回放客户端
+func NewClient(ctx context.Context, cfg *Config) Client {
+	if cfg.Replay != nil {
+		return NewReplayClient(ctx, cfg)
+	}
+	return NewWsClient(ctx, cfg)
 }
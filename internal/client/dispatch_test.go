@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowObserver 每条消息随机耗时，共享 worker 时同键消息会乱序
+type slowObserver struct {
+	recordObserver
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	seen map[string][]int
+}
+
+func (o *slowObserver) OnMessage(data []byte) error {
+	defer o.wg.Done()
+	var key string
+	var seq int
+	_, _ = fmt.Sscanf(strings.Replace(string(data), ":", " ", 1), "%s %d", &key, &seq)
+	time.Sleep(time.Duration(seq%3) * time.Millisecond)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seen[key] = append(o.seen[key], seq)
+	return nil
+}
+
+func TestOrderedDispatch(t *testing.T) {
+	cfg := NewConfig().SetReadBufferSize(256).SetReadWorkerNum(4).SetDispatchKey(func(data []byte) string {
+		key, _, _ := strings.Cut(string(data), ":")
+		return key
+	})
+	cfg.Logger = slog.New(slog.DiscardHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ob := &slowObserver{seen: make(map[string][]int)}
+	c := NewWsClient(ctx, cfg)
+	c.SetObserver(ob)
+	c.startWorkers()
+
+	keys := []string{"BTC", "ETH", "SOL"}
+	const n = 30
+	ob.wg.Add(n * len(keys))
+	for i := 0; i < n; i++ {
+		for _, key := range keys {
+			c.enqueue(1, []byte(fmt.Sprintf("%s:%d", key, i)), time.Now())
+		}
+	}
+	ob.wg.Wait()
+
+	for _, key := range keys {
+		got := ob.seen[key]
+		if len(got) != n {
+			t.Fatalf("%s: got %d messages, want %d", key, len(got), n)
+		}
+		for i, seq := range got {
+			if seq != i {
+				t.Fatalf("%s: out of order at %d: %v", key, i, got)
+			}
+		}
+	}
+}
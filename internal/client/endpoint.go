@@ -0,0 +1,98 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailoverAfter 同一个地址连续拨号失败多少次后切换
+	DefaultFailoverAfter = 3
+	// DefaultStableAfter 连接保持超过该时间才算稳定
+	DefaultStableAfter = 30 * time.Second
+	// healthWeight 每次连接结果在健康度中的权重
+	healthWeight = 0.3
+)
+
+// EndpointObserver ConnectionObserver 可选实现，连接上的地址变化时回调，第一次连接也会回调
+type EndpointObserver interface {
+	OnEndpoint(url string)
+}
+
+// endpointSet 按优先级排列的地址与健康度
+// 健康度在 0~1 之间，连接成功向 1 靠拢，拨号失败或连接质量下降向 0 靠拢
+type endpointSet struct {
+	mu       sync.Mutex
+	urls     []string
+	health   []float64
+	active   int
+	failures int // 当前地址的连续失败次数
+	after    int
+}
+
+func newEndpointSet(urls []string, after int) *endpointSet {
+	if after <= 0 {
+		after = DefaultFailoverAfter
+	}
+	health := make([]float64, len(urls))
+	for i := range health {
+		health[i] = 1
+	}
+	return &endpointSet{urls: urls, health: health, after: after}
+}
+
+// current 当前使用的地址
+func (s *endpointSet) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.urls[s.active]
+}
+
+// succeeded 当前地址连接成功
+func (s *endpointSet) succeeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = 0
+	s.score(1)
+}
+
+// failed 当前地址拨号失败，连续失败达到阈值后切换，返回切换后的地址
+func (s *endpointSet) failed() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.score(0)
+	s.failures++
+	if s.failures < s.after {
+		return "", false
+	}
+	return s.switchLocked()
+}
+
+// degraded 当前地址的连接质量下降（连接很快断开、心跳超时），立即切换
+func (s *endpointSet) degraded() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.score(0)
+	return s.switchLocked()
+}
+
+func (s *endpointSet) score(v float64) {
+	s.health[s.active] = s.health[s.active]*(1-healthWeight) + v*healthWeight
+}
+
+// switchLocked 切换到健康度最高的其他地址，健康度相同时按列表顺序取当前地址之后的第一个
+func (s *endpointSet) switchLocked() (string, bool) {
+	s.failures = 0
+	if len(s.urls) < 2 {
+		return "", false
+	}
+	best := -1
+	for i := 1; i < len(s.urls); i++ {
+		j := (s.active + i) % len(s.urls)
+		if best < 0 || s.health[j] > s.health[best] {
+			best = j
+		}
+	}
+	s.active = best
+	return s.urls[best], true
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/simonks2016/dex_plus/metrics"
+)
+
+func TestEndpointSet(t *testing.T) {
+	s := newEndpointSet([]string{"a", "b", "c"}, 2)
+
+	if _, ok := s.failed(); ok {
+		t.Fatal("switched after one failure")
+	}
+	if next, ok := s.failed(); !ok || next != "b" {
+		t.Fatalf("failed() = %q, %v, want b", next, ok)
+	}
+
+	// b 质量下降，a 的健康度低于 c，切到 c
+	if next, ok := s.degraded(); !ok || next != "c" {
+		t.Fatalf("degraded() = %q, %v, want c", next, ok)
+	}
+	// a 连续两次失败，健康度仍低于 b
+	s.succeeded()
+	if next, ok := s.degraded(); !ok || next != "b" {
+		t.Fatalf("degraded() = %q, %v, want b", next, ok)
+	}
+
+	single := newEndpointSet([]string{"a"}, 1)
+	if _, ok := single.failed(); ok {
+		t.Fatal("single endpoint switched")
+	}
+}
+
+// endpointObserver 记录上报的地址
+type endpointObserver struct {
+	recordObserver
+	urls chan string
+}
+
+func (o *endpointObserver) OnEndpoint(url string) { o.urls <- url }
+
+func TestWsClientFailover(t *testing.T) {
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	// 先占用再释放一个端口，拨号会被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "ws://" + ln.Addr().String()
+	ln.Close()
+	live := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	cfg := NewConfig().WithEndpoints(dead, live).SetFailover(2, 0)
+	cfg.ReconnectBackoffMin = 10 * time.Millisecond
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	sink := metrics.NewPrometheus()
+	cfg.Metrics = sink
+
+	ob := &endpointObserver{urls: make(chan string, 4)}
+	c := NewWsClient(context.Background(), cfg)
+	c.SetObserver(ob)
+	c.Start()
+	defer c.Close()
+
+	select {
+	case url := <-ob.urls:
+		if url != live {
+			t.Fatalf("OnEndpoint(%q), want %q", url, live)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("did not fail over")
+	}
+	if got := c.Endpoint(); got != live {
+		t.Fatalf("Endpoint() = %q, want %q", got, live)
+	}
+
+	// 拨号指标记在实际拨号的地址上
+	var sb strings.Builder
+	_ = sink.WriteText(&sb)
+	text := sb.String()
+	for _, want := range []string{
+		metrics.WsDialErrorsTotal + `{endpoint="` + endpointLabel(dead) + `"} 2`,
+		metrics.WsDialSeconds + `_count{endpoint="` + endpointLabel(live) + `"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/latency"
+)
+
+// timedObserver 记录回调收到的到达时间
+type timedObserver struct {
+	recordObserver
+	received []time.Time
+}
+
+func (o *timedObserver) OnTimedMessage(data []byte, received time.Time) error {
+	o.received = append(o.received, received)
+	return o.OnMessage(data)
+}
+
+func TestHandleLatency(t *testing.T) {
+	tracker := latency.New("test", latency.Config{})
+	cfg := NewConfig().WithLatency(tracker)
+	cfg.Logger = slog.New(slog.DiscardHandler)
+
+	ob := &timedObserver{}
+	c := NewWsClient(context.Background(), cfg)
+	c.SetObserver(ob)
+
+	received := time.Now().Add(-50 * time.Millisecond)
+	c.enqueue(1, []byte("a"), received)
+	c.handle(0, <-c.readCh)
+
+	if len(ob.received) != 1 || !ob.received[0].Equal(received) || len(ob.messages) != 1 {
+		t.Fatalf("received = %v, messages = %v", ob.received, ob.messages)
+	}
+	s := tracker.Snapshot()
+	if s.Queue.Count != 1 || s.Queue.Max < 50*time.Millisecond || s.Handler.Count != 1 {
+		t.Fatalf("snapshot = %+v", s)
+	}
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"log"
+	"log/slog"
+)
+
+// NewStdLogger 把 *log.Logger 包装成 *slog.Logger，保留原 logger 的前缀与时间格式
+// 记录以 key=value 的文本格式输出，兼容旧的 WithLogger(*log.Logger) 选项
+func NewStdLogger(logger *log.Logger) *slog.Logger {
+	if logger == nil {
+		return DiscardLogger()
+	}
+	opts := &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// 时间由 *log.Logger 自己输出
+			if len(groups) == 0 && a.Key == slog.TimeKey && logger.Flags()&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	return slog.New(slog.NewTextHandler(stdWriter{logger}, opts))
+}
+
+// OrDefaultLogger logger 为空时返回 slog.Default()
+func OrDefaultLogger(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// DiscardLogger 丢弃全部记录
+func DiscardLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// stdWriter TextHandler 每条记录调用一次 Write
+type stdWriter struct {
+	logger *log.Logger
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	return len(p), w.logger.Output(4, string(p))
+}
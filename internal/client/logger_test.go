@@ -0,0 +1,29 @@
+package client
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "[dex] ", log.LstdFlags))
+
+	logger.With("exchange", "okx").Info("subscribed", "channel", "trades", "conn_id", 3)
+	logger.Debug("dropped")
+
+	out := buf.String()
+	if strings.Count(out, "\n") != 1 {
+		t.Fatalf("expected one line, got %q", out)
+	}
+	if !strings.HasPrefix(out, "[dex] ") || strings.Contains(out, "time=") {
+		t.Fatalf("prefix or time not handled by *log.Logger: %q", out)
+	}
+	for _, want := range []string{"level=INFO", "msg=subscribed", "exchange=okx", "channel=trades", "conn_id=3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in %q", want, out)
+		}
+	}
+}
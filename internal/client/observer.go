@@ -1,5 +1,7 @@
 package client
 
+import "time"
+
 type ConnectionObserver interface {
 	OnConnecting(reason string)
 	OnConnected()     // 表示已连接上
@@ -8,3 +10,15 @@ type ConnectionObserver interface {
 	OnMessage(data []byte) error // 表示已接收到信息
 	OnError(err error)
 }
+
+// TimedObserver ConnectionObserver 可选实现，实现后代替 OnMessage 回调，received 为 readPump 读完整帧的时间
+// 适配器用它与交易所时间戳计算线路延迟
+type TimedObserver interface {
+	OnTimedMessage(data []byte, received time.Time) error
+}
+
+// frame 读队列中的一帧与到达时间
+type frame struct {
+	data     []byte
+	received time.Time
+}
@@ -1,3 +0,0 @@
-package client
-
-type Option func(cfg *Config)
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/recording"
+)
+
+// ReplayConfig 回放配置
+type ReplayConfig struct {
+	Path string
+	// Speed 回放速度：1 为原速，2 为两倍速，<=0 表示不等待、尽可能快
+	Speed float64
+}
+
+// ReplayClient 将录制文件中的入站帧按顺序交给 ConnectionObserver，用于复现解析和盘口问题
+// 发送的数据会被丢弃；录制中的连接编号变化时模拟一次断线重连
+type ReplayClient struct {
+	cfg    *Config
+	replay ReplayConfig
+	logger *slog.Logger
+
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+
+	ob     ConnectionObserver
+	closed atomic.Bool
+	connID atomic.Uint64
+	once   sync.Once
+	done   chan struct{}
+}
+
+func NewReplayClient(ctx context.Context, cfg *Config) *ReplayClient {
+	ctx, cancel := context.WithCancel(ctx)
+
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	var replay ReplayConfig
+	if cfg.Replay != nil {
+		replay = *cfg.Replay
+	}
+
+	return &ReplayClient{
+		cfg:        cfg,
+		replay:     replay,
+		logger:     cfg.Logger.With("component", "replay"),
+		ctx:        ctx,
+		cancelFunc: cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+func (c *ReplayClient) Start() {
+	if c.ob == nil {
+		log.Fatal("websocket observer is nil")
+	}
+	c.once.Do(func() {
+		go c.run()
+	})
+}
+
+// Done 回放结束（读完、出错或被关闭）后关闭
+func (c *ReplayClient) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *ReplayClient) run() {
+	defer close(c.done)
+
+	reader, err := recording.Open(c.replay.Path)
+	if err != nil {
+		c.ob.OnError(err)
+		return
+	}
+	defer reader.Close()
+
+	var (
+		connected bool
+		connID    uint64
+		// 录制时间与回放时间的对应关系，连接切换时重新对齐
+		baseRecorded time.Time
+		baseReplay   time.Time
+	)
+
+	for !c.closed.Load() {
+		frame, err := reader.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.logger.Error("stop reading recording", "path", c.replay.Path, "error", err)
+			}
+			break
+		}
+		if frame.Direction != recording.Inbound {
+			continue
+		}
+
+		if !connected || frame.ConnID != connID {
+			if connected {
+				c.ob.OnDisconnecting()
+				c.ob.OnDisconnected()
+			}
+			c.ob.OnConnecting("replay")
+			c.ob.OnConnected()
+			connected, connID = true, frame.ConnID
+			c.connID.Store(connID)
+			baseRecorded, baseReplay = frame.Time, time.Now()
+		}
+
+		if !c.wait(baseReplay, frame.Time.Sub(baseRecorded)) {
+			break
+		}
+		// 到达时间取录制时的时间，回放时的线路延迟与录制时一致
+		if ob, ok := c.ob.(TimedObserver); ok {
+			err = ob.OnTimedMessage(frame.Data, frame.Time)
+		} else {
+			err = c.ob.OnMessage(frame.Data)
+		}
+		if err != nil {
+			c.logger.Error("failed to handle message", "conn_id", connID, "error", err)
+		}
+	}
+
+	if connected {
+		c.ob.OnDisconnecting()
+		c.ob.OnDisconnected()
+	}
+}
+
+// wait 按回放速度等待到该帧的时间点，被关闭时返回 false
+func (c *ReplayClient) wait(base time.Time, offset time.Duration) bool {
+	if c.replay.Speed <= 0 {
+		return c.ctx.Err() == nil
+	}
+	d := time.Until(base.Add(time.Duration(float64(offset) / c.replay.Speed)))
+	if d <= 0 {
+		return c.ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Send 回放时发送的数据（订阅请求、ping 等）直接丢弃
+func (c *ReplayClient) Send(ctx context.Context, _ []byte) error {
+	if c.closed.Load() {
+		return common.ErrClosed
+	}
+	return ctx.Err()
+}
+
+func (c *ReplayClient) Close() {
+	if !c.closed.Swap(true) {
+		c.cancelFunc()
+	}
+}
+
+// Shutdown 停止回放并等待正在执行的回调完成
+func (c *ReplayClient) Shutdown(ctx context.Context) error {
+	c.Close()
+	// 还没有 Start 时直接结束，之后也不会再启动
+	c.once.Do(func() { close(c.done) })
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ConnID 录制文件中当前帧的连接编号
+func (c *ReplayClient) ConnID() uint64 {
+	return c.connID.Load()
+}
+
+// Endpoint 回放时为配置的地址
+func (c *ReplayClient) Endpoint() string {
+	return c.cfg.URL
+}
+
+// Reconnect 回放时忽略
+func (c *ReplayClient) Reconnect(string) {}
+
+func (c *ReplayClient) SetObserver(ob ConnectionObserver) Client {
+	c.ob = ob
+	return c
+}
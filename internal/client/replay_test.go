@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/recording"
+)
+
+type recordObserver struct {
+	mu       sync.Mutex
+	events   []string
+	messages []string
+}
+
+func (o *recordObserver) add(e string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+func (o *recordObserver) OnConnecting(string) { o.add("connecting") }
+func (o *recordObserver) OnConnected()        { o.add("connected") }
+func (o *recordObserver) OnDisconnecting()    { o.add("disconnecting") }
+func (o *recordObserver) OnDisconnected()     { o.add("disconnected") }
+func (o *recordObserver) OnError(error)       { o.add("error") }
+func (o *recordObserver) OnMessage(data []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, string(data))
+	return nil
+}
+
+func TestReplayClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws.rec")
+	w, err := recording.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	_ = w.Record(recording.Frame{Time: now, ConnID: 1, Direction: recording.Outbound, Data: []byte("sub")})
+	_ = w.Record(recording.Frame{Time: now, ConnID: 1, Direction: recording.Inbound, Data: []byte("a")})
+	_ = w.Record(recording.Frame{Time: now.Add(time.Hour), ConnID: 1, Direction: recording.Inbound, Data: []byte("b")})
+	_ = w.Record(recording.Frame{Time: now.Add(2 * time.Hour), ConnID: 2, Direction: recording.Inbound, Data: []byte("c")})
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ob := &recordObserver{}
+	cfg := NewConfig().WithReplay(&ReplayConfig{Path: path})
+	cli := NewClient(context.Background(), cfg).SetObserver(ob).(*ReplayClient)
+	cli.Start()
+
+	select {
+	case <-cli.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay did not finish")
+	}
+
+	if got := len(ob.messages); got != 3 || ob.messages[0] != "a" || ob.messages[2] != "c" {
+		t.Fatalf("messages = %v", ob.messages)
+	}
+	// 连接编号变化时模拟一次重连
+	want := []string{"connecting", "connected", "disconnecting", "disconnected", "connecting", "connected", "disconnecting", "disconnected"}
+	if len(ob.events) != len(want) {
+		t.Fatalf("events = %v", ob.events)
+	}
+	for i := range want {
+		if ob.events[i] != want[i] {
+			t.Fatalf("events = %v", ob.events)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+// DefaultReleaseTimeout ctx 没有截止时间时，ReleasePool 等待池中任务的最长时间
+const DefaultReleaseTimeout = time.Minute
+
+// ReleasePool 关闭协程池并等待池中正在执行的回调完成，已经关闭的池直接返回
+func ReleasePool(ctx context.Context, pool *ants.Pool) error {
+	if pool == nil || pool.IsClosed() {
+		return nil
+	}
+	timeout := DefaultReleaseTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = max(time.Until(deadline), time.Millisecond)
+	}
+	err := pool.ReleaseTimeout(timeout)
+	if err == nil || errors.Is(err, ants.ErrPoolClosed) {
+		return nil
+	}
+	if errors.Is(err, ants.ErrTimeout) {
+		return fmt.Errorf("release pool: %w", context.DeadlineExceeded)
+	}
+	return fmt.Errorf("release pool: %w", err)
+}
+
+// Wait 等待 wg 或 ctx 到期，适配器用它等待提交到协程池的回调
+func Wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
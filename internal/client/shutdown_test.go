@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingObserver 回调阻塞到 release 关闭
+type blockingObserver struct {
+	recordObserver
+	release chan struct{}
+}
+
+func (o *blockingObserver) OnMessage(data []byte) error {
+	<-o.release
+	return o.recordObserver.OnMessage(data)
+}
+
+func TestShutdownDrains(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		cfg := NewConfig().SetReadBufferSize(64).SetReadWorkerNum(2)
+		if ordered {
+			cfg.SetDispatchKey(func(data []byte) string {
+				key, _, _ := strings.Cut(string(data), ":")
+				return key
+			})
+		}
+		cfg.Logger = slog.New(slog.DiscardHandler)
+
+		ob := &blockingObserver{release: make(chan struct{})}
+		c := NewWsClient(context.Background(), cfg)
+		c.SetObserver(ob)
+		c.startWorkers()
+
+		const n = 20
+		for i := 0; i < n; i++ {
+			c.enqueue(1, []byte(fmt.Sprintf("key%d:%d", i%3, i)), time.Now())
+		}
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(ob.release)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := c.Shutdown(ctx); err != nil {
+			t.Fatalf("ordered=%v: Shutdown: %v", ordered, err)
+		}
+		cancel()
+		if got := len(ob.messages); got != n {
+			t.Fatalf("ordered=%v: handled %d messages, want %d", ordered, got, n)
+		}
+		if err := c.Send(context.Background(), []byte("x")); err == nil {
+			t.Fatalf("ordered=%v: Send after Shutdown succeeded", ordered)
+		}
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	cfg := NewConfig().SetReadBufferSize(8).SetReadWorkerNum(1)
+	cfg.Logger = slog.New(slog.DiscardHandler)
+
+	ob := &blockingObserver{release: make(chan struct{})}
+	defer close(ob.release)
+	c := NewWsClient(context.Background(), cfg)
+	c.SetObserver(ob)
+	c.startWorkers()
+	c.enqueue(1, []byte("stuck"), time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want deadline exceeded", err)
+	}
+}
@@ -4,33 +4,59 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"iter"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/latency"
+	"github.com/simonks2016/dex_plus/metrics"
+	"github.com/simonks2016/dex_plus/ratelimit"
+	"github.com/simonks2016/dex_plus/recording"
 	"golang.org/x/net/proxy"
 )
 
 type WsClient struct {
 	cfg    *Config
-	logger *log.Logger
+	base   *slog.Logger
 	dialer *websocket.Dialer
 
+	metrics metrics.MetricsSink
+	tag     atomic.Pointer[endpointTag] // 当前拨号地址的指标标签与日志，每次拨号时更新
+
+	endpoints   *endpointSet
+	connectedAt atomic.Int64 // 当前连接建立的时间，判断连接是否过早断开
+	reported    string       // 最近一次通过 EndpointObserver 上报的地址，只在 handleConnect 中读写
+
 	ctx        context.Context
 	cancelFunc context.CancelFunc
+	// loopCtx 拨号、读写协程使用，Shutdown 先停止收发，回调协程继续消费 readCh
+	loopCtx    context.Context
+	stopLoops  context.CancelFunc
+	loops      sync.WaitGroup // connLoop、writePump 与 readPump
+	workers    sync.WaitGroup // 回调协程与分区协程
+	draining   chan struct{}  // Shutdown 时关闭，回调协程消费完 readCh 后退出
+	flushCh    chan chan struct{}
+	shutdownMu sync.Mutex
 
 	closed atomic.Bool
 	conn   atomic.Pointer[websocket.Conn]
-	mu     sync.Mutex // 用于保护连接切换时的原子性，避免重复重连
+	connID atomic.Uint64 // 每次拨号成功递增，写入录制文件
+	mu     sync.Mutex    // 用于保护连接切换时的原子性，避免重复重连
 
 	reconnectCh chan string
 	writeCh     chan []byte
-	readCh      chan []byte
+	readCh      chan frame
+	conflate    *conflator // 仅 BackpressureConflate 使用
 
 	ob ConnectionObserver
 }
@@ -114,28 +140,66 @@ func NewWsClient(ctx context.Context, cfg *Config) *WsClient {
 	}
 
 	if cfg.Logger == nil {
-		cfg.Logger = log.Default()
+		cfg.Logger = slog.Default()
 	}
 
+	loopCtx, stopLoops := context.WithCancel(ctx)
+
 	w := &WsClient{
-		logger:      cfg.Logger,
+		base:        cfg.Logger,
 		cfg:         cfg,
+		metrics:     metrics.OrDiscard(cfg.Metrics),
+		endpoints:   newEndpointSet(cfg.EndpointList(), cfg.FailoverAfter),
 		ctx:         ctx,
 		dialer:      &d,
 		cancelFunc:  cancel,
+		loopCtx:     loopCtx,
+		stopLoops:   stopLoops,
+		draining:    make(chan struct{}),
+		flushCh:     make(chan chan struct{}),
 		reconnectCh: make(chan string, 1),
 		writeCh:     make(chan []byte, writeBuf),
-		readCh:      make(chan []byte, readBuf),
+		readCh:      make(chan frame, readBuf),
 	}
+	if cfg.Backpressure == BackpressureConflate {
+		w.conflate = newConflator()
+	}
+	w.setEndpoint(w.endpoints.current())
 
 	return w
 }
 
+// endpointTag 一个地址的指标标签与带 endpoint 属性的日志
+type endpointTag struct {
+	label  string
+	logger *slog.Logger
+}
+
+// setEndpoint 切换指标标签与日志中的地址，拨号前调用，之后的指标都计入实际拨号的地址
+func (c *WsClient) setEndpoint(rawURL string) {
+	label := endpointLabel(rawURL)
+	if t := c.tag.Load(); t != nil && t.label == label {
+		return
+	}
+	c.tag.Store(&endpointTag{label: label, logger: c.base.With("endpoint", label)})
+}
+
+// endpoint 当前地址的指标标签，取 URL 的 host
+func (c *WsClient) endpoint() string {
+	return c.tag.Load().label
+}
+
+// log 带当前地址的日志
+func (c *WsClient) log() *slog.Logger {
+	return c.tag.Load().logger
+}
+
 func (c *WsClient) Start() {
 	if c.ob == nil {
 		log.Fatal("websocket observer is nil")
 	}
 
+	c.loops.Add(2)
 	go c.connLoop()
 	go c.writePump()
 	c.startWorkers()
@@ -146,9 +210,10 @@ func (c *WsClient) Start() {
 
 // connLoop 负责管理生命周期：拨号、重连、清理
 func (c *WsClient) connLoop() {
+	defer c.loops.Done()
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-c.loopCtx.Done():
 			return
 		case reason := <-c.reconnectCh:
 			if c.closed.Load() {
@@ -165,6 +230,8 @@ func (c *WsClient) handleConnect(reason string) {
 
 	// 1. 清理旧连接
 	c.closeAndClearConn()
+	c.metrics.Counter(metrics.WsReconnectsTotal, 1, "endpoint", c.endpoint(), "reason", metrics.Reason(reason))
+	c.log().Info("connecting", "conn_id", c.connID.Load(), "reason", reason)
 
 	c.ob.OnConnecting(reason)
 
@@ -179,29 +246,70 @@ func (c *WsClient) handleConnect(reason string) {
 	}
 
 	for {
-		conn, _, err := c.dialer.DialContext(c.ctx, c.cfg.URL, c.cfg.Header)
+		start := time.Now()
+		url := c.endpoints.current()
+		c.setEndpoint(url)
+		conn, _, err := c.dialer.DialContext(c.loopCtx, url, c.cfg.Header)
 		if err == nil {
+			c.metrics.Observe(metrics.WsDialSeconds, time.Since(start).Seconds(), "endpoint", c.endpoint())
+			c.endpoints.succeeded()
 			c.setupConn(conn)
 			c.conn.Store(conn)
-			go c.readPump(conn) // 为每个新连接开启独立的 readPump
+			c.connectedAt.Store(time.Now().UnixNano())
+			c.loops.Add(1)
+			go c.readPump(conn, c.connID.Add(1)) // 为每个新连接开启独立的 readPump
+			if url != c.reported {
+				c.reported = url
+				if eo, ok := c.ob.(EndpointObserver); ok {
+					eo.OnEndpoint(url)
+				}
+			}
 			c.ob.OnConnected()
 			return
 		}
 
-		c.logger.Printf("[ws] dial failed: %v, retry in %v", err, backoff)
+		c.metrics.Counter(metrics.WsDialErrorsTotal, 1, "endpoint", c.endpoint())
+		if next, ok := c.endpoints.failed(); ok {
+			// 换到新地址后从最小退避重新开始
+			c.log().Warn("dial failed, switching endpoint", "url", url, "error", err, "next", next)
+			backoff = c.cfg.ReconnectBackoffMin
+			if backoff <= 0 {
+				backoff = time.Second
+			}
+		} else {
+			c.log().Warn("dial failed", "url", url, "error", err, "retry_in", backoff)
+		}
 
 		timer := time.NewTimer(backoff)
 		select {
-		case <-c.ctx.Done():
+		case <-c.loopCtx.Done():
 			timer.Stop()
 			return
 		case <-timer.C:
-			backoff = min(backoff*2, c.cfg.ReconnectBackoffMax)
+			backoff = min(backoff*2, maxBackoff)
 		}
 	}
 }
 
+// degraded 连接过早断开或心跳超时说明当前地址质量下降，切换到其他地址
+func (c *WsClient) degraded(err error) {
+	stable := c.cfg.StableAfter
+	if stable <= 0 {
+		stable = DefaultStableAfter
+	}
+	lived := time.Since(time.Unix(0, c.connectedAt.Load()))
+	var ne net.Error
+	timeout := errors.As(err, &ne) && ne.Timeout()
+	if lived >= stable && !timeout {
+		return
+	}
+	if next, ok := c.endpoints.degraded(); ok {
+		c.log().Warn("connection degraded, switching endpoint", "lived", lived, "error", err, "next", next)
+	}
+}
+
 func (c *WsClient) writePump() {
+	defer c.loops.Done()
 
 	pingInterval := c.cfg.PingInterval
 	if pingInterval <= 0 {
@@ -212,16 +320,43 @@ func (c *WsClient) writePump() {
 
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-c.loopCtx.Done():
 			return
 		case <-ticker.C:
 			c.doWrite(websocket.PingMessage, nil)
 		case msg := <-c.writeCh:
 			c.doWrite(websocket.TextMessage, msg)
+		case done := <-c.flushCh:
+			// 写完队列中已有的消息后通知 flush
+			for flushed := false; !flushed; {
+				select {
+				case msg := <-c.writeCh:
+					c.doWrite(websocket.TextMessage, msg)
+				default:
+					flushed = true
+				}
+			}
+			close(done)
 		}
 	}
 }
 
+// flush 等待写队列中已有的消息写出，超时或停止后直接返回
+func (c *WsClient) flush(ctx context.Context) {
+	done := make(chan struct{})
+	select {
+	case c.flushCh <- done:
+	case <-ctx.Done():
+		return
+	case <-c.loopCtx.Done():
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
 // 统一写入方法，解决并发安全和超时问题
 func (c *WsClient) doWrite(mt int, data []byte) {
 	conn := c.conn.Load()
@@ -235,7 +370,12 @@ func (c *WsClient) doWrite(mt int, data []byte) {
 	if mt == websocket.PingMessage {
 		err = conn.WriteControl(mt, data, time.Now().Add(c.cfg.WriteTimeout))
 	} else {
+		c.record(recording.Outbound, c.connID.Load(), data)
 		err = conn.WriteMessage(mt, data)
+		if err == nil {
+			c.metrics.Counter(metrics.WsFramesTotal, 1, "endpoint", c.endpoint(), "direction", "out")
+			c.metrics.Counter(metrics.WsBytesTotal, float64(len(data)), "endpoint", c.endpoint(), "direction", "out")
+		}
 	}
 
 	if err != nil {
@@ -243,10 +383,13 @@ func (c *WsClient) doWrite(mt int, data []byte) {
 	}
 }
 
-func (c *WsClient) readPump(conn *websocket.Conn) {
+func (c *WsClient) readPump(conn *websocket.Conn, connID uint64) {
 	// 确保 readPump 退出时，如果是当前连接则触发重连
+	defer c.loops.Done()
+	var readErr error
 	defer func() {
 		if c.conn.Load() == conn && !c.closed.Load() {
+			c.degraded(readErr)
 			c.signalReconnect("read_pump_exit")
 		}
 	}()
@@ -254,15 +397,12 @@ func (c *WsClient) readPump(conn *websocket.Conn) {
 	for {
 		msgType, r, err := conn.NextReader()
 		if err != nil {
+			readErr = err
 			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-				c.signalReconnect("the client has been disconnected,reconnecting...")
+				c.log().Info("the client has been disconnected, reconnecting", "conn_id", connID)
 				return
 			}
-			if c.logger != nil {
-				c.logger.Printf("[ws] websocket reader error: %v", err)
-			} else {
-				fmt.Printf("[ws] websocket reader error: %v \n", err)
-			}
+			c.log().Warn("websocket reader error", "conn_id", connID, "error", err)
 			return
 		}
 
@@ -274,43 +414,128 @@ func (c *WsClient) readPump(conn *websocket.Conn) {
 		}
 
 		data, err := io.ReadAll(io.LimitReader(r, c.cfg.maxMessageSize))
+		received := time.Now()
 		if err != nil {
 			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 				c.Reconnect("[error]failed to read message on websocket reader")
 			}
-			if c.logger != nil {
-				c.logger.Printf("[error]failed to read message from websocket: %s", err.Error())
-			} else {
-				fmt.Printf("[error]failed to read message from websocket: %s\n", err.Error())
-			}
+			c.log().Error("failed to read message", "conn_id", connID, "error", err)
 			continue
 		}
+		// 在投递之前录制，被丢弃的帧也会保留在录制文件中
+		c.record(recording.Inbound, connID, data)
+		c.metrics.Counter(metrics.WsFramesTotal, 1, "endpoint", c.endpoint(), "direction", "in")
+		c.metrics.Counter(metrics.WsBytesTotal, float64(len(data)), "endpoint", c.endpoint(), "direction", "in")
+
+		if !c.enqueue(connID, data, received) {
+			return
+		}
+	}
+}
 
+// enqueue 按 Backpressure 策略写入 readCh，被关闭时返回 false
+func (c *WsClient) enqueue(connID uint64, data []byte, received time.Time) bool {
+	f := frame{data: data, received: received}
+	dropped := 0
+
+	switch c.cfg.Backpressure {
+	case BackpressureBlock:
 		select {
-		case c.readCh <- data:
-		default:
-			if c.logger != nil {
-				c.logger.Printf("[ws] readCh full, drop msg")
-			} else {
-				fmt.Printf("[ws] readCh full, drop msg\n")
+		case c.readCh <- f:
+		case <-c.loopCtx.Done():
+			return false
+		}
+	case BackpressureDropOldest:
+		for sent := false; !sent; {
+			select {
+			case c.readCh <- f:
+				sent = true
+			default:
+				select {
+				case <-c.readCh:
+					dropped++
+				default:
+				}
+			}
+		}
+	case BackpressureConflate:
+		sent := false
+		// 暂存区还有帧时新帧也要进暂存区，避免同一个键的旧帧排在新帧后面
+		if c.conflate.flush(c.readCh) {
+			select {
+			case c.readCh <- f:
+				sent = true
+			default:
 			}
 		}
+		if !sent {
+			key := ""
+			if c.cfg.ConflateKey != nil {
+				key = c.cfg.ConflateKey(data)
+			}
+			if c.conflate.put(key, f) {
+				dropped++
+			}
+		}
+	default:
+		select {
+		case c.readCh <- f:
+		default:
+			dropped++
+		}
+	}
+
+	c.metrics.Gauge(metrics.WsReadQueueDepth, float64(len(c.readCh)), "endpoint", c.endpoint())
+	if dropped > 0 {
+		c.onDropped(connID, dropped)
+	}
+	return true
+}
+
+// onDropped 记录丢帧并通知实现了 DropObserver 的观察者
+func (c *WsClient) onDropped(connID uint64, count int) {
+	policy := c.cfg.Backpressure.String()
+	c.metrics.Counter(metrics.WsDroppedFramesTotal, float64(count), "endpoint", c.endpoint(), "policy", policy)
+	c.log().Warn("read queue full, frames dropped", "conn_id", connID, "policy", policy, "count", count)
+
+	if ob, ok := c.ob.(DropObserver); ok {
+		ob.OnDropped(count)
+	}
+}
+
+// record 录制原始帧，录制失败只打印日志
+func (c *WsClient) record(dir recording.Direction, connID uint64, data []byte) {
+	if c.cfg.Recorder == nil {
+		return
+	}
+	err := c.cfg.Recorder.Record(recording.Frame{
+		Time:      time.Now(),
+		ConnID:    connID,
+		Direction: dir,
+		Data:      data,
+	})
+	if err != nil {
+		c.log().Error("failed to record frame", "conn_id", connID, "error", err)
 	}
 }
 
 // Send 业务层调用的发送方法
 func (c *WsClient) Send(ctx context.Context, data []byte) error {
 	if c.closed.Load() {
-		return fmt.Errorf("client closed")
+		return common.ErrClosed
+	}
+	if err := c.cfg.RateLimiter.Acquire(ctx, c.cfg.RateLimitMode, ratelimit.Message(c.ConnID(), data)); err != nil {
+		return err
 	}
 
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	case c.writeCh <- data:
+		c.metrics.Gauge(metrics.WsWriteQueueDepth, float64(len(c.writeCh)), "endpoint", c.endpoint())
 		return nil
 	case <-time.After(time.Second): // 避免 writeCh 满时永久阻塞业务协程
-		return fmt.Errorf("write channel busy")
+		return fmt.Errorf("write channel busy: %w", common.ErrQueueFull)
 	}
 }
 
@@ -325,7 +550,10 @@ func (c *WsClient) signalReconnect(reason string) {
 func (c *WsClient) closeAndClearConn() {
 	conn := c.conn.Swap(nil)
 	if conn != nil {
-		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+		// WriteControl 可以与 writePump 并发调用，WriteMessage 不可以
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"),
+			time.Now().Add(time.Second))
 		_ = conn.Close()
 		c.ob.OnDisconnected()
 	}
@@ -338,6 +566,41 @@ func (c *WsClient) Close() {
 	}
 }
 
+// Shutdown 优雅关闭：取消订阅并写出写队列，关闭连接后不再接收新帧，
+// 回调协程处理完 readCh 中已有的消息后退出，全部协程退出后返回
+// ctx 到期时放弃剩余的消息，立即关闭并返回 ctx.Err()
+func (c *WsClient) Shutdown(ctx context.Context) error {
+	c.shutdownMu.Lock()
+	defer c.shutdownMu.Unlock()
+	if c.closed.Load() {
+		return nil
+	}
+
+	// 1. 取消订阅，等待取消订阅的请求写出
+	if c.conn.Load() != nil {
+		c.ob.OnDisconnecting()
+		c.flush(ctx)
+	}
+
+	// 2. 停止收发：关闭连接，等待拨号、读写协程退出，之后不会再有新帧进入 readCh
+	c.closed.Store(true)
+	c.stopLoops()
+	c.closeAndClearConn()
+	if err := Wait(ctx, &c.loops); err != nil {
+		c.cancelFunc()
+		return err
+	}
+
+	// 3. 回调协程处理完剩余的消息后退出
+	close(c.draining)
+	if err := Wait(ctx, &c.workers); err != nil {
+		c.cancelFunc()
+		return err
+	}
+	c.cancelFunc()
+	return nil
+}
+
 // setupConn 初始化连接
 func (c *WsClient) setupConn(conn *websocket.Conn) {
 	// 1. 设置读取限制，防止大包攻击
@@ -366,33 +629,138 @@ func (c *WsClient) setupConn(conn *websocket.Conn) {
 }
 
 // startWorkers 启动共工作节点
+// 设置了 DispatchKey 时按键哈希分区，每个分区只有一个协程消费，同一个键的消息按到达顺序处理。
+// 分区在这里确定，Connect 之后再设置 DispatchKey 会与读协程竞争，所以适配器在构造客户端时就设置；
+// 适配器的普通回调仍然提交到自己的协程池，不受分区影响。
+// 适配器在 OnMessage 中取出回调 slice 后不持锁遍历，运行时注册回调要复制出新的 slice 再替换，不能原地追加正在遍历的旧 slice
 func (c *WsClient) startWorkers() {
 	workerNum := c.cfg.ReadWorkerNum
 	if workerNum <= 0 {
 		workerNum = 5 // 默认 5 个协程并行处理业务逻辑
 	}
 
-	for i := 0; i < workerNum; i++ {
-		go func(id int) {
-			for {
-				select {
-				case <-c.ctx.Done():
+	if c.cfg.DispatchKey == nil {
+		for i := 0; i < workerNum; i++ {
+			c.workers.Add(1)
+			go c.worker(i, c.readCh, c.draining)
+		}
+		return
+	}
+
+	// 分区协程排空 readCh 后关闭分区，分区的回调协程处理完分区中的消息后退出
+	partitions := make([]chan frame, workerNum)
+	for i := range partitions {
+		partitions[i] = make(chan frame, partitionBufferSize)
+		c.workers.Add(1)
+		go c.worker(i, partitions[i], nil)
+	}
+	c.workers.Add(1)
+	go c.partition(partitions)
+}
+
+// partitionBufferSize 每个分区的缓冲，分区写满时阻塞分发协程，压力回传到 readCh
+const partitionBufferSize = 64
+
+// partition 从 readCh 取出消息并按 DispatchKey 分发到固定的分区
+func (c *WsClient) partition(partitions []chan frame) {
+	defer c.workers.Done()
+	dispatch := func(msg frame) bool {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(c.cfg.DispatchKey(msg.data)))
+		select {
+		case partitions[h.Sum32()%uint32(len(partitions))] <- msg:
+			return true
+		case <-c.ctx.Done():
+			return false
+		}
+	}
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg := <-c.readCh:
+			if !dispatch(msg) {
+				return
+			}
+		case <-c.draining:
+			for msg := range c.pending() {
+				if !dispatch(msg) {
+					return
+				}
+			}
+			for _, p := range partitions {
+				close(p)
+			}
+			return
+		}
+	}
+}
+
+// pending 依次取出 readCh 与合并暂存区中剩余的消息，只在 Shutdown 停止读协程之后使用
+func (c *WsClient) pending() iter.Seq[frame] {
+	return func(yield func(frame) bool) {
+		for {
+			select {
+			case msg := <-c.readCh:
+				if !yield(msg) {
+					return
+				}
+			default:
+				if c.conflate == nil || c.conflate.len() == 0 {
+					return
+				}
+				c.conflate.flush(c.readCh)
+			}
+		}
+	}
+}
+
+// worker 执行业务回调；draining 关闭后处理完剩余的消息再退出，分区的回调协程在分区关闭后退出
+func (c *WsClient) worker(id int, ch <-chan frame, draining <-chan struct{}) {
+	defer c.workers.Done()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.handle(id, msg)
+		case <-draining:
+			for msg := range c.pending() {
+				if c.ctx.Err() != nil {
 					return
-				case msg, ok := <-c.readCh:
-					if !ok {
-						return
-					}
-					// 执行业务回调
-					if c.ob != nil {
-						if err := c.ob.OnMessage(msg); err != nil {
-							if c.logger != nil {
-								c.logger.Printf("[worker-%d] OnMessage error: %v", id, err)
-							}
-						}
-					}
 				}
+				c.handle(id, msg)
 			}
-		}(i)
+			return
+		}
+	}
+}
+
+func (c *WsClient) handle(id int, msg frame) {
+	// 执行业务回调
+	if c.ob != nil {
+		start := time.Now()
+		c.cfg.Latency.Observe(latency.Queue, start.Sub(msg.received))
+		var err error
+		if ob, ok := c.ob.(TimedObserver); ok {
+			err = ob.OnTimedMessage(msg.data, msg.received)
+		} else {
+			err = c.ob.OnMessage(msg.data)
+		}
+		elapsed := time.Since(start)
+		c.cfg.Latency.Observe(latency.Handler, elapsed)
+		c.metrics.Observe(metrics.WsHandlerSeconds, elapsed.Seconds(), "endpoint", c.endpoint())
+		if err != nil {
+			c.metrics.Counter(metrics.WsHandlerErrorsTotal, 1, "endpoint", c.endpoint())
+			c.log().Error("failed to handle message", "worker", id, "conn_id", c.connID.Load(), "error", err)
+		}
+	}
+	// 读队列有空位了，回填合并暂存区
+	if c.conflate != nil {
+		c.conflate.flush(c.readCh)
 	}
 }
 
@@ -402,11 +770,30 @@ func (cli *WsClient) SetObserver(ob ConnectionObserver) Client {
 	return cli
 }
 
+// ConnID 当前连接编号
+func (cli *WsClient) ConnID() uint64 {
+	return cli.connID.Load()
+}
+
+// Endpoint 当前使用的地址，断线期间为下一次拨号的地址
+func (cli *WsClient) Endpoint() string {
+	return cli.endpoints.current()
+}
+
 // Reconnect 重启
 func (cli *WsClient) Reconnect(reason string) {
 	cli.signalReconnect(reason)
 }
 
+// endpointLabel 取 URL 的 host 作为指标标签
+func endpointLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
 // DirectDialer 代理器
 type directDialer struct {
 	base          *net.Dialer
@@ -4,12 +4,10 @@ import (
 	"context"
 
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 	"testing"
 
 	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/dextest"
 	"github.com/simonks2016/dex_plus/internal/client"
 )
 
@@ -71,12 +69,7 @@ func (ob *Ob) OnError(err error) {
 }
 
 func TestClient(t *testing.T) {
-
-	ctx, stop := signal.NotifyContext(
-		context.Background(),
-		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
-	// 释放资源
-	defer stop()
+	ctx := dextest.LiveContext(t)
 
 	cfg := client.NewConfig().WithURL("wss://ws.okx.com:8443/ws/v5/public")
 	cfg.IsForbidIPV6 = true
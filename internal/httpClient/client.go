@@ -9,9 +9,15 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/metrics"
+	"github.com/simonks2016/dex_plus/ratelimit"
 )
 
 type Client struct {
@@ -21,6 +27,13 @@ type Client struct {
 
 	workerSize int
 
+	metrics metrics.MetricsSink
+
+	exchange string
+
+	limiter   *ratelimit.Limiter
+	limitMode ratelimit.Mode
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -40,6 +53,17 @@ type Config struct {
 	MaxIdleConn        int
 	MaxIdleConnPerHost int
 	IdleConnTimeout    time.Duration
+
+	// Metrics 请求数、重试、延迟与队列深度，为空时不记录
+	Metrics metrics.MetricsSink
+
+	// Exchange 交易所名称，写入 GetJSON 返回的错误
+	Exchange string
+
+	// RateLimiter 每次发出请求（包括重试）之前获取令牌，为空时不限速
+	RateLimiter *ratelimit.Limiter
+	// RateLimitMode 令牌不足时等待或立即返回 *common.RateLimitError
+	RateLimitMode ratelimit.Mode
 }
 
 func NewClient(cfg Config) *Client {
@@ -92,6 +116,10 @@ func NewClient(cfg Config) *Client {
 		},
 		queue:      make(chan Request, cfg.QueueSize),
 		workerSize: cfg.WorkerSize,
+		metrics:    metrics.OrDiscard(cfg.Metrics),
+		exchange:   cfg.Exchange,
+		limiter:    cfg.RateLimiter,
+		limitMode:  cfg.RateLimitMode,
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -124,12 +152,36 @@ func (c *Client) Close() {
 func (c *Client) DoAsync(req Request) error {
 	select {
 	case <-c.ctx.Done():
-		return c.ctx.Err()
+		return fmt.Errorf("http client: %w", common.ErrClosed)
 	case c.queue <- req:
+		c.metrics.Gauge(metrics.HttpQueueDepth, float64(len(c.queue)))
 		return nil
 	default:
-		return fmt.Errorf("http client queue is full")
+		return fmt.Errorf("http client: %w", common.ErrQueueFull)
+	}
+}
+
+// Do 同步发送请求，不经过队列，按 req.Retry 重试
+func (c *Client) Do(req Request) (*Response, error) {
+	return c.doWithRetry(req)
+}
+
+// GetJSON 同步 GET 并将响应体解析到 out，非 2xx 状态码按 Response.StatusError 返回错误
+func (c *Client) GetJSON(ctx context.Context, rawURL string, header map[string]string, out any) error {
+	resp, err := c.Do(Request{
+		Method: GET,
+		URL:    rawURL,
+		Header: header,
+		Ctx:    ctx,
+		Retry:  2,
+	})
+	if err != nil {
+		return err
 	}
+	if err := resp.StatusError(c.exchange); err != nil {
+		return fmt.Errorf("GET %s: %w", rawURL, err)
+	}
+	return json.Unmarshal(resp.Body, out)
 }
 
 func (c *Client) worker(workerID int) {
@@ -165,8 +217,18 @@ func (c *Client) doWithRetry(req Request) (*Response, error) {
 		maxRetry = 0
 	}
 
+	host := hostLabel(req.URL)
+	method := methodToString(req.Method)
+
 	for i := 0; i <= maxRetry; i++ {
+		if i > 0 {
+			c.metrics.Counter(metrics.HttpRetriesTotal, 1, "host", host, "method", method)
+		}
+		if err := c.acquire(req, method); err != nil {
+			return lastResp, err
+		}
 		resp, err := c.do(req, i)
+		c.observe(host, method, resp, err)
 		if err == nil && resp != nil && resp.StatusCode < 500 {
 			return resp, nil
 		}
@@ -332,6 +394,42 @@ func (c *Client) do(req Request, retryCount int) (*Response, error) {
 	}, lastErr
 }
 
+// acquire 获取请求的令牌，等待受 req.Ctx 与客户端关闭的约束
+func (c *Client) acquire(req Request, method string) error {
+	if c.limiter == nil {
+		return nil
+	}
+	ctx := req.Ctx
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	rawURL, err := buildURL(req.URL, req.Query)
+	if err != nil {
+		rawURL = req.URL
+	}
+	return c.limiter.Acquire(ctx, c.limitMode, ratelimit.HTTPRequest(method, rawURL, req.Instruments...))
+}
+
+// observe 记录单次请求的状态码与延迟，网络错误的状态记为 error
+func (c *Client) observe(host, method string, resp *Response, err error) {
+	status := "error"
+	if err == nil && resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	c.metrics.Counter(metrics.HttpRequestsTotal, 1, "host", host, "method", method, "status", status)
+	if resp != nil {
+		c.metrics.Observe(metrics.HttpRequestSeconds, resp.Latency.Seconds(), "host", host, "method", method)
+	}
+}
+
+func hostLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
 func buildURL(rawURL string, query map[string]string) (string, error) {
 	if len(query) == 0 {
 		return rawURL, nil
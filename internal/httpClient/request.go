@@ -32,6 +32,7 @@ type Request struct {
 	Priority      int               `json:"priority"`
 	Tags          []string          `json:"tags"`
 	Meta          map[string]any    `json:"meta"`
+	Instruments   []string          `json:"instruments"` // 按品种计数的限频规则使用，批量请求每个订单一项
 	CreatedAt     time.Time         `json:"created_at"`
 	Callback      Callback          `json:"-"`
 }
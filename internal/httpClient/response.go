@@ -2,7 +2,10 @@ package httpClient
 
 import (
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/simonks2016/dex_plus/common"
 )
 
 type Response struct {
@@ -14,3 +17,40 @@ type Response struct {
 	RetryCount int           `json:"retry_count"`
 	Err        error         `json:"err"`
 }
+
+// StatusError 非 2xx 状态码对应的错误，2xx 返回 nil
+// 429、418 返回 *common.RateLimitError，按 Retry-After 头设置等待时间；其余返回 *common.ExchangeError，5xx 可重试
+func (r *Response) StatusError(exchange string) error {
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return nil
+	}
+	code := strconv.Itoa(r.StatusCode)
+	if r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusTeapot {
+		return &common.RateLimitError{
+			Exchange:   exchange,
+			Code:       code,
+			Msg:        string(r.Body),
+			RetryAfter: retryAfter(r.Header.Get("Retry-After")),
+		}
+	}
+	return &common.ExchangeError{
+		Exchange:  exchange,
+		Code:      code,
+		Msg:       string(r.Body),
+		Retryable: r.StatusCode >= 500,
+	}
+}
+
+// retryAfter 解析 Retry-After 头，支持秒数与 HTTP 日期
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,205 @@
+package shard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Keys 订阅键与 (channel, symbol) 之间的换算
+type Keys interface {
+	Key(channel, symbol string) string
+	Split(key string) (channel, symbol string)
+}
+
+// FeedKeys 以 channel:symbol 为订阅键，不带品种的频道以频道名为键
+var FeedKeys Keys = feedKeys{}
+
+type feedKeys struct{}
+
+func (feedKeys) Key(channel, symbol string) string {
+	if symbol == "" {
+		return channel
+	}
+	return channel + ":" + symbol
+}
+
+func (feedKeys) Split(key string) (string, string) {
+	channel, symbol, _ := strings.Cut(key, ":")
+	return channel, symbol
+}
+
+// Batch 一组要在同一个连接上注册的回调
+type Batch[H any] struct {
+	Ordered bool
+	Callers []H
+}
+
+// comparableConn 可以作为 map 键的连接
+type comparableConn interface {
+	Conn
+	comparable
+}
+
+// channelState 频道的回调，下标 0 为普通回调，1 为保序回调
+type channelState[C comparable, H any] struct {
+	callers   [2][]H
+	installed map[C][2]int // 每个连接上已经注册的回调数量
+}
+
+func orderedIndex(ordered bool) int {
+	if ordered {
+		return 1
+	}
+	return 0
+}
+
+// Channels 在 Pool 之上按频道记录回调，频道分到哪个连接就在哪个连接上注册一份，
+// 新分到这个频道的连接补齐之前注册的全部回调；适配器只需要负责在连接上编码和发送订阅
+type Channels[C comparableConn, H any] struct {
+	pool *Pool[C]
+	keys Keys
+
+	mu       sync.Mutex
+	channels map[string]*channelState[C, H]
+}
+
+func NewChannels[C comparableConn, H any](pool *Pool[C], keys Keys) *Channels[C, H] {
+	return &Channels[C, H]{
+		pool:     pool,
+		keys:     keys,
+		channels: make(map[string]*channelState[C, H]),
+	}
+}
+
+// Subscribe 记录频道的回调并为品种分配连接，不传品种时以频道本身为订阅键；
+// apply 在每个分到的连接上调用一次，batches 为该连接上还没有注册的回调，保序与普通回调分开并各自带上 ordered；
+// 同一频道可以先后注册两种回调。callers 为空时沿用该频道已经注册的回调
+func (c *Channels[C, H]) Subscribe(channel string, symbols []string, ordered bool, callers []H, apply func(conn C, symbols []string, batches []Batch[H])) {
+	c.mu.Lock()
+	s, ok := c.channels[channel]
+	if !ok {
+		s = &channelState[C, H]{installed: make(map[C][2]int)}
+		c.channels[channel] = s
+	}
+	i := orderedIndex(ordered)
+	s.callers[i] = append(s.callers[i], callers...)
+	c.mu.Unlock()
+
+	keys := []string{c.keys.Key(channel, "")}
+	if len(symbols) > 0 {
+		keys = keys[:0]
+		for _, symbol := range symbols {
+			if key := c.keys.Key(channel, symbol); !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	c.pool.Subscribe(keys, func(conn C, keys []string) {
+		c.mu.Lock()
+		var batches []Batch[H]
+		installed := s.installed[conn]
+		for i, ordered := range []bool{false, true} {
+			if pending := s.callers[i][installed[i]:]; len(pending) > 0 {
+				batches = append(batches, Batch[H]{Ordered: ordered, Callers: pending})
+			}
+			installed[i] = len(s.callers[i])
+		}
+		s.installed[conn] = installed
+		c.mu.Unlock()
+
+		apply(conn, c.symbols(keys), batches)
+	})
+}
+
+// Unsubscribe 在品种所在的连接上调用 remove，不传品种时取消整个频道；
+// remove 返回连接上是否还有该频道，没有时之后再分到这个频道需要重新注册回调，频道在全部连接上都没有时移除回调记录
+func (c *Channels[C, H]) Unsubscribe(channel string, symbols []string, remove func(conn C, symbols []string) (bool, error)) error {
+	var keys []string
+	if len(symbols) == 0 {
+		for _, key := range c.pool.Keys() {
+			if ch, _ := c.keys.Split(key); ch == channel {
+				keys = append(keys, key)
+			}
+		}
+	} else {
+		for _, symbol := range symbols {
+			keys = append(keys, c.keys.Key(channel, symbol))
+		}
+	}
+
+	var errs []error
+	c.pool.Unsubscribe(keys, func(conn C, keys []string) {
+		remaining, err := remove(conn, c.symbols(keys))
+		errs = append(errs, err)
+		if !remaining {
+			c.mu.Lock()
+			if s, ok := c.channels[channel]; ok {
+				delete(s.installed, conn)
+			}
+			c.mu.Unlock()
+		}
+	})
+
+	c.mu.Lock()
+	if s, ok := c.channels[channel]; ok && len(s.installed) == 0 {
+		delete(c.channels, channel)
+	}
+	c.mu.Unlock()
+
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("unsubscribe %s: %w", channel, err)
+		}
+	}
+	return nil
+}
+
+// Each 在每个品种所在的连接上调用 fn，未分配的品种忽略，遇到错误立即返回
+func (c *Channels[C, H]) Each(channel string, symbols []string, fn func(conn C, symbol string) error) error {
+	for _, symbol := range symbols {
+		conn, ok := c.pool.Owner(c.keys.Key(channel, symbol))
+		if !ok {
+			continue
+		}
+		if err := fn(conn, symbol); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Has 频道是否注册过回调
+func (c *Channels[C, H]) Has(channel string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.channels[channel]
+	return ok
+}
+
+// Rebalance 清空各连接上的注册记录后重新分配连接，回调在新连接上重新注册
+func (c *Channels[C, H]) Rebalance() {
+	c.mu.Lock()
+	for _, s := range c.channels {
+		clear(s.installed)
+	}
+	c.mu.Unlock()
+	c.pool.Rebalance()
+}
+
+// Reconnected 某个连接自动重连后调用，见 Pool.Reconnected
+func (c *Channels[C, H]) Reconnected() {
+	c.pool.Reconnected(c.Rebalance)
+}
+
+// symbols 订阅键中的品种，不带品种的键忽略
+func (c *Channels[C, H]) symbols(keys []string) []string {
+	ret := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, symbol := c.keys.Split(key); symbol != "" {
+			ret = append(ret, symbol)
+		}
+	}
+	return ret
+}
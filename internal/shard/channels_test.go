@@ -0,0 +1,130 @@
+package shard
+
+import (
+	"slices"
+	"testing"
+)
+
+// installed 记录每个连接上注册过的回调
+type installed map[*fakeConn][]string
+
+func (in installed) subscribe(c *Channels[*fakeConn, string], channel string, symbols []string, callers ...string) {
+	c.Subscribe(channel, symbols, false, callers, func(conn *fakeConn, symbols []string, batches []Batch[string]) {
+		for _, b := range batches {
+			in[conn] = append(in[conn], b.Callers...)
+		}
+		conn.keys = append(conn.keys, symbols...)
+	})
+}
+
+func TestChannelsSubscribe(t *testing.T) {
+	p, created := newFakePool(2)
+	c := NewChannels[*fakeConn, string](p, FeedKeys)
+	in := make(installed)
+
+	in.subscribe(c, "trade", []string{"a", "b"}, "h1")
+	in.subscribe(c, "trade", []string{"a"}, "h2")
+	conns := *created
+	if !slices.Equal(in[conns[0]], []string{"h1", "h2"}) {
+		t.Fatalf("shard 0 callers = %v", in[conns[0]])
+	}
+
+	// 新分到这个频道的连接补齐之前注册的全部回调，已有的连接不重复注册
+	in.subscribe(c, "trade", []string{"c"})
+	conns = *created
+	if len(conns) != 2 || !slices.Equal(in[conns[1]], []string{"h1", "h2"}) {
+		t.Fatalf("shard 1 callers = %v", in[conns[1]])
+	}
+	if !slices.Equal(in[conns[0]], []string{"h1", "h2"}) {
+		t.Fatalf("shard 0 callers = %v", in[conns[0]])
+	}
+
+	// 不带品种的频道以频道名为键，apply 收到的品种为空
+	in.subscribe(c, "status", nil, "h3")
+	if _, ok := p.Owner("status"); !ok {
+		t.Fatal("status not assigned")
+	}
+}
+
+func TestChannelsUnsubscribe(t *testing.T) {
+	p, created := newFakePool(2)
+	c := NewChannels[*fakeConn, string](p, FeedKeys)
+	in := make(installed)
+	in.subscribe(c, "trade", []string{"a", "b", "c"}, "h1")
+
+	var removed []string
+	remove := func(conn *fakeConn, symbols []string) (bool, error) {
+		removed = append(removed, symbols...)
+		conn.keys = slices.DeleteFunc(conn.keys, func(s string) bool { return slices.Contains(symbols, s) })
+		return len(conn.keys) > 0, nil
+	}
+	if err := c.Unsubscribe("trade", []string{"c"}, remove); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Has("trade") {
+		t.Fatal("trade removed while a, b still subscribed")
+	}
+
+	// 连接上已经没有这个频道，再分到时重新注册回调
+	shard1 := (*created)[1]
+	delete(in, shard1)
+	in.subscribe(c, "trade", []string{"c"})
+	if !slices.Equal(in[shard1], []string{"h1"}) {
+		t.Fatalf("shard 1 callers = %v", in[shard1])
+	}
+
+	if err := c.Unsubscribe("trade", nil, remove); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(removed)
+	if !slices.Equal(removed, []string{"a", "b", "c", "c"}) {
+		t.Fatalf("removed = %v", removed)
+	}
+	if c.Has("trade") {
+		t.Fatal("trade still registered")
+	}
+}
+
+func TestChannelsRebalance(t *testing.T) {
+	p, created := newFakePool(2)
+	c := NewChannels[*fakeConn, string](p, FeedKeys)
+	p.SetRetire(func(conn *fakeConn) { conn.Close() })
+	in := make(installed)
+	in.subscribe(c, "trade", []string{"a", "b", "c"}, "h1", "h2")
+
+	// 重新分配后新连接上重新注册全部回调
+	c.Rebalance()
+	for _, conn := range p.Conns() {
+		if !slices.Equal(in[conn], []string{"h1", "h2"}) {
+			t.Fatalf("shard %d callers = %v", conn.index, in[conn])
+		}
+	}
+	if !(*created)[0].closed {
+		t.Fatal("old shard not retired")
+	}
+}
+
+func TestChannelsOrderedBatches(t *testing.T) {
+	p, _ := newFakePool(2)
+	c := NewChannels[*fakeConn, string](p, FeedKeys)
+
+	var got []Batch[string]
+	apply := func(conn *fakeConn, symbols []string, batches []Batch[string]) {
+		got = append(got, batches...)
+	}
+	// 先普通订阅后保序订阅，保序回调单独成批并带上自己的 ordered
+	c.Subscribe("trade", []string{"a"}, false, []string{"h1"}, apply)
+	c.Subscribe("trade", []string{"a"}, true, []string{"h2"}, apply)
+	want := []Batch[string]{{Ordered: false, Callers: []string{"h1"}}, {Ordered: true, Callers: []string{"h2"}}}
+	if len(got) != 2 || got[0].Ordered || !slices.Equal(got[0].Callers, want[0].Callers) ||
+		!got[1].Ordered || !slices.Equal(got[1].Callers, want[1].Callers) {
+		t.Fatalf("batches = %+v, want %+v", got, want)
+	}
+
+	// 新连接同时补齐两种回调
+	got = nil
+	c.Subscribe("trade", []string{"b", "c"}, false, nil, apply)
+	if len(got) != 2 || got[0].Ordered || !got[1].Ordered {
+		t.Fatalf("batches on new shard = %+v", got)
+	}
+}
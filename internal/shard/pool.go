@@ -0,0 +1,378 @@
+// Package shard 把订阅分散到多个连接上，单个连接的订阅数不超过交易所的上限
+package shard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetireTimeout Rebalance 等待旧连接排空读队列的最长时间，超时后直接关闭
+const RetireTimeout = 5 * time.Second
+
+// Conn 分片中的一个连接
+type Conn interface {
+	Connect()
+	Close()
+}
+
+// Apply 把分配到同一个连接的订阅键应用到该连接上
+type Apply[C Conn] func(conn C, keys []string)
+
+type spec[C Conn] struct {
+	keys  []string
+	apply Apply[C]
+}
+
+type shard[C Conn] struct {
+	conn C
+	keys int
+}
+
+// Pool 连接池；订阅键按先到先得填满一个连接后再新建连接，Rebalance 时按订阅顺序平均分配
+type Pool[C Conn] struct {
+	mu      sync.Mutex
+	limit   int // 每个连接的订阅数上限，<=0 表示不限
+	newConn func(index int) C
+	shards  []*shard[C]
+	owner   map[string]int
+	specs   []spec[C] // 按订阅顺序记录，重新分配时重放
+	started bool
+
+	retire      func(conn C) // Rebalance 停止旧连接的方式，默认见 retireConn
+	rebalanceMu sync.Mutex   // 同一时间只有一次 Rebalance
+	pending     atomic.Bool  // Reconnected 已经安排了一次 Rebalance
+}
+
+// NewPool 创建连接池，并立即创建第一个连接
+func NewPool[C Conn](limit int, newConn func(index int) C) *Pool[C] {
+	p := &Pool[C]{
+		limit:   limit,
+		newConn: newConn,
+		owner:   make(map[string]int),
+	}
+	p.shards = append(p.shards, &shard[C]{conn: newConn(0)})
+	return p
+}
+
+// SetRetire 设置 Rebalance 停止旧连接的方式；不设置时使用 retireConn
+func (p *Pool[C]) SetRetire(retire func(conn C)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retire = retire
+}
+
+// First 第一个连接，用于查询类操作（日志、交易对信息等）
+func (p *Pool[C]) First() C {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.shards[0].conn
+}
+
+// Conns 全部连接
+func (p *Pool[C]) Conns() []C {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ret := make([]C, 0, len(p.shards))
+	for _, s := range p.shards {
+		ret = append(ret, s.conn)
+	}
+	return ret
+}
+
+// Owner 订阅键所在的连接
+func (p *Pool[C]) Owner(key string) (C, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, ok := p.owner[key]
+	if !ok {
+		var zero C
+		return zero, false
+	}
+	return p.shards[i].conn, true
+}
+
+// Subscribe 为一组订阅键分配连接，按连接分组后调用 apply；已经分配过的键留在原连接上
+// 连接池已启动时，新建的连接会在 apply 之后自动连接
+func (p *Pool[C]) Subscribe(keys []string, apply Apply[C]) {
+	p.mu.Lock()
+	p.specs = append(p.specs, spec[C]{keys: keys, apply: apply})
+	first := len(p.shards)
+	groups := p.assign(keys)
+	var fresh []C
+	for i := first; i < len(p.shards); i++ {
+		fresh = append(fresh, p.shards[i].conn)
+	}
+	started := p.started
+	p.mu.Unlock()
+
+	for _, g := range groups {
+		apply(g.conn, g.keys)
+	}
+	if started {
+		for _, conn := range fresh {
+			conn.Connect()
+		}
+	}
+}
+
+type group[C Conn] struct {
+	conn C
+	keys []string
+}
+
+// assign 按先到先得分配订阅键，调用方持有锁
+func (p *Pool[C]) assign(keys []string) []group[C] {
+	var groups []group[C]
+	index := make(map[int]int)
+
+	add := func(shardIndex int, key string) {
+		gi, ok := index[shardIndex]
+		if !ok {
+			gi = len(groups)
+			index[shardIndex] = gi
+			groups = append(groups, group[C]{conn: p.shards[shardIndex].conn})
+		}
+		groups[gi].keys = append(groups[gi].keys, key)
+	}
+
+	for _, key := range keys {
+		if i, ok := p.owner[key]; ok {
+			add(i, key)
+			continue
+		}
+		i := p.vacant()
+		p.owner[key] = i
+		p.shards[i].keys++
+		add(i, key)
+	}
+	return groups
+}
+
+// vacant 第一个还有空位的连接，都满了就新建一个
+func (p *Pool[C]) vacant() int {
+	for i, s := range p.shards {
+		if p.limit <= 0 || s.keys < p.limit {
+			return i
+		}
+	}
+	p.shards = append(p.shards, &shard[C]{conn: p.newConn(len(p.shards))})
+	return len(p.shards) - 1
+}
+
+// Unsubscribe 释放一组订阅键，按所在连接分组后调用 remove；未分配过的键忽略
+// 释放后的空位留给之后的订阅，Rebalance 时不再重放这些键
+func (p *Pool[C]) Unsubscribe(keys []string, remove Apply[C]) {
+	p.mu.Lock()
+	released := make(map[string]struct{}, len(keys))
+	var groups []group[C]
+	index := make(map[int]int)
+	for _, key := range keys {
+		i, ok := p.owner[key]
+		if !ok {
+			continue
+		}
+		delete(p.owner, key)
+		p.shards[i].keys--
+		released[key] = struct{}{}
+
+		gi, ok := index[i]
+		if !ok {
+			gi = len(groups)
+			index[i] = gi
+			groups = append(groups, group[C]{conn: p.shards[i].conn})
+		}
+		groups[gi].keys = append(groups[gi].keys, key)
+	}
+
+	specs := p.specs[:0]
+	for _, s := range p.specs {
+		kept := make([]string, 0, len(s.keys))
+		for _, key := range s.keys {
+			if _, ok := released[key]; !ok {
+				kept = append(kept, key)
+			}
+		}
+		if len(kept) > 0 {
+			s.keys = kept
+			specs = append(specs, s)
+		}
+	}
+	p.specs = specs
+	p.mu.Unlock()
+
+	for _, g := range groups {
+		remove(g.conn, g.keys)
+	}
+}
+
+// Keys 已分配的全部订阅键
+func (p *Pool[C]) Keys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ret := make([]string, 0, len(p.owner))
+	for key := range p.owner {
+		ret = append(ret, key)
+	}
+	return ret
+}
+
+// Connect 连接全部连接
+func (p *Pool[C]) Connect() {
+	p.mu.Lock()
+	p.started = true
+	conns := make([]C, 0, len(p.shards))
+	for _, s := range p.shards {
+		conns = append(conns, s.conn)
+	}
+	p.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Connect()
+	}
+}
+
+// Close 关闭全部连接
+func (p *Pool[C]) Close() {
+	p.mu.Lock()
+	p.started = false
+	conns := make([]C, 0, len(p.shards))
+	for _, s := range p.shards {
+		conns = append(conns, s.conn)
+	}
+	p.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// Shutdown 停止连接池，并发调用 shutdown 关闭全部连接，等待全部返回后合并错误
+func (p *Pool[C]) Shutdown(shutdown func(conn C) error) error {
+	p.mu.Lock()
+	p.started = false
+	conns := make([]C, 0, len(p.shards))
+	for _, s := range p.shards {
+		conns = append(conns, s.conn)
+	}
+	p.mu.Unlock()
+
+	errs := make([]error, len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Go(func() {
+			errs[i] = shutdown(conn)
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Rebalance 停止全部连接，按最少的连接数平均分配全部订阅后重新连接
+// 先到先得的分配在订阅陆续增减后会不均衡，整体重连时顺便整理；旧连接按 SetRetire 的方式并发停止，
+// 全部停止后才连接新连接，旧连接读队列中已经收到的推送先回调完，不会与新连接的推送交错
+func (p *Pool[C]) Rebalance() {
+	p.rebalanceMu.Lock()
+	defer p.rebalanceMu.Unlock()
+
+	p.mu.Lock()
+	old := p.shards
+	started := p.started
+	retire := p.retire
+
+	n := p.needed()
+	total := len(p.owner)
+	perConn := total
+	if n > 1 {
+		perConn = (total + n - 1) / n
+	}
+
+	p.shards = make([]*shard[C], 0, n)
+	for i := 0; i < n; i++ {
+		p.shards = append(p.shards, &shard[C]{conn: p.newConn(i)})
+	}
+	p.owner = make(map[string]int)
+	next := 0
+	for _, s := range p.specs {
+		for _, key := range s.keys {
+			if _, ok := p.owner[key]; ok {
+				continue
+			}
+			if perConn > 0 && p.shards[next].keys >= perConn && next < n-1 {
+				next++
+			}
+			p.owner[key] = next
+			p.shards[next].keys++
+		}
+	}
+	specs := p.specs
+	p.specs = nil
+	p.mu.Unlock()
+
+	if retire == nil {
+		retire = retireConn[C]
+	}
+	var wg sync.WaitGroup
+	for _, s := range old {
+		wg.Go(func() { retire(s.conn) })
+	}
+	wg.Wait()
+
+	for _, s := range specs {
+		p.Subscribe(s.keys, s.apply)
+	}
+	if started {
+		p.Connect()
+	}
+}
+
+// retireConn 连接支持 Shutdown 时取消订阅并等待读队列排空，最多等待 RetireTimeout；否则直接 Close
+func retireConn[C Conn](conn C) {
+	d, ok := any(conn).(interface{ Shutdown(context.Context) error })
+	if !ok {
+		conn.Close()
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), RetireTimeout)
+	defer cancel()
+	_ = d.Shutdown(ctx)
+}
+
+// needed 全部订阅需要的最少连接数，调用方持有锁
+func (p *Pool[C]) needed() int {
+	total := len(p.owner)
+	if p.limit <= 0 || total <= p.limit {
+		return 1
+	}
+	return (total + p.limit - 1) / p.limit
+}
+
+// Unbalanced 连接数是否多于全部订阅需要的最少连接数，退订较多后会出现
+func (p *Pool[C]) Unbalanced() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.shards) > p.needed()
+}
+
+// Reconnected 某个连接自动重连之后调用；连接数多于需要的数量时，在新的协程中调用 rebalance 重新分配，
+// 否则不做任何事，其余连接不受影响。在连接的回调中调用也不会阻塞该连接，同一时间最多安排一次
+func (p *Pool[C]) Reconnected(rebalance func()) {
+	if !p.Unbalanced() || !p.pending.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer p.pending.Store(false)
+		if p.Unbalanced() {
+			rebalance()
+		}
+	}()
+}
+
+// Len 连接数
+func (p *Pool[C]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.shards)
+}
@@ -0,0 +1,208 @@
+package shard
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeConn struct {
+	index     int
+	keys      []string
+	connected bool
+	closed    bool
+}
+
+func (c *fakeConn) Connect() { c.connected = true }
+func (c *fakeConn) Close()   { c.closed = true }
+
+func newFakePool(limit int) (*Pool[*fakeConn], *[]*fakeConn) {
+	var created []*fakeConn
+	p := NewPool(limit, func(index int) *fakeConn {
+		c := &fakeConn{index: index}
+		created = append(created, c)
+		return c
+	})
+	return p, &created
+}
+
+func keys(prefix string, n int) []string {
+	ret := make([]string, n)
+	for i := range ret {
+		ret[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return ret
+}
+
+func subscribe(p *Pool[*fakeConn], keys []string) {
+	p.Subscribe(keys, func(conn *fakeConn, keys []string) {
+		conn.keys = append(conn.keys, keys...)
+	})
+}
+
+func TestPoolSubscribe(t *testing.T) {
+	p, _ := newFakePool(3)
+
+	subscribe(p, keys("trade:", 4))
+	if p.Len() != 2 {
+		t.Fatalf("shards = %d, want 2", p.Len())
+	}
+	conns := p.Conns()
+	if got := len(conns[0].keys); got != 3 {
+		t.Fatalf("shard 0 has %d keys, want 3", got)
+	}
+
+	// 已分配过的键留在原连接上
+	subscribe(p, []string{"trade:0", "book:0"})
+	if !slices.Equal(conns[0].keys, []string{"trade:0", "trade:1", "trade:2", "trade:0"}) {
+		t.Fatalf("shard 0 keys = %v", conns[0].keys)
+	}
+	if !slices.Equal(conns[1].keys, []string{"trade:3", "book:0"}) {
+		t.Fatalf("shard 1 keys = %v", conns[1].keys)
+	}
+
+	// 启动后新建的连接自动连接
+	p.Connect()
+	subscribe(p, keys("ticker:", 2))
+	conns = p.Conns()
+	if len(conns) != 3 || !conns[2].connected {
+		t.Fatalf("new shard not connected: %d shards", len(conns))
+	}
+	if c, ok := p.Owner("ticker:1"); !ok || c != conns[2] {
+		t.Fatalf("ticker:1 owner = %v", c)
+	}
+}
+
+func TestPoolUnsubscribe(t *testing.T) {
+	p, _ := newFakePool(2)
+	subscribe(p, keys("trade:", 4))
+
+	var removed []string
+	p.Unsubscribe([]string{"trade:1", "trade:3", "book:0"}, func(conn *fakeConn, keys []string) {
+		removed = append(removed, fmt.Sprintf("%d:%v", conn.index, keys))
+	})
+	if !slices.Equal(removed, []string{"0:[trade:1]", "1:[trade:3]"}) {
+		t.Fatalf("removed = %v", removed)
+	}
+
+	// 空出来的位置留给新的订阅
+	subscribe(p, []string{"book:0"})
+	if c, _ := p.Owner("book:0"); c.index != 0 {
+		t.Fatalf("book:0 on shard %d, want 0", c.index)
+	}
+
+	p.Rebalance()
+	if got := p.Len(); got != 2 {
+		t.Fatalf("shards = %d, want 2", got)
+	}
+	var replayed []string
+	for _, c := range p.Conns() {
+		replayed = append(replayed, c.keys...)
+	}
+	slices.Sort(replayed)
+	if !slices.Equal(replayed, []string{"book:0", "trade:0", "trade:2"}) {
+		t.Fatalf("replayed = %v", replayed)
+	}
+}
+
+func TestPoolRebalance(t *testing.T) {
+	p, created := newFakePool(4)
+	subscribe(p, keys("trade:", 5))
+	subscribe(p, keys("book:", 1))
+	p.Connect()
+
+	old := p.Conns()
+	p.Rebalance()
+
+	for _, c := range old {
+		if !c.closed {
+			t.Fatalf("old shard %d not closed", c.index)
+		}
+	}
+	conns := p.Conns()
+	if len(conns) != 2 || len(*created) != 4 {
+		t.Fatalf("shards = %d, created = %d", len(conns), len(*created))
+	}
+	// 6 个键平均分到 2 个连接
+	for _, c := range conns {
+		if len(c.keys) != 3 || !c.connected {
+			t.Fatalf("shard %d keys = %v, connected = %v", c.index, c.keys, c.connected)
+		}
+	}
+}
+
+func TestPoolRetireBeforeConnect(t *testing.T) {
+	p, _ := newFakePool(2)
+	subscribe(p, keys("trade:", 4))
+	p.Connect()
+
+	// 旧连接全部停止之后才连接新连接
+	var mu sync.Mutex
+	var events []string
+	p.SetRetire(func(conn *fakeConn) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf("retire %d", conn.index))
+		conn.closed = true
+		for _, c := range p.Conns() {
+			if c.connected {
+				events = append(events, fmt.Sprintf("shard %d connected early", c.index))
+			}
+		}
+	})
+	old := p.Conns()
+	p.Rebalance()
+
+	for _, c := range old {
+		if !c.closed {
+			t.Fatalf("old shard %d not retired", c.index)
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %v", events)
+	}
+	for _, c := range p.Conns() {
+		if !c.connected {
+			t.Fatalf("shard %d not connected", c.index)
+		}
+	}
+}
+
+func TestPoolReconnected(t *testing.T) {
+	p, _ := newFakePool(2)
+	subscribe(p, keys("trade:", 4))
+
+	rebalanced := make(chan struct{}, 1)
+	rebalance := func() {
+		p.Rebalance()
+		rebalanced <- struct{}{}
+	}
+
+	// 连接数与订阅数相符时不重新分配
+	p.Reconnected(rebalance)
+	if p.Unbalanced() {
+		t.Fatal("balanced pool reported unbalanced")
+	}
+
+	// 退订后一个连接就够了
+	p.Unsubscribe([]string{"trade:1", "trade:3"}, func(*fakeConn, []string) {})
+	if !p.Unbalanced() {
+		t.Fatal("pool with a spare shard reported balanced")
+	}
+	p.Reconnected(rebalance)
+	select {
+	case <-rebalanced:
+	case <-time.After(time.Second):
+		t.Fatal("did not rebalance after reconnect")
+	}
+	if got := p.Len(); got != 1 {
+		t.Fatalf("shards = %d, want 1", got)
+	}
+	select {
+	case <-rebalanced:
+		t.Fatal("rebalanced twice")
+	default:
+	}
+}
@@ -0,0 +1,312 @@
+// Package subscription 跟踪每个 (channel, symbol) 订阅的状态：按请求 id 关联交易所的确认，超时未确认时重发，重试用尽后标记为失败
+package subscription
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+type State = common.SubscriptionState
+
+const (
+	Unsubscribed  = common.SubscriptionUnsubscribed
+	Subscribing   = common.SubscriptionSubscribing
+	Subscribed    = common.SubscriptionSubscribed
+	Resubscribing = common.SubscriptionResubscribing
+	Failed        = common.SubscriptionFailed
+)
+
+const (
+	// DefaultAckTimeout 默认的确认超时
+	DefaultAckTimeout = 10 * time.Second
+	// DefaultMaxRetries 默认的重发次数
+	DefaultMaxRetries = 2
+)
+
+// Feed 一个 (channel, symbol) 订阅，没有品种的频道 Symbol 为空
+type Feed struct {
+	Channel string
+	Symbol  string
+}
+
+// Request 一次订阅请求，适配器把 ID 写入请求，交易所在确认中原样返回
+type Request struct {
+	ID    string
+	Feeds []Feed
+}
+
+// Sender 发送订阅请求，首次发送与超时重发都通过它
+type Sender func(req Request) error
+
+// Config 确认超时与重试
+type Config struct {
+	// AckTimeout 等待确认的时间，默认 DefaultAckTimeout
+	AckTimeout time.Duration
+	// MaxRetries 超时后重发的次数，0 使用 DefaultMaxRetries，<0 表示不重发
+	MaxRetries int
+}
+
+type pending struct {
+	id       string
+	feeds    []Feed // 尚未确认的订阅
+	attempts int
+	timer    *time.Timer
+}
+
+// Tracker 订阅状态机；连接断开期间只记录状态，不发送请求，连接建立后由适配器重放
+type Tracker struct {
+	timeout time.Duration
+	retries int
+	send    Sender
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	states   map[Feed]State
+	pending  map[string]*pending
+	nextID   uint64
+	active   bool
+	onChange func(common.SubscriptionChange)
+}
+
+// New 创建状态机
+func New(cfg Config, send Sender, logger *slog.Logger) *Tracker {
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = DefaultAckTimeout
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Tracker{
+		timeout: cfg.AckTimeout,
+		retries: max(cfg.MaxRetries, 0),
+		send:    send,
+		logger:  logger,
+		states:  make(map[Feed]State),
+		pending: make(map[string]*pending),
+	}
+}
+
+// SetOnChange 设置状态变化回调，回调在触发变化的协程中同步执行，不能阻塞
+func (t *Tracker) SetOnChange(fn func(common.SubscriptionChange)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onChange = fn
+}
+
+// State 订阅的当前状态，没有跟踪的订阅为 Unsubscribed
+func (t *Tracker) State(channel, symbol string) State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.states[Feed{channel, symbol}]
+}
+
+// Subscribe 标记为等待确认并发送订阅请求；已经订阅过的变为 Resubscribing
+// 连接断开期间只标记状态，返回 nil
+func (t *Tracker) Subscribe(feeds ...Feed) error {
+	if len(feeds) == 0 {
+		return nil
+	}
+	var changes []common.SubscriptionChange
+	t.mu.Lock()
+	for _, f := range feeds {
+		to := Subscribing
+		if s := t.states[f]; s == Subscribed || s == Resubscribing || s == Failed {
+			to = Resubscribing
+		}
+		changes = t.switchLocked(changes, f, to, nil)
+	}
+	var req Request
+	if t.active {
+		req = t.trackLocked(feeds)
+	}
+	t.mu.Unlock()
+	t.notify(changes)
+
+	if req.ID == "" {
+		return nil
+	}
+	return t.send(req)
+}
+
+// Resubscribing 只标记为 Resubscribing，用于先取消再订阅、等待取消确认的交易所
+func (t *Tracker) Resubscribing(feeds ...Feed) {
+	var changes []common.SubscriptionChange
+	t.mu.Lock()
+	for _, f := range feeds {
+		changes = t.switchLocked(changes, f, Resubscribing, nil)
+	}
+	t.mu.Unlock()
+	t.notify(changes)
+}
+
+// Ack 按请求 id 确认整个请求，err 不为空表示交易所拒绝了该请求
+func (t *Tracker) Ack(id string, err error) {
+	var changes []common.SubscriptionChange
+	t.mu.Lock()
+	if p, ok := t.pending[id]; ok {
+		p.timer.Stop()
+		delete(t.pending, id)
+		for _, f := range p.feeds {
+			changes = t.ackLocked(changes, f, err)
+		}
+	}
+	t.mu.Unlock()
+	t.notify(changes)
+}
+
+// AckFeed 确认请求中的一个订阅；id 为空时在全部等待中的请求里查找该订阅
+// 交易所主动推送的确认（没有对应请求）同样会更新已跟踪的订阅
+func (t *Tracker) AckFeed(id string, feed Feed, err error) {
+	var changes []common.SubscriptionChange
+	t.mu.Lock()
+	if _, ok := t.states[feed]; ok {
+		for pid, p := range t.pending {
+			if id != "" && pid != id {
+				continue
+			}
+			if i := slices.Index(p.feeds, feed); i >= 0 {
+				p.feeds = slices.Delete(p.feeds, i, i+1)
+				if len(p.feeds) == 0 {
+					p.timer.Stop()
+					delete(t.pending, pid)
+				}
+				break
+			}
+		}
+		changes = t.ackLocked(changes, feed, err)
+	}
+	t.mu.Unlock()
+	t.notify(changes)
+}
+
+// Remove 不再跟踪这些订阅，取消订阅后调用
+func (t *Tracker) Remove(feeds ...Feed) {
+	var changes []common.SubscriptionChange
+	t.mu.Lock()
+	for _, f := range feeds {
+		if _, ok := t.states[f]; !ok {
+			continue
+		}
+		changes = t.switchLocked(changes, f, Unsubscribed, nil)
+		delete(t.states, f)
+		for pid, p := range t.pending {
+			if i := slices.Index(p.feeds, f); i >= 0 {
+				p.feeds = slices.Delete(p.feeds, i, i+1)
+				if len(p.feeds) == 0 {
+					p.timer.Stop()
+					delete(t.pending, pid)
+				}
+			}
+		}
+	}
+	t.mu.Unlock()
+	t.notify(changes)
+}
+
+// Resume 连接建立后开始发送请求
+func (t *Tracker) Resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = true
+}
+
+// Pause 连接断开时丢弃等待中的请求，重连后由适配器重放
+func (t *Tracker) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = false
+	for id, p := range t.pending {
+		p.timer.Stop()
+		delete(t.pending, id)
+	}
+}
+
+// trackLocked 登记一个等待确认的请求，调用方持有锁
+func (t *Tracker) trackLocked(feeds []Feed) Request {
+	t.nextID++
+	p := &pending{id: strconv.FormatUint(t.nextID, 10), feeds: slices.Clone(feeds)}
+	p.timer = time.AfterFunc(t.timeout, func() { t.expire(p.id) })
+	t.pending[p.id] = p
+	return Request{ID: p.id, Feeds: slices.Clone(feeds)}
+}
+
+// expire 请求超时未确认：还能重试时用同一个 id 重发，否则标记为失败
+func (t *Tracker) expire(id string) {
+	var changes []common.SubscriptionChange
+	var retry Request
+	t.mu.Lock()
+	p, ok := t.pending[id]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	if p.attempts < t.retries {
+		p.attempts++
+		p.timer.Reset(t.timeout)
+		retry = Request{ID: id, Feeds: slices.Clone(p.feeds)}
+	} else {
+		delete(t.pending, id)
+		for _, f := range p.feeds {
+			changes = t.switchLocked(changes, f, Failed, common.ErrSubscriptionTimeout)
+		}
+	}
+	attempts, remaining := p.attempts, len(p.feeds)
+	t.mu.Unlock()
+	t.notify(changes)
+
+	if retry.ID == "" {
+		t.logger.Error("subscription ack timed out", "request_id", id, "feeds", remaining)
+		return
+	}
+	t.logger.Warn("subscription ack timed out, retrying", "request_id", id, "attempt", attempts, "feeds", len(retry.Feeds))
+	if err := t.send(retry); err != nil {
+		t.logger.Error("failed to resend subscription", "request_id", id, "error", err)
+	}
+}
+
+func (t *Tracker) ackLocked(changes []common.SubscriptionChange, f Feed, err error) []common.SubscriptionChange {
+	if err != nil {
+		return t.switchLocked(changes, f, Failed, err)
+	}
+	return t.switchLocked(changes, f, Subscribed, nil)
+}
+
+// switchLocked 切换状态并记录变化，状态没变时不记录
+func (t *Tracker) switchLocked(changes []common.SubscriptionChange, f Feed, to State, err error) []common.SubscriptionChange {
+	from := t.states[f]
+	t.states[f] = to
+	if from == to || t.onChange == nil {
+		return changes
+	}
+	return append(changes, common.SubscriptionChange{
+		Channel: f.Channel,
+		Symbol:  f.Symbol,
+		From:    from,
+		To:      to,
+		Err:     err,
+		Time:    time.Now(),
+	})
+}
+
+func (t *Tracker) notify(changes []common.SubscriptionChange) {
+	if len(changes) == 0 {
+		return
+	}
+	t.mu.Lock()
+	fn := t.onChange
+	t.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	for _, c := range changes {
+		fn(c)
+	}
+}
@@ -0,0 +1,91 @@
+package subscription
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+func newTestTracker(retries int) (*Tracker, *[]Request, *[]common.SubscriptionChange) {
+	var sent []Request
+	var changes []common.SubscriptionChange
+	t := New(Config{AckTimeout: time.Hour, MaxRetries: retries}, func(req Request) error {
+		sent = append(sent, req)
+		return nil
+	}, slog.New(slog.DiscardHandler))
+	t.SetOnChange(func(c common.SubscriptionChange) { changes = append(changes, c) })
+	return t, &sent, &changes
+}
+
+func TestTrackerAck(t *testing.T) {
+	tr, sent, changes := newTestTracker(0)
+
+	// 连接断开期间只记录状态
+	btc, eth := Feed{"trades", "BTC-USD"}, Feed{"trades", "ETH-USD"}
+	if err := tr.Subscribe(btc); err != nil || len(*sent) != 0 {
+		t.Fatalf("sent while paused: %v", *sent)
+	}
+	if s := tr.State("trades", "BTC-USD"); s != Subscribing {
+		t.Fatalf("state = %v, want subscribing", s)
+	}
+
+	tr.Resume()
+	_ = tr.Subscribe(btc, eth)
+	if len(*sent) != 1 || len((*sent)[0].Feeds) != 2 {
+		t.Fatalf("sent = %+v", *sent)
+	}
+	id := (*sent)[0].ID
+
+	tr.AckFeed(id, eth, nil)
+	tr.Ack(id, nil)
+	if tr.State("trades", "BTC-USD") != Subscribed || tr.State("trades", "ETH-USD") != Subscribed {
+		t.Fatalf("states = %v %v", tr.State("trades", "BTC-USD"), tr.State("trades", "ETH-USD"))
+	}
+
+	// 已订阅的再次订阅变为 Resubscribing，被拒绝后变为 Failed
+	_ = tr.Subscribe(btc)
+	rejected := errors.New("invalid symbol")
+	tr.Ack((*sent)[1].ID, rejected)
+	last := (*changes)[len(*changes)-1]
+	if last.From != Resubscribing || last.To != Failed || !errors.Is(last.Err, rejected) {
+		t.Fatalf("last change = %+v", last)
+	}
+
+	tr.Remove(btc)
+	if s := tr.State("trades", "BTC-USD"); s != Unsubscribed {
+		t.Fatalf("state after remove = %v", s)
+	}
+}
+
+func TestTrackerTimeout(t *testing.T) {
+	tr, sent, changes := newTestTracker(1)
+	tr.Resume()
+
+	feed := Feed{"book", "BTC-USD"}
+	_ = tr.Subscribe(feed)
+	id := (*sent)[0].ID
+
+	// 第一次超时用同一个 id 重发
+	tr.expire(id)
+	if len(*sent) != 2 || (*sent)[1].ID != id {
+		t.Fatalf("sent = %+v", *sent)
+	}
+	if s := tr.State("book", "BTC-USD"); s != Subscribing {
+		t.Fatalf("state after retry = %v", s)
+	}
+
+	tr.expire(id)
+	last := (*changes)[len(*changes)-1]
+	if last.To != Failed || !errors.Is(last.Err, common.ErrSubscriptionTimeout) {
+		t.Fatalf("last change = %+v", last)
+	}
+
+	// 重试用尽后迟到的确认仍然生效
+	tr.AckFeed("", feed, nil)
+	if s := tr.State("book", "BTC-USD"); s != Subscribed {
+		t.Fatalf("state after late ack = %v", s)
+	}
+}
@@ -0,0 +1,43 @@
+package kraken
+
+import (
+	"context"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/httpClient"
+	"github.com/simonks2016/dex_plus/kraken/internal"
+	"github.com/simonks2016/dex_plus/kraken/payload"
+)
+
+// TimeSource 通过 /0/public/Time 获取 Kraken 服务器时间，用于 clocksync.New
+// 接口只返回秒级时间，校正误差在 1 秒以内
+type TimeSource struct {
+	BaseURL string
+	client  *httpClient.Client
+}
+
+func NewTimeSource() *TimeSource {
+	return &TimeSource{
+		BaseURL: internal.RestURL,
+		client:  httpClient.NewClient(httpClient.Config{WorkerSize: 1, Exchange: common.Kraken}),
+	}
+}
+
+// Close 释放 HTTP 连接，时钟 Stop 之后调用
+func (s *TimeSource) Close() {
+	s.client.Close()
+}
+
+func (s *TimeSource) Exchange() string { return common.Kraken }
+
+func (s *TimeSource) ServerTime(ctx context.Context) (time.Time, error) {
+	var out payload.ServerTime
+	if err := s.client.GetJSON(ctx, s.BaseURL+"/0/public/Time", nil, &out); err != nil {
+		return time.Time{}, err
+	}
+	if len(out.Error) > 0 {
+		return time.Time{}, &common.ExchangeError{Exchange: common.Kraken, Code: out.Error[0]}
+	}
+	return time.Unix(out.Result.UnixTime, 0), nil
+}
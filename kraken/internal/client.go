@@ -3,57 +3,86 @@ package internal
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/subscription"
 	"github.com/simonks2016/dex_plus/kraken/params"
 	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/watchdog"
 )
 
 type KrakenClient struct {
 	ctx               context.Context
-	client            *client.WsClient
-	logger            *log.Logger
+	client            client.Client
+	logger            *slog.Logger
 	pool              *ants.Pool
 	cfg               *client.Config
 	isConnected       atomic.Bool
 	isAuthDone        atomic.Bool
 	isRequireAuth     bool
 	handler           map[string][]Caller
-	subscribeRequest  map[string][]string
+	orderedHandler    map[string][]Caller // 保序订阅的回调，在读协程里直接执行
+	handlerMu         sync.RWMutex        // 运行时订阅、取消订阅与读协程并发访问回调表
+	subMu             sync.Mutex
+	subscribeRequest  map[string][]string // 按频道记录已订阅的品种，重连后重放
 	instrumentService *InstrumentService
-	channelState      *SubscribeChannelState
+	tracker           *subscription.Tracker // 订阅状态机，按 req_id 关联确认
+	watchdog          *watchdog.Watchdog    // 静默检测，未配置时为 nil
+	resyncMu          sync.Mutex
+	resyncAt          map[string]time.Time // 每个品种上一次因丢帧重新订阅的时间，避免连续丢帧时反复重订阅
+	onReconnected     func()               // 自动重连成功后调用，连接池据此判断是否需要重新分配
 }
 
 func NewKrakenClient(ctx context.Context, cfg *client.Config) *KrakenClient {
+	cfg.SetDispatchKey(MessageKey)
 
 	pool, _ := ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
 
 	krakenClient := &KrakenClient{
 		ctx:               ctx,
-		client:            client.NewWsClient(ctx, cfg),
-		logger:            cfg.Logger,
+		client:            client.NewClient(ctx, cfg),
+		logger:            client.OrDefaultLogger(cfg.Logger).With("exchange", "kraken"),
 		pool:              pool,
 		cfg:               cfg,
 		isRequireAuth:     cfg.IsNeedAuth,
 		handler:           make(map[string][]Caller),
+		orderedHandler:    make(map[string][]Caller),
 		subscribeRequest:  make(map[string][]string),
 		instrumentService: NewInstrumentService(),
-		channelState:      NewSubscribeChannelState(),
+		resyncAt:          make(map[string]time.Time),
 	}
+	krakenClient.tracker = subscription.New(cfg.Subscription(), krakenClient.sendRequest, krakenClient.logger)
 	krakenClient.client.SetObserver(krakenClient)
+	if cfg.Watchdog != nil {
+		krakenClient.watchdog = watchdog.New(*cfg.Watchdog, krakenClient, krakenClient.logger)
+	}
 	// 添加处理instrument
 	krakenClient.handler["instrument"] = append(krakenClient.handler["instrument"], krakenClient.onInstrument)
 
 	return krakenClient
 }
 
+func (k *KrakenClient) Logger() *slog.Logger {
+	return k.logger
+}
+
+// SetInstrumentRegistry 设置 instrument 频道写入的注册表，nil 表示不写入
+func (k *KrakenClient) SetInstrumentRegistry(registry *common.InstrumentRegistry) {
+	k.instrumentService.SetRegistry(registry)
+}
+
 func (k *KrakenClient) Send(data []byte) error {
 
 	if !k.isConnected.Load() {
-		return errors.New("the client is not connected")
+		return common.ErrNotConnected
 	}
 
 	ctx, cancel := context.WithTimeout(k.ctx, k.cfg.SendTimeout)
@@ -64,62 +93,220 @@ func (k *KrakenClient) Send(data []byte) error {
 }
 
 func (k *KrakenClient) Connect() {
+	k.watchdog.Start(k.ctx)
 	k.client.Start()
 }
 
 func (k *KrakenClient) Close() {
+	k.watchdog.Stop()
 	k.client.Close()
 }
 
+// Shutdown 取消订阅并停止接收，等待已收到的消息回调完成后释放协程池
+func (k *KrakenClient) Shutdown(ctx context.Context) error {
+	k.watchdog.Stop()
+	err := k.client.Shutdown(ctx)
+	return errors.Join(err, client.ReleasePool(ctx, k.pool))
+}
+
+func (k *KrakenClient) Reconnect(reason string) {
+	k.client.Reconnect(reason)
+}
+
+// Subscribe 订阅频道，连接已建立时立即发送新增的品种，并记录下来在重连后重放
 func (k *KrakenClient) Subscribe(channels ...SubscribeChannel) {
 	for _, channel := range channels {
-		k.subscribeRequest[channel.Channel] = append(k.subscribeRequest[channel.Channel], channel.Symbols...)
-		k.handler[channel.Channel] = append(k.handler[channel.Channel], channel.Caller...)
-
+		// 同一频道可能被多次订阅（例如盘口快照与盘口增量），品种需要去重
+		var fresh []string
+		k.subMu.Lock()
 		for _, symbol := range channel.Symbols {
-			k.channelState.Switch(channel.Channel, symbol, Subscribing)
+			if !slices.Contains(k.subscribeRequest[channel.Channel], symbol) {
+				k.subscribeRequest[channel.Channel] = append(k.subscribeRequest[channel.Channel], symbol)
+				fresh = append(fresh, symbol)
+			}
+		}
+		k.subMu.Unlock()
+		k.AddHandler(channel.Channel, channel.Ordered, channel.Caller...)
+
+		for _, symbol := range fresh {
+			k.watchdog.Watch(channel.Channel, symbol)
 		}
+		// 连接建立前只记录状态，连接后在 OnConnected 中重放
+		if err := k.tracker.Subscribe(feeds(channel.Channel, fresh...)...); err != nil {
+			k.logger.Error("failed to subscribe channel", "conn_id", k.client.ConnID(), "channel", channel.Channel, "error", err)
+		}
+	}
+}
+
+// AddHandler 只注册回调，不发送订阅；ordered 的回调在读协程里按到达顺序直接执行
+func (k *KrakenClient) AddHandler(channel string, ordered bool, caller ...Caller) {
+	if len(caller) == 0 {
+		return
+	}
+	k.handlerMu.Lock()
+	defer k.handlerMu.Unlock()
+	if ordered {
+		k.orderedHandler[channel] = append(slices.Clip(k.orderedHandler[channel]), caller...)
+	} else {
+		k.handler[channel] = append(slices.Clip(k.handler[channel]), caller...)
+	}
+}
+
+// HasChannel 频道中是否还有订阅的品种
+func (k *KrakenClient) HasChannel(channel string) bool {
+	k.subMu.Lock()
+	defer k.subMu.Unlock()
+	return len(k.subscribeRequest[channel]) > 0
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有品种时一并移除回调
+func (k *KrakenClient) Unsubscribe(channel string, symbols ...string) error {
+	var removed []string
+	k.subMu.Lock()
+	kept := make([]string, 0, len(k.subscribeRequest[channel]))
+	for _, symbol := range k.subscribeRequest[channel] {
+		if len(symbols) == 0 || slices.Contains(symbols, symbol) {
+			removed = append(removed, symbol)
+			continue
+		}
+		kept = append(kept, symbol)
+	}
+	empty := len(kept) == 0
+	if empty {
+		delete(k.subscribeRequest, channel)
+	} else {
+		k.subscribeRequest[channel] = kept
+	}
+	k.subMu.Unlock()
+
+	// 取消订阅的 ack 找不到状态时直接忽略
+	k.tracker.Remove(feeds(channel, removed...)...)
+	for _, symbol := range removed {
+		k.watchdog.Forget(channel, symbol)
+	}
+	if empty {
+		k.handlerMu.Lock()
+		delete(k.handler, channel)
+		delete(k.orderedHandler, channel)
+		k.handlerMu.Unlock()
+	}
+
+	if len(removed) == 0 || !k.isAuthDone.Load() {
+		return nil
+	}
+	return k.Send(params.NewKrakenParams(params.Unsubscribe, channel, removed...).Json())
+}
+
+// subscriptions 已订阅品种的快照
+func (k *KrakenClient) subscriptions() map[string][]string {
+	k.subMu.Lock()
+	defer k.subMu.Unlock()
+	ret := make(map[string][]string, len(k.subscribeRequest))
+	for channel, symbols := range k.subscribeRequest {
+		ret[channel] = slices.Clone(symbols)
 	}
+	return ret
 }
 
 type SubscribeChannel struct {
 	Channel string   `json:"channel"`
 	Symbols []string `json:"symbols"`
 	Caller  []Caller `json:"caller"`
+	// Ordered 同一个 (channel, symbol) 的推送按到达顺序串行回调
+	Ordered bool `json:"ordered"`
 }
 
 func (k *KrakenClient) GetTradingPair(symbol string) (payload.Pair, bool) {
 	return k.instrumentService.GetTradingPair(symbol)
 }
 
+// Resubscribe 先取消订阅，收到取消的 ack 后再重新订阅
 func (k *KrakenClient) Resubscribe(channel string, symbols ...string) error {
+	return k.resubscribe(false, channel, symbols...)
+}
+
+// ResubscribeFeed 重新订阅一个 (channel, symbol)
+// 订阅或重订阅一直没有 ack 时同样强制重新订阅
+func (k *KrakenClient) ResubscribeFeed(channel, symbol string) error {
+	return k.resubscribe(true, channel, symbol)
+}
+
+// resubscribe force 为 false 时只允许已订阅或失败的进入重订阅，避免重复 resubscribe
+func (k *KrakenClient) resubscribe(force bool, channel string, symbols ...string) error {
 	var newSymbols []string
 
 	for _, symbol := range symbols {
-		s, ex := k.channelState.Get(channel, symbol)
-		if !ex {
+		switch k.tracker.State(channel, symbol) {
+		case subscription.Unsubscribed:
 			continue
+		case subscription.Subscribing, subscription.Resubscribing:
+			if !force {
+				continue
+			}
 		}
-		// 只允许已订阅的进入重订阅，避免重复 resubscribe
-		if s == Subscribed || s == SubscribeFailed {
-			k.channelState.Switch(channel, symbol, Resubscribing)
-			newSymbols = append(newSymbols, symbol)
-		}
+		newSymbols = append(newSymbols, symbol)
 	}
 
 	// 没有需要重订阅的，直接返回，避免空 unsubscribe/subscribe
 	if len(newSymbols) == 0 {
 		return nil
 	}
+	k.tracker.Resubscribing(feeds(channel, newSymbols...)...)
 
 	unsubscribeParam := params.NewKrakenParams(params.Unsubscribe, channel, newSymbols...)
 	if err := k.Send(unsubscribeParam.Json()); err != nil {
-		// 发送失败，建议恢复状态
-		for _, symbol := range newSymbols {
-			k.channelState.Switch(channel, symbol, Subscribed)
+		// 发送失败，标记为失败以便之后重试
+		for _, f := range feeds(channel, newSymbols...) {
+			k.tracker.AckFeed("", f, err)
 		}
 		return err
 	}
 
 	return nil
 }
+
+// sendRequest 发送订阅请求，按频道拆分，req_id 使用状态机分配的请求 id
+func (k *KrakenClient) sendRequest(req subscription.Request) error {
+	id, _ := strconv.ParseInt(req.ID, 10, 64)
+	var channels []string
+	symbols := make(map[string][]string)
+	for _, f := range req.Feeds {
+		if _, ok := symbols[f.Channel]; !ok {
+			channels = append(channels, f.Channel)
+			symbols[f.Channel] = nil
+		}
+		if f.Symbol != "" {
+			symbols[f.Channel] = append(symbols[f.Channel], f.Symbol)
+		}
+	}
+	for _, channel := range channels {
+		p := params.NewKrakenParams(params.Subscribe, channel, symbols[channel]...).WithReqId(id)
+		if err := k.Send(p.Json()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SubscriptionState 订阅的当前状态
+func (k *KrakenClient) SubscriptionState(channel, symbol string) subscription.State {
+	return k.tracker.State(channel, symbol)
+}
+
+// SetOnSubscriptionChange 设置订阅状态变化回调
+func (k *KrakenClient) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	k.tracker.SetOnChange(fn)
+}
+
+// SetOnReconnected 设置自动重连成功后的回调，首次连接不调用；需在 Connect 之前设置
+func (k *KrakenClient) SetOnReconnected(fn func()) {
+	k.onReconnected = fn
+}
+
+func feeds(channel string, symbols ...string) []subscription.Feed {
+	ret := make([]subscription.Feed, 0, len(symbols))
+	for _, symbol := range symbols {
+		ret = append(ret, subscription.Feed{Channel: channel, Symbol: symbol})
+	}
+	return ret
+}
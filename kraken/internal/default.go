@@ -1,5 +1,6 @@
 package internal
 
 const (
-	WsURL = "wss://ws.kraken.com/v2"
+	WsURL   = "wss://ws.kraken.com/v2"
+	RestURL = "https://api.kraken.com"
 )
@@ -1,38 +1,47 @@
 package internal
 
 import (
+	"cmp"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/subscription"
 	"github.com/simonks2016/dex_plus/kraken/params"
 	"github.com/simonks2016/dex_plus/kraken/payload"
 )
 
 func (k *KrakenClient) OnConnecting(reason string) {
-	//TODO implement me
-	if k.logger != nil {
-		k.logger.Println(reason)
-	}
+	k.logger.Info("connecting", "conn_id", k.client.ConnID(), "reason", reason)
+}
+
+// OnEndpoint 连接上的地址变化
+func (k *KrakenClient) OnEndpoint(url string) {
+	k.logger.Info("endpoint active", "url", url)
 }
 
 func (k *KrakenClient) OnConnected() {
 	//TODO implement me
 	//存储已连接状态
 	k.isConnected.Store(true)
+	k.logger.Info("connected", "conn_id", k.client.ConnID())
+	k.watchdog.Resume()
+	if k.onReconnected != nil && k.client.ConnID() > 1 {
+		k.onReconnected()
+	}
 	if !k.isRequireAuth {
 		// 存储验证状态
 		k.isAuthDone.Store(true)
+		k.tracker.Resume()
 		//
-		for channel, s := range k.subscribeRequest {
+		for channel, s := range k.subscriptions() {
 			if len(s) > 0 {
-				// 构建订阅参数
-				p := params.NewKrakenParams(params.Subscribe, channel, s...)
-				// 发送订阅参数
-				if err := k.Send(p.Json()); err != nil {
-					if k.logger != nil {
-						k.logger.Printf("send subscribe error,%s", err.Error())
-					}
+				// 发送订阅参数，等待 ack
+				if err := k.tracker.Subscribe(feeds(channel, s...)...); err != nil {
+					k.logger.Error("failed to subscribe channel", "conn_id", k.client.ConnID(), "channel", channel, "error", err)
 					return
 				}
 			}
@@ -41,9 +50,7 @@ func (k *KrakenClient) OnConnected() {
 		// 订阅instrument频道
 		p := params.NewKrakenParams(params.Subscribe, "instrument")
 		if err := k.Send(p.Json()); err != nil {
-			if k.logger != nil {
-				k.logger.Printf("failed to subscribe instrument channel,%s", err.Error())
-			}
+			k.logger.Error("failed to subscribe channel", "conn_id", k.client.ConnID(), "channel", "instrument", "error", err)
 			return
 		}
 
@@ -51,38 +58,40 @@ func (k *KrakenClient) OnConnected() {
 }
 
 func (k *KrakenClient) OnDisconnecting() {
-	//TODO implement me
-	if k.logger != nil {
-		k.logger.Println("on disconnecting")
-	}
+	k.logger.Info("disconnecting", "conn_id", k.client.ConnID())
 	// 全部取消订阅
-	for channel, strs := range k.subscribeRequest {
+	for channel, strs := range k.subscriptions() {
 		// 构建订阅参数
 		p := params.NewKrakenParams(params.Unsubscribe, channel, strs...)
 		// 发送订阅参数
 		if err := k.Send(p.Json()); err != nil {
-			if k.logger != nil {
-				k.logger.Printf("send subscribe error,%s", err.Error())
-			}
+			k.logger.Error("failed to unsubscribe channel", "conn_id", k.client.ConnID(), "channel", channel, "error", err)
 			return
 		}
 	}
 }
 
 func (k *KrakenClient) OnDisconnected() {
-	//TODO implement me
-	if k.logger != nil {
-		k.logger.Println("on disconnected")
-	}
+	k.isConnected.Store(false)
+	k.isAuthDone.Store(false)
+	k.tracker.Pause()
+	k.watchdog.Pause()
+	k.logger.Info("disconnected", "conn_id", k.client.ConnID())
 }
 
 func (k *KrakenClient) OnMessage(data []byte) error {
+	return k.OnTimedMessage(data, time.Time{})
+}
+
+// OnTimedMessage received 为收到该帧的时间，配置了延迟统计时按推送中的时间戳记录线路延迟
+func (k *KrakenClient) OnTimedMessage(data []byte, received time.Time) error {
 	//TODO implement me
 	var e payload.KrakenEnvelope
 	// 转码JSON对象
 	if err := json.Unmarshal(data, &e); err != nil {
 		return fmt.Errorf("failed to unmarshal message,%s", err.Error())
 	}
+	k.cfg.Latency.ObserveWire(e.Timestamp(), received)
 
 	// 假如是ACK 消息
 	if e.IsAck() {
@@ -100,38 +109,43 @@ func (k *KrakenClient) OnMessage(data []byte) error {
 
 	if e.IsSubscription() {
 		channel := e.GetChannel()
+		k.touch(channel, e.Data)
 
 		if strings.EqualFold(channel, "status") {
 			// 提交任务处理status
 			if err := k.pool.Submit(func() {
 				if err := k.onStatus(&e); err != nil {
-					if k.logger != nil {
-						k.logger.Printf("[error]failed to handler message,channel=%s,error=%s", channel, err.Error())
-					}
+					k.logger.Error("failed to handle message", "channel", channel, "error", err)
 					return
 				}
 			}); err != nil {
-				if k.logger != nil {
-					k.logger.Printf("[error]failed to submit task to ants pool,%s", err.Error())
-				}
+				k.logger.Error("failed to submit task to pool", "channel", channel, "error", err)
 				return err
 			}
 		}
 
-		if callers, ex := k.handler[channel]; ex {
+		k.handlerMu.RLock()
+		ordered := k.orderedHandler[channel]
+		callers := k.handler[channel]
+		k.handlerMu.RUnlock()
+
+		// 保序回调，读协程已按 (channel, symbol) 分区，直接执行
+		for _, caller := range ordered {
+			if err := caller(&e); err != nil {
+				k.logger.Error("failed to handle message", "channel", channel, "error", err)
+			}
+		}
+
+		if len(callers) > 0 {
 			// 遍历处理字典
 			for _, caller := range callers {
 				if err := k.pool.Submit(func() {
 					if err := caller(&e); err != nil {
-						if k.logger != nil {
-							k.logger.Printf("[error]failed to handler message,%s,%s", channel, err.Error())
-						}
+						k.logger.Error("failed to handle message", "channel", channel, "error", err)
 						return
 					}
 				}); err != nil {
-					if k.logger != nil {
-						k.logger.Printf("[error]failed to submit task to ants pool,%s", err.Error())
-					}
+					k.logger.Error("failed to submit task to pool", "channel", channel, "error", err)
 					return err
 				}
 			}
@@ -140,11 +154,41 @@ func (k *KrakenClient) OnMessage(data []byte) error {
 	return nil
 }
 
-func (k *KrakenClient) OnError(err error) {
-	//TODO implement me
-	if k.logger != nil {
-		k.logger.Printf("[error]get error,%s", err.Error())
+// dropResyncInterval 同一个品种两次因丢帧重新订阅之间的最短间隔
+const dropResyncInterval = time.Second
+
+// OnDropped 读队列丢帧后盘口增量已经不连续，重新订阅 book 频道拿到新的快照
+// 在读协程上调用：按品种限流，重新订阅需要等待发送，放到单独的协程
+func (k *KrakenClient) OnDropped(count int) {
+	symbols := k.resyncDue(k.subscriptions()["book"], time.Now())
+	if len(symbols) == 0 {
+		return
+	}
+	k.logger.Warn("frames dropped, resyncing order books", "conn_id", k.client.ConnID(), "channel", "book", "count", count)
+	go func() {
+		if err := k.Resubscribe("book", symbols...); err != nil {
+			k.logger.Error("failed to resubscribe channel", "conn_id", k.client.ConnID(), "channel", "book", "error", err)
+		}
+	}()
+}
+
+// resyncDue 距离上一次因丢帧重新订阅超过 dropResyncInterval 的品种，并记录本次的时间
+func (k *KrakenClient) resyncDue(symbols []string, now time.Time) []string {
+	k.resyncMu.Lock()
+	defer k.resyncMu.Unlock()
+	due := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if now.Sub(k.resyncAt[symbol]) < dropResyncInterval {
+			continue
+		}
+		k.resyncAt[symbol] = now
+		due = append(due, symbol)
 	}
+	return due
+}
+
+func (k *KrakenClient) OnError(err error) {
+	k.logger.Error("client error", "conn_id", k.client.ConnID(), "error", err)
 }
 
 func (k *KrakenClient) onStatus(data *payload.KrakenEnvelope) error {
@@ -158,14 +202,10 @@ func (k *KrakenClient) onStatus(data *payload.KrakenEnvelope) error {
 
 		for _, status := range s2 {
 			if strings.EqualFold(status.System, "online") {
-				if k.logger != nil {
-					k.logger.Printf("[status]Successfully connected to Kraken. Status: Online. Version: %s. API Version: %s.",
-						status.Version, status.ApiVersion)
-				}
+				k.logger.Info("system online", "conn_id", k.client.ConnID(),
+					"version", status.Version, "api_version", status.ApiVersion)
 			} else {
-				if k.logger != nil {
-					k.logger.Printf("[status]recive status from kraken,%s", string(data.Data))
-				}
+				k.logger.Warn("system status changed", "conn_id", k.client.ConnID(), "status", status.System)
 			}
 		}
 	}
@@ -189,30 +229,20 @@ func (k *KrakenClient) onSubscribeAck(e *payload.KrakenEnvelope) error {
 
 	channel, _ := e.Result["channel"].(string)
 	symbol, _ := e.Result["symbol"].(string)
+	id := reqId(e)
 
 	if e.Success != nil && *e.Success {
-		if symbol != "" {
-			k.channelState.Switch(channel, symbol, Subscribed)
-		}
-		if k.logger != nil {
-			k.logger.Printf("[success] Successfully subscribed to channel=%s %s", channel, func() string {
-				if symbol == "" {
-					return ""
-				}
-				return fmt.Sprintf("symbol=%s", symbol)
-			}())
-		}
+		k.tracker.AckFeed(id, subscription.Feed{Channel: channel, Symbol: symbol}, nil)
+		k.logger.Info("subscribed", "conn_id", k.client.ConnID(), "channel", channel, "symbol", symbol)
 	} else {
-		if symbol != "" {
-			k.channelState.Switch(channel, symbol, SubscribeFailed)
-		}
-		if k.logger != nil {
-			errMsg := ""
-			if e.Error != nil {
-				errMsg = *e.Error
-			}
-			k.logger.Printf("[error] failed to subscribe channel=%s symbol=%s because %s", channel, symbol, errMsg)
+		err := &common.ExchangeError{Exchange: common.Kraken, Msg: cmp.Or(ackError(e), "rejected by kraken")}
+		if channel == "" {
+			// 请求本身被拒绝时没有 result，按 req_id 确认整个请求
+			k.tracker.Ack(id, err)
+		} else {
+			k.tracker.AckFeed(id, subscription.Feed{Channel: channel, Symbol: symbol}, err)
 		}
+		k.logger.Error("failed to subscribe channel", "conn_id", k.client.ConnID(), "channel", channel, "symbol", symbol, "reason", ackError(e))
 	}
 	return nil
 }
@@ -222,40 +252,71 @@ func (k *KrakenClient) onUnsubscribeAck(e *payload.KrakenEnvelope) error {
 	channel, _ := e.Result["channel"].(string)
 	symbol, _ := e.Result["symbol"].(string)
 
+	feed := subscription.Feed{Channel: channel, Symbol: symbol}
+
 	if e.Success != nil && *e.Success {
-		if symbol != "" {
-			// 获取状态
-			s, ex := k.channelState.Get(channel, symbol)
-			if !ex {
-				return nil
-			}
-			// 假如是重新订阅中,则重新订阅
-			if s == Resubscribing || s == SubscribeFailed {
-				subscribeParam := params.NewKrakenParams(params.Subscribe, channel, symbol)
-				if err := k.Send(subscribeParam.Json()); err != nil {
-					k.channelState.Switch(channel, symbol, SubscribeFailed)
-					return err
-				}
-			} else {
-				// 设置取消订阅
-				k.channelState.Switch(channel, symbol, Unsubscribed)
+		// 假如是重新订阅中,则重新订阅；取消订阅时状态已经移除
+		if s := k.tracker.State(channel, symbol); s == subscription.Resubscribing || s == subscription.Failed {
+			if err := k.tracker.Subscribe(feed); err != nil {
+				k.tracker.AckFeed("", feed, err)
+				return err
 			}
 		}
-		if k.logger != nil {
-			k.logger.Printf("[success] Successfully unsubscribed to channel=%s symbol=%s", channel, symbol)
-		}
+		k.logger.Info("unsubscribed", "conn_id", k.client.ConnID(), "channel", channel, "symbol", symbol)
 
 	} else {
-		if symbol != "" {
-			k.channelState.Switch(channel, symbol, SubscribeFailed)
-		}
-		if k.logger != nil {
-			errMsg := ""
-			if e.Error != nil {
-				errMsg = *e.Error
-			}
-			k.logger.Printf("[error] failed to unsubscribe channel=%s symbol=%s because %s", channel, symbol, errMsg)
+		if k.tracker.State(channel, symbol) == subscription.Resubscribing {
+			k.tracker.AckFeed("", feed, &common.ExchangeError{Exchange: common.Kraken, Msg: cmp.Or(ackError(e), "rejected by kraken")})
 		}
+		k.logger.Error("failed to unsubscribe channel", "conn_id", k.client.ConnID(), "channel", channel, "symbol", symbol, "reason", ackError(e))
 	}
 	return nil
 }
+
+// reqId ack 中的 req_id，没有时为空
+func reqId(e *payload.KrakenEnvelope) string {
+	if e.ReqId == 0 {
+		return ""
+	}
+	return strconv.FormatInt(e.ReqId, 10)
+}
+
+func ackError(e *payload.KrakenEnvelope) string {
+	if e.Error != nil {
+		return *e.Error
+	}
+	return ""
+}
+
+// touch 按推送中的每一个品种记录静默检测
+func (k *KrakenClient) touch(channel string, data json.RawMessage) {
+	if k.watchdog == nil {
+		return
+	}
+	var items []struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return
+	}
+	for _, item := range items {
+		if item.Symbol != "" {
+			k.watchdog.Touch(channel, item.Symbol)
+		}
+	}
+}
+
+// MessageKey 消息的 (channel, symbol) 键，用于按键合并与按键顺序分发
+func MessageKey(data []byte) string {
+	var v struct {
+		Channel string `json:"channel"`
+		Data    []struct {
+			Symbol string `json:"symbol"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(data, &v)
+	if len(v.Data) == 0 {
+		return v.Channel
+	}
+	return v.Channel + ":" + v.Data[0].Symbol
+}
@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/internal/client"
+)
+
+func TestResyncDue(t *testing.T) {
+	k := NewKrakenClient(context.Background(), client.NewConfig())
+	now := time.Now()
+
+	if got := k.resyncDue([]string{"BTC/USD", "ETH/USD"}, now); !slices.Equal(got, []string{"BTC/USD", "ETH/USD"}) {
+		t.Fatalf("first drop = %v, want both symbols", got)
+	}
+	// 连续丢帧时同一个品种在间隔内只重新订阅一次，新增的品种不受影响
+	if got := k.resyncDue([]string{"BTC/USD", "SOL/USD"}, now.Add(100*time.Millisecond)); !slices.Equal(got, []string{"SOL/USD"}) {
+		t.Fatalf("drop within interval = %v, want [SOL/USD]", got)
+	}
+	if got := k.resyncDue([]string{"BTC/USD"}, now.Add(dropResyncInterval)); !slices.Equal(got, []string{"BTC/USD"}) {
+		t.Fatalf("drop after interval = %v, want [BTC/USD]", got)
+	}
+}
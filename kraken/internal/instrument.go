@@ -3,6 +3,7 @@ package internal
 import (
 	"sync"
 
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/kraken/payload"
 )
 
@@ -11,21 +12,33 @@ type InstrumentService struct {
 	assets      map[string]payload.Asset
 	pairMu      sync.Mutex
 	assetMu     sync.Mutex
+	// 收到的交易对同步写入注册表，nil 表示不写入
+	registry *common.InstrumentRegistry
 }
 
 func NewInstrumentService() *InstrumentService {
 	return &InstrumentService{
 		tradingPair: make(map[string]payload.Pair),
 		assets:      make(map[string]payload.Asset),
+		registry:    common.DefaultInstrumentRegistry,
 	}
 }
 
+func (s *InstrumentService) SetRegistry(registry *common.InstrumentRegistry) {
+	s.pairMu.Lock()
+	defer s.pairMu.Unlock()
+	s.registry = registry
+}
+
 func (s *InstrumentService) AddTradingPairs(pairs ...payload.Pair) {
 	s.pairMu.Lock()
 	defer s.pairMu.Unlock()
 
 	for _, pair := range pairs {
 		s.tradingPair[pair.Symbol] = pair
+		if s.registry != nil {
+			s.registry.Add(pair.ToInstrument())
+		}
 	}
 }
 
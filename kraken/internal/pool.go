@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/shard"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/kraken/payload"
+)
+
+// MaxSubscriptionsPerConn Kraken 会限速品种列表过长的订阅，默认每个连接订阅 200 个 (channel, symbol)
+const MaxSubscriptionsPerConn = 200
+
+// KrakenPool 按 (channel, symbol) 数量把订阅分散到多个 KrakenClient，所有连接的推送回调到同一组处理函数
+type KrakenPool struct {
+	logger   *slog.Logger
+	registry *common.InstrumentRegistry
+	onChange func(common.SubscriptionChange) // 订阅状态变化回调，新建的连接同样使用
+	pool     *shard.Pool[*KrakenClient]
+	channels *shard.Channels[*KrakenClient, Caller] // 按频道记录的回调
+}
+
+func NewKrakenPool(ctx context.Context, cfg *client.Config) *KrakenPool {
+	p := &KrakenPool{
+		logger: client.OrDefaultLogger(cfg.Logger).With("exchange", "kraken"),
+	}
+	p.pool = shard.NewPool(cfg.SubscriptionLimit(MaxSubscriptionsPerConn), func(index int) *KrakenClient {
+		conn := NewKrakenClient(ctx, cfg.ForShard(index))
+		conn.SetInstrumentRegistry(p.registry)
+		conn.SetOnSubscriptionChange(p.onChange)
+		conn.SetOnReconnected(func() { p.channels.Reconnected() })
+		return conn
+	})
+	p.channels = shard.NewChannels[*KrakenClient, Caller](p.pool, shard.FeedKeys)
+
+	return p
+}
+
+func (p *KrakenPool) Logger() *slog.Logger {
+	return p.logger
+}
+
+// SetInstrumentRegistry 设置 instrument 频道写入的注册表，nil 表示不写入
+func (p *KrakenPool) SetInstrumentRegistry(registry *common.InstrumentRegistry) {
+	p.registry = registry
+	for _, conn := range p.pool.Conns() {
+		conn.SetInstrumentRegistry(registry)
+	}
+}
+
+// SetOnSubscriptionChange 设置所有连接的订阅状态变化回调
+func (p *KrakenPool) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.onChange = fn
+	for _, conn := range p.pool.Conns() {
+		conn.SetOnSubscriptionChange(fn)
+	}
+}
+
+// SubscriptionState 在品种所在的连接上查询订阅状态
+func (p *KrakenPool) SubscriptionState(channel, symbol string) subscription.State {
+	conn, ok := p.pool.Owner(shard.FeedKeys.Key(channel, symbol))
+	if !ok {
+		return subscription.Unsubscribed
+	}
+	return conn.SubscriptionState(channel, symbol)
+}
+
+func (p *KrakenPool) Connect() {
+	p.pool.Connect()
+}
+
+func (p *KrakenPool) Close() {
+	p.pool.Close()
+}
+
+// Shutdown 并发关闭全部连接，等待已收到的消息回调完成
+func (p *KrakenPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(func(conn *KrakenClient) error {
+		return conn.Shutdown(ctx)
+	})
+}
+
+// Reconnect 重新连接，并按订阅数量重新平均分配连接
+func (p *KrakenPool) Reconnect() {
+	p.channels.Rebalance()
+}
+
+// Subscribe 订阅频道，品种按连接拆分，当前连接已满时新建连接
+// Caller 为空时沿用该频道已经注册的回调，用于运行时追加品种
+func (p *KrakenPool) Subscribe(channels ...SubscribeChannel) {
+	for _, channel := range channels {
+		p.channels.Subscribe(channel.Channel, channel.Symbols, channel.Ordered, channel.Caller, func(conn *KrakenClient, symbols []string, batches []shard.Batch[Caller]) {
+			for _, b := range batches {
+				conn.AddHandler(channel.Channel, b.Ordered, b.Callers...)
+			}
+			sub := channel
+			sub.Caller = nil
+			if len(sub.Symbols) > 0 {
+				sub.Symbols = symbols
+			}
+			conn.Subscribe(sub)
+		})
+	}
+}
+
+// HasChannel 频道是否注册过回调
+func (p *KrakenPool) HasChannel(channel string) bool {
+	return p.channels.Has(channel)
+}
+
+// Unsubscribe 在品种所在的连接上取消订阅，不传品种时取消整个频道；频道中已经没有订阅时一并移除回调
+func (p *KrakenPool) Unsubscribe(channel string, symbols ...string) error {
+	return p.channels.Unsubscribe(channel, symbols, func(conn *KrakenClient, symbols []string) (bool, error) {
+		err := conn.Unsubscribe(channel, symbols...)
+		return conn.HasChannel(channel), err
+	})
+}
+
+// Resubscribe 在品种所在的连接上重新订阅
+func (p *KrakenPool) Resubscribe(channel string, symbols ...string) error {
+	return p.channels.Each(channel, symbols, func(conn *KrakenClient, symbol string) error {
+		return conn.Resubscribe(channel, symbol)
+	})
+}
+
+// GetTradingPair 每个连接都会订阅 instrument 频道，取第一个有该交易对的连接
+func (p *KrakenPool) GetTradingPair(symbol string) (payload.Pair, bool) {
+	for _, conn := range p.pool.Conns() {
+		if pair, ok := conn.GetTradingPair(symbol); ok {
+			return pair, true
+		}
+	}
+	return payload.Pair{}, false
+}
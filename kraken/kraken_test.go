@@ -1,27 +1,20 @@
 package kraken
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 	"testing"
 	"time"
 
 	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
 	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/options"
 )
 
 func TestClient(t *testing.T) {
+	ctx := dextest.LiveContext(t)
 
-	ctx, stop := signal.NotifyContext(
-		context.Background(),
-		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
-	// 释放资源
-	defer stop()
-
-	p1 := NewPublic(ctx, common.KrakenSymbol(common.BTC))
+	p1 := NewPublic(ctx, options.WithSymbols(common.KrakenSymbol(common.BTC)))
 
 	p1.SubscribeTrade(func(trades []payload.Trade) error {
 		fmt.Println(trades)
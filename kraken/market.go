@@ -0,0 +1,134 @@
+package kraken
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simonks2016/dex_plus/candles"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/kraken/internal"
+	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+var _ market.MarketDataSource = (*Public)(nil)
+
+// OnTrade 订阅统一格式的成交数据
+func (p *Public) OnTrade(handler market.TradeHandler) error {
+	p.SubscribeTrade(func(trades []payload.Trade) error {
+		for _, t := range trades {
+			ts, _ := time.Parse(time.RFC3339Nano, t.Timestamp)
+			if err := handler(market.Trade{
+				Exchange:  common.Kraken,
+				Symbol:    t.Symbol,
+				TradeId:   strconv.Itoa(t.TradeId),
+				Price:     t.PriceDecimal,
+				Size:      t.QtyDecimal,
+				Side:      market.Side(t.Side),
+				Timestamp: ts,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnBookSnapshot 按 interval 定时回调本地维护的盘口快照
+func (p *Public) OnBookSnapshot(interval time.Duration, handler market.BookSnapshotHandler) error {
+	p.SubscribeOrderBook(interval, func(books []payload.OrderBook) error {
+		for _, book := range books {
+			if err := handler(market.BookSnapshot{
+				Exchange:  common.Kraken,
+				Symbol:    book.Symbol,
+				Bids:      toLevels(book.Bids),
+				Asks:      toLevels(book.Asks),
+				Timestamp: book.Timestamp,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnBookDelta 订阅原始的盘口增量，首包为全量快照
+func (p *Public) OnBookDelta(handler market.BookDeltaHandler) error {
+	p.client.Subscribe(internal.SubscribeChannel{
+		Channel: "book",
+		Symbols: p.symbols,
+		Caller: []internal.Caller{
+			func(envelope *payload.KrakenEnvelope) error {
+				data, err := payload.ParseData[payload.OrderBook](envelope)
+				if err != nil {
+					return err
+				}
+				isSnapshot := envelope.Type != nil && strings.EqualFold(*envelope.Type, "snapshot")
+
+				for _, book := range data {
+					if err := handler(market.BookDelta{
+						Exchange:  common.Kraken,
+						Symbol:    book.Symbol,
+						Bids:      toLevels(book.Bids),
+						Asks:      toLevels(book.Asks),
+						Snapshot:  isSnapshot,
+						Timestamp: book.Timestamp,
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	})
+	return nil
+}
+
+// OnTicker 订阅统一格式的Tick行情
+func (p *Public) OnTicker(handler market.TickerHandler) error {
+	p.SubscribeTicker(func(tickers []payload.Ticker) error {
+		for _, t := range tickers {
+			ts := t.Timestamp
+			if ts.IsZero() {
+				ts = time.Now()
+			}
+			if err := handler(market.Ticker{
+				Exchange:  common.Kraken,
+				Symbol:    t.Symbol,
+				Last:      t.LastDecimal,
+				BidPrice:  t.BidDecimal,
+				BidSize:   t.BidQtyDecimal,
+				AskPrice:  t.AskDecimal,
+				AskSize:   t.AskQtyDecimal,
+				Volume24h: t.VolumeDecimal,
+				Timestamp: ts,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnCandle 由成交在本地合成统一格式的K线，未收盘的K线随每笔成交回调，下一个周期的第一笔成交到达时收盘
+func (p *Public) OnCandle(interval time.Duration, handler market.CandleHandler) error {
+	builder, err := candles.New(candles.Every(interval), candles.Config{Partial: true}, func(bar candles.Bar) error {
+		return handler(bar.Candle)
+	})
+	if err != nil {
+		return err
+	}
+	return p.OnTrade(builder.OnTrade)
+}
+
+func toLevels(items []payload.OrderBookItem) []market.Level {
+	ret := make([]market.Level, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, market.Level{Price: item.PriceDecimal, Size: item.QtyDecimal})
+	}
+	return ret
+}
@@ -0,0 +1,99 @@
+package kraken
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestOnTradeOffline(t *testing.T) {
+	srv := dextest.NewKrakenServer()
+	defer srv.Close()
+
+	p := NewPublic(t.Context(),
+		options.WithURL(srv.URL()),
+		options.WithSymbols("BTC/USD"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+		options.WithInstrumentRegistry(common.NewInstrumentRegistry()),
+	)
+	defer p.Close()
+
+	// 数值原样保留小数位数，时间取 RFC3339 字符串
+	dextest.ExpectOfflineTrade(t, srv.Server, p, "trade", func() error {
+		_ = srv.Status("online")
+		// 原样发送，保留价格的小数位数
+		return srv.Broadcast(`{"channel":"trade","type":"update","data":[{"symbol":"BTC/USD","side":"buy",` +
+			`"price":42000.10,"qty":0.5,"ord_type":"market","trade_id":1,"timestamp":"2023-11-14T22:13:20.000000Z"}]}`)
+	}, market.Trade{
+		Exchange: common.Kraken, Symbol: "BTC/USD", TradeId: "1",
+		Price: common.MustDecimal("42000.10"), Size: common.MustDecimal("0.5"),
+		Side: market.Buy, Timestamp: time.Unix(1700000000, 0),
+	})
+}
+
+// 同一频道先普通订阅、后保序订阅时，保序回调仍然串行执行
+func TestOrderedAfterUnorderedOffline(t *testing.T) {
+	srv := dextest.NewKrakenServer()
+	defer srv.Close()
+
+	p := NewPublic(t.Context(),
+		options.WithURL(srv.URL()),
+		options.WithSymbols("BTC/USD"),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+		options.WithInstrumentRegistry(common.NewInstrumentRegistry()),
+	)
+
+	const n = 30
+	var wg sync.WaitGroup
+	wg.Add(2 * n)
+	p.SubscribeTrade(func(trades []payload.Trade) error {
+		wg.Done()
+		return nil
+	})
+	var mu sync.Mutex
+	var seen []int
+	p.SubscribeTrade(func(trades []payload.Trade) error {
+		defer wg.Done()
+		// 每笔耗时不同，提交到协程池时会乱序
+		time.Sleep(time.Duration(trades[0].TradeId%3) * time.Millisecond)
+		mu.Lock()
+		seen = append(seen, trades[0].TradeId)
+		mu.Unlock()
+		return nil
+	}, options.WithOrdered())
+	p.Connect()
+	defer p.Close()
+
+	if _, ok := srv.WaitRequest("subscribe", "trade", 5*time.Second); !ok {
+		t.Fatal("no subscribe request")
+	}
+	_ = srv.Status("online")
+	for i := 0; i < n; i++ {
+		_ = srv.Broadcast(fmt.Sprintf(`{"channel":"trade","type":"update","data":[{"symbol":"BTC/USD","side":"buy",`+
+			`"price":42000.1,"qty":0.5,"ord_type":"market","trade_id":%d,"timestamp":"2023-11-14T22:13:20.000000Z"}]}`, i))
+	}
+
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(dextest.ExpectTimeout):
+		t.Fatal("not all trades delivered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, id := range seen {
+		if id != i {
+			t.Fatalf("ordered caller out of order at %d: %v", i, seen)
+		}
+	}
+}
@@ -1,17 +1,17 @@
 package kraken
 
-import "log"
+import (
+	"github.com/simonks2016/dex_plus/kraken/internal"
+	"github.com/simonks2016/dex_plus/options"
+)
 
-type Option func(public *Public)
+// Option 构造选项，见 options 包；静默检测的频道名与 Kraken 推送一致，如 "book"、"trade"
+type Option = options.Option
 
-func WithSymbols(symbols ...string) Option {
-	return func(public *Public) {
-		public.symbols = append(public.symbols, symbols...)
-	}
-}
+// SubscribeOption 订阅选项，WithOrdered 时同一个 (channel, symbol) 的推送按到达顺序串行回调
+type SubscribeOption = options.SubscribeOption
 
-func WithLogger(logger *log.Logger) Option {
-	return func(public *Public) {
-		public.logger = logger
-	}
+func newSubscribeChannel(channel internal.SubscribeChannel, opts ...SubscribeOption) internal.SubscribeChannel {
+	channel.Ordered = options.NewSubscribeOptions(opts...).Ordered
+	return channel
 }
@@ -12,6 +12,8 @@ const (
 type KrakenParams struct {
 	Method string `json:"method"`
 	Params Param  `json:"params"`
+	// ReqId 请求 id，交易所在确认中原样返回
+	ReqId int64 `json:"req_id,omitempty"`
 }
 
 type Param struct {
@@ -29,6 +31,12 @@ func NewKrakenParams(method, channel string, symbols ...string) *KrakenParams {
 	}
 }
 
+// WithReqId 设置请求 id
+func (k *KrakenParams) WithReqId(id int64) *KrakenParams {
+	k.ReqId = id
+	return k
+}
+
 func (k *KrakenParams) Json() []byte {
 
 	marshal, err := json.Marshal(k)
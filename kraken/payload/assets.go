@@ -1,5 +1,7 @@
 package payload
 
+import "github.com/simonks2016/dex_plus/common"
+
 type Asset struct {
 	Id               string  `json:"id"`
 	Status           string  `json:"status"`
@@ -34,3 +36,22 @@ type Instrument struct {
 	Assets []Asset `json:"assets"`
 	Pairs  []Pair  `json:"pairs"`
 }
+
+// ToInstrument 转换为统一的产品信息，Symbol 形如 "BTC/USD"
+func (p Pair) ToInstrument() common.Instrument {
+	tick := p.PriceIncrement
+	if tick == 0 {
+		tick = p.TickSize
+	}
+	return common.Instrument{
+		Exchange:    common.Kraken,
+		Symbol:      p.Symbol,
+		Type:        common.InstrumentSpot,
+		Base:        p.Base,
+		Quote:       p.Quote,
+		TickSize:    common.NewDecimalFromFloat(tick),
+		LotSize:     common.NewDecimalFromFloat(p.QtyIncrement),
+		MinSize:     common.NewDecimalFromFloat(p.QtyMin),
+		MinNotional: common.NewDecimalFromFloat(p.CostMin),
+	}
+}
@@ -0,0 +1,67 @@
+package payload
+
+import (
+	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// Decimal 精确小数
+// Kraken 的价格、数量是裸数字，解码时同时保存一份精确值，浮点字段由精确值换算得到
+type Decimal = common.Decimal
+
+func (t *Trade) UnmarshalJSON(b []byte) error {
+	type alias Trade
+	var raw struct {
+		alias
+		Qty   Decimal `json:"qty"`
+		Price Decimal `json:"price"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*t = Trade(raw.alias)
+	t.QtyDecimal, t.PriceDecimal = raw.Qty, raw.Price
+	t.Qty, t.Price = raw.Qty.Float64(), raw.Price.Float64()
+	return nil
+}
+
+func (o *OrderBookItem) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Price Decimal `json:"price"`
+		Qty   Decimal `json:"qty"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*o = OrderBookItem{
+		Price:        raw.Price.Float64(),
+		Qty:          raw.Qty.Float64(),
+		PriceDecimal: raw.Price,
+		QtyDecimal:   raw.Qty,
+	}
+	return nil
+}
+
+func (t *Ticker) UnmarshalJSON(b []byte) error {
+	type alias Ticker
+	var raw struct {
+		alias
+		Bid    Decimal `json:"bid"`
+		BidQty Decimal `json:"bid_qty"`
+		Ask    Decimal `json:"ask"`
+		AskQty Decimal `json:"ask_qty"`
+		Last   Decimal `json:"last"`
+		Volume Decimal `json:"volume"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*t = Ticker(raw.alias)
+	t.BidDecimal, t.BidQtyDecimal = raw.Bid, raw.BidQty
+	t.AskDecimal, t.AskQtyDecimal = raw.Ask, raw.AskQty
+	t.LastDecimal, t.VolumeDecimal = raw.Last, raw.Volume
+	t.Bid, t.BidQty = raw.Bid.Float64(), raw.BidQty.Float64()
+	t.Ask, t.AskQty = raw.Ask.Float64(), raw.AskQty.Float64()
+	t.Last, t.Volume = raw.Last.Float64(), raw.Volume.Float64()
+	return nil
+}
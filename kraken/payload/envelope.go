@@ -14,6 +14,7 @@ type KrakenEnvelope struct {
 	Result  map[string]any  `json:"result,omitempty"`
 	Success *bool           `json:"success,omitempty"`
 	Error   *string         `json:"error,omitempty"`
+	ReqId   int64           `json:"req_id,omitempty"`
 	TimeIn  time.Time       `json:"time_in,omitempty"`
 	TimeOut time.Time       `json:"time_out,omitempty"`
 }
@@ -30,3 +31,20 @@ func (e *KrakenEnvelope) GetChannel() string {
 	}
 	return *e.Channel
 }
+
+// Timestamp 交易所的时间：请求的响应取 time_out，推送取第一条数据的 timestamp，都没有时返回零值
+func (e *KrakenEnvelope) Timestamp() time.Time {
+	if !e.TimeOut.IsZero() {
+		return e.TimeOut
+	}
+	if len(e.Data) == 0 || e.Data[0] != '[' {
+		return time.Time{}
+	}
+	var items []struct {
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if err := json.Unmarshal(e.Data, &items); err != nil || len(items) == 0 {
+		return time.Time{}
+	}
+	return items[0].Timestamp
+}
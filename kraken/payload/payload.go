@@ -10,11 +10,17 @@ type Trade struct {
 	OrdType   string  `json:"ord_type"`
 	TradeId   int     `json:"trade_id"`
 	Timestamp string  `json:"timestamp"`
+
+	QtyDecimal   Decimal `json:"-"`
+	PriceDecimal Decimal `json:"-"`
 }
 
 type OrderBookItem struct {
 	Price float64 `json:"price"`
 	Qty   float64 `json:"qty"`
+
+	PriceDecimal Decimal `json:"-"`
+	QtyDecimal   Decimal `json:"-"`
 }
 
 type OrderBook struct {
@@ -54,4 +60,11 @@ type Ticker struct {
 	Change    float64   `json:"change"`
 	ChangePct float64   `json:"change_pct"`
 	Timestamp time.Time `json:"timestamp"`
+
+	BidDecimal    Decimal `json:"-"`
+	BidQtyDecimal Decimal `json:"-"`
+	AskDecimal    Decimal `json:"-"`
+	AskQtyDecimal Decimal `json:"-"`
+	LastDecimal   Decimal `json:"-"`
+	VolumeDecimal Decimal `json:"-"`
 }
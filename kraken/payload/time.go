@@ -0,0 +1,10 @@
+package payload
+
+// ServerTime GET /0/public/Time
+type ServerTime struct {
+	Error  []string `json:"error"`
+	Result struct {
+		UnixTime int64  `json:"unixtime"`
+		RFC1123  string `json:"rfc1123"`
+	} `json:"result"`
+}
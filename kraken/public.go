@@ -4,23 +4,32 @@ import (
 	"context"
 	"fmt"
 	"hash/crc32"
-	"log"
+	"log/slog"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/simonks2016/book_manager"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
 	"github.com/simonks2016/dex_plus/internal/client"
 	"github.com/simonks2016/dex_plus/kraken/internal"
 	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
 )
 
 type Public struct {
-	client      *internal.KrakenClient
+	client      *internal.KrakenPool
+	cfg         *client.Config
 	ctx         context.Context
 	symbols     []string
-	logger      *log.Logger
+	logger      *slog.Logger
+	registry    *common.InstrumentRegistry
 	bookManager *bookManager.BookManager
+	// scales 每个交易对盘口的价格与数量精度，来自 instrument 频道的 price_precision 与 qty_precision
+	scales  *bookscale.Scales
+	streams stream.Group
 }
 
 func NewPublic(ctx context.Context, opts ...Option) *Public {
@@ -36,41 +45,43 @@ func NewPublic(ctx context.Context, opts ...Option) *Public {
 	// 每5秒就发送ping
 	cfg.SetPingInterval(time.Duration(5) * time.Second)
 	cfg.ForbidIPV6()
+	// BackpressureConflate 时按 channel + 第一条数据的 symbol 合并
+	cfg.SetConflateKey(internal.MessageKey)
 
-	cli := internal.NewKrakenClient(ctx, cfg)
-
+	// 先应用配置，再创建客户端
+	o := options.New(cfg, opts...)
 	p1 := &Public{
-		client:  cli,
-		symbols: []string{},
-		logger:  cfg.Logger,
-		ctx:     ctx,
+		cfg:      cfg,
+		symbols:  append([]string{}, o.Symbols()...),
+		registry: o.Registry(common.DefaultInstrumentRegistry),
+		ctx:      ctx,
 		bookManager: bookManager.NewBookManagerWithWorkers(10, 4000,
 			bookManager.WithCrossedThreshold(10)),
+		scales: bookscale.NewScales(),
 	}
-	// 设置Kraken checksum
+
+	p1.client = internal.NewKrakenPool(ctx, cfg)
+	p1.logger = p1.client.Logger()
+	p1.client.SetInstrumentRegistry(p1.registry)
+
+	// 设置Kraken checksum，只校验精度来自交易对信息的盘口
 	p1.bookManager.ChecksumFunc(p1.checksum)
-	p1.bookManager.EnableChecksum(false, nil)
+	p1.bookManager.EnableChecksum(true, p1.scales.Verified)
 	p1.bookManager.OnMarkDirty(func(symbol string, reason string, ev *bookManager.BookEvent, book *bookManager.OrderBook) {
 		// 重新订阅盘口数据
 		if err := p1.client.Resubscribe("book", symbol); err != nil {
-			if p1.logger != nil {
-				p1.logger.Printf("[error]failed to resubscribe channel,%v", err)
-			}
+			p1.logger.Error("failed to resubscribe channel", "channel", "book", "symbol", symbol, "reason", reason, "error", err)
 			return
 		}
 	})
 
-	for _, opt := range opts {
-		opt(p1)
-	}
-
 	return p1
 }
 
-func (p *Public) SubscribeTrade(callback func(trades []payload.Trade) error) {
+func (p *Public) SubscribeTrade(callback func(trades []payload.Trade) error, opts ...SubscribeOption) {
 
 	// 订阅Trade频道
-	p.client.Subscribe(internal.SubscribeChannel{
+	p.client.Subscribe(newSubscribeChannel(internal.SubscribeChannel{
 		Channel: "trade",
 		Symbols: p.symbols,
 		Caller: []internal.Caller{
@@ -82,12 +93,29 @@ func (p *Public) SubscribeTrade(callback func(trades []payload.Trade) error) {
 				return callback(data)
 			},
 		},
-	})
+	}, opts...))
+}
+
+// SubscribeTicker 订阅Tick行情
+func (p *Public) SubscribeTicker(callback func(tickers []payload.Ticker) error, opts ...SubscribeOption) {
+	p.client.Subscribe(newSubscribeChannel(internal.SubscribeChannel{
+		Channel: "ticker",
+		Symbols: p.symbols,
+		Caller: []internal.Caller{
+			func(envelope *payload.KrakenEnvelope) error {
+				data, err := payload.ParseData[payload.Ticker](envelope)
+				if err != nil {
+					return err
+				}
+				return callback(data)
+			},
+		},
+	}, opts...))
 }
 
-func (p *Public) SubscribeOrderBook(interval time.Duration, callback func(ob []payload.OrderBook) error) {
+func (p *Public) SubscribeOrderBook(interval time.Duration, callback func(ob []payload.OrderBook) error, opts ...SubscribeOption) {
 	// 订阅盘口数据
-	p.client.Subscribe(internal.SubscribeChannel{
+	p.client.Subscribe(newSubscribeChannel(internal.SubscribeChannel{
 		Channel: "book",
 		Symbols: p.symbols,
 		Caller: []internal.Caller{
@@ -95,11 +123,35 @@ func (p *Public) SubscribeOrderBook(interval time.Duration, callback func(ob []p
 				return p.handlerOrderBook(envelope)
 			},
 		},
-	})
+	}, opts...))
 	// 异步定时发送盘口快照
 	p.setSnapshotTimer(p.ctx, interval, 20, callback)
 }
 
+// Subscribe 在已经订阅过的频道中追加品种，沿用该频道的回调；连接上立即生效并在重连后重放
+func (p *Public) Subscribe(channel string, symbols ...string) error {
+	if !p.client.HasChannel(channel) {
+		return fmt.Errorf("channel %s has no handler, subscribe it with a Subscribe* method first", channel)
+	}
+	p.client.Subscribe(internal.SubscribeChannel{Channel: channel, Symbols: symbols})
+	return nil
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有品种时回调一并移除
+func (p *Public) Unsubscribe(channel string, symbols ...string) error {
+	return p.client.Unsubscribe(channel, symbols...)
+}
+
+// SubscriptionState 订阅的当前状态，没有订阅过的为 common.SubscriptionUnsubscribed
+func (p *Public) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认、被拒绝或确认超时；回调不能阻塞
+func (p *Public) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
+}
+
 // Connect 连接
 func (p *Public) Connect() {
 	p.client.Connect()
@@ -108,6 +160,21 @@ func (p *Public) Connect() {
 // Close 关闭连接
 func (p *Public) Close() {
 	p.client.Close()
+	p.streams.Close()
+}
+
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Public) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	// 回调处理完之后再关闭 Stream，阻塞在 Stream 上的回调随之退出
+	p.streams.Close()
+	return err
+}
+
+// Reconnect 重新连接，订阅超过单连接上限时按订阅数量重新分配连接
+func (p *Public) Reconnect() {
+	p.client.Reconnect()
 }
 
 // ExchangeName 交易所名字
@@ -135,24 +202,34 @@ func (p *Public) handlerOrderBook(env *payload.KrakenEnvelope) error {
 
 	// 3. 直接遍历数据进行处理，避免创建中间 map (o2)
 	for _, datum := range data {
+		// 快照按交易对信息确定精度，之后的增量沿用同一个精度
+		var prec bookscale.Precision
+		if msgType == "snapshot" {
+			if pair, ok := p.client.GetTradingPair(datum.Symbol); ok {
+				p.scales.Set(datum.Symbol, bookscale.Precision{PriceDecimals: int32(pair.PricePrecision), SizeDecimals: int32(pair.QtyPrecision)})
+			}
+			prec, _ = p.scales.ForSnapshot(datum.Symbol)
+		} else {
+			prec = p.scales.ForBook(datum.Symbol)
+		}
+
 		// 预分配 level 切片容量
 		levels := make([]bookManager.Level, 0, len(datum.Bids)+len(datum.Asks))
 
-		// 填充 Bids
+		// 按精度换算成整数价格，不经过浮点数
 		for _, bid := range datum.Bids {
-			levels = append(levels, bookManager.Level{
-				PriceTicks: bookManager.NewPrice(bid.Price),
-				Size:       bid.Qty,
-				IsBids:     true,
-			})
+			level, err := prec.Level(bid.PriceDecimal, bid.QtyDecimal, true)
+			if err != nil {
+				return fmt.Errorf("book %s: %w", datum.Symbol, err)
+			}
+			levels = append(levels, level)
 		}
-		// 填充 Asks
 		for _, ask := range datum.Asks {
-			levels = append(levels, bookManager.Level{
-				PriceTicks: bookManager.NewPrice(ask.Price),
-				Size:       ask.Qty,
-				IsBids:     false,
-			})
+			level, err := prec.Level(ask.PriceDecimal, ask.QtyDecimal, false)
+			if err != nil {
+				return fmt.Errorf("book %s: %w", datum.Symbol, err)
+			}
+			levels = append(levels, level)
 		}
 		// 提交更新事件
 		if !p.bookManager.Submit(bookManager.BookEvent{
@@ -171,9 +248,7 @@ func (p *Public) handlerOrderBook(env *payload.KrakenEnvelope) error {
 			Levels:   levels,
 			Checksum: datum.Checksum,
 		}) {
-			if p.logger != nil {
-				p.logger.Printf("[error] failed to submit order book event,the queue is full")
-			}
+			p.logger.Warn("failed to submit order book event, the queue is full", "channel", "book", "symbol", datum.Symbol)
 		}
 	}
 	return nil
@@ -188,25 +263,9 @@ func (p *Public) setSnapshotTimer(ctx context.Context, interval time.Duration, n
 		response := make([]payload.OrderBook, len(snapshots))
 
 		for index, snapshot := range snapshots {
-			// 分别初始化 Bids 和 Asks
-			bids := make([]payload.OrderBookItem, len(snapshot.Bids))
-			asks := make([]payload.OrderBookItem, len(snapshot.Asks))
-
-			// 填充 Bids
-			for i, bid := range snapshot.Bids {
-				bids[i] = payload.OrderBookItem{
-					Price: bookManager.PriceTo(bid.PriceTicks),
-					Qty:   bid.Size,
-				}
-			}
-
-			// 填充 Asks (修正：写入 asks 数组)
-			for i, ask := range snapshot.Asks {
-				asks[i] = payload.OrderBookItem{
-					Price: bookManager.PriceTo(ask.PriceTicks),
-					Qty:   ask.Size,
-				}
-			}
+			prec := p.scales.ForBook(snapshot.ProductID)
+			bids := bookItems(prec, snapshot.Bids)
+			asks := bookItems(prec, snapshot.Asks)
 
 			response[index] = payload.OrderBook{
 				Symbol:    snapshot.ProductID,
@@ -219,27 +278,34 @@ func (p *Public) setSnapshotTimer(ctx context.Context, interval time.Duration, n
 		// 执行回调（建议考虑是否需要 go callback(response) 异步处理）
 		go func() {
 			if err := callback(response); err != nil {
-				if p.logger != nil {
-					p.logger.Printf("snapshot error: %v", err)
-				}
+				p.logger.Error("failed to handle book snapshot", "channel", "book", "error", err)
 			}
 		}()
 	})
 }
 
-func (p *Public) checksum(symbol string, bids, asks []bookManager.Level) uint32 {
-
-	if i, ex := p.client.GetTradingPair(symbol); ex {
-		return rawChecksum(bids, asks, i.PricePrecision, i.QtyPrecision)
-	} else {
-		if p.logger != nil {
-			p.logger.Printf("[error] failed to get trading pair,the trading pair does not exist")
+// bookItems 按盘口的精度还原档位，PriceDecimal 与 QtyDecimal 是精确值
+func bookItems(prec bookscale.Precision, levels []bookManager.Level) []payload.OrderBookItem {
+	items := make([]payload.OrderBookItem, len(levels))
+	for i, l := range levels {
+		price, qty := prec.Price(l.PriceTicks), prec.Size(l.Size)
+		items[i] = payload.OrderBookItem{
+			Price:        price.Float64(),
+			Qty:          qty.Float64(),
+			PriceDecimal: price,
+			QtyDecimal:   qty,
 		}
-		return 0
 	}
+	return items
+}
+
+// checksum 只在 scales.Verified 为 true 时调用，盘口的精度与交易对信息一致
+func (p *Public) checksum(symbol string, bids, asks []bookManager.Level) uint32 {
+	return rawChecksum(bids, asks, p.scales.ForBook(symbol))
 }
 
-func rawChecksum(bids, asks []bookManager.Level, krakenPricePrecision, krakenQtyPrecision int) uint32 {
+// rawChecksum Kraken 的 CRC32 校验和：前 10 档卖盘与买盘的价格、数量按精度格式化，去掉小数点与前导 0 后依次拼接
+func rawChecksum(bids, asks []bookManager.Level, prec bookscale.Precision) uint32 {
 	// 复制，避免修改外部 slice
 	bs := append([]bookManager.Level(nil), bids...)
 	as := append([]bookManager.Level(nil), asks...)
@@ -264,24 +330,16 @@ func rawChecksum(bids, asks []bookManager.Level, krakenPricePrecision, krakenQty
 	var sb strings.Builder
 
 	for _, ask := range as {
-		price := normalizeKrakenChecksumValue(
-			formatFixed(bookManager.PriceTo(ask.PriceTicks), krakenPricePrecision),
-		)
-		qty := normalizeKrakenChecksumValue(
-			formatFixed(ask.Size, krakenQtyPrecision),
-		)
+		price := normalizeKrakenChecksumValue(prec.Price(ask.PriceTicks).String())
+		qty := normalizeKrakenChecksumValue(prec.Size(ask.Size).String())
 
 		sb.WriteString(price)
 		sb.WriteString(qty)
 	}
 
 	for _, bid := range bs {
-		price := normalizeKrakenChecksumValue(
-			formatFixed(bookManager.PriceTo(bid.PriceTicks), krakenPricePrecision),
-		)
-		qty := normalizeKrakenChecksumValue(
-			formatFixed(bid.Size, krakenQtyPrecision),
-		)
+		price := normalizeKrakenChecksumValue(prec.Price(bid.PriceTicks).String())
+		qty := normalizeKrakenChecksumValue(prec.Size(bid.Size).String())
 
 		sb.WriteString(price)
 		sb.WriteString(qty)
@@ -290,10 +348,6 @@ func rawChecksum(bids, asks []bookManager.Level, krakenPricePrecision, krakenQty
 	return crc32.ChecksumIEEE([]byte(sb.String()))
 }
 
-func formatFixed(v float64, precision int) string {
-	return fmt.Sprintf("%.*f", precision, v)
-}
-
 func normalizeKrakenChecksumValue(s string) string {
 	s = strings.ReplaceAll(s, ".", "")
 	s = strings.TrimLeft(s, "0")
@@ -0,0 +1,28 @@
+package kraken
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/simonks2016/book_manager"
+	"github.com/simonks2016/dex_plus/internal/bookscale"
+)
+
+func TestRawChecksum(t *testing.T) {
+	prec := bookscale.Precision{PriceDecimals: 1, SizeDecimals: 8}
+	level := func(price, size string, isBid bool) bookManager.Level {
+		l, err := prec.ParseLevel(price, size, isBid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	bids := []bookManager.Level{level("45283.4", "0.5", true), level("45283.5", "0.1", true)}
+	asks := []bookManager.Level{level("45285.2", "0.001", false)}
+
+	// 卖盘从低到高、买盘从高到低，价格与数量按精度补齐后去掉小数点与前导 0
+	want := crc32.ChecksumIEEE([]byte("452852" + "100000" + "452835" + "10000000" + "452834" + "50000000"))
+	if got := rawChecksum(bids, asks, prec); got != want {
+		t.Fatalf("rawChecksum = %d, want %d", got, want)
+	}
+}
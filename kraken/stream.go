@@ -0,0 +1,58 @@
+package kraken
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/simonks2016/dex_plus/kraken/payload"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
+)
+
+// openStream 按 (channel, symbol) 保序订阅并写入 Stream，ctx 结束或客户端关闭时 channel 关闭
+// ctx 结束后订阅仍然保留，不再需要时调用 Unsubscribe
+func openStream[T any](ctx context.Context, p *Public, subscribe func(func(T) error, ...SubscribeOption), opts []stream.Option) (<-chan T, error) {
+	return stream.Subscribe(ctx, &p.streams, func(push func(T) error) error {
+		subscribe(push, options.WithOrdered())
+		return nil
+	}, opts...)
+}
+
+// TradesStream 以 channel 的形式订阅成交数据
+func (p *Public) TradesStream(ctx context.Context, opts ...stream.Option) (<-chan []payload.Trade, error) {
+	return openStream(ctx, p, p.SubscribeTrade, opts)
+}
+
+// TradesSeq 以迭代器的形式订阅成交数据，退出循环时关闭
+func (p *Public) TradesSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]payload.Trade, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []payload.Trade, error) {
+		return p.TradesStream(ctx, opts...)
+	})
+}
+
+// TickersStream 以 channel 的形式订阅Tick行情
+func (p *Public) TickersStream(ctx context.Context, opts ...stream.Option) (<-chan []payload.Ticker, error) {
+	return openStream(ctx, p, p.SubscribeTicker, opts)
+}
+
+// TickersSeq 以迭代器的形式订阅Tick行情，退出循环时关闭
+func (p *Public) TickersSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]payload.Ticker, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []payload.Ticker, error) {
+		return p.TickersStream(ctx, opts...)
+	})
+}
+
+// BooksStream 以 channel 的形式订阅本地维护的盘口，每隔 interval 推送一次快照
+func (p *Public) BooksStream(ctx context.Context, interval time.Duration, opts ...stream.Option) (<-chan []payload.OrderBook, error) {
+	return openStream(ctx, p, func(push func([]payload.OrderBook) error, o ...SubscribeOption) {
+		p.SubscribeOrderBook(interval, push, o...)
+	}, opts)
+}
+
+// BooksSeq 以迭代器的形式订阅本地维护的盘口，退出循环时关闭
+func (p *Public) BooksSeq(ctx context.Context, interval time.Duration, opts ...stream.Option) iter.Seq2[[]payload.OrderBook, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []payload.OrderBook, error) {
+		return p.BooksStream(ctx, interval, opts...)
+	})
+}
@@ -0,0 +1,176 @@
+// Package latency 统计一条推送从交易所到业务回调的延迟，分为三段：
+// 线路延迟（交易所时间戳到 readPump 收到）、排队等待（收到到回调开始）与回调耗时
+// 每段保留最近的 Window 个样本，按百分位输出，用于比较不同交易所、发现变慢的链路
+package latency
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/simonks2016/dex_plus/clocksync"
+	"github.com/simonks2016/dex_plus/metrics"
+)
+
+// Stage 延迟的分段
+type Stage int
+
+const (
+	// Wire 交易所时间戳到本地收到；未配置 Config.Clock 时包含两端的时钟偏差，偏差较大时可能为负
+	Wire Stage = iota
+	// Queue 收到到回调开始，即在读队列与分区中等待的时间
+	Queue
+	// Handler 回调耗时
+	Handler
+	stageCount
+)
+
+func (s Stage) String() string {
+	switch s {
+	case Wire:
+		return "wire"
+	case Queue:
+		return "queue"
+	default:
+		return "handler"
+	}
+}
+
+// DefaultWindow 每段默认保留的样本数
+const DefaultWindow = 4096
+
+// Config 延迟统计配置
+type Config struct {
+	// Window 每段保留最近的样本数，默认 DefaultWindow
+	Window int
+	// Metrics 同时写入延迟直方图，标签为 exchange 与 stage
+	Metrics metrics.MetricsSink
+	// Clock 与交易所同步的时钟，线路延迟扣除本地与交易所的时钟偏差
+	Clock *clocksync.Clock
+}
+
+// Stats 一段延迟在窗口内的统计
+type Stats struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Snapshot 一个交易所三段延迟的统计
+type Snapshot struct {
+	Exchange string
+	Wire     Stats
+	Queue    Stats
+	Handler  Stats
+}
+
+// ring 固定大小的样本环
+type ring struct {
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func (r *ring) add(d time.Duration) {
+	r.samples[r.next] = d
+	r.next++
+	if r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *ring) stats() Stats {
+	n := r.next
+	if r.full {
+		n = len(r.samples)
+	}
+	if n == 0 {
+		return Stats{}
+	}
+	sorted := slices.Clone(r.samples[:n])
+	slices.Sort(sorted)
+	at := func(q float64) time.Duration {
+		return sorted[min(int(q*float64(n)), n-1)]
+	}
+	return Stats{Count: n, P50: at(0.5), P90: at(0.9), P99: at(0.99), Max: sorted[n-1]}
+}
+
+// Tracker 一个交易所的延迟统计，并发安全；nil 的 Tracker 忽略所有调用
+type Tracker struct {
+	exchange string
+	metrics  metrics.MetricsSink
+	clock    *clocksync.Clock
+
+	mu     sync.Mutex
+	stages [stageCount]ring
+}
+
+// New 创建交易所的延迟统计，多个连接或分片可以共用一个 Tracker
+func New(exchange string, cfg Config) *Tracker {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	t := &Tracker{exchange: exchange, metrics: metrics.OrDiscard(cfg.Metrics), clock: cfg.Clock}
+	for i := range t.stages {
+		t.stages[i].samples = make([]time.Duration, cfg.Window)
+	}
+	return t
+}
+
+// Exchange 交易所名称
+func (t *Tracker) Exchange() string {
+	if t == nil {
+		return ""
+	}
+	return t.exchange
+}
+
+// Observe 记录一个样本
+func (t *Tracker) Observe(stage Stage, d time.Duration) {
+	if t == nil || stage < 0 || stage >= stageCount {
+		return
+	}
+	t.mu.Lock()
+	t.stages[stage].add(d)
+	t.mu.Unlock()
+	t.metrics.Observe(metrics.LatencySeconds, d.Seconds(), "exchange", t.exchange, "stage", stage.String())
+}
+
+// ObserveWire 记录交易所时间戳到本地收到的延迟，任一时间为零值时忽略
+func (t *Tracker) ObserveWire(exchangeTime, received time.Time) {
+	if t == nil || exchangeTime.IsZero() || received.IsZero() {
+		return
+	}
+	t.Observe(Wire, received.Add(t.clock.Offset()).Sub(exchangeTime))
+}
+
+// Snapshot 当前窗口内的统计
+func (t *Tracker) Snapshot() Snapshot {
+	if t == nil {
+		return Snapshot{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Snapshot{
+		Exchange: t.exchange,
+		Wire:     t.stages[Wire].stats(),
+		Queue:    t.stages[Queue].stats(),
+		Handler:  t.stages[Handler].stats(),
+	}
+}
+
+// Reset 清空全部样本
+func (t *Tracker) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.stages {
+		clear(t.stages[i].samples)
+		t.stages[i].next, t.stages[i].full = 0, false
+	}
+}
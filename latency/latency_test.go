@@ -0,0 +1,35 @@
+package latency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackerPercentiles(t *testing.T) {
+	tr := New("okx", Config{Window: 100})
+	// 窗口为 100，前 50 个样本被覆盖
+	for i := 1; i <= 150; i++ {
+		tr.Observe(Queue, time.Duration(i)*time.Millisecond)
+	}
+	tr.ObserveWire(time.Time{}, time.Now())
+
+	s := tr.Snapshot()
+	if s.Exchange != "okx" || s.Wire.Count != 0 {
+		t.Fatalf("snapshot = %+v", s)
+	}
+	want := Stats{Count: 100, P50: 101 * time.Millisecond, P90: 141 * time.Millisecond, P99: 150 * time.Millisecond, Max: 150 * time.Millisecond}
+	if s.Queue != want {
+		t.Fatalf("queue = %+v, want %+v", s.Queue, want)
+	}
+
+	tr.Reset()
+	if s := tr.Snapshot(); s.Queue.Count != 0 {
+		t.Fatalf("after Reset: %+v", s.Queue)
+	}
+
+	var nilTracker *Tracker
+	nilTracker.Observe(Handler, time.Second)
+	if s := nilTracker.Snapshot(); s.Handler.Count != 0 {
+		t.Fatalf("nil tracker: %+v", s)
+	}
+}
@@ -0,0 +1,38 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotSupported 交易所不提供该类数据
+var ErrNotSupported = errors.New("market: data type not supported by this exchange")
+
+type TradeHandler func(trade Trade) error
+type BookSnapshotHandler func(snapshot BookSnapshot) error
+type BookDeltaHandler func(delta BookDelta) error
+type TickerHandler func(ticker Ticker) error
+type CandleHandler func(candle Candle) error
+
+// MarketDataSource 跨交易所统一的行情接口
+// 各交易所的 Public 在保留原生接口的同时实现该接口，策略代码只需要编写一次
+type MarketDataSource interface {
+	ExchangeName() string
+	Connect()
+	Close()
+	// Shutdown 优雅关闭：取消订阅，等待已收到的消息回调完成后返回
+	Shutdown(ctx context.Context) error
+
+	// OnTrade 订阅成交
+	OnTrade(handler TradeHandler) error
+	// OnBookSnapshot 订阅盘口快照
+	// interval 仅对在本地维护盘口的交易所（Kraken、Coinbase、Binance）生效，其余交易所按推送频率回调
+	OnBookSnapshot(interval time.Duration, handler BookSnapshotHandler) error
+	// OnBookDelta 订阅盘口增量
+	OnBookDelta(handler BookDeltaHandler) error
+	// OnTicker 订阅最新行情
+	OnTicker(handler TickerHandler) error
+	// OnCandle 订阅K线
+	OnCandle(interval time.Duration, handler CandleHandler) error
+}
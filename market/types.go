@@ -0,0 +1,75 @@
+package market
+
+import (
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+// Trade 统一的成交数据，Side 为主动成交（taker）方向
+type Trade struct {
+	Exchange  string         `json:"exchange"`
+	Symbol    string         `json:"symbol"`
+	TradeId   string         `json:"trade_id"`
+	Price     common.Decimal `json:"price"`
+	Size      common.Decimal `json:"size"`
+	Side      Side           `json:"side"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Level 盘口档位
+type Level = common.PriceLevel
+
+// BookSnapshot 盘口快照，Bids 从高到低，Asks 从低到高
+type BookSnapshot struct {
+	Exchange  string    `json:"exchange"`
+	Symbol    string    `json:"symbol"`
+	Bids      []Level   `json:"bids"`
+	Asks      []Level   `json:"asks"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// BookDelta 盘口增量
+// Size 为 0 表示删除该价位；Snapshot 为 true 时表示本次数据是全量数据，需要先清空本地盘口
+type BookDelta struct {
+	Exchange  string    `json:"exchange"`
+	Symbol    string    `json:"symbol"`
+	Bids      []Level   `json:"bids"`
+	Asks      []Level   `json:"asks"`
+	Snapshot  bool      `json:"snapshot"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Ticker 最新行情
+type Ticker struct {
+	Exchange  string         `json:"exchange"`
+	Symbol    string         `json:"symbol"`
+	Last      common.Decimal `json:"last"`
+	BidPrice  common.Decimal `json:"bid_price"`
+	BidSize   common.Decimal `json:"bid_size"`
+	AskPrice  common.Decimal `json:"ask_price"`
+	AskSize   common.Decimal `json:"ask_size"`
+	Volume24h common.Decimal `json:"volume_24h"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Candle K线，Start 为该K线的开始时间
+type Candle struct {
+	Exchange string         `json:"exchange"`
+	Symbol   string         `json:"symbol"`
+	Interval time.Duration  `json:"interval"`
+	Start    time.Time      `json:"start"`
+	Open     common.Decimal `json:"open"`
+	High     common.Decimal `json:"high"`
+	Low      common.Decimal `json:"low"`
+	Close    common.Decimal `json:"close"`
+	Volume   common.Decimal `json:"volume"`
+	Closed   bool           `json:"closed"`
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 延迟直方图默认分桶（秒）
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type kind int
+
+const (
+	kindCounter kind = iota
+	kindGauge
+	kindHistogram
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindCounter:
+		return "counter"
+	case kindGauge:
+		return "gauge"
+	default:
+		return "histogram"
+	}
+}
+
+type series struct {
+	labels string // 已格式化的 {k="v",...}
+	value  float64
+
+	// 直方图
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type family struct {
+	kind   kind
+	help   string
+	series map[string]*series
+}
+
+// Prometheus 内存中的指标汇总，实现 MetricsSink 与 http.Handler（Prometheus 文本格式）
+type Prometheus struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*family
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		buckets:  DefaultBuckets,
+		families: make(map[string]*family),
+	}
+}
+
+// SetBuckets 设置直方图分桶，需在记录数据前调用
+func (p *Prometheus) SetBuckets(buckets ...float64) *Prometheus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buckets = append([]float64(nil), buckets...)
+	sort.Float64s(p.buckets)
+	return p
+}
+
+// Help 设置指标的说明
+func (p *Prometheus) Help(name, help string) *Prometheus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if f, ok := p.families[name]; ok {
+		f.help = help
+	} else {
+		p.families[name] = &family{kind: -1, help: help, series: make(map[string]*series)}
+	}
+	return p
+}
+
+func (p *Prometheus) Counter(name string, delta float64, labels ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.get(name, kindCounter, labels).value += delta
+}
+
+func (p *Prometheus) Gauge(name string, value float64, labels ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.get(name, kindGauge, labels).value = value
+}
+
+func (p *Prometheus) Observe(name string, value float64, labels ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.get(name, kindHistogram, labels)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(p.buckets))
+	}
+	for i, b := range p.buckets {
+		if value <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// get 调用方需持有锁
+func (p *Prometheus) get(name string, k kind, labels []string) *series {
+	f, ok := p.families[name]
+	if !ok {
+		f = &family{kind: k, series: make(map[string]*series)}
+		p.families[name] = f
+	}
+	if f.kind < 0 {
+		f.kind = k
+	}
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+	return s
+}
+
+// ServeHTTP 输出 Prometheus 文本格式
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = p.WriteText(w)
+}
+
+// WriteText 按名称排序输出全部指标
+func (p *Prometheus) WriteText(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.families))
+	for name := range p.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := p.families[name]
+		if len(f.series) == 0 {
+			continue
+		}
+		if f.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, f.help)
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			s := f.series[k]
+			if f.kind != kindHistogram {
+				fmt.Fprintf(w, "%s%s %s\n", name, s.labels, formatFloat(s.value))
+				continue
+			}
+			for i, b := range p.buckets {
+				var c uint64
+				if i < len(s.counts) {
+					c = s.counts[i]
+				}
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(s.labels, "le", formatFloat(b)), c)
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(s.labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, s.labels, formatFloat(s.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, s.labels, s.count)
+		}
+	}
+	return w.Flush()
+}
+
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(labels[i])
+		sb.WriteString(`="`)
+		sb.WriteString(escape(labels[i+1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func withLabel(labels, key, value string) string {
+	l := key + `="` + value + `"`
+	if labels == "" {
+		return "{" + l + "}"
+	}
+	return labels[:len(labels)-1] + "," + l + "}"
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusExport(t *testing.T) {
+	p := NewPrometheus().SetBuckets(0.1, 1).Help(WsFramesTotal, "WebSocket frames")
+
+	p.Counter(WsFramesTotal, 1, "direction", "in", "endpoint", "ws.okx.com")
+	p.Counter(WsFramesTotal, 2, "direction", "in", "endpoint", "ws.okx.com")
+	p.Gauge(WsReadQueueDepth, 7)
+	p.Observe(WsHandlerSeconds, 0.05)
+	p.Observe(WsHandlerSeconds, 0.5)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		"# HELP dex_ws_frames_total WebSocket frames\n",
+		"# TYPE dex_ws_frames_total counter\n",
+		`dex_ws_frames_total{direction="in",endpoint="ws.okx.com"} 3` + "\n",
+		"dex_ws_read_queue_depth 7\n",
+		`dex_ws_handler_seconds_bucket{le="0.1"} 1` + "\n",
+		`dex_ws_handler_seconds_bucket{le="+Inf"} 2` + "\n",
+		"dex_ws_handler_seconds_count 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestReason(t *testing.T) {
+	if got := Reason("write_err: broken pipe"); got != "write_err" {
+		t.Errorf("Reason = %q", got)
+	}
+	if got := Reason(""); got != "unknown" {
+		t.Errorf("Reason = %q", got)
+	}
+}
@@ -0,0 +1,67 @@
+// Package metrics 定义连接与请求管道的指标接口，并提供 Prometheus 文本格式的导出器
+package metrics
+
+import "strings"
+
+// MetricsSink 指标接收端，labels 为成对的 key、value
+// 实现需要并发安全；调用发生在读写协程中，不能阻塞
+type MetricsSink interface {
+	Counter(name string, delta float64, labels ...string)
+	Gauge(name string, value float64, labels ...string)
+	Observe(name string, value float64, labels ...string)
+}
+
+// 指标名称
+const (
+	WsFramesTotal        = "dex_ws_frames_total"         // counter, direction=in|out
+	WsBytesTotal         = "dex_ws_bytes_total"          // counter, direction=in|out
+	WsDroppedFramesTotal = "dex_ws_dropped_frames_total" // counter
+	WsReconnectsTotal    = "dex_ws_reconnects_total"     // counter, reason
+	WsDialSeconds        = "dex_ws_dial_seconds"         // histogram
+	WsDialErrorsTotal    = "dex_ws_dial_errors_total"    // counter
+	WsReadQueueDepth     = "dex_ws_read_queue_depth"     // gauge
+	WsWriteQueueDepth    = "dex_ws_write_queue_depth"    // gauge
+	WsHandlerSeconds     = "dex_ws_handler_seconds"      // histogram
+	WsHandlerErrorsTotal = "dex_ws_handler_errors_total" // counter
+
+	LatencySeconds     = "dex_latency_seconds"      // histogram, exchange, stage=wire|queue|handler
+	ClockOffsetSeconds = "dex_clock_offset_seconds" // gauge, exchange
+	ClockRTTSeconds    = "dex_clock_rtt_seconds"    // gauge, exchange
+
+	HttpRequestsTotal  = "dex_http_requests_total"  // counter, method, status
+	HttpRetriesTotal   = "dex_http_retries_total"   // counter, method
+	HttpRequestSeconds = "dex_http_request_seconds" // histogram, method
+	HttpQueueDepth     = "dex_http_queue_depth"     // gauge
+)
+
+// Discard 丢弃所有指标
+var Discard MetricsSink = discard{}
+
+type discard struct{}
+
+func (discard) Counter(string, float64, ...string) {}
+func (discard) Gauge(string, float64, ...string)   {}
+func (discard) Observe(string, float64, ...string) {}
+
+// OrDiscard sink 为空时返回 Discard，调用方无需判空
+func OrDiscard(sink MetricsSink) MetricsSink {
+	if sink == nil {
+		return Discard
+	}
+	return sink
+}
+
+// Reason 将重连原因规范为低基数的标签值，如 "write_err: broken pipe" -> "write_err"
+func Reason(reason string) string {
+	if i := strings.IndexAny(reason, ":,"); i >= 0 {
+		reason = reason[:i]
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "unknown"
+	}
+	if len(reason) > 64 {
+		reason = reason[:64]
+	}
+	return reason
+}
@@ -1,20 +1,30 @@
 package business
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
 
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/param"
+	"github.com/simonks2016/dex_plus/options"
 )
 
-func (O *Business) SubscribeTradeAll(callback func(trade []okx.RawTrades) error) {
-	//TODO implement me
-	subscribe[okx.RawTrades]("trades-all", callback, O)
+func (O *Business) SubscribeTradeAll(callback func(trade []okx.RawTrades) error, opts ...options.SubscribeOption) {
+	subscribe[okx.RawTrades]("trades-all", callback, O, opts...)
 }
 
 func (O *Business) SetLogger(logger *log.Logger) OKXBusiness {
-	//TODO implement me
-	O.logger = logger
+	return O.SetSlogLogger(client.NewStdLogger(logger))
+}
+
+func (O *Business) SetSlogLogger(logger *slog.Logger) OKXBusiness {
+	O.logger = O.client.SetLogger(logger).Logger()
 	return O
 }
 
@@ -38,12 +48,19 @@ func (p *Business) Connect() {
 // Close 关闭并且取消订阅
 func (p *Business) Close() { p.client.Close() }
 
-// Reconnect 重新连接
-func (p *Business) Reconnect()           { p.client.Reconnect("") }
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Business) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	return errors.Join(err, client.ReleasePool(ctx, p.pool))
+}
+
+// Reconnect 重新连接，订阅超过单连接上限时按订阅数量重新分配连接
+func (p *Business) Reconnect()           { p.client.Reconnect("manual") }
 func (O *Business) ExchangeName() string { return "okx" }
 
 // subscribe: 通用订阅逻辑
-func subscribe[T okx.MarketEvent](channel string, callback func([]T) error, p *Business) {
+func subscribe[T okx.MarketEvent](channel string, callback func([]T) error, p *Business, opts ...options.SubscribeOption) {
 	caller := func(resp *okx.Payload) error {
 		data, err := okx.ParseData[T](resp)
 		if err != nil {
@@ -55,10 +72,8 @@ func subscribe[T okx.MarketEvent](channel string, callback func([]T) error, p *B
 	args := p.buildSubscribeArgs(channel)
 	payload := param.NewSubscribeParameters(args...).Encode()
 
-	if err := p.client.SubscribeChannel(payload, channel, caller); err != nil {
-		if p.logger != nil {
-			p.logger.Printf("[ERROR] %s", err)
-		}
+	if err := p.client.Subscribe(payload, channel, options.NewSubscribeOptions(opts...), caller); err != nil {
+		p.logger.Error("failed to subscribe channel", "channel", channel, "error", err)
 		return
 	}
 }
@@ -89,3 +104,30 @@ func (p *Business) buildSubscribeArgs(channel string) []param.SubscribeChannelPa
 	}
 	return args
 }
+
+// Subscribe 为已经通过 Subscribe* 订阅过的频道追加品种，沿用该频道的回调；连接后调用立即生效
+func (p *Business) Subscribe(channel string, instIds ...string) error {
+	if !p.client.HasChannel(channel) {
+		return fmt.Errorf("channel %s has no handler, subscribe it with a Subscribe* method first", channel)
+	}
+	args := make([]param.SubscribeChannelParams, 0, len(instIds))
+	for _, id := range instIds {
+		args = append(args, param.NewInstIdArg(id, channel))
+	}
+	return p.client.Subscribe(param.NewSubscribeParameters(args...).Encode(), channel, options.SubscribeOptions{})
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有订阅时一并移除回调
+func (p *Business) Unsubscribe(channel string, instIds ...string) error {
+	return p.client.Unsubscribe(channel, instIds...)
+}
+
+// SubscriptionState 订阅的当前状态，symbol 为 instId、instFamily 或 instType；没有订阅过的为 common.SubscriptionUnsubscribed
+func (p *Business) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认、被拒绝或确认超时；回调不能阻塞
+func (p *Business) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
+}
@@ -3,34 +3,45 @@ package business
 import (
 	"context"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/internal"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/ratelimit"
 )
 
 type Business struct {
-	client     *internal.OKXClient
-	logger     *log.Logger
+	client     *internal.OKXPool
+	logger     *slog.Logger
 	instId     []string
 	instFamily []string
 	ctx        context.Context
+	pool       *ants.Pool // 内部创建的协程池，Shutdown 时释放；外部传入的由调用方释放
 }
 
 type OKXBusiness interface {
-	SubscribeTradeAll(callback func(trade []okx.RawTrades) error)
+	SubscribeTradeAll(callback func(trade []okx.RawTrades) error, opts ...options.SubscribeOption)
+	Subscribe(channel string, instIds ...string) error
+	Unsubscribe(channel string, instIds ...string) error
+	SubscriptionState(channel, symbol string) common.SubscriptionState
+	OnSubscriptionChange(fn func(common.SubscriptionChange))
 	SetLogger(logger *log.Logger) OKXBusiness
+	SetSlogLogger(logger *slog.Logger) OKXBusiness
 	SetInstId(id ...string) OKXBusiness
 	SetInstFamily(id ...string) OKXBusiness
 	Connect()
 	Reconnect()
 	Close()
+	Shutdown(ctx context.Context) error
 	ExchangeName() string
 }
 
-func NewBusiness(bg context.Context, pool *ants.Pool, opts ...client.Option) OKXBusiness {
+func NewBusiness(bg context.Context, pool *ants.Pool, opts ...options.Option) OKXBusiness {
 
 	cfg := client.NewConfig()
 	cfg.SetWriteBufferSize(4000)
@@ -39,24 +50,29 @@ func NewBusiness(bg context.Context, pool *ants.Pool, opts ...client.Option) OKX
 	cfg.SetReadTimeout(time.Second * time.Duration(10))
 	cfg.SetWriteTimeout(time.Second * time.Duration(10))
 	cfg.SendTimeout = time.Minute * time.Duration(10)
-	cfg.WithURL(okx.BusinessURL(true))
+	cfg.WithEndpoints(okx.BusinessEndpoints()...)
+	cfg.WithRateLimit(ratelimit.DefaultRegistry.Get(common.OKX), ratelimit.Wait)
 	cfg.IsNeedAuth = false
 	cfg.IsForbidIPV6 = false
 
-	for _, opt := range opts {
-		opt(cfg)
-	}
+	// BackpressureConflate 时按 channel + instId 合并
+	cfg.SetConflateKey(okx.MessageKey)
+	options.New(cfg, opts...)
 
+	var owned *ants.Pool
 	if pool == nil {
 		pool, _ = ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
+		owned = pool
 	}
 
 	// 创建一个新的客户端
-	cli := internal.NewOKXClient(bg, nil, cfg)
+	cli := internal.NewOKXPool(bg, nil, cfg)
 	cli.SetThreadPool(pool)
 
 	return &Business{
 		ctx:    bg,
 		client: cli,
+		logger: cli.Logger(),
+		pool:   owned,
 	}
 }
@@ -1,11 +1,22 @@
 package okx
 
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
 const (
 	TickersChannel            = "tickers"
 	CallAuctionDetailsChannel = "call-auction-details"
 	TradesAllChannel          = "trades-all"
 	TradesChannel             = "trades"
 	Books5Channel             = "books5"
+	BooksChannel              = "books"
+	BooksL2TBTChannel         = "books-l2-tbt"
+	Books50L2TBTChannel       = "books50-l2-tbt"
 	KLine1SChannel            = "candle1s"
 	KLine1DChannel            = "candle1d"
 	SandboxPublicURL          = "wss://wspap.okx.com:8443/ws/v5/public"
@@ -14,8 +25,26 @@ const (
 	ProductionPublicURL       = "wss://ws.okx.com:8443/ws/v5/public"
 	ProductionPrivateURL      = "wss://ws.okx.com:8443/ws/v5/private"
 	ProductionBusinessURL     = "wss://ws.okx.com:8443/ws/v5/business"
+	AWSPublicURL              = "wss://wsaws.okx.com:8443/ws/v5/public"
+	AWSPrivateURL             = "wss://wsaws.okx.com:8443/ws/v5/private"
+	AWSBusinessURL            = "wss://wsaws.okx.com:8443/ws/v5/business"
 )
 
+// PublicEndpoints 生产环境公共频道的地址，ws.okx.com 不可用时切换到 AWS 地址
+func PublicEndpoints() []string {
+	return []string{ProductionPublicURL, AWSPublicURL}
+}
+
+// PrivateEndpoints 生产环境私有频道的地址
+func PrivateEndpoints() []string {
+	return []string{ProductionPrivateURL, AWSPrivateURL}
+}
+
+// BusinessEndpoints 生产环境业务频道的地址
+func BusinessEndpoints() []string {
+	return []string{ProductionBusinessURL, AWSBusinessURL}
+}
+
 func PublicURL(isProduction bool) string {
 
 	if isProduction {
@@ -36,3 +65,33 @@ func BusinessURL(isProduction bool) string {
 	}
 	return SandboxPublicURL
 }
+
+// klineBars OKX 支持的K线周期（不含 1s）
+var klineBars = []string{"1m", "3m", "5m", "15m", "30m", "1H", "2H", "4H", "6H", "12H", "1D", "2D", "3D", "1W", "1M", "3M"}
+
+// KLineChannel 根据周期生成K线频道名，如 1m -> candle1m，4h -> candle4H，1d -> candle1D，7d -> candle1W
+// 月线按 30 天计，30 天 -> candle1M，90 天 -> candle3M；OKX 不支持的周期返回 common.ErrInvalidParams
+func KLineChannel(interval time.Duration) (string, error) {
+	const day = 24 * time.Hour
+	var bar string
+	switch {
+	case interval == time.Second:
+		return KLine1SChannel, nil
+	case interval == 30*day:
+		bar = "1M"
+	case interval == 90*day:
+		bar = "3M"
+	case interval == 7*day:
+		bar = "1W"
+	case interval >= day && interval%day == 0:
+		bar = fmt.Sprintf("%dD", interval/day)
+	case interval >= time.Hour && interval%time.Hour == 0:
+		bar = fmt.Sprintf("%dH", interval/time.Hour)
+	case interval >= time.Minute && interval%time.Minute == 0:
+		bar = fmt.Sprintf("%dm", interval/time.Minute)
+	}
+	if !slices.Contains(klineBars, bar) {
+		return "", fmt.Errorf("%w: unsupported kline interval %s", common.ErrInvalidParams, interval)
+	}
+	return "candle" + bar, nil
+}
@@ -0,0 +1,35 @@
+package okx
+
+import (
+	"hash/crc32"
+	"strings"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// ChecksumDepth 参与校验和计算的档位数
+const ChecksumDepth = 25
+
+// BookChecksum 按 OKX 的规则计算盘口校验和：取前 25 档，买卖交替拼接为 "bidPx:bidSz:askPx:askSz:..."，
+// 某一侧不足 25 档时只拼接另一侧，再对字符串取 CRC32；价格与数量必须保留推送时的原始字符串
+func BookChecksum(bids, asks []common.PriceLevel) int32 {
+	var sb strings.Builder
+	for i := 0; i < ChecksumDepth; i++ {
+		if i < len(bids) {
+			writeLevel(&sb, bids[i])
+		}
+		if i < len(asks) {
+			writeLevel(&sb, asks[i])
+		}
+	}
+	return int32(crc32.ChecksumIEEE([]byte(sb.String())))
+}
+
+func writeLevel(sb *strings.Builder, l common.PriceLevel) {
+	if sb.Len() > 0 {
+		sb.WriteByte(':')
+	}
+	sb.WriteString(l.Price.String())
+	sb.WriteByte(':')
+	sb.WriteString(l.Size.String())
+}
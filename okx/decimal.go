@@ -0,0 +1,48 @@
+package okx
+
+import "github.com/simonks2016/dex_plus/common"
+
+// Decimal 精确小数，OKX 的价格、数量都以字符串下发，可以无损转换
+type Decimal = common.Decimal
+
+func decimal(s string) Decimal {
+	d, _ := common.ParseDecimal(s)
+	return d
+}
+
+func (b BookLevel) PriceDecimal() Decimal { return decimal(b.Price) }
+func (b BookLevel) SizeDecimal() Decimal  { return decimal(b.Size) }
+
+// BidLevels 买盘档位（精确小数）
+func (o *OrderBook) BidLevels() []common.PriceLevel { return bookLevels(o.Bids) }
+
+// AskLevels 卖盘档位（精确小数）
+func (o *OrderBook) AskLevels() []common.PriceLevel { return bookLevels(o.Asks) }
+
+func bookLevels(items [][]string) []common.PriceLevel {
+	levels, _ := common.ParseLevels(items)
+	return levels
+}
+
+func (t AggregatedTrades) PxDecimal() Decimal { return decimal(t.Px) }
+func (t AggregatedTrades) SzDecimal() Decimal { return decimal(t.Sz) }
+
+func (t RawTrades) PxDecimal() Decimal { return decimal(t.Px) }
+func (t RawTrades) SzDecimal() Decimal { return decimal(t.Sz) }
+
+func (t Ticker) LastDecimal() Decimal   { return decimal(t.Last) }
+func (t Ticker) LastSzDecimal() Decimal { return decimal(t.LastSz) }
+func (t Ticker) AskPxDecimal() Decimal  { return decimal(t.AskPx) }
+func (t Ticker) AskSzDecimal() Decimal  { return decimal(t.AskSz) }
+func (t Ticker) BidPxDecimal() Decimal  { return decimal(t.BidPx) }
+func (t Ticker) BidSzDecimal() Decimal  { return decimal(t.BidSz) }
+func (t Ticker) Vol24hDecimal() Decimal { return decimal(t.Vol24h) }
+
+func (k Kline) OpenDecimal() Decimal   { return decimal(k.OpenPrice) }
+func (k Kline) HighDecimal() Decimal   { return decimal(k.HighPrice) }
+func (k Kline) LowDecimal() Decimal    { return decimal(k.LowPrice) }
+func (k Kline) CloseDecimal() Decimal  { return decimal(k.ClosePrice) }
+func (k Kline) VolumeDecimal() Decimal { return decimal(k.Volume) }
+
+func (f TradeFill) FillPxDecimal() Decimal { return decimal(f.FillPx) }
+func (f TradeFill) FillSzDecimal() Decimal { return decimal(f.FillSz) }
@@ -0,0 +1,15 @@
+package okx
+
+import "github.com/simonks2016/dex_plus/common"
+
+// NewError 按 OKX 错误码生成错误：限频返回 *common.RateLimitError，其余返回 *common.ExchangeError
+// 系统繁忙、服务暂不可用等临时故障标记为可重试
+func NewError(code, msg string) error {
+	switch code {
+	case "50011", "50061":
+		return &common.RateLimitError{Exchange: common.OKX, Code: code, Msg: msg}
+	case "50001", "50004", "50013", "50026":
+		return &common.ExchangeError{Exchange: common.OKX, Code: code, Msg: msg, Retryable: true}
+	}
+	return &common.ExchangeError{Exchange: common.OKX, Code: code, Msg: msg}
+}
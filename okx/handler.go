@@ -41,10 +41,6 @@ func ParseData[T MarketEvent](resp *Payload) ([]T, error) {
 			return nil, fmt.Errorf("unmarshal kline [][]string failed: %w", err)
 		}
 
-		if len(raw) > 0 {
-			fmt.Println(string(resp.Data))
-		}
-
 		kl, err := DecodeOKXLine(raw...) // 你的解码函数：([]OKXKline, error)
 		if err != nil {
 			return nil, err
@@ -52,6 +48,9 @@ func ParseData[T MarketEvent](resp *Payload) ([]T, error) {
 		// []OKXKline -> []T
 		ret := make([]T, len(kl))
 		for i := range kl {
+			if resp.Arg != nil && resp.Arg.InstId != nil {
+				kl[i].InstId = *resp.Arg.InstId
+			}
 			ret[i] = any(kl[i]).(T)
 		}
 		return ret, nil
@@ -86,6 +85,9 @@ func ParseData[T MarketEvent](resp *Payload) ([]T, error) {
 			if resp.Arg != nil && resp.Arg.InstId != nil {
 				ob.InstId = *resp.Arg.InstId
 			}
+			if len(resp.Action) > 0 {
+				ob.Action = resp.Action
+			}
 			ret[i] = any(ob).(T) // T 必须是 OrderBook
 		}
 		return ret, nil
@@ -6,12 +6,16 @@ import (
 	"encoding/base64"
 	"strings"
 	"time"
+
+	"github.com/simonks2016/dex_plus/clocksync"
 )
 
 type Auth struct {
 	ApiKey     string
 	Passphrase string
 	SecretKey  string
+	// Clock 与 OKX 服务器同步的时钟，签名时间戳使用校正后的时间；nil 时使用本地时间
+	Clock *clocksync.Clock
 }
 
 func NewAuth(apiKey, passphrase, secretKey string) *Auth {
@@ -22,9 +26,14 @@ func NewAuth(apiKey, passphrase, secretKey string) *Auth {
 	}
 }
 
+// Now 签名使用的当前时间
+func (auth *Auth) Now() time.Time {
+	return auth.Clock.Now()
+}
+
 // Timestamp 返回 OKX 要求的 UTC ISO8601 毫秒时间
 func (auth *Auth) Timestamp() string {
-	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	return auth.Now().UTC().Format("2006-01-02T15:04:05.000Z")
 }
 
 // Signature 生成签名
@@ -40,7 +49,15 @@ func (auth *Auth) Signature(timestamp, method, requestPath, body string) string
 }
 
 // Headers 生成请求头
+// 未配置密钥时只返回公共请求头，用于访问公共接口
 func (auth *Auth) Headers(method, requestPath, body string, extraHeaders ...ExtraHeader) map[string]string {
+	if auth == nil {
+		d := map[string]string{"Content-Type": "application/json"}
+		for _, setHeader := range extraHeaders {
+			setHeader(d)
+		}
+		return d
+	}
 	timestamp := auth.Timestamp()
 	sign := auth.Signature(timestamp, method, requestPath, body)
 
@@ -2,74 +2,108 @@ package internal
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/goccy/go-json"
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/subscription"
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/param"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/watchdog"
 )
 
 type OKXClient struct {
-	client *client.WsClient
+	client client.Client
+	cfg    *client.Config
 	auth   *Auth
 	ctx    context.Context
 
 	//
-	logger *log.Logger
+	logger *slog.Logger
 	pool   *ants.Pool
+	// inflight 已经提交到协程池、还没有执行完的回调；协程池可能由调用方传入并在多个客户端间共用，
+	// Shutdown 不能靠释放协程池等待，按客户端单独计数
+	inflight sync.WaitGroup
 
 	handlerMap map[string][]okx.Caller
-
-	authDone        atomic.Bool
-	sendTimeOut     time.Duration
-	subscribeParams [][]byte
-	isNeedAuth      bool
-	url             string
+	// orderedHandlerMap 保序订阅的回调，在读协程里直接执行，不经过协程池
+	orderedHandlerMap map[string][]okx.Caller
+
+	// handlerMu 运行时订阅、取消订阅与读协程并发访问回调表
+	handlerMu sync.RWMutex
+
+	authDone    atomic.Bool
+	sendTimeOut time.Duration
+	isNeedAuth  bool
+
+	// subscriptions 当前的订阅参数，重连后按频道重放
+	subMu         sync.Mutex
+	subscriptions []param.SubscribeChannelParams
+	// tracker 订阅状态机，按请求 id 关联订阅确认
+	tracker *subscription.Tracker
+
+	// watchdog 静默检测，未配置时为 nil
+	watchdog *watchdog.Watchdog
+	// onReconnected 自动重连成功后调用，连接池据此判断是否需要重新分配
+	onReconnected func()
 }
 
 func NewOKXClient(ctx context.Context, auth *Auth, cfg *client.Config) *OKXClient {
+	cfg.SetDispatchKey(okx.MessageKey)
 
 	cli := &OKXClient{
-		client:          client.NewWsClient(ctx, cfg),
-		auth:            auth,
-		ctx:             ctx,
-		sendTimeOut:     cfg.SendTimeout,
-		subscribeParams: make([][]byte, 0),
-		handlerMap:      make(map[string][]okx.Caller),
-		isNeedAuth:      cfg.IsNeedAuth,
-		logger:          cfg.Logger,
-		url:             cfg.URL,
+		client:            client.NewClient(ctx, cfg),
+		cfg:               cfg,
+		auth:              auth,
+		ctx:               ctx,
+		sendTimeOut:       cfg.SendTimeout,
+		handlerMap:        make(map[string][]okx.Caller),
+		orderedHandlerMap: make(map[string][]okx.Caller),
+		isNeedAuth:        cfg.IsNeedAuth,
+		logger:            client.OrDefaultLogger(cfg.Logger).With("exchange", "okx"),
 	}
+	cli.tracker = subscription.New(cfg.Subscription(), cli.sendRequest, cli.logger)
 	cli.client.SetObserver(cli)
+	if cfg.Watchdog != nil {
+		cli.watchdog = watchdog.New(*cfg.Watchdog, cli, cli.logger)
+	}
 
 	return cli
 }
 
 func (o *OKXClient) OnError(err error) {
-	//TODO implement me
-	if o.logger != nil {
-		o.logger.Println(err)
-	}
-	return
+	o.logger.Error("client error", "conn_id", o.client.ConnID(), "error", err)
 }
 
 func (o *OKXClient) OnMessage(msg []byte) error {
+	return o.OnTimedMessage(msg, time.Time{})
+}
+
+// OnTimedMessage received 为收到该帧的时间，配置了延迟统计时按推送中的时间戳记录线路延迟
+func (o *OKXClient) OnTimedMessage(msg []byte, received time.Time) error {
 
 	resp, err := okx.ConvertResponse(msg)
 	if err != nil {
-		o.logger.Printf("[error] failed to encode payload,%s", err.Error())
+		o.logger.Error("failed to decode payload", "conn_id", o.client.ConnID(), "error", err)
 		return nil
 	}
+	o.cfg.Latency.ObserveWire(resp.Timestamp(), received)
 
 	// 将消息分流到各个处理单位上
 	switch true {
 	case resp.IsSubscribe():
+		if channel, symbol := argFeed(resp.Arg); symbol != "" {
+			o.watchdog.Touch(channel, symbol)
+		}
 		return o.onSubscribe(resp.GetChannel(), resp)
 	case resp.IsEvent():
 		return o.onEvent(resp.Event, resp)
@@ -81,20 +115,30 @@ func (o *OKXClient) OnMessage(msg []byte) error {
 }
 
 func (o *OKXClient) onSubscribe(channel string, payload *okx.Payload) error {
-	callers, exist := o.handlerMap[channel]
-	if !exist || len(callers) == 0 {
+	o.handlerMu.RLock()
+	ordered := o.orderedHandlerMap[channel]
+	callers := o.handlerMap[channel]
+	o.handlerMu.RUnlock()
+
+	// 读协程按 (channel, instId) 分区，直接执行即可保证同一品种的顺序
+	for _, caller := range ordered {
+		o.callOrdered(channel, caller, payload)
+	}
+
+	if len(callers) == 0 {
 		return nil
 	}
 
-	// 如果 handlerMap 运行时可能会增删（并发写），建议 copy 一份 slice 再遍历
-	local := make([]okx.Caller, len(callers))
-	copy(local, callers)
+	// 订阅与取消订阅只会整体替换 slice，这里拿到的是一份快照
+	local := callers
 
 	for _, c := range local {
 		caller := c // 避免闭包捕获循环变量
 
 		// 关键：Submit 失败才返回 error；任务执行错误内部记录
+		o.inflight.Add(1)
 		if err := o.pool.Submit(func() {
+			defer o.inflight.Done()
 			// 防止某个 handler panic 把整个 worker 干崩（ants worker 会退出）
 			defer func() {
 				if r := recover(); r != nil {
@@ -105,6 +149,7 @@ func (o *OKXClient) onSubscribe(channel string, payload *okx.Payload) error {
 				o.OnError(err)
 			}
 		}); err != nil {
+			o.inflight.Done()
 			// 只返回“提交失败”的错误（池满 / Nonblocking / 已关闭等）
 			return err
 		}
@@ -112,6 +157,17 @@ func (o *OKXClient) onSubscribe(channel string, payload *okx.Payload) error {
 	return nil
 }
 
+func (o *OKXClient) callOrdered(channel string, caller okx.Caller, payload *okx.Payload) {
+	defer func() {
+		if r := recover(); r != nil {
+			o.OnError(fmt.Errorf("handler panic, channel=%s: %v", channel, r))
+		}
+	}()
+	if err := caller(payload); err != nil {
+		o.OnError(err)
+	}
+}
+
 func (o *OKXClient) onEvent(event string, payload *okx.Payload) error {
 
 	switch strings.ToLower(event) {
@@ -123,32 +179,18 @@ func (o *OKXClient) onEvent(event string, payload *okx.Payload) error {
 			o.sendSubscribeChannelMessage()
 		}
 	case "error":
-		if o.logger != nil {
-			o.logger.Printf("[error] %s,%s", payload.Code, payload.Msg)
+		o.logger.Error("received an error event", "conn_id", o.client.ConnID(), "id", payload.Id, "code", payload.Code, "reason", payload.Msg)
+		if payload.Id != "" {
+			// 订阅请求被拒绝时整个请求失败
+			o.tracker.Ack(payload.Id, okx.NewError(payload.Code, payload.Msg))
 		}
 	case "notice":
-		o.client.Reconnect("the okx command we ar reconnect")
+		o.logger.Warn("received a notice event, reconnecting", "conn_id", o.client.ConnID(), "reason", payload.Msg)
+		o.client.Reconnect("okx_notice: " + payload.Msg)
 	case "subscribe":
-		if o.logger != nil {
-			o.logger.Printf("[info]Successfully subscribed to “%s” of %s",
-				func() string {
-					if payload.Arg != nil {
-						return payload.Arg.Channel
-					}
-					return ""
-				}(),
-				func() string {
-					if payload.Arg != nil && payload.Arg.InstId != nil {
-						return *payload.Arg.InstId
-					}
-					if payload.Arg != nil && payload.Arg.InstFamily != nil {
-						return *payload.Arg.InstFamily
-					}
-					return "okx"
-				}(),
-			)
-
-		}
+		feed := ackFeed(payload.Arg)
+		o.tracker.AckFeed(payload.Id, feed, nil)
+		o.logger.Info("subscribed", "conn_id", o.client.ConnID(), "channel", feed.Channel, "symbol", feed.Symbol)
 
 	}
 	return nil
@@ -159,30 +201,25 @@ func (o *OKXClient) onOpEvent(event string, payload *okx.Payload) error {
 	switch strings.ToLower(event) {
 	case "order":
 		if payload.Code == "0" {
-			if o.logger != nil {
-				o.logger.Printf("[info] success to submit order \n")
-			}
+			o.logger.Info("order submitted", "conn_id", o.client.ConnID())
 		} else {
-			if o.logger != nil {
+			var errMsg []string
 
-				var errMsg []string
-
-				d, err := okx.ParseDataToMap(payload.Data)
-				if err != nil {
-					return err
-				}
-				for _, m := range d {
-					errMsg = append(errMsg, m["sMsg"].(string))
+			d, err := okx.ParseDataToMap(payload.Data)
+			if err != nil {
+				return err
+			}
+			for _, m := range d {
+				if msg, ok := m["sMsg"].(string); ok {
+					errMsg = append(errMsg, msg)
 				}
-				// 打印错误信息
-				o.logger.Printf("[info] failed to submit order,%s \n", strings.Join(errMsg, ","))
 			}
+			// 打印错误信息
+			o.logger.Error("failed to submit order", "conn_id", o.client.ConnID(), "code", payload.Code, "reason", strings.Join(errMsg, ","))
 		}
 		return nil
 	default:
-		if o.logger != nil {
-			o.logger.Printf("[info.%s] recive op message from okx,%s \n", event, string(payload.Data))
-		}
+		o.logger.Debug("received an op message", "conn_id", o.client.ConnID(), "op", event, "data", string(payload.Data))
 		return nil
 	}
 }
@@ -203,8 +240,12 @@ func buildSubParams(channel, instId, instType string) param.SubscribeChannelPara
 
 func (o *OKXClient) sendWithTimeout(data []byte) error {
 
+	if o.isNeedAuth && o.auth == nil {
+		return fmt.Errorf("okx: %w, credentials are not configured", common.ErrAuthRequired)
+	}
 	if o.isNeedAuth && !o.authDone.Load() {
-		return errors.New("authentication required. Identity verification is enabled for this API, but no valid authentication was found")
+		// 登录尚未完成，登录后可以重试
+		return fmt.Errorf("okx: login has not completed: %w", common.ErrNotConnected)
 	}
 
 	ctx, cancel := context.WithTimeout(o.ctx, o.sendTimeOut)
@@ -213,48 +254,130 @@ func (o *OKXClient) sendWithTimeout(data []byte) error {
 	return o.client.Send(ctx, data)
 }
 
-// SubscribeChannel 订阅频道
+// SubscribeChannel 订阅频道，已连接时立即发送订阅请求
 // Parameters:
-// @param string 可选
+// @string 订阅参数
 // @caller []okx.Okx 回调函数
 func (o *OKXClient) SubscribeChannel(param []byte, channel string, caller ...okx.Caller) error {
+	return o.subscribe(param, channel, false, caller...)
+}
 
-	// 将订阅参数放入到数组里面
-	o.subscribeParams = append(o.subscribeParams, param)
-	// 将处理函数放入map当中
-	o.handlerMap[channel] = append(o.handlerMap[channel], caller...)
-	return nil
+// SubscribeOrderedChannel 订阅频道，同一个 (channel, instId) 的推送按到达顺序串行回调
+func (o *OKXClient) SubscribeOrderedChannel(param []byte, channel string, caller ...okx.Caller) error {
+	return o.subscribe(param, channel, true, caller...)
 }
 
-// UnsubscribeAll 取消订阅各个频道
-// Parameters:
-// @instId string 可选
-// @instType string 可选
-func (o *OKXClient) UnsubscribeAll() error {
+func (o *OKXClient) subscribe(data []byte, channel string, ordered bool, caller ...okx.Caller) error {
+	var p param.Parameters[param.SubscribeChannelParams]
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	o.AddHandler(channel, ordered, caller...)
 
-	if len(o.handlerMap) == 0 {
+	// 未完成验证时只记录状态，验证后在 sendSubscribeChannelMessage 中重放
+	added := o.addSubscriptions(p.Args...)
+	return o.tracker.Subscribe(argFeeds(added...)...)
+}
+
+// sendRequest 发送订阅请求，请求 id 使用状态机分配的 id
+func (o *OKXClient) sendRequest(req subscription.Request) error {
+	args := o.subscriptionsOf(req.Feeds...)
+	if len(args) == 0 {
 		return nil
 	}
-	channels := make([]string, 0, len(o.handlerMap))
-	for ch := range o.handlerMap {
-		channels = append(channels, ch)
+	return o.sendWithTimeout(param.NewSubscribeParameters(args...).WithId(req.ID).Encode())
+}
+
+// SubscriptionState 订阅的当前状态，symbol 为 instId、instFamily 或 instType
+func (o *OKXClient) SubscriptionState(channel, symbol string) subscription.State {
+	return o.tracker.State(channel, symbol)
+}
+
+// SetOnSubscriptionChange 设置订阅状态变化回调
+func (o *OKXClient) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	o.tracker.SetOnChange(fn)
+}
+
+// SetOnReconnected 设置自动重连成功后的回调，首次连接不调用；需在 Connect 之前设置
+func (o *OKXClient) SetOnReconnected(fn func()) {
+	o.onReconnected = fn
+}
+
+// AddHandler 只注册回调，不发送订阅；ordered 的回调在读协程里按到达顺序直接执行
+func (o *OKXClient) AddHandler(channel string, ordered bool, caller ...okx.Caller) {
+	if len(caller) == 0 {
+		return
 	}
+	o.handlerMu.Lock()
+	defer o.handlerMu.Unlock()
+	if ordered {
+		o.orderedHandlerMap[channel] = append(slices.Clip(o.orderedHandlerMap[channel]), caller...)
+	} else {
+		o.handlerMap[channel] = append(slices.Clip(o.handlerMap[channel]), caller...)
+	}
+}
 
-	args := make([]param.SubscribeChannelParams, 0, len(channels))
-	for _, ch := range channels {
-		args = append(args, buildSubParams(ch, "", "ANY"))
+// HasChannel 频道中是否还有订阅
+func (o *OKXClient) HasChannel(channel string) bool {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+	return slices.ContainsFunc(o.subscriptions, func(arg param.SubscribeChannelParams) bool { return arg.Channel == channel })
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道
+// 频道中已经没有订阅时一并移除回调
+func (o *OKXClient) Unsubscribe(channel string, symbols ...string) error {
+	removed, empty := o.removeSubscriptions(channel, symbols...)
+	o.tracker.Remove(argFeeds(removed...)...)
+	if empty {
+		o.handlerMu.Lock()
+		delete(o.handlerMap, channel)
+		delete(o.orderedHandlerMap, channel)
+		o.handlerMu.Unlock()
 	}
+	if len(removed) == 0 || !o.authDone.Load() {
+		return nil
+	}
+	return o.sendWithTimeout(param.NewUnsubscribeParameters(removed...).Encode())
+}
 
-	data := param.NewUnsubscribeParameters(args...).Encode()
+// sendUnsubscribeAll 只发送取消订阅全部频道的请求，保留订阅状态与回调
+// 优雅关闭时读队列中还有已经收到的推送，回调要留到排空之后
+func (o *OKXClient) sendUnsubscribeAll() error {
+	o.subMu.Lock()
+	args := slices.Clone(o.subscriptions)
+	o.subMu.Unlock()
 
-	if err := o.sendWithTimeout(data); err != nil {
-		return err
+	if len(args) == 0 || !o.authDone.Load() {
+		return nil
 	}
+	return o.sendWithTimeout(param.NewUnsubscribeParameters(args...).Encode())
+}
+
+// UnsubscribeAll 取消订阅全部频道并移除回调
+func (o *OKXClient) UnsubscribeAll() error {
 
-	for _, ch := range channels {
-		delete(o.handlerMap, ch)
+	o.subMu.Lock()
+	args := o.subscriptions
+	o.subscriptions = nil
+	o.subMu.Unlock()
+
+	o.handlerMu.Lock()
+	clear(o.handlerMap)
+	clear(o.orderedHandlerMap)
+	o.handlerMu.Unlock()
+
+	for _, arg := range args {
+		if channel, symbol := argFeed(&okx.Arg{Channel: arg.Channel, InstId: arg.InstId, InstFamily: arg.InstFamily}); symbol != "" {
+			o.watchdog.Forget(channel, symbol)
+		}
 	}
-	return nil
+	o.tracker.Remove(argFeeds(args...)...)
+	if len(args) == 0 || !o.authDone.Load() {
+		return nil
+	}
+	return o.sendWithTimeout(param.NewUnsubscribeParameters(args...).Encode())
 }
 
 func (o *OKXClient) Send(msg []byte) error {
@@ -266,30 +389,84 @@ func (o *OKXClient) Connect() {
 		panic("the thread pool is nil")
 	}
 
+	o.watchdog.Start(o.ctx)
 	o.client.Start()
 }
 func (o *OKXClient) Close() {
+	o.watchdog.Stop()
 	o.client.Close()
 }
+
+// Shutdown 取消订阅并停止接收，等待已收到的消息回调完成，包括已经提交到协程池的回调；协程池由创建方释放
+func (o *OKXClient) Shutdown(ctx context.Context) error {
+	o.watchdog.Stop()
+	if err := o.client.Shutdown(ctx); err != nil {
+		return err
+	}
+	return client.Wait(ctx, &o.inflight)
+}
 func (o *OKXClient) Reconnect(reason string) {
 	o.client.Reconnect(reason)
 }
 
 func (o *OKXClient) sendSubscribeChannelMessage() {
-	// 遍历订阅
-	for _, subscribeParam := range o.subscribeParams {
-		// 发送订阅信息
-		if err := o.sendWithTimeout(subscribeParam); err != nil {
-			if o.logger != nil {
-				o.logger.Printf("[error] failed to subscribe channel: %v", err.Error())
-			}
+	o.tracker.Resume()
+	// 按频道合并成一条订阅请求
+	for _, args := range o.subscriptionsByChannel() {
+		// 发送订阅信息，等待确认
+		if err := o.tracker.Subscribe(argFeeds(args...)...); err != nil {
+			o.logger.Error("failed to subscribe channel", "conn_id", o.client.ConnID(), "channel", args[0].Channel, "error", err)
 			return
 		}
 	}
-	return
+}
+
+// Subscribe 按订阅选项选择保序或并发回调
+func (o *OKXClient) Subscribe(param []byte, channel string, opts options.SubscribeOptions, caller ...okx.Caller) error {
+	if opts.Ordered {
+		return o.SubscribeOrderedChannel(param, channel, caller...)
+	}
+	return o.SubscribeChannel(param, channel, caller...)
+}
+
+// SetLogger 替换日志记录器，需在 Connect 之前调用
+func (o *OKXClient) SetLogger(logger *slog.Logger) *OKXClient {
+	o.logger = client.OrDefaultLogger(logger).With("exchange", "okx")
+	return o
+}
+
+func (o *OKXClient) Logger() *slog.Logger {
+	return o.logger
 }
 
 func (o *OKXClient) SetThreadPool(pool *ants.Pool) *OKXClient {
 	o.pool = pool
 	return o
 }
+
+// argFeed 推送或订阅参数中的 (channel, symbol)，symbol 为 instId 或 instFamily
+func argFeed(arg *okx.Arg) (string, string) {
+	if arg == nil {
+		return "", ""
+	}
+	switch {
+	case arg.InstId != nil:
+		return arg.Channel, *arg.InstId
+	case arg.InstFamily != nil:
+		return arg.Channel, *arg.InstFamily
+	default:
+		return arg.Channel, ""
+	}
+}
+
+// ResubscribeFeed 先取消再重新订阅一个 (channel, instId)，重新订阅经过状态机等待确认；没有订阅过时返回错误
+func (o *OKXClient) ResubscribeFeed(channel, symbol string) error {
+	arg, ok := o.subscription(channel + ":" + symbol)
+	if !ok {
+		return fmt.Errorf("okx: %s %s is not subscribed: %w", channel, symbol, common.ErrInvalidParams)
+	}
+	if err := o.sendWithTimeout(param.NewUnsubscribeParameters(arg).Encode()); err != nil {
+		return err
+	}
+	return o.tracker.Subscribe(argFeeds(arg)...)
+}
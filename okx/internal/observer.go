@@ -7,26 +7,25 @@ import (
 )
 
 func (o *OKXClient) OnDisconnecting() {
-	// 取消全部订阅
-	if err := o.UnsubscribeAll(); err != nil {
-		if o.logger != nil {
-			o.logger.Printf("[error]Failed to unsubscribe channel:%v",
-				err)
-		}
+	// 只发送取消订阅，回调保留到读队列排空之后
+	if err := o.sendUnsubscribeAll(); err != nil {
+		o.logger.Error("failed to unsubscribe channels", "conn_id", o.client.ConnID(), "error", err)
 		return
 	}
-	if o.logger != nil {
-		o.logger.Printf("[info]Disconnecting from OKX(%s)...",
-			o.url)
-	}
+	o.logger.Info("disconnecting", "conn_id", o.client.ConnID(), "url", o.client.Endpoint())
+}
+func (o *OKXClient) OnDisconnected() {
+	// 重连后需要重新登录、重新订阅
+	o.authDone.Store(false)
+	o.tracker.Pause()
+	o.watchdog.Pause()
 }
-func (o *OKXClient) OnDisconnected() {}
 func (o *OKXClient) OnConnected() {
 
-	if o.logger != nil {
-		o.logger.Printf("[info]Successfully connected to OKX(%s)",
-			o.url,
-		)
+	o.logger.Info("connected", "conn_id", o.client.ConnID(), "url", o.client.Endpoint())
+	o.watchdog.Resume()
+	if o.onReconnected != nil && o.client.ConnID() > 1 {
+		o.onReconnected()
 	}
 
 	if !o.isNeedAuth {
@@ -43,16 +42,20 @@ func (o *OKXClient) OnConnected() {
 			ctx, cancel := context.WithTimeout(o.ctx, o.sendTimeOut)
 			defer cancel()
 			// 生成信息
-			data := param.NewLoginParameters(o.auth.ApiKey, o.auth.Passphrase, o.auth.SecretKey)
+			data := param.NewLoginParametersAt(o.auth.ApiKey, o.auth.Passphrase, o.auth.SecretKey, o.auth.Now())
 			// 发送消息
 			if err := o.client.Send(ctx, data); err != nil {
-				if o.logger != nil {
-					o.logger.Printf("[error] Failed to connect to the server:%s",
-						err.Error())
-				}
+				o.logger.Error("failed to send login request", "conn_id", o.client.ConnID(), "error", err)
 				return
 			}
 		}
 	}
 }
-func (o *OKXClient) OnConnecting(reason string) {}
+
+// OnEndpoint 连接上的地址变化
+func (o *OKXClient) OnEndpoint(url string) {
+	o.logger.Info("endpoint active", "url", url)
+}
+func (o *OKXClient) OnConnecting(reason string) {
+	o.logger.Info("connecting", "conn_id", o.client.ConnID(), "reason", reason)
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/goccy/go-json"
+	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/internal/shard"
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/okx/param"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+// MaxSubscriptionsPerConn 单条订阅请求不能超过 64KB，频道过多时单个连接的推送也会积压，默认每个连接订阅 300 个 (channel, instId)
+const MaxSubscriptionsPerConn = 300
+
+// OKXPool 按 (channel, instId) 数量把订阅分散到多个 OKXClient，所有连接的推送回调到同一组处理函数
+type OKXPool struct {
+	logger     *slog.Logger
+	base       *slog.Logger // SetLogger 设置的日志记录器，新建的连接同样使用
+	threadPool *ants.Pool
+	onChange   func(common.SubscriptionChange) // 订阅状态变化回调，新建的连接同样使用
+	pool       *shard.Pool[*OKXClient]
+	channels   *shard.Channels[*OKXClient, okx.Caller] // 按频道记录的回调
+}
+
+func NewOKXPool(ctx context.Context, auth *Auth, cfg *client.Config) *OKXPool {
+	p := &OKXPool{
+		logger: client.OrDefaultLogger(cfg.Logger).With("exchange", "okx"),
+	}
+	p.pool = shard.NewPool(cfg.SubscriptionLimit(MaxSubscriptionsPerConn), func(index int) *OKXClient {
+		conn := NewOKXClient(ctx, auth, cfg.ForShard(index))
+		if p.base != nil {
+			conn.SetLogger(p.base.With("shard", index))
+		}
+		conn.SetThreadPool(p.threadPool)
+		conn.SetOnSubscriptionChange(p.onChange)
+		conn.SetOnReconnected(func() { p.channels.Reconnected() })
+		return conn
+	})
+	p.channels = shard.NewChannels[*OKXClient, okx.Caller](p.pool, shard.FeedKeys)
+	return p
+}
+
+// SetLogger 替换日志记录器，需在 Connect 之前调用
+func (p *OKXPool) SetLogger(logger *slog.Logger) *OKXPool {
+	p.base = client.OrDefaultLogger(logger)
+	p.logger = p.base.With("exchange", "okx")
+	for i, conn := range p.pool.Conns() {
+		conn.SetLogger(p.base.With("shard", i))
+	}
+	return p
+}
+
+func (p *OKXPool) Logger() *slog.Logger {
+	return p.logger
+}
+
+// SetThreadPool 所有连接共用一个回调协程池
+func (p *OKXPool) SetThreadPool(pool *ants.Pool) *OKXPool {
+	p.threadPool = pool
+	for _, conn := range p.pool.Conns() {
+		conn.SetThreadPool(pool)
+	}
+	return p
+}
+
+// SetOnSubscriptionChange 设置所有连接的订阅状态变化回调
+func (p *OKXPool) SetOnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.onChange = fn
+	for _, conn := range p.pool.Conns() {
+		conn.SetOnSubscriptionChange(fn)
+	}
+}
+
+// SubscriptionState 在品种所在的连接上查询订阅状态
+func (p *OKXPool) SubscriptionState(channel, symbol string) subscription.State {
+	conn, ok := p.pool.Owner(shard.FeedKeys.Key(channel, symbol))
+	if !ok {
+		return subscription.Unsubscribed
+	}
+	return conn.SubscriptionState(channel, symbol)
+}
+
+func (p *OKXPool) Connect() {
+	p.pool.Connect()
+}
+
+func (p *OKXPool) Close() {
+	p.pool.Close()
+}
+
+// Shutdown 并发关闭全部连接，等待已收到的消息回调完成
+func (p *OKXPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(func(conn *OKXClient) error {
+		return conn.Shutdown(ctx)
+	})
+}
+
+// Reconnect 重新连接，并按订阅数量重新平均分配连接
+func (p *OKXPool) Reconnect(reason string) {
+	p.logger.Info("reconnecting all shards", "reason", reason)
+	p.channels.Rebalance()
+}
+
+// Subscribe 订阅频道，订阅参数中的 args 按连接拆分，当前连接已满时新建连接
+// caller 为空时沿用该频道已经注册的回调，用于运行时追加品种
+func (p *OKXPool) Subscribe(data []byte, channel string, opts options.SubscribeOptions, caller ...okx.Caller) error {
+	var params param.Parameters[param.SubscribeChannelParams]
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+
+	args := make(map[string]param.SubscribeChannelParams, len(params.Args))
+	symbols := make([]string, 0, len(params.Args))
+	for _, arg := range params.Args {
+		symbol := argSymbol(arg)
+		if _, ok := args[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+		args[symbol] = arg
+	}
+
+	var err error
+	p.channels.Subscribe(channel, symbols, opts.Ordered, caller, func(conn *OKXClient, symbols []string, batches []shard.Batch[okx.Caller]) {
+		for _, b := range batches {
+			conn.AddHandler(channel, b.Ordered, b.Callers...)
+		}
+		subset := make([]param.SubscribeChannelParams, 0, len(symbols))
+		for _, symbol := range symbols {
+			subset = append(subset, args[symbol])
+		}
+		if len(subset) == 0 {
+			// 不带品种的频道
+			subset = append(subset, args[""])
+		}
+		if e := conn.Subscribe(param.NewSubscribeParameters(subset...).Encode(), channel, options.SubscribeOptions{}); e != nil {
+			err = e
+		}
+	})
+	return err
+}
+
+// Resubscribe 在品种所在的连接上重新订阅，用于本地盘口校验失败后重新获取快照
+func (p *OKXPool) Resubscribe(channel string, symbols ...string) error {
+	return p.channels.Each(channel, symbols, func(conn *OKXClient, symbol string) error {
+		return conn.ResubscribeFeed(channel, symbol)
+	})
+}
+
+// HasChannel 频道是否注册过回调
+func (p *OKXPool) HasChannel(channel string) bool {
+	return p.channels.Has(channel)
+}
+
+// Unsubscribe 在品种所在的连接上取消订阅，不传品种时取消整个频道；频道中已经没有订阅时一并移除回调
+func (p *OKXPool) Unsubscribe(channel string, symbols ...string) error {
+	return p.channels.Unsubscribe(channel, symbols, func(conn *OKXClient, symbols []string) (bool, error) {
+		err := conn.Unsubscribe(channel, symbols...)
+		return conn.HasChannel(channel), err
+	})
+}
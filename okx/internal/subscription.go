@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"slices"
+
+	"github.com/simonks2016/dex_plus/internal/subscription"
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/okx/param"
+)
+
+// argKey 订阅参数的键，如 "books5:BTC-USDT"，分片与取消订阅时使用
+func argKey(arg param.SubscribeChannelParams) string {
+	if symbol := argSymbol(arg); symbol != "" {
+		return arg.Channel + ":" + symbol
+	}
+	return arg.Channel
+}
+
+// argSymbol 订阅参数中的品种，依次取 instId、instFamily、instType
+func argSymbol(arg param.SubscribeChannelParams) string {
+	switch {
+	case arg.InstId != nil:
+		return *arg.InstId
+	case arg.InstFamily != nil:
+		return *arg.InstFamily
+	case arg.InstType != nil:
+		return *arg.InstType
+	default:
+		return ""
+	}
+}
+
+// argFeeds 订阅参数对应的状态机订阅
+func argFeeds(args ...param.SubscribeChannelParams) []subscription.Feed {
+	ret := make([]subscription.Feed, 0, len(args))
+	for _, arg := range args {
+		ret = append(ret, subscription.Feed{Channel: arg.Channel, Symbol: argSymbol(arg)})
+	}
+	return ret
+}
+
+// ackFeed 订阅确认中的 arg 对应的状态机订阅
+func ackFeed(arg *okx.Arg) subscription.Feed {
+	if arg == nil {
+		return subscription.Feed{}
+	}
+	return argFeeds(param.SubscribeChannelParams{
+		Channel:    arg.Channel,
+		InstId:     arg.InstId,
+		InstFamily: arg.InstFamily,
+		InstType:   arg.InstType,
+	})[0]
+}
+
+// addSubscriptions 加入订阅集合，返回之前没有订阅过的参数
+func (o *OKXClient) addSubscriptions(args ...param.SubscribeChannelParams) []param.SubscribeChannelParams {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+
+	var added []param.SubscribeChannelParams
+	for _, arg := range args {
+		key := argKey(arg)
+		if slices.ContainsFunc(o.subscriptions, func(a param.SubscribeChannelParams) bool { return argKey(a) == key }) {
+			continue
+		}
+		o.subscriptions = append(o.subscriptions, arg)
+		added = append(added, arg)
+		// 只按 instType 订阅的频道不做静默检测
+		if channel, symbol := argFeed(&okx.Arg{Channel: arg.Channel, InstId: arg.InstId, InstFamily: arg.InstFamily}); symbol != "" {
+			o.watchdog.Watch(channel, symbol)
+		}
+	}
+	return added
+}
+
+// removeSubscriptions 从订阅集合中移除频道中的品种，symbols 为空时移除整个频道
+// 返回被移除的参数，以及该频道是否已经没有订阅
+func (o *OKXClient) removeSubscriptions(channel string, symbols ...string) ([]param.SubscribeChannelParams, bool) {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+
+	var removed []param.SubscribeChannelParams
+	empty := true
+	kept := o.subscriptions[:0]
+	for _, arg := range o.subscriptions {
+		if arg.Channel != channel {
+			kept = append(kept, arg)
+			continue
+		}
+		if len(symbols) > 0 && !slices.Contains(symbols, argSymbol(arg)) {
+			kept = append(kept, arg)
+			empty = false
+			continue
+		}
+		removed = append(removed, arg)
+		if _, symbol := argFeed(&okx.Arg{Channel: arg.Channel, InstId: arg.InstId, InstFamily: arg.InstFamily}); symbol != "" {
+			o.watchdog.Forget(channel, symbol)
+		}
+	}
+	o.subscriptions = kept
+	return removed, empty
+}
+
+// subscription 按键查找订阅参数
+func (o *OKXClient) subscription(key string) (param.SubscribeChannelParams, bool) {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+	for _, arg := range o.subscriptions {
+		if argKey(arg) == key {
+			return arg, true
+		}
+	}
+	return param.SubscribeChannelParams{}, false
+}
+
+// subscriptionsOf 状态机订阅对应的订阅参数
+func (o *OKXClient) subscriptionsOf(feeds ...subscription.Feed) []param.SubscribeChannelParams {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+	var ret []param.SubscribeChannelParams
+	for _, arg := range o.subscriptions {
+		if slices.Contains(feeds, subscription.Feed{Channel: arg.Channel, Symbol: argSymbol(arg)}) {
+			ret = append(ret, arg)
+		}
+	}
+	return ret
+}
+
+// subscriptionsByChannel 按频道分组的订阅参数，保持订阅顺序
+func (o *OKXClient) subscriptionsByChannel() [][]param.SubscribeChannelParams {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+
+	var groups [][]param.SubscribeChannelParams
+	index := make(map[string]int)
+	for _, arg := range o.subscriptions {
+		i, ok := index[arg.Channel]
+		if !ok {
+			i = len(groups)
+			index[arg.Channel] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], arg)
+	}
+	return groups
+}
@@ -1,47 +1,41 @@
 package okx
 
 import (
-	"log"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/clocksync"
+	"github.com/simonks2016/dex_plus/options"
 )
 
-func WithLogger(log *log.Logger) client.Option {
-
-	return func(cfg *client.Config) {
-		cfg.Logger = log
+func WithSendTimeout(timeout time.Duration) options.Option {
+	return func(o *options.Options) {
+		o.Config().SendTimeout = timeout
 	}
 }
 
-func WithSendTimeout(timeout time.Duration) client.Option {
-	return func(cfg *client.Config) {
-		cfg.SendTimeout = timeout
+func WithSetAuth() options.Option {
+	return func(o *options.Options) {
+		o.Config().IsNeedAuth = true
 	}
 }
 
-func WithSetAuth() client.Option {
-	return func(cfg *client.Config) {
-		cfg.IsNeedAuth = true
+func WithForbidIpV6() options.Option {
+	return func(o *options.Options) {
+		o.Config().IsForbidIPV6 = true
 	}
 }
-
-func WithForbidIpV6() client.Option {
-	return func(cfg *client.Config) {
-		cfg.IsForbidIPV6 = true
-	}
-}
-func WithNetDialer(dialer *websocket.Dialer) client.Option {
-	return func(cfg *client.Config) {
-		cfg.Dialer = dialer
+func WithNetDialer(dialer *websocket.Dialer) options.Option {
+	return func(o *options.Options) {
+		o.Config().Dialer = dialer
 	}
 }
 
-func WithSandboxEnv() client.Option {
-	return func(cfg *client.Config) {
+func WithSandboxEnv() options.Option {
+	return func(o *options.Options) {
+		cfg := o.Config()
 
 		u, err := url.Parse(cfg.URL)
 		if err != nil {
@@ -60,14 +54,15 @@ func WithSandboxEnv() client.Option {
 				u.Host += ":" + port
 			}
 		}
-		cfg.URL = u.String()
+		// 模拟盘只有一个地址，不再切换到生产环境的备用地址
+		cfg.WithURL(u.String())
 		return
 	}
 }
 
-func WithURL(url string) client.Option {
-
-	return func(cfg *client.Config) {
-		cfg.URL = url
+// WithClock 登录签名使用与 OKX 服务器同步的时钟，时钟由调用方 Start 与 Stop
+func WithClock(clock *clocksync.Clock) options.Option {
+	return func(o *options.Options) {
+		o.Config().WithClock(clock)
 	}
 }
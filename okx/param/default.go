@@ -33,6 +33,12 @@ type UnionArg interface {
 	SubscribeChannelParams | PlaceOrderParams | CancelOrder | AmendOrder | LoginParameters
 }
 
+// WithId 设置请求 id，交易所在响应中原样返回
+func (p *Parameters[T]) WithId(id string) *Parameters[T] {
+	p.Id = &id
+	return p
+}
+
 func (p *Parameters[T]) Encode() []byte {
 	d, _ := json.Marshal(p)
 	return d
@@ -16,8 +16,13 @@ type LoginParameters struct {
 }
 
 func NewLoginParameters(apiKey, passphrase, secretKey string) []byte {
+	return NewLoginParametersAt(apiKey, passphrase, secretKey, time.Now())
+}
+
+// NewLoginParametersAt 使用指定的时间生成登录消息，now 通常为与服务器同步后的时间
+func NewLoginParametersAt(apiKey, passphrase, secretKey string, now time.Time) []byte {
 	// 1) timestamp: Unix epoch seconds (string)
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	timestamp := fmt.Sprintf("%d", now.Unix())
 
 	// 2) prehash = timestamp + method + requestPath
 	method := "GET"
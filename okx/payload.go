@@ -1,13 +1,16 @@
 package okx
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-json"
 )
 
 type Payload struct {
 	Event   string          `json:"event"`
+	Action  string          `json:"action"`
 	Id      string          `json:"id"`
 	Arg     *Arg            `json:"arg,omitempty"`
 	Code    string          `json:"code"`
@@ -23,6 +26,7 @@ type Arg struct {
 	Channel    string  `json:"channel"`
 	InstId     *string `json:"instId,omitempty"`
 	InstFamily *string `json:"instFamily,omitempty"`
+	InstType   *string `json:"instType,omitempty"`
 }
 
 func (o *Payload) IsSubscribe() bool {
@@ -69,6 +73,7 @@ type OrderBook struct {
 	PrevSeqId int64      `json:"prevSeqId"`
 	SeqId     int64      `json:"seqId"`
 	InstId    string     `json:"instId"`
+	Action    string     `json:"action"`
 }
 
 type BookLevel struct {
@@ -141,6 +146,7 @@ type Kline struct {
 	VolCcy      string `json:"vol_ccy"`
 	VolCcyQuote string `json:"vol_ccy_quote"`
 	Confirm     string `json:"confirm"`
+	InstId      string `json:"-"` // 推送数组中不带品种，取自推送的 arg.instId
 }
 
 func DecodeOKXLine(raws ...[]string) ([]Kline, error) {
@@ -401,3 +407,38 @@ type OrderState struct {
 	UTime             string        `json:"uTime"`
 	LinkedAlgoOrd     LinkedAlgoOrd `json:"linkedAlgoOrd"`
 }
+
+// MessageKey 消息的 (channel, symbol) 键，用于按键合并与按键顺序分发
+func MessageKey(data []byte) string {
+	var v struct {
+		Arg struct {
+			Channel string `json:"channel"`
+			InstId  string `json:"instId"`
+		} `json:"arg"`
+	}
+	_ = json.Unmarshal(data, &v)
+	return v.Arg.Channel + ":" + v.Arg.InstId
+}
+
+// Timestamp 交易所的时间：下单等操作的响应取 outTime（微秒），推送取第一条数据的 ts（毫秒），都没有时返回零值
+func (o *Payload) Timestamp() time.Time {
+	if o.OutTime != nil {
+		if us, err := strconv.ParseInt(*o.OutTime, 10, 64); err == nil && us > 0 {
+			return time.UnixMicro(us)
+		}
+	}
+	if len(o.Data) == 0 || o.Data[0] != '[' {
+		return time.Time{}
+	}
+	var items []struct {
+		Ts string `json:"ts"`
+	}
+	if err := json.Unmarshal(o.Data, &items); err != nil || len(items) == 0 {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(items[0].Ts, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
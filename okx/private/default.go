@@ -3,21 +3,26 @@ package private
 import (
 	"context"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/internal"
 	"github.com/simonks2016/dex_plus/okx/param"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/ratelimit"
 )
 
 type Private struct {
 	client *internal.OKXClient
-	logger *log.Logger
+	logger *slog.Logger
+	pool   *ants.Pool // 内部创建的协程池，Shutdown 时释放；外部传入的由调用方释放
 }
 
-func NewPrivate(apiKey, secretKey, passphrase string, bg context.Context, pool *ants.Pool, opts ...client.Option) OKXPrivate {
+func NewPrivate(apiKey, secretKey, passphrase string, bg context.Context, pool *ants.Pool, opts ...options.Option) OKXPrivate {
 
 	var cfg = client.NewConfig()
 	cfg.SetWriteBufferSize(4000)
@@ -25,39 +30,47 @@ func NewPrivate(apiKey, secretKey, passphrase string, bg context.Context, pool *
 	cfg.SetReadWorkerNum(100)
 	cfg.SetReadTimeout(time.Second * time.Duration(10))
 	cfg.SetWriteTimeout(time.Second * time.Duration(10))
-	cfg.WithURL(okx.PrivateURL(true))
+	cfg.WithEndpoints(okx.PrivateEndpoints()...)
+	cfg.WithRateLimit(ratelimit.DefaultRegistry.Get(common.OKX), ratelimit.Wait)
 	cfg.IsNeedAuth = true
 	cfg.SendTimeout = time.Minute * time.Duration(5)
 
-	for _, opt := range opts {
-		opt(cfg)
-	}
+	// BackpressureConflate 时按 channel + instId 合并
+	cfg.SetConflateKey(okx.MessageKey)
+	options.New(cfg, opts...)
 	cfg.IsNeedAuth = true
 
+	var owned *ants.Pool
 	if pool == nil {
 		pool, _ = ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
+		owned = pool
 	}
+	auth := internal.NewAuth(apiKey, passphrase, secretKey)
+	auth.Clock = cfg.Clock
 	// 创建新的
-	cli := internal.NewOKXClient(bg,
-		internal.NewAuth(apiKey, passphrase, secretKey),
-		cfg)
+	cli := internal.NewOKXClient(bg, auth, cfg)
 	cli.SetThreadPool(pool)
 
-	return &Private{client: cli}
+	return &Private{client: cli, logger: cli.Logger(), pool: owned}
 }
 
 type OKXPrivate interface {
 	SetLogger(logger *log.Logger) OKXPrivate
+	SetSlogLogger(logger *slog.Logger) OKXPrivate
 
-	SubscribePosition(func(pos ...okx.Position) error, *int64)
-	SubscribePositionAndBalance(func(posAndBala ...okx.PositionAndBalance) error)
-	SubscribeTrade(func(trade ...okx.TradeFill) error)
-	SubscribeOrderFilled(func(orders ...okx.OrderState) error)
+	SubscribePosition(func(pos ...okx.Position) error, *int64, ...options.SubscribeOption)
+	SubscribePositionAndBalance(func(posAndBala ...okx.PositionAndBalance) error, ...options.SubscribeOption)
+	SubscribeTrade(func(trade ...okx.TradeFill) error, ...options.SubscribeOption)
+	SubscribeOrderFilled(func(orders ...okx.OrderState) error, ...options.SubscribeOption)
+	Unsubscribe(channel string) error
+	SubscriptionState(channel, symbol string) common.SubscriptionState
+	OnSubscriptionChange(fn func(common.SubscriptionChange))
 
 	PlaceOrder(...param.PlaceOrderParams) error
 	AmendOrder(...param.AmendOrder) error
 	CancelOrder(...param.CancelOrder) error
 	Connect()
 	Close()
+	Shutdown(ctx context.Context) error
 	Reconnect()
 }
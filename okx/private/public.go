@@ -1,16 +1,22 @@
 package private
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/param"
+	"github.com/simonks2016/dex_plus/options"
 )
 
 // SubscribePositionAndBalance 	订阅持仓和余额
 // parameters:
 // @handler func(posAndBala []okx.PositionAndBalance) error
-func (p *Private) SubscribePositionAndBalance(handler func(posAndBala ...okx.PositionAndBalance) error) {
+func (p *Private) SubscribePositionAndBalance(handler func(posAndBala ...okx.PositionAndBalance) error, opts ...options.SubscribeOption) {
 
 	instType := "ANY"
 	channel := "balance_and_position"
@@ -22,7 +28,7 @@ func (p *Private) SubscribePositionAndBalance(handler func(posAndBala ...okx.Pos
 		},
 	).Encode()
 
-	if err := p.client.SubscribeChannel(p1, channel, func(payload *okx.Payload) error {
+	if err := p.client.Subscribe(p1, channel, options.NewSubscribeOptions(opts...), func(payload *okx.Payload) error {
 		data, err := okx.ParseData[okx.PositionAndBalance](payload)
 		if err != nil {
 			return err
@@ -37,7 +43,7 @@ func (p *Private) SubscribePositionAndBalance(handler func(posAndBala ...okx.Pos
 // SubscribeTrade 订阅交易信息
 // parameters:
 // @handler func(trade okx.Trade) error
-func (p *Private) SubscribeTrade(handler func(trade ...okx.TradeFill) error) {
+func (p *Private) SubscribeTrade(handler func(trade ...okx.TradeFill) error, opts ...options.SubscribeOption) {
 
 	instType := "ANY"
 	channel := "fills"
@@ -50,7 +56,7 @@ func (p *Private) SubscribeTrade(handler func(trade ...okx.TradeFill) error) {
 	).Encode()
 
 	//fills
-	if err := p.client.SubscribeChannel(p1, channel, func(payload *okx.Payload) error {
+	if err := p.client.Subscribe(p1, channel, options.NewSubscribeOptions(opts...), func(payload *okx.Payload) error {
 		data, err := okx.ParseData[okx.TradeFill](payload)
 		if err != nil {
 			return err
@@ -61,7 +67,7 @@ func (p *Private) SubscribeTrade(handler func(trade ...okx.TradeFill) error) {
 	}
 }
 
-func (p *Private) SubscribePosition(handler func(pos ...okx.Position) error, updateIntervalMS *int64) {
+func (p *Private) SubscribePosition(handler func(pos ...okx.Position) error, updateIntervalMS *int64, opts ...options.SubscribeOption) {
 
 	instType := "ANY"
 	channel := "positions"
@@ -83,7 +89,7 @@ func (p *Private) SubscribePosition(handler func(pos ...okx.Position) error, upd
 	).Encode()
 
 	//positions
-	if err := p.client.SubscribeChannel(p1, channel, func(payload *okx.Payload) error {
+	if err := p.client.Subscribe(p1, channel, options.NewSubscribeOptions(opts...), func(payload *okx.Payload) error {
 		data, err := okx.ParseData[okx.Position](payload)
 		if err != nil {
 			return err
@@ -94,7 +100,7 @@ func (p *Private) SubscribePosition(handler func(pos ...okx.Position) error, upd
 	}
 }
 
-func (p *Private) SubscribeOrderFilled(handler func(orders ...okx.OrderState) error) {
+func (p *Private) SubscribeOrderFilled(handler func(orders ...okx.OrderState) error, opts ...options.SubscribeOption) {
 
 	instType := "ANY"
 	channel := "orders"
@@ -107,7 +113,7 @@ func (p *Private) SubscribeOrderFilled(handler func(orders ...okx.OrderState) er
 	).Encode()
 
 	//order
-	if err := p.client.SubscribeChannel(p1, channel, func(payload *okx.Payload) error {
+	if err := p.client.Subscribe(p1, channel, options.NewSubscribeOptions(opts...), func(payload *okx.Payload) error {
 		data, err := okx.ParseData[okx.OrderState](payload)
 		if err != nil {
 			return err
@@ -122,7 +128,12 @@ func (p *Private) SubscribeOrderFilled(handler func(orders ...okx.OrderState) er
 // parameters:
 // @logger *log.Logger
 func (p *Private) SetLogger(logger *log.Logger) OKXPrivate {
-	p.logger = logger
+	return p.SetSlogLogger(client.NewStdLogger(logger))
+}
+
+// SetSlogLogger 设置结构化日志记录器，需在 Connect 之前调用
+func (p *Private) SetSlogLogger(logger *slog.Logger) OKXPrivate {
+	p.logger = p.client.SetLogger(logger).Logger()
 	return p
 }
 
@@ -136,6 +147,13 @@ func (p *Private) Close() {
 	p.client.Close()
 }
 
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Private) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	return errors.Join(err, client.ReleasePool(ctx, p.pool))
+}
+
 // Reconnect 重新连接
 func (p *Private) Reconnect() {
 	// 升级
@@ -172,3 +190,18 @@ func (p *Private) AmendOrder(params ...param.AmendOrder) error {
 	// 发送信息
 	return p.client.Send(p1)
 }
+
+// Unsubscribe 取消订阅私有频道并移除回调，如 "orders"、"positions"
+func (p *Private) Unsubscribe(channel string) error {
+	return p.client.Unsubscribe(channel)
+}
+
+// SubscriptionState 订阅的当前状态，symbol 为 instId、instFamily 或 instType；没有订阅过的为 common.SubscriptionUnsubscribed
+func (p *Private) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认、被拒绝或确认超时；回调不能阻塞
+func (p *Private) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
+}
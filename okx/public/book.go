@@ -0,0 +1,210 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/orderbook"
+)
+
+// DefaultBookDepth 本地盘口快照回调的默认档位数
+const DefaultBookDepth = 20
+
+// BookOption 本地盘口的选项
+type BookOption func(*bookConfig)
+
+type bookConfig struct {
+	depth      int
+	onSnapshot func(market.BookSnapshot) error
+	onChange   func(market.BookDelta) error
+}
+
+// WithBookDepth 快照回调与 LocalBooks.Snapshot 默认的档位数
+func WithBookDepth(n int) BookOption {
+	return func(c *bookConfig) {
+		if n > 0 {
+			c.depth = n
+		}
+	}
+}
+
+// WithBookSnapshot 每次应用快照或增量并通过校验后，回调前 N 档的快照
+func WithBookSnapshot(fn func(market.BookSnapshot) error) BookOption {
+	return func(c *bookConfig) { c.onSnapshot = fn }
+}
+
+// WithBookChange 每次应用快照或增量并通过校验后，回调本次变化的档位；Snapshot 为 true 时表示盘口被全量替换
+func WithBookChange(fn func(market.BookDelta) error) BookOption {
+	return func(c *bookConfig) { c.onChange = fn }
+}
+
+// errNotSynced 还没有收到快照（或校验失败后等待重新订阅的快照），增量被忽略
+var errNotSynced = errors.New("book not synced")
+
+// LocalBooks 在本地维护的 OKX 盘口，按 prevSeqId/seqId 检查增量是否连续，并校验每次推送的 CRC32 校验和；
+// 不连续、校验和不一致或买卖盘交叉时丢弃该品种的盘口并重新订阅，收到新的快照后恢复，并发安全
+// 盘口保存在 orderbook.Book 中而不是 bookManager，校验和需要推送的原始字符串（见 orderbook 包说明）；
+// 推送按 (channel, instId) 保序回调，客户端构造时就开启了分区，连接后收到的第一条快照也不会与增量乱序
+type LocalBooks struct {
+	p       *Public
+	channel string
+	cfg     bookConfig
+
+	mu    sync.Mutex
+	books map[string]*orderbook.Book
+	// resyncing 已经发起重新订阅、正在等待快照的品种
+	resyncing map[string]bool
+	resyncs   atomic.Uint64
+}
+
+// SubscribeLocalBook 订阅 books、books-l2-tbt 或 books50-l2-tbt 频道并在本地维护盘口
+// books-l2-tbt 与 books50-l2-tbt 需要登录且达到对应的 VIP 等级
+func (p *Public) SubscribeLocalBook(channel string, opts ...BookOption) (*LocalBooks, error) {
+	switch channel {
+	case okx.BooksChannel, okx.BooksL2TBTChannel, okx.Books50L2TBTChannel:
+	default:
+		return nil, fmt.Errorf("%w: channel %s has no seqId or checksum", common.ErrInvalidParams, channel)
+	}
+
+	cfg := bookConfig{depth: DefaultBookDepth}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	lb := &LocalBooks{
+		p:         p,
+		channel:   channel,
+		cfg:       cfg,
+		books:     make(map[string]*orderbook.Book),
+		resyncing: make(map[string]bool),
+	}
+	// 增量必须按顺序应用
+	if err := subscribe[okx.OrderBook](channel, lb.handle, p, options.WithOrdered()); err != nil {
+		return nil, err
+	}
+	return lb, nil
+}
+
+// Snapshot 品种前 depth 档的快照，depth <= 0 时使用 WithBookDepth 的档位数；盘口未同步时返回 false
+func (lb *LocalBooks) Snapshot(instId string, depth int) (market.BookSnapshot, bool) {
+	if depth <= 0 {
+		depth = lb.cfg.depth
+	}
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	book, ok := lb.books[instId]
+	if !ok {
+		return market.BookSnapshot{}, false
+	}
+	return book.Snapshot(depth), true
+}
+
+// Synced 品种的盘口是否已经同步
+func (lb *LocalBooks) Synced(instId string) bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	_, ok := lb.books[instId]
+	return ok
+}
+
+// Resyncs 校验失败后重新订阅的次数
+func (lb *LocalBooks) Resyncs() uint64 {
+	return lb.resyncs.Load()
+}
+
+func (lb *LocalBooks) handle(books []okx.OrderBook) error {
+	var errs []error
+	for i := range books {
+		ob := &books[i]
+		snapshot, err := lb.apply(ob)
+		switch {
+		case errors.Is(err, errNotSynced):
+			continue
+		case err != nil:
+			lb.resync(ob.InstId, err)
+			continue
+		}
+
+		if lb.cfg.onChange != nil {
+			if err := lb.cfg.onChange(market.BookDelta{
+				Exchange:  common.OKX,
+				Symbol:    ob.InstId,
+				Bids:      ob.BidLevels(),
+				Asks:      ob.AskLevels(),
+				Snapshot:  ob.Action == "snapshot",
+				Timestamp: parseMilli(ob.Ts),
+			}); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if lb.cfg.onSnapshot != nil {
+			if err := lb.cfg.onSnapshot(snapshot); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apply 应用一条快照或增量并校验，返回应用后的前 N 档快照；校验失败时丢弃该品种的盘口
+func (lb *LocalBooks) apply(ob *okx.OrderBook) (market.BookSnapshot, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	book, ok := lb.books[ob.InstId]
+	if ob.Action == "snapshot" {
+		book = orderbook.New(common.OKX, ob.InstId)
+		book.Reset(ob.BidLevels(), ob.AskLevels())
+		lb.books[ob.InstId] = book
+		delete(lb.resyncing, ob.InstId)
+	} else {
+		if !ok {
+			return market.BookSnapshot{}, errNotSynced
+		}
+		if ob.PrevSeqId != book.Seq {
+			delete(lb.books, ob.InstId)
+			return market.BookSnapshot{}, fmt.Errorf("sequence gap: prevSeqId %d, local seqId %d", ob.PrevSeqId, book.Seq)
+		}
+		book.Update(ob.BidLevels(), ob.AskLevels())
+	}
+	book.Seq = ob.SeqId
+	book.Updated = parseMilli(ob.Ts)
+
+	if sum := okx.BookChecksum(book.Bids(okx.ChecksumDepth), book.Asks(okx.ChecksumDepth)); int64(sum) != ob.Checksum {
+		delete(lb.books, ob.InstId)
+		return market.BookSnapshot{}, fmt.Errorf("checksum mismatch: local %d, exchange %d", sum, ob.Checksum)
+	}
+	if book.Crossed() {
+		delete(lb.books, ob.InstId)
+		return market.BookSnapshot{}, errors.New("book crossed")
+	}
+	return book.Snapshot(lb.cfg.depth), nil
+}
+
+// resync 重新订阅品种以获取新的快照，等待快照期间不重复发起
+func (lb *LocalBooks) resync(instId string, reason error) {
+	lb.mu.Lock()
+	pending := lb.resyncing[instId]
+	lb.resyncing[instId] = true
+	lb.mu.Unlock()
+	if pending {
+		return
+	}
+
+	lb.resyncs.Add(1)
+	lb.p.logger.Warn("order book out of sync, resubscribing", "channel", lb.channel, "instId", instId, "reason", reason)
+	// 回调运行在读协程上，重新订阅需要等待发送，放到单独的协程
+	go func() {
+		if err := lb.p.client.Resubscribe(lb.channel, instId); err != nil {
+			lb.p.logger.Error("failed to resubscribe channel", "channel", lb.channel, "instId", instId, "error", err)
+			lb.mu.Lock()
+			delete(lb.resyncing, instId)
+			lb.mu.Unlock()
+		}
+	}()
+}
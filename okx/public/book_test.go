@@ -0,0 +1,100 @@
+package public
+
+import (
+	"context"
+	"hash/crc32"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+// bookMsg 构造一条盘口推送，checksum 按推送后的本地盘口计算
+func bookMsg(bids, asks [][]string, prev, seq int64, sum int32) []map[string]any {
+	return []map[string]any{{
+		"bids": bids, "asks": asks, "ts": "1700000000000",
+		"prevSeqId": prev, "seqId": seq, "checksum": sum,
+	}}
+}
+
+func levels(items [][]string) []common.PriceLevel {
+	l, _ := common.ParseLevels(items)
+	return l
+}
+
+func TestLocalBookOffline(t *testing.T) {
+	srv := dextest.NewOKXServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := NewPublic(ctx, nil,
+		options.WithURL(srv.URL()),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	p.SetInstId("BTC-USDT")
+
+	snapshots := make(chan market.BookSnapshot, 4)
+	books, err := p.SubscribeLocalBook(okx.BooksChannel, WithBookDepth(1), WithBookSnapshot(func(s market.BookSnapshot) error {
+		snapshots <- s
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Connect()
+	defer p.Close()
+
+	if _, ok := srv.WaitRequest("subscribe", okx.BooksChannel, 5*time.Second); !ok {
+		t.Fatal("no subscribe request")
+	}
+
+	bids := [][]string{{"100.0", "1", "0", "1"}, {"99.5", "2", "0", "1"}}
+	asks := [][]string{{"100.5", "3", "0", "1"}}
+	sum := okx.BookChecksum(levels(bids), levels(asks))
+	_ = srv.PushBook(okx.BooksChannel, "BTC-USDT", "snapshot", bookMsg(bids, asks, -1, 10, sum))
+
+	// 删除 100.0 的买盘
+	sum = okx.BookChecksum(levels(bids[1:]), levels(asks))
+	_ = srv.PushBook(okx.BooksChannel, "BTC-USDT", "update", bookMsg([][]string{{"100.0", "0", "0", "0"}}, nil, 10, 11, sum))
+
+	for i, want := range []string{"100.0", "99.5"} {
+		select {
+		case s := <-snapshots:
+			if len(s.Bids) != 1 || s.Bids[0].Price.String() != want {
+				t.Fatalf("snapshot %d bids = %v, want %s", i, s.Bids, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no snapshot %d", i)
+		}
+	}
+
+	// 序号不连续时丢弃盘口并重新订阅
+	_ = srv.PushBook(okx.BooksChannel, "BTC-USDT", "update", bookMsg(nil, nil, 12, 13, sum))
+	if !srv.WaitRequestCount("subscribe", okx.BooksChannel, 2, 10*time.Second) {
+		t.Fatal("did not resubscribe after sequence gap")
+	}
+	if books.Synced("BTC-USDT") || books.Resyncs() != 1 {
+		t.Fatalf("synced = %v, resyncs = %d", books.Synced("BTC-USDT"), books.Resyncs())
+	}
+
+	if _, err := p.SubscribeLocalBook(okx.Books5Channel); err == nil {
+		t.Fatal("books5 accepted")
+	}
+}
+
+func TestBookChecksum(t *testing.T) {
+	bids := levels([][]string{{"3366.1", "7"}, {"3366", "6"}})
+	asks := levels([][]string{{"3366.8", "9"}, {"3368", "8"}, {"3372", "8"}})
+	want := "3366.1:7:3366.8:9:3366:6:3368:8:3372:8"
+	if got := okx.BookChecksum(bids, asks); got != int32(crc32.ChecksumIEEE([]byte(want))) {
+		t.Fatalf("checksum = %d, want crc32(%q)", got, want)
+	}
+}
@@ -2,24 +2,33 @@ package public
 
 import (
 	"context"
+	"iter"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/market"
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/internal"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/ratelimit"
+	"github.com/simonks2016/dex_plus/stream"
 )
 
 type Public struct {
-	client     *internal.OKXClient
-	logger     *log.Logger
+	client     *internal.OKXPool
+	logger     *slog.Logger
 	instId     []string
 	instFamily []string
 	ctx        context.Context
+	pool       *ants.Pool // 内部创建的协程池，Shutdown 时释放；外部传入的由调用方释放
+	streams    stream.Group
 }
 
-func NewPublic(bg context.Context, pool *ants.Pool, opts ...client.Option) OKXPublic {
+func NewPublic(bg context.Context, pool *ants.Pool, opts ...options.Option) OKXPublic {
 
 	cfg := client.NewConfig()
 	cfg.SetWriteBufferSize(4000)
@@ -28,38 +37,58 @@ func NewPublic(bg context.Context, pool *ants.Pool, opts ...client.Option) OKXPu
 	cfg.SetReadTimeout(time.Second * time.Duration(10))
 	cfg.SetWriteTimeout(time.Second * time.Duration(10))
 	cfg.SendTimeout = time.Minute * time.Duration(10)
-	cfg.WithURL(okx.PublicURL(true))
+	cfg.WithEndpoints(okx.PublicEndpoints()...)
+	cfg.WithRateLimit(ratelimit.DefaultRegistry.Get(common.OKX), ratelimit.Wait)
 	cfg.IsNeedAuth = false
 	cfg.IsForbidIPV6 = false
 
-	for _, opt := range opts {
-		opt(cfg)
-	}
+	// BackpressureConflate 时按 channel + instId 合并
+	cfg.SetConflateKey(okx.MessageKey)
+	options.New(cfg, opts...)
 
+	var owned *ants.Pool
 	if pool == nil {
 		pool, _ = ants.NewPool(ants.DefaultAntsPoolSize, ants.WithNonblocking(true))
+		owned = pool
 	}
 
 	// 创建一个新的客户端
-	cli := internal.NewOKXClient(bg, nil, cfg)
+	cli := internal.NewOKXPool(bg, nil, cfg)
 	cli.SetThreadPool(pool)
 
 	return &Public{
 		ctx:    bg,
 		client: cli,
+		logger: cli.Logger(),
+		pool:   owned,
 	}
 }
 
 type OKXPublic interface {
+	market.MarketDataSource
+
 	SetLogger(logger *log.Logger) OKXPublic
+	SetSlogLogger(logger *slog.Logger) OKXPublic
 	SetInstId(id ...string) OKXPublic
 	SetInstFamily(id ...string) OKXPublic
 	Connect()
 	Reconnect()
 	Close()
-	SubscribeTicker(callback func(tickers []okx.Ticker) error)
-	SubscribeTrade(callback func(trade []okx.AggregatedTrades) error)
+	Shutdown(ctx context.Context) error
+	SubscribeTicker(callback func(tickers []okx.Ticker) error, opts ...options.SubscribeOption)
+	SubscribeTrade(callback func(trade []okx.AggregatedTrades) error, opts ...options.SubscribeOption)
 
-	SubscribeBook(channel string, callback func(books []okx.OrderBook) error)
+	SubscribeBook(channel string, callback func(books []okx.OrderBook) error, opts ...options.SubscribeOption)
+	SubscribeLocalBook(channel string, opts ...BookOption) (*LocalBooks, error)
+	TickersStream(ctx context.Context, opts ...stream.Option) (<-chan []okx.Ticker, error)
+	TickersSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]okx.Ticker, error]
+	TradesStream(ctx context.Context, opts ...stream.Option) (<-chan []okx.AggregatedTrades, error)
+	TradesSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]okx.AggregatedTrades, error]
+	BooksStream(ctx context.Context, channel string, opts ...stream.Option) (<-chan []okx.OrderBook, error)
+	BooksSeq(ctx context.Context, channel string, opts ...stream.Option) iter.Seq2[[]okx.OrderBook, error]
+	Subscribe(channel string, instIds ...string) error
+	Unsubscribe(channel string, instIds ...string) error
+	SubscriptionState(channel, symbol string) common.SubscriptionState
+	OnSubscriptionChange(fn func(common.SubscriptionChange))
 	ExchangeName() string
 }
@@ -0,0 +1,132 @@
+package public
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/okx"
+)
+
+var _ market.MarketDataSource = (*Public)(nil)
+
+// OnTrade 订阅统一格式的成交数据
+func (p *Public) OnTrade(handler market.TradeHandler) error {
+	p.SubscribeTrade(func(trades []okx.AggregatedTrades) error {
+		for _, t := range trades {
+			if err := handler(market.Trade{
+				Exchange:  common.OKX,
+				Symbol:    t.InstId,
+				TradeId:   t.TradeId,
+				Price:     t.PxDecimal(),
+				Size:      t.SzDecimal(),
+				Side:      market.Side(t.Side),
+				Timestamp: parseMilli(t.Ts),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnBookSnapshot 订阅 books5 频道，每次推送都是完整的5档快照
+func (p *Public) OnBookSnapshot(_ time.Duration, handler market.BookSnapshotHandler) error {
+	p.SubscribeBook(okx.Books5Channel, func(books []okx.OrderBook) error {
+		for _, book := range books {
+			if err := handler(market.BookSnapshot{
+				Exchange:  common.OKX,
+				Symbol:    book.InstId,
+				Bids:      book.BidLevels(),
+				Asks:      book.AskLevels(),
+				Timestamp: parseMilli(book.Ts),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnBookDelta 订阅 books 频道，首包为全量快照，之后为增量
+func (p *Public) OnBookDelta(handler market.BookDeltaHandler) error {
+	p.SubscribeBook(okx.BooksChannel, func(books []okx.OrderBook) error {
+		for _, book := range books {
+			if err := handler(market.BookDelta{
+				Exchange:  common.OKX,
+				Symbol:    book.InstId,
+				Bids:      book.BidLevels(),
+				Asks:      book.AskLevels(),
+				Snapshot:  book.Action == "snapshot",
+				Timestamp: parseMilli(book.Ts),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnTicker 订阅统一格式的Tick行情
+func (p *Public) OnTicker(handler market.TickerHandler) error {
+	p.SubscribeTicker(func(tickers []okx.Ticker) error {
+		for _, t := range tickers {
+			if err := handler(market.Ticker{
+				Exchange:  common.OKX,
+				Symbol:    t.InstId,
+				Last:      t.LastDecimal(),
+				BidPrice:  t.BidPxDecimal(),
+				BidSize:   t.BidSzDecimal(),
+				AskPrice:  t.AskPxDecimal(),
+				AskSize:   t.AskSzDecimal(),
+				Volume24h: t.Vol24hDecimal(),
+				Timestamp: parseMilli(t.Ts),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// OnCandle 订阅统一格式的K线，Symbol 取自推送的 arg.instId；OKX 不支持的周期返回 common.ErrInvalidParams
+func (p *Public) OnCandle(interval time.Duration, handler market.CandleHandler) error {
+	channel, err := okx.KLineChannel(interval)
+	if err != nil {
+		return err
+	}
+
+	p.SubscribeKline(channel, func(klines []okx.Kline) error {
+		for _, k := range klines {
+			if err := handler(market.Candle{
+				Exchange: common.OKX,
+				Symbol:   k.InstId,
+				Interval: interval,
+				Start:    parseMilli(k.Ts),
+				Open:     k.OpenDecimal(),
+				High:     k.HighDecimal(),
+				Low:      k.LowDecimal(),
+				Close:    k.CloseDecimal(),
+				Volume:   k.VolumeDecimal(),
+				Closed:   k.Confirm == "1",
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+func parseMilli(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
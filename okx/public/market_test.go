@@ -0,0 +1,109 @@
+package public
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+func TestOnTradeOffline(t *testing.T) {
+	srv := dextest.NewOKXServer()
+	defer srv.Close()
+
+	p := NewPublic(t.Context(), nil,
+		options.WithURL(srv.URL()),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	p.SetInstId("BTC-USDT")
+	defer p.Close()
+
+	dextest.ExpectOfflineTrade(t, srv.Server, p, okx.TradesChannel, func() error {
+		return srv.Push(okx.TradesChannel, "BTC-USDT", []map[string]string{{
+			"instId": "BTC-USDT", "tradeId": "1", "px": "42000.10", "sz": "0.01", "side": "sell", "ts": "1700000000000",
+		}})
+	}, market.Trade{
+		Exchange: common.OKX, Symbol: "BTC-USDT", TradeId: "1",
+		Price: common.MustDecimal("42000.10"), Size: common.MustDecimal("0.01"),
+		Side: market.Sell, Timestamp: time.UnixMilli(1700000000000),
+	})
+
+	// 断线后应当重新订阅
+	srv.Disconnect()
+	if !srv.WaitRequestCount("subscribe", okx.TradesChannel, 2, 10*time.Second) {
+		t.Fatal("did not resubscribe after disconnect")
+	}
+}
+
+func TestOnCandleOffline(t *testing.T) {
+	srv := dextest.NewOKXServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := NewPublic(ctx, nil,
+		options.WithURL(srv.URL()),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	p.SetInstId("BTC-USDT", "ETH-USDT")
+
+	if err := p.OnCandle(90*time.Minute, func(market.Candle) error { return nil }); !errors.Is(err, common.ErrInvalidParams) {
+		t.Fatalf("OnCandle(90m) = %v, want ErrInvalidParams", err)
+	}
+
+	candles := make(chan market.Candle, 1)
+	if err := p.OnCandle(time.Minute, func(c market.Candle) error {
+		candles <- c
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p.Connect()
+	defer p.Close()
+
+	if !srv.WaitRequestCount("subscribe", "candle1m", 2, 5*time.Second) {
+		t.Fatal("no subscribe request")
+	}
+	// 订阅了多个品种时 Symbol 仍然取自推送
+	_ = srv.Push("candle1m", "ETH-USDT", [][]string{{"1700000000000", "2000.1", "2001", "1999", "2000.5", "3", "6000", "6000", "0"}})
+
+	select {
+	case c := <-candles:
+		if c.Symbol != "ETH-USDT" || c.Close.String() != "2000.5" || c.Closed {
+			t.Fatalf("unexpected candle: %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no candle received")
+	}
+}
+
+func TestKLineChannel(t *testing.T) {
+	for interval, want := range map[time.Duration]string{
+		time.Second:         "candle1s",
+		15 * time.Minute:    "candle15m",
+		4 * time.Hour:       "candle4H",
+		12 * time.Hour:      "candle12H",
+		3 * 24 * time.Hour:  "candle3D",
+		7 * 24 * time.Hour:  "candle1W",
+		30 * 24 * time.Hour: "candle1M",
+		90 * 24 * time.Hour: "candle3M",
+	} {
+		if got, err := okx.KLineChannel(interval); err != nil || got != want {
+			t.Fatalf("KLineChannel(%s) = %q, %v, want %q", interval, got, err, want)
+		}
+	}
+	for _, interval := range []time.Duration{0, 2 * time.Second, 90 * time.Minute, 8 * time.Hour, 5 * 24 * time.Hour} {
+		if got, err := okx.KLineChannel(interval); err == nil {
+			t.Fatalf("KLineChannel(%s) = %q, want error", interval, got)
+		}
+	}
+}
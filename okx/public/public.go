@@ -1,17 +1,30 @@
 package public
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
 
 	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/param"
+	"github.com/simonks2016/dex_plus/options"
 )
 
 // SetLogger 设置日志记录器
 // parameters:
 // @logger *log.logger
 func (ws *Public) SetLogger(logger *log.Logger) OKXPublic {
-	ws.logger = logger
+	return ws.SetSlogLogger(client.NewStdLogger(logger))
+}
+
+// SetSlogLogger 设置结构化日志记录器，需在 Connect 之前调用
+func (ws *Public) SetSlogLogger(logger *slog.Logger) OKXPublic {
+	ws.logger = ws.client.SetLogger(logger).Logger()
 	return ws
 }
 
@@ -39,12 +52,21 @@ func (p *Public) Connect() {
 // Close 关闭并且取消订阅
 func (p *Public) Close() {
 	p.client.Close()
+	p.streams.Close()
+}
+
+// Shutdown 优雅关闭：取消订阅并停止接收，等待已收到的消息回调完成、释放协程池后返回
+// ctx 到期时立即关闭连接，返回 ctx 的错误
+func (p *Public) Shutdown(ctx context.Context) error {
+	err := p.client.Shutdown(ctx)
+	// 回调处理完之后再关闭 Stream，阻塞在 Stream 上的回调随之退出
+	p.streams.Close()
+	return errors.Join(err, client.ReleasePool(ctx, p.pool))
 }
 
-// Reconnect 重新连接
+// Reconnect 重新连接，订阅超过单连接上限时按订阅数量重新分配连接
 func (p *Public) Reconnect() {
-	// 升级
-	p.client.Reconnect("")
+	p.client.Reconnect("manual")
 }
 
 func (p *Public) buildSubscribeArgs(channel string) []param.SubscribeChannelParams {
@@ -75,7 +97,7 @@ func (p *Public) buildSubscribeArgs(channel string) []param.SubscribeChannelPara
 }
 
 // subscribe: 通用订阅逻辑
-func subscribe[T okx.MarketEvent](channel string, callback func([]T) error, p *Public) {
+func subscribe[T okx.MarketEvent](channel string, callback func([]T) error, p *Public, opts ...options.SubscribeOption) error {
 	caller := func(resp *okx.Payload) error {
 		data, err := okx.ParseData[T](resp)
 		if err != nil {
@@ -87,41 +109,67 @@ func subscribe[T okx.MarketEvent](channel string, callback func([]T) error, p *P
 	args := p.buildSubscribeArgs(channel)
 	payload := param.NewSubscribeParameters(args...).Encode()
 
-	if err := p.client.SubscribeChannel(payload, channel, caller); err != nil {
-		if p.logger != nil {
-			p.logger.Printf("[ERROR] %s", err)
-		}
-		return
+	if err := p.client.Subscribe(payload, channel, options.NewSubscribeOptions(opts...), caller); err != nil {
+		p.logger.Error("failed to subscribe channel", "channel", channel, "error", err)
+		return err
 	}
+	return nil
 }
 
 // SubscribeKline 订阅k线频道
-func (p *Public) SubscribeKline(channel string, callback func([]okx.Kline) error) {
-	subscribe[okx.Kline](channel, callback, p)
+func (p *Public) SubscribeKline(channel string, callback func([]okx.Kline) error, opts ...options.SubscribeOption) {
+	subscribe[okx.Kline](channel, callback, p, opts...)
 }
 
 // SubscribeTrade 订阅公共聚合交易数据
-func (p *Public) SubscribeTrade(callback func(trade []okx.AggregatedTrades) error) {
+func (p *Public) SubscribeTrade(callback func(trade []okx.AggregatedTrades) error, opts ...options.SubscribeOption) {
 	//TODO implement me
-	subscribe[okx.AggregatedTrades](okx.TradesChannel, callback, p)
+	subscribe[okx.AggregatedTrades](okx.TradesChannel, callback, p, opts...)
 }
 
 // SubscribeTradeAll 订阅公共逐笔交易数据
-func (p *Public) SubscribeTradeAll(callback func(trade []okx.RawTrades) error) {
+func (p *Public) SubscribeTradeAll(callback func(trade []okx.RawTrades) error, opts ...options.SubscribeOption) {
 	//TODO implement me
-	subscribe[okx.RawTrades]("trades-all", callback, p)
+	subscribe[okx.RawTrades]("trades-all", callback, p, opts...)
 }
 
 // SubscribeBook 订阅实时盘口数据
-func (p *Public) SubscribeBook(channel string, callback func([]okx.OrderBook) error) {
-	subscribe[okx.OrderBook](channel, callback, p)
+func (p *Public) SubscribeBook(channel string, callback func([]okx.OrderBook) error, opts ...options.SubscribeOption) {
+	subscribe[okx.OrderBook](channel, callback, p, opts...)
 }
 
 // SubscribeTicker 订阅Tick数据行情
-func (p *Public) SubscribeTicker(callback func([]okx.Ticker) error) {
-	subscribe[okx.Ticker](okx.TickersChannel, callback, p)
+func (p *Public) SubscribeTicker(callback func([]okx.Ticker) error, opts ...options.SubscribeOption) {
+	subscribe[okx.Ticker](okx.TickersChannel, callback, p, opts...)
 }
 
 func (p *Public) ExchangeName() string {
 	return "okx"
 }
+
+// Subscribe 为已经通过 Subscribe* 订阅过的频道追加品种，沿用该频道的回调；连接后调用立即生效
+func (p *Public) Subscribe(channel string, instIds ...string) error {
+	if !p.client.HasChannel(channel) {
+		return fmt.Errorf("channel %s has no handler, subscribe it with a Subscribe* method first", channel)
+	}
+	args := make([]param.SubscribeChannelParams, 0, len(instIds))
+	for _, id := range instIds {
+		args = append(args, param.NewInstIdArg(id, channel))
+	}
+	return p.client.Subscribe(param.NewSubscribeParameters(args...).Encode(), channel, options.SubscribeOptions{})
+}
+
+// Unsubscribe 取消订阅频道中的品种，不传品种时取消整个频道；频道中已经没有订阅时一并移除回调
+func (p *Public) Unsubscribe(channel string, instIds ...string) error {
+	return p.client.Unsubscribe(channel, instIds...)
+}
+
+// SubscriptionState 订阅的当前状态，symbol 为 instId、instFamily 或 instType；没有订阅过的为 common.SubscriptionUnsubscribed
+func (p *Public) SubscriptionState(channel, symbol string) common.SubscriptionState {
+	return p.client.SubscriptionState(channel, symbol)
+}
+
+// OnSubscriptionChange 订阅状态变化时回调，如收到确认、被拒绝或确认超时；回调不能阻塞
+func (p *Public) OnSubscriptionChange(fn func(common.SubscriptionChange)) {
+	p.client.SetOnSubscriptionChange(fn)
+}
@@ -0,0 +1,91 @@
+package public
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/simonks2016/dex_plus/dextest"
+	"github.com/simonks2016/dex_plus/market"
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/options"
+)
+
+// TestShutdownDrainsOffline 关闭时读队列中已经收到的推送仍然交给回调，
+// 包括保序回调与提交到调用方协程池的回调
+func TestShutdownDrainsOffline(t *testing.T) {
+	srv := dextest.NewOKXServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, _ := ants.NewPool(10)
+	defer pool.Release()
+
+	p := NewPublic(ctx, pool,
+		options.WithURL(srv.URL()),
+		options.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	p.SetInstId("BTC-USDT")
+
+	// 回调阻塞到取消订阅的请求发出之后，推送堆积在读队列与协程池中
+	release := make(chan struct{})
+	var changes, trades atomic.Int32
+	if _, err := p.SubscribeLocalBook(okx.BooksChannel, WithBookChange(func(market.BookDelta) error {
+		<-release
+		changes.Add(1)
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.OnTrade(func(market.Trade) error {
+		<-release
+		trades.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p.Connect()
+	defer p.Close()
+
+	for _, channel := range []string{okx.BooksChannel, okx.TradesChannel} {
+		if _, ok := srv.WaitRequest("subscribe", channel, 5*time.Second); !ok {
+			t.Fatalf("no subscribe request for %s", channel)
+		}
+	}
+
+	const n = 5
+	bids := [][]string{{"100.0", "1", "0", "1"}}
+	asks := [][]string{{"100.5", "3", "0", "1"}}
+	sum := okx.BookChecksum(levels(bids), levels(asks))
+	for i := range n {
+		_ = srv.PushBook(okx.BooksChannel, "BTC-USDT", "snapshot", bookMsg(bids, asks, -1, int64(i+1), sum))
+		_ = srv.Push(okx.TradesChannel, "BTC-USDT", []map[string]string{{
+			"instId": "BTC-USDT", "tradeId": "1", "px": "42000.10", "sz": "0.01", "side": "sell", "ts": "1700000000000",
+		}})
+	}
+	// 等待推送读入队列
+	time.Sleep(200 * time.Millisecond)
+
+	go func() {
+		srv.WaitRequest("unsubscribe", okx.BooksChannel, 5*time.Second)
+		close(release)
+	}()
+
+	sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer scancel()
+	if err := p.Shutdown(sctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := changes.Load(); got != n {
+		t.Fatalf("book callbacks = %d, want %d", got, n)
+	}
+	if got := trades.Load(); got != n {
+		t.Fatalf("trade callbacks = %d, want %d", got, n)
+	}
+}
@@ -0,0 +1,78 @@
+package public
+
+import (
+	"context"
+	"iter"
+
+	"github.com/simonks2016/dex_plus/okx"
+	"github.com/simonks2016/dex_plus/options"
+	"github.com/simonks2016/dex_plus/stream"
+)
+
+// openStream 按 (channel, instId) 保序订阅并写入 Stream
+// ctx 结束或客户端关闭时 channel 关闭；ctx 结束后订阅仍然保留，不再需要时调用 Unsubscribe
+func openStream[T okx.MarketEvent](ctx context.Context, p *Public, channel string, opts []stream.Option) (<-chan []T, error) {
+	return stream.Subscribe(ctx, &p.streams, func(push func([]T) error) error {
+		return subscribe[T](channel, push, p, options.WithOrdered())
+	}, opts...)
+}
+
+// TradesStream 以 channel 的形式订阅公共聚合交易数据
+func (p *Public) TradesStream(ctx context.Context, opts ...stream.Option) (<-chan []okx.AggregatedTrades, error) {
+	return openStream[okx.AggregatedTrades](ctx, p, okx.TradesChannel, opts)
+}
+
+// TradesSeq 以迭代器的形式订阅公共聚合交易数据，退出循环时关闭
+func (p *Public) TradesSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]okx.AggregatedTrades, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []okx.AggregatedTrades, error) {
+		return p.TradesStream(ctx, opts...)
+	})
+}
+
+// TradesAllStream 以 channel 的形式订阅公共逐笔交易数据
+func (p *Public) TradesAllStream(ctx context.Context, opts ...stream.Option) (<-chan []okx.RawTrades, error) {
+	return openStream[okx.RawTrades](ctx, p, okx.TradesAllChannel, opts)
+}
+
+// TradesAllSeq 以迭代器的形式订阅公共逐笔交易数据，退出循环时关闭
+func (p *Public) TradesAllSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]okx.RawTrades, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []okx.RawTrades, error) {
+		return p.TradesAllStream(ctx, opts...)
+	})
+}
+
+// TickersStream 以 channel 的形式订阅Tick数据行情
+func (p *Public) TickersStream(ctx context.Context, opts ...stream.Option) (<-chan []okx.Ticker, error) {
+	return openStream[okx.Ticker](ctx, p, okx.TickersChannel, opts)
+}
+
+// TickersSeq 以迭代器的形式订阅Tick数据行情，退出循环时关闭
+func (p *Public) TickersSeq(ctx context.Context, opts ...stream.Option) iter.Seq2[[]okx.Ticker, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []okx.Ticker, error) {
+		return p.TickersStream(ctx, opts...)
+	})
+}
+
+// BooksStream 以 channel 的形式订阅盘口数据，channel 为盘口频道名，如 okx.BooksChannel、okx.Books5Channel
+func (p *Public) BooksStream(ctx context.Context, channel string, opts ...stream.Option) (<-chan []okx.OrderBook, error) {
+	return openStream[okx.OrderBook](ctx, p, channel, opts)
+}
+
+// BooksSeq 以迭代器的形式订阅盘口数据，退出循环时关闭
+func (p *Public) BooksSeq(ctx context.Context, channel string, opts ...stream.Option) iter.Seq2[[]okx.OrderBook, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []okx.OrderBook, error) {
+		return p.BooksStream(ctx, channel, opts...)
+	})
+}
+
+// KlinesStream 以 channel 的形式订阅k线，channel 为k线频道名，可以用 okx.KLineChannel 生成
+func (p *Public) KlinesStream(ctx context.Context, channel string, opts ...stream.Option) (<-chan []okx.Kline, error) {
+	return openStream[okx.Kline](ctx, p, channel, opts)
+}
+
+// KlinesSeq 以迭代器的形式订阅k线，退出循环时关闭
+func (p *Public) KlinesSeq(ctx context.Context, channel string, opts ...stream.Option) iter.Seq2[[]okx.Kline, error] {
+	return stream.Seq(ctx, func(ctx context.Context) (<-chan []okx.Kline, error) {
+		return p.KlinesStream(ctx, channel, opts...)
+	})
+}
@@ -0,0 +1,6 @@
+package response
+
+// SystemTime GET /api/v5/public/time
+type SystemTime struct {
+	Ts string `json:"ts"`
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/goccy/go-json"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/httpClient"
+	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/response"
 )
 
@@ -27,11 +29,8 @@ func OKXCallback[T any](resultCh chan<- asyncResult[T]) httpClient.Callback {
 			return
 		}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			resultCh <- asyncResult[T]{
-				zero,
-				fmt.Errorf("http status=%d, body=%s", resp.StatusCode, string(resp.Body)),
-			}
+		if err := resp.StatusError(common.OKX); err != nil {
+			resultCh <- asyncResult[T]{zero, err}
 			return
 		}
 
@@ -42,15 +41,7 @@ func OKXCallback[T any](resultCh chan<- asyncResult[T]) httpClient.Callback {
 		}
 
 		if out.Code != "0" {
-			resultCh <- asyncResult[T]{
-				out.Data,
-				fmt.Errorf("okx code=%s%s", out.Code, func() string {
-					if len(out.Msg) >= 0 {
-						return ""
-					}
-					return fmt.Sprintf(" , msg=%s", out.Msg)
-				}()),
-			}
+			resultCh <- asyncResult[T]{out.Data, okx.NewError(out.Code, out.Msg)}
 			return
 		}
 		resultCh <- asyncResult[T]{out.Data, nil}
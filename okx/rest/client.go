@@ -1,14 +1,19 @@
 package rest
 
 import (
-	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/simonks2016/dex_plus/clocksync"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/httpClient"
+	"github.com/simonks2016/dex_plus/metrics"
+	"github.com/simonks2016/dex_plus/okx"
 	"github.com/simonks2016/dex_plus/okx/internal"
 	"github.com/simonks2016/dex_plus/okx/param"
 	"github.com/simonks2016/dex_plus/okx/response"
+	"github.com/simonks2016/dex_plus/ratelimit"
 )
 
 type Client struct {
@@ -16,34 +21,43 @@ type Client struct {
 	auth      *internal.Auth
 	BaseUrl   string
 	isSandBox bool
+	metrics   metrics.MetricsSink
+	logger    *slog.Logger
+	clock     *clocksync.Clock
+	limiter   *ratelimit.Limiter
+	limitMode ratelimit.Mode
 }
 
 func (c *Client) PlaceOrder(params ...param.PlaceOrderParams) error {
 
 	if !c.isSandBox {
-		fmt.Println("⚠️ LIVE TRADING ENABLED - Real orders will be executed")
+		c.logger.Warn("live trading enabled, real orders will be executed", "orders", len(params))
 	}
 
 	if len(params) == 0 {
-		return errors.New("params is empty")
+		return fmt.Errorf("%w: params is empty", common.ErrInvalidParams)
 	}
 	if len(params) > 20 {
-		return errors.New("a maximum of 20 orders can be canceled at once")
+		return fmt.Errorf("%w: a maximum of 20 orders at once", common.ErrInvalidParams)
 	}
 	// 生成path
 	path := buildPath("/api/v5/trade/batch-orders")
+	instIds := make([]string, len(params))
+	for i, p := range params {
+		instIds[i] = instKey(p.InstId, p.InstIdCode)
+	}
 	// POST请求
-	if results, err := doPOST[[]response.ResultAsPlaceOrder](c.BaseUrl, path, c, params); err != nil {
+	if results, err := doPOST[[]response.ResultAsPlaceOrder](c.BaseUrl, path, c, params, instIds...); err != nil {
 		for _, r := range results {
 			if r.SCode != "0" {
-				return fmt.Errorf("%v, error_msg = %s", err, r.SMsg)
+				return fmt.Errorf("%w, order: %w", err, okx.NewError(r.SCode, r.SMsg))
 			}
 		}
 		return err
 	} else {
 		for _, r := range results {
 			if r.SCode != "0" {
-				return errors.New(r.SMsg)
+				return okx.NewError(r.SCode, r.SMsg)
 			}
 		}
 		return nil
@@ -52,21 +66,25 @@ func (c *Client) PlaceOrder(params ...param.PlaceOrderParams) error {
 
 func (c *Client) CancelOrder(params ...param.CancelOrder) error {
 	if len(params) == 0 {
-		return errors.New("params is empty")
+		return fmt.Errorf("%w: params is empty", common.ErrInvalidParams)
 	}
 	if len(params) > 20 {
-		return errors.New("a maximum of 20 orders can be canceled at once")
+		return fmt.Errorf("%w: a maximum of 20 orders at once", common.ErrInvalidParams)
 	}
 	// 生成Path
 	path := buildPath("/api/v5/trade/cancel-batch-orders")
+	instIds := make([]string, len(params))
+	for i, p := range params {
+		instIds[i] = instKey(p.InstId, p.InstIdCode)
+	}
 
 	// 发送POST请求
-	if results, err := doPOST[[]response.ResultAsCancelOrder](c.BaseUrl, path, c, params); err != nil {
+	if results, err := doPOST[[]response.ResultAsCancelOrder](c.BaseUrl, path, c, params, instIds...); err != nil {
 		return err
 	} else {
 		for _, r := range results {
 			if r.SCode != "0" {
-				return errors.New(r.SMsg)
+				return okx.NewError(r.SCode, r.SMsg)
 			}
 		}
 		return nil
@@ -82,7 +100,7 @@ func (c *Client) GetInstruments(
 		return nil, fmt.Errorf("client is nil")
 	}
 	if instType == "" {
-		return nil, fmt.Errorf("instType is required")
+		return nil, fmt.Errorf("%w: instType is required", common.ErrInvalidParams)
 	}
 
 	params := []QueryParam{
@@ -132,7 +150,7 @@ func (c *Client) GetOrderStatus(
 ) ([]response.OrderStatus, error) {
 
 	if instId == "" {
-		return nil, fmt.Errorf("instId is required")
+		return nil, fmt.Errorf("%w: instId is required", common.ErrInvalidParams)
 	}
 	params := []QueryParam{
 		WithInstId(instId),
@@ -142,24 +160,36 @@ func (c *Client) GetOrderStatus(
 	// 生成path
 	path := buildPath("/api/v5/trade/order", params...)
 	// GET请求
-	return doGET[[]response.OrderStatus](c.BaseUrl, path, c)
+	return doGET[[]response.OrderStatus](c.BaseUrl, path, c, instId)
 }
 
 func NewOKXRestClient(opts ...Option) OKXRestAPI {
 
 	cli := &Client{
-		client: httpClient.NewClient(httpClient.Config{
-			WorkerSize: 10,
-			QueueSize:  100,
-			Timeout:    time.Second * time.Duration(30),
-		}),
 		auth:    nil,
 		BaseUrl: "https://www.okx.com",
+		logger:  slog.Default(),
+		limiter: ratelimit.DefaultRegistry.Get(common.OKX),
 	}
 
 	for _, opt := range opts {
 		opt(cli)
 	}
+	cli.logger = cli.logger.With("exchange", "okx")
+	// WithAuth 与 WithClock 的顺序不固定，在选项之后设置
+	if cli.auth != nil {
+		cli.auth.Clock = cli.clock
+	}
+	// 选项里可能指定了 metrics，需要在选项之后创建
+	cli.client = httpClient.NewClient(httpClient.Config{
+		WorkerSize:    10,
+		QueueSize:     100,
+		Timeout:       time.Second * time.Duration(30),
+		Metrics:       cli.metrics,
+		Exchange:      common.OKX,
+		RateLimiter:   cli.limiter,
+		RateLimitMode: cli.limitMode,
+	})
 	// 启动client
 	cli.client.Run()
 	// 返回
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/okx/response"
+)
+
+// InstrumentLoader 通过 /api/v5/public/instruments 加载 OKX 产品信息
+// 期权需要指定 instFamily，默认只加载现货、永续和交割合约
+type InstrumentLoader struct {
+	api       OKXRestAPI
+	instTypes []string
+}
+
+func NewInstrumentLoader(api OKXRestAPI, instTypes ...string) *InstrumentLoader {
+	if len(instTypes) == 0 {
+		instTypes = []string{"SPOT", "SWAP", "FUTURES"}
+	}
+	return &InstrumentLoader{api: api, instTypes: instTypes}
+}
+
+func (l *InstrumentLoader) Exchange() string { return common.OKX }
+
+func (l *InstrumentLoader) LoadInstruments(ctx context.Context) ([]common.Instrument, error) {
+	ret := make([]common.Instrument, 0)
+
+	for _, instType := range l.instTypes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		items, err := l.api.GetInstruments(instType)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			ret = append(ret, ToInstrument(item))
+		}
+	}
+	return ret, nil
+}
+
+// ToInstrument 转换为统一的产品信息
+func ToInstrument(item response.Instruments) common.Instrument {
+	inst := common.Instrument{
+		Exchange:   common.OKX,
+		Symbol:     item.InstId,
+		Base:       item.BaseCcy,
+		Quote:      item.QuoteCcy,
+		Settle:     item.SettleCcy,
+		OptionType: item.OptType,
+	}
+
+	switch item.InstType {
+	case "SWAP":
+		inst.Type = common.InstrumentSwap
+	case "FUTURES":
+		inst.Type = common.InstrumentFuture
+	case "OPTION":
+		inst.Type = common.InstrumentOption
+	default:
+		inst.Type = common.InstrumentSpot
+	}
+
+	// 衍生品的 baseCcy/quoteCcy 为空，从标的指数（如 BTC-USDT）中取
+	if inst.Base == "" || inst.Quote == "" {
+		uly := item.Uly
+		if uly == "" {
+			uly = item.InstFamily
+		}
+		if parts := strings.Split(uly, "-"); len(parts) >= 2 {
+			inst.Base, inst.Quote = parts[0], parts[1]
+		}
+	}
+
+	inst.TickSize, _ = common.ParseDecimal(item.TickSz)
+	inst.LotSize, _ = common.ParseDecimal(item.LotSz)
+	inst.MinSize, _ = common.ParseDecimal(item.MinSz)
+	inst.ContractValue, _ = common.ParseDecimal(item.CtVal)
+	inst.Strike, _ = common.ParseDecimal(item.Stk)
+
+	if ms, err := strconv.ParseInt(item.ExpTime, 10, 64); err == nil && ms > 0 {
+		inst.Expiry = time.UnixMilli(ms).UTC()
+	}
+	return inst
+}
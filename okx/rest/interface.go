@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"time"
+
 	"github.com/simonks2016/dex_plus/okx/param"
 	"github.com/simonks2016/dex_plus/okx/response"
 )
@@ -11,6 +13,7 @@ type OKXRestAPI interface {
 	GetPositions(...QueryParam) ([]response.Position, error)
 	GetBalance(...QueryParam) ([]response.AccountBalance, error)
 	GetOrderStatus(instId string, queryParams ...QueryParam) ([]response.OrderStatus, error)
+	GetSystemTime() (time.Time, error)
 
 	PlaceOrder(...param.PlaceOrderParams) error
 	CancelOrder(...param.CancelOrder) error
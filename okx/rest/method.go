@@ -2,16 +2,18 @@ package rest
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/google/uuid"
+	"github.com/simonks2016/dex_plus/common"
 	"github.com/simonks2016/dex_plus/internal/httpClient"
 	"github.com/simonks2016/dex_plus/okx/internal"
 )
 
-// GET方法
-func doGET[T any](host, path string, client *Client) (T, error) {
+// GET方法，instIds 为按品种限频的品种
+func doGET[T any](host, path string, client *Client, instIds ...string) (T, error) {
 	var zero T
 
 	resultCh := make(chan asyncResult[T], 1)
@@ -35,10 +37,11 @@ func doGET[T any](host, path string, client *Client) (T, error) {
 					return "0"
 				}()),
 		),
-		Timeout:   5 * time.Second,
-		Retry:     2,
-		CreatedAt: time.Now(),
-		Callback:  OKXCallback[T](resultCh),
+		Timeout:     5 * time.Second,
+		Instruments: instIds,
+		Retry:       2,
+		CreatedAt:   time.Now(),
+		Callback:    OKXCallback[T](resultCh),
 	}
 
 	if err := client.client.DoAsync(req); err != nil {
@@ -49,11 +52,11 @@ func doGET[T any](host, path string, client *Client) (T, error) {
 	case result := <-resultCh:
 		return result.data, result.err
 	case <-time.After(30 * time.Minute):
-		return zero, fmt.Errorf("request timeout: %s", path)
+		return zero, fmt.Errorf("%w: %s", common.ErrTimeout, path)
 	}
 }
 
-func doPOST[T any](host, path string, client *Client, data any) (T, error) {
+func doPOST[T any](host, path string, client *Client, data any, instIds ...string) (T, error) {
 	var zero T
 
 	resultCh := make(chan asyncResult[T], 1)
@@ -83,10 +86,11 @@ func doPOST[T any](host, path string, client *Client, data any) (T, error) {
 					return "0"
 				}()),
 		),
-		Timeout:   5 * time.Second,
-		Retry:     2,
-		CreatedAt: time.Now(),
-		Callback:  OKXCallback[T](resultCh),
+		Timeout:     5 * time.Second,
+		Instruments: instIds,
+		Retry:       2,
+		CreatedAt:   time.Now(),
+		Callback:    OKXCallback[T](resultCh),
 	}
 
 	if err := client.client.DoAsync(req); err != nil {
@@ -97,6 +101,17 @@ func doPOST[T any](host, path string, client *Client, data any) (T, error) {
 	case result := <-resultCh:
 		return result.data, result.err
 	case <-time.After(30 * time.Minute):
-		return zero, fmt.Errorf("request timeout: %s", path)
+		return zero, fmt.Errorf("%w: %s", common.ErrTimeout, path)
 	}
 }
+
+// instKey 限频使用的品种，未指定 instId 时使用 instIdCode
+func instKey(instId *string, instIdCode *int) string {
+	if instId != nil {
+		return *instId
+	}
+	if instIdCode != nil {
+		return strconv.Itoa(*instIdCode)
+	}
+	return ""
+}
@@ -1,10 +1,14 @@
 package rest
 
 import (
+	"log/slog"
 	"net/url"
 	"strings"
 
+	"github.com/simonks2016/dex_plus/clocksync"
+	"github.com/simonks2016/dex_plus/metrics"
 	"github.com/simonks2016/dex_plus/okx/internal"
+	"github.com/simonks2016/dex_plus/ratelimit"
 )
 
 type Option func(*Client)
@@ -34,6 +38,38 @@ func WithRealTrading() Option {
 	}
 }
 
+// WithSlogLogger 设置结构化日志记录器
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithClock 签名使用与 OKX 服务器同步的时钟，可以用 NewTimeSource 创建时钟的数据源
+func WithClock(clock *clocksync.Clock) Option {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// WithRateLimit 替换默认的限速器与令牌不足时的处理方式，limiter 为 nil 时不限速
+// 默认使用 ratelimit.DefaultRegistry 中 OKX 的规则并等待令牌
+func WithRateLimit(limiter *ratelimit.Limiter, mode ratelimit.Mode) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+		c.limitMode = mode
+	}
+}
+
+// WithMetrics 记录请求数、重试与延迟
+func WithMetrics(sink metrics.MetricsSink) Option {
+	return func(c *Client) {
+		c.metrics = sink
+	}
+}
+
 type QueryParam func(map[string]string)
 
 func WithQueryParam(name string, value string) QueryParam {
@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/okx/response"
+)
+
+// GetSystemTime 获取 OKX 服务器时间
+func (c *Client) GetSystemTime() (time.Time, error) {
+	items, err := doGET[[]response.SystemTime](c.BaseUrl, "/api/v5/public/time", c)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(items) == 0 {
+		return time.Time{}, fmt.Errorf("okx: empty system time response")
+	}
+	ms, err := strconv.ParseInt(items[0].Ts, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// TimeSource 通过 /api/v5/public/time 获取 OKX 服务器时间，用于 clocksync.New
+type TimeSource struct {
+	api OKXRestAPI
+}
+
+func NewTimeSource(api OKXRestAPI) *TimeSource {
+	return &TimeSource{api: api}
+}
+
+func (s *TimeSource) Exchange() string { return common.OKX }
+
+func (s *TimeSource) ServerTime(ctx context.Context) (time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return s.api.GetSystemTime()
+}
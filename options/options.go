@@ -0,0 +1,189 @@
+// Package options 各交易所适配器共用的构造选项
+//
+// 连接相关的选项（地址、故障切换、延迟统计、录制、回放、背压、静默检测、订阅超时、分片上限）对所有交易所的含义相同，
+// 统一在这里提供，各适配器的构造函数接收 ...options.Option；只对某个交易所有意义的选项仍然放在交易所自己的包中
+package options
+
+import (
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/internal/client"
+	"github.com/simonks2016/dex_plus/latency"
+	"github.com/simonks2016/dex_plus/metrics"
+	"github.com/simonks2016/dex_plus/ratelimit"
+	"github.com/simonks2016/dex_plus/recording"
+	"github.com/simonks2016/dex_plus/watchdog"
+)
+
+// Options 适配器构造时的选项，包装 internal/client 的连接配置，另外记录品种、REST 地址等适配器在创建客户端前读取的设置
+type Options struct {
+	cfg      *client.Config
+	symbols  []string
+	restURL  string
+	registry *common.InstrumentRegistry
+}
+
+type Option func(o *Options)
+
+// New 在适配器的默认配置上应用选项，之后适配器再用 cfg 创建客户端
+func New(cfg *client.Config, opts ...Option) *Options {
+	o := &Options{cfg: cfg}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// Config 连接配置，交易所自己的选项通过它修改配置
+func (o *Options) Config() *client.Config {
+	return o.cfg
+}
+
+// Symbols WithSymbols 设置的品种
+func (o *Options) Symbols() []string {
+	return o.symbols
+}
+
+// RestURL WithRestURL 设置的 REST 地址，没有设置时返回 def
+func (o *Options) RestURL(def string) string {
+	if o.restURL == "" {
+		return def
+	}
+	return o.restURL
+}
+
+// Registry WithInstrumentRegistry 设置的注册表，没有设置时返回 def
+func (o *Options) Registry(def *common.InstrumentRegistry) *common.InstrumentRegistry {
+	if o.registry == nil {
+		return def
+	}
+	return o.registry
+}
+
+// WithSymbols 订阅的品种，格式与交易所一致；OKX 通过 SetInstId 设置，不使用该选项
+func WithSymbols(symbols ...string) Option {
+	return func(o *Options) {
+		o.symbols = append(o.symbols, symbols...)
+	}
+}
+
+// WithRestURL 替换适配器使用的 REST 地址（币安的盘口快照，Coinbase、Bitstamp 的产品信息），用于测试环境或本地模拟服务
+func WithRestURL(url string) Option {
+	return func(o *Options) {
+		o.restURL = url
+	}
+}
+
+// WithInstrumentRegistry 交易所下发的交易对写入指定的注册表，默认写入 common.DefaultInstrumentRegistry；目前只有 Kraken 使用
+func WithInstrumentRegistry(registry *common.InstrumentRegistry) Option {
+	return func(o *Options) {
+		o.registry = registry
+	}
+}
+
+// WithLogger 使用 *log.Logger 输出日志，记录会以 key=value 文本格式写入
+func WithLogger(logger *log.Logger) Option {
+	return func(o *Options) {
+		o.cfg.WithStdLogger(logger)
+	}
+}
+
+// WithSlogLogger 使用结构化日志，记录带有 exchange、channel、symbol、conn_id 等属性
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.cfg.WithLogger(logger)
+	}
+}
+
+// WithURL 替换 WebSocket 地址，用于测试环境或本地模拟服务
+func WithURL(url string) Option {
+	return func(o *Options) {
+		o.cfg.WithURL(url)
+	}
+}
+
+// WithEndpoints 按优先级替换 WebSocket 地址，连续拨号失败或连接质量下降时切换到下一个
+func WithEndpoints(urls ...string) Option {
+	return func(o *Options) {
+		o.cfg.WithEndpoints(urls...)
+	}
+}
+
+// WithFailover 同一个地址连续拨号失败 after 次后切换；连接在 stable 之内断开视为质量下降，立即切换
+func WithFailover(after int, stable time.Duration) Option {
+	return func(o *Options) {
+		o.cfg.SetFailover(after, stable)
+	}
+}
+
+// WithLatency 统计线路延迟、排队等待与回调耗时，多个客户端可以共用一个 Tracker
+func WithLatency(tracker *latency.Tracker) Option {
+	return func(o *Options) {
+		o.cfg.WithLatency(tracker)
+	}
+}
+
+// WithRateLimit 替换默认的限速器与令牌不足时的处理方式，limiter 为 nil 时不限速
+// OKX 与币安默认使用 ratelimit.DefaultRegistry 中的规则并等待令牌
+func WithRateLimit(limiter *ratelimit.Limiter, mode ratelimit.Mode) Option {
+	return func(o *Options) {
+		o.cfg.WithRateLimit(limiter, mode)
+	}
+}
+
+// WithRecorder 录制所有收发的原始帧
+func WithRecorder(recorder recording.Recorder) Option {
+	return func(o *Options) {
+		o.cfg.WithRecorder(recorder)
+	}
+}
+
+// WithMetrics 记录连接与读写管道的指标
+func WithMetrics(sink metrics.MetricsSink) Option {
+	return func(o *Options) {
+		o.cfg.WithMetrics(sink)
+	}
+}
+
+// WithReplay 从录制文件回放，不再连接交易所；speed 为回放速度，<=0 表示尽可能快
+func WithReplay(path string, speed float64) Option {
+	return func(o *Options) {
+		o.cfg.WithReplay(&client.ReplayConfig{Path: path, Speed: speed})
+	}
+}
+
+// WithBackpressure 读队列写满时的处理策略，默认丢弃新帧
+// BackpressureConflate 时按各交易所保序分发的键合并，即同一个 (channel, symbol) 只保留最新的一帧
+func WithBackpressure(policy common.BackpressurePolicy) Option {
+	return func(o *Options) {
+		o.cfg.SetBackpressure(policy)
+	}
+}
+
+// WithWatchdog 按 (channel, symbol) 检测静默的订阅，阈值中的频道名与交易所推送一致，如 OKX 的 "books5"、币安 stream 中 @ 之后的 "depth"
+func WithWatchdog(wd watchdog.Config) Option {
+	return func(o *Options) {
+		o.cfg.WithWatchdog(&wd)
+	}
+}
+
+// WithSubscribeTimeout 订阅确认的超时与超时后的重发次数，默认 10 秒、重发 2 次；retries <0 表示不重发
+// 重试用尽后订阅状态变为 common.SubscriptionFailed
+func WithSubscribeTimeout(timeout time.Duration, retries int) Option {
+	return func(o *Options) {
+		o.cfg.SetSubscribeTimeout(timeout, retries)
+	}
+}
+
+// WithMaxSubscriptionsPerConn 每个连接最多订阅的 (channel, symbol) 数，超过后自动新建连接；<0 表示不限
+// 默认 OKX 300、Kraken 200、币安 1024（按 stream 计），Coinbase 与 Bitstamp 只使用一个连接
+func WithMaxSubscriptionsPerConn(n int) Option {
+	return func(o *Options) {
+		o.cfg.SetMaxSubscriptions(n)
+	}
+}
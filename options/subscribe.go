@@ -0,0 +1,27 @@
+package options
+
+// SubscribeOptions 订阅选项
+type SubscribeOptions struct {
+	// Ordered 同一个 (channel, symbol) 的推送严格按到达顺序串行回调，不同品种之间仍然并行
+	// 默认回调提交到协程池并发执行，不保证顺序
+	Ordered bool
+}
+
+type SubscribeOption func(*SubscribeOptions)
+
+// WithOrdered 按 (channel, symbol) 保序回调，盘口增量、订单状态等对顺序敏感的频道使用，多品种订阅盘口时建议开启
+func WithOrdered() SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Ordered = true
+	}
+}
+
+func NewSubscribeOptions(opts ...SubscribeOption) SubscribeOptions {
+	var o SubscribeOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
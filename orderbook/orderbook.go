@@ -0,0 +1,126 @@
+// Package orderbook 用精确小数在本地维护一个品种的盘口，交易所适配器在其上实现快照、增量、序号与校验和的处理
+// 价格保留交易所下发时的小数位数，计算依赖原始字符串的校验和（如 OKX 的 CRC32）时可以直接使用 String
+//
+// 为什么不用 bookManager：bookManager 以整数价格和 float64 数量保存档位，只能按固定精度格式化回字符串。
+// Kraken 的校验和按品种精度格式化，可以由 bookManager 的档位还原；OKX 的 CRC32 拼接的是推送中的原始字符串，
+// 同一品种的数量可能带或不带末尾的 0（"0.10"、"0.1"），float64 无法区分，算出的校验和会不一致。
+// 因此需要逐笔校验原始字符串的 OKX 盘口使用本包，其余交易所沿用 bookManager
+package orderbook
+
+import (
+	"slices"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+	"github.com/simonks2016/dex_plus/market"
+)
+
+// Book 一个品种的盘口，Bids 从高到低，Asks 从低到高；不是并发安全的，由调用方加锁
+type Book struct {
+	Exchange string
+	Symbol   string
+	// Seq 最近一次应用的序号，含义由交易所决定
+	Seq int64
+	// Updated 最近一次更新的交易所时间
+	Updated time.Time
+
+	bids []common.PriceLevel
+	asks []common.PriceLevel
+}
+
+func New(exchange, symbol string) *Book {
+	return &Book{Exchange: exchange, Symbol: symbol}
+}
+
+// Reset 用全量快照替换盘口，数量为 0 的档位被忽略
+func (b *Book) Reset(bids, asks []common.PriceLevel) {
+	b.bids, b.asks = b.bids[:0], b.asks[:0]
+	b.Update(bids, asks)
+}
+
+// Update 应用增量，数量为 0 表示删除该价位
+func (b *Book) Update(bids, asks []common.PriceLevel) {
+	for _, l := range bids {
+		b.bids = apply(b.bids, l, true)
+	}
+	for _, l := range asks {
+		b.asks = apply(b.asks, l, false)
+	}
+}
+
+// apply 在按价格排序的档位中更新一个价位，desc 为 true 时从高到低
+func apply(levels []common.PriceLevel, l common.PriceLevel, desc bool) []common.PriceLevel {
+	i, found := slices.BinarySearchFunc(levels, l.Price, func(x common.PriceLevel, price common.Decimal) int {
+		if desc {
+			return price.Cmp(x.Price)
+		}
+		return x.Price.Cmp(price)
+	})
+	switch {
+	case l.Size.Sign() <= 0:
+		if found {
+			levels = slices.Delete(levels, i, i+1)
+		}
+	case found:
+		levels[i] = l
+	default:
+		levels = slices.Insert(levels, i, l)
+	}
+	return levels
+}
+
+// Bids 前 n 档买盘的副本，n <= 0 时返回全部
+func (b *Book) Bids(n int) []common.PriceLevel {
+	return top(b.bids, n)
+}
+
+// Asks 前 n 档卖盘的副本，n <= 0 时返回全部
+func (b *Book) Asks(n int) []common.PriceLevel {
+	return top(b.asks, n)
+}
+
+func top(levels []common.PriceLevel, n int) []common.PriceLevel {
+	if n <= 0 || n > len(levels) {
+		n = len(levels)
+	}
+	return slices.Clone(levels[:n])
+}
+
+// Depth 买盘与卖盘的档位数
+func (b *Book) Depth() (bids, asks int) {
+	return len(b.bids), len(b.asks)
+}
+
+// BestBid 最优买价
+func (b *Book) BestBid() (common.PriceLevel, bool) {
+	if len(b.bids) == 0 {
+		return common.PriceLevel{}, false
+	}
+	return b.bids[0], true
+}
+
+// BestAsk 最优卖价
+func (b *Book) BestAsk() (common.PriceLevel, bool) {
+	if len(b.asks) == 0 {
+		return common.PriceLevel{}, false
+	}
+	return b.asks[0], true
+}
+
+// Crossed 最优买价不低于最优卖价，通常说明丢失了增量
+func (b *Book) Crossed() bool {
+	bid, ok1 := b.BestBid()
+	ask, ok2 := b.BestAsk()
+	return ok1 && ok2 && bid.Price.Cmp(ask.Price) >= 0
+}
+
+// Snapshot 前 n 档的快照，n <= 0 时返回全部
+func (b *Book) Snapshot(n int) market.BookSnapshot {
+	return market.BookSnapshot{
+		Exchange:  b.Exchange,
+		Symbol:    b.Symbol,
+		Bids:      b.Bids(n),
+		Asks:      b.Asks(n),
+		Timestamp: b.Updated,
+	}
+}
@@ -0,0 +1,40 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+func levels(items ...[]string) []common.PriceLevel {
+	l, _ := common.ParseLevels(items)
+	return l
+}
+
+func TestUpdate(t *testing.T) {
+	b := New("x", "BTC-USD")
+	b.Reset(levels([]string{"99", "1"}, []string{"101", "2"}, []string{"100", "0"}), levels([]string{"103", "1"}, []string{"102", "1"}))
+	if bids, asks := b.Depth(); bids != 2 || asks != 2 {
+		t.Fatalf("depth = %d/%d, want 2/2", bids, asks)
+	}
+
+	b.Update(levels([]string{"100", "3"}, []string{"101", "0"}), levels([]string{"102", "5"}))
+	bids := b.Bids(0)
+	if len(bids) != 2 || bids[0].Price.String() != "100" || bids[1].Price.String() != "99" {
+		t.Fatalf("bids = %v", bids)
+	}
+	if ask, _ := b.BestAsk(); ask.Size.String() != "5" {
+		t.Fatalf("best ask = %v", ask)
+	}
+	if b.Crossed() {
+		t.Fatal("book should not be crossed")
+	}
+
+	b.Update(levels([]string{"102.5", "1"}), nil)
+	if !b.Crossed() {
+		t.Fatal("book should be crossed")
+	}
+	if s := b.Snapshot(1); len(s.Bids) != 1 || len(s.Asks) != 1 || s.Bids[0].Price.String() != "102.5" {
+		t.Fatalf("snapshot = %+v", s)
+	}
+}
@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// Endpoint 匹配指定方法与路径的 REST 请求
+func Endpoint(method, path string) func(Request) bool {
+	return func(req Request) bool {
+		return req.Kind == HTTP && req.Method == method && req.Path == path
+	}
+}
+
+// PathPrefix 匹配路径以 prefix 开头的 REST 请求
+func PathPrefix(prefix string) func(Request) bool {
+	return func(req Request) bool {
+		return req.Kind == HTTP && strings.HasPrefix(req.Path, prefix)
+	}
+}
+
+// Messages 匹配全部 WebSocket 消息
+func Messages(req Request) bool {
+	return req.Kind == WS
+}
+
+// endpointRule 以 "METHOD path" 命名的单个接口规则
+func endpointRule(method, path string, limit Limit, scope Scope) Rule {
+	return Rule{Name: method + " " + path, Limit: limit, Scope: scope, Match: Endpoint(method, path)}
+}
+
+// okxOps 匹配 op 为指定值的 OKX WebSocket 请求
+func okxOps(ops ...string) func(Request) bool {
+	patterns := make([][]byte, len(ops))
+	for i, op := range ops {
+		patterns[i] = []byte(`"op":"` + op + `"`)
+	}
+	return func(req Request) bool {
+		if req.Kind != WS {
+			return false
+		}
+		for _, p := range patterns {
+			if bytes.Contains(req.Data, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OKX 的限频规则：交易接口按品种计数（批量接口按订单数），账户与公共接口按账户或 IP 计数；
+// WebSocket 每个连接每小时最多 480 次 login/subscribe/unsubscribe
+func OKX() *Limiter {
+	twoSeconds := 2 * time.Second
+	return NewLimiter(common.OKX,
+		endpointRule("POST", "/api/v5/trade/order", Every(60, twoSeconds), PerInstrument),
+		endpointRule("POST", "/api/v5/trade/batch-orders", Every(300, twoSeconds), PerInstrument),
+		endpointRule("POST", "/api/v5/trade/cancel-order", Every(60, twoSeconds), PerInstrument),
+		endpointRule("POST", "/api/v5/trade/cancel-batch-orders", Every(300, twoSeconds), PerInstrument),
+		endpointRule("POST", "/api/v5/trade/amend-order", Every(60, twoSeconds), PerInstrument),
+		endpointRule("GET", "/api/v5/trade/order", Every(60, twoSeconds), PerInstrument),
+		endpointRule("GET", "/api/v5/trade/orders-pending", Every(60, twoSeconds), Global),
+		endpointRule("GET", "/api/v5/account/positions", Every(10, twoSeconds), Global),
+		endpointRule("GET", "/api/v5/account/balance", Every(10, twoSeconds), Global),
+		endpointRule("GET", "/api/v5/public/instruments", Every(20, twoSeconds), Global),
+		endpointRule("GET", "/api/v5/public/time", Every(10, twoSeconds), Global),
+		Rule{
+			Name:  "ws subscribe",
+			Limit: Every(480, time.Hour),
+			Scope: PerConnection,
+			Match: okxOps("login", "subscribe", "unsubscribe"),
+		},
+	)
+}
+
+// binanceWeights 现货 REST 接口的请求权重，未列出的接口为 1
+var binanceWeights = map[string]float64{
+	"/api/v3/exchangeInfo":     20,
+	"/api/v3/trades":           25,
+	"/api/v3/historicalTrades": 25,
+	"/api/v3/aggTrades":        4,
+	"/api/v3/klines":           2,
+	"/api/v3/uiKlines":         2,
+	"/api/v3/avgPrice":         2,
+	"/api/v3/account":          20,
+	"/api/v3/openOrders":       6,
+	"/api/v3/allOrders":        20,
+	"/api/v3/myTrades":         20,
+	"/api/v3/order":            1,
+}
+
+// binanceWeight 请求权重，深度与 24 小时行情的权重随参数变化
+func binanceWeight(req Request) float64 {
+	switch req.Path {
+	case "/api/v3/depth":
+		limit, _ := strconv.Atoi(req.Query.Get("limit"))
+		switch {
+		case limit > 1000:
+			return 250
+		case limit > 500:
+			return 50
+		case limit > 100:
+			return 25
+		default:
+			return 5
+		}
+	case "/api/v3/ticker/24hr":
+		if req.Query.Get("symbol") == "" {
+			return 80
+		}
+		return 2
+	}
+	if w, ok := binanceWeights[req.Path]; ok {
+		return w
+	}
+	return 1
+}
+
+func binanceOrder(req Request) bool {
+	return req.Kind == HTTP && req.Method == "POST" &&
+		(req.Path == "/api/v3/order" || strings.HasPrefix(req.Path, "/api/v3/orderList/"))
+}
+
+// Binance 币安现货的限频规则：每分钟 6000 请求权重、每 10 秒 100 个订单、每天 200000 个订单；
+// WebSocket 每个连接每秒最多 5 条消息（含 ping/pong），为控制帧预留余量按每秒 4 条限速
+func Binance() *Limiter {
+	return NewLimiter(common.Binance,
+		Rule{
+			Name:  "request weight",
+			Limit: Every(6000, time.Minute),
+			Match: PathPrefix("/api/"),
+			Cost:  binanceWeight,
+		},
+		Rule{Name: "orders 10s", Limit: Every(100, 10*time.Second), Match: binanceOrder},
+		Rule{Name: "orders 1d", Limit: Every(200000, 24*time.Hour), Match: binanceOrder},
+		Rule{Name: "ws messages", Limit: Every(4, time.Second), Scope: PerConnection, Match: Messages},
+	)
+}
+
+// krakenPrivateCost 私有接口计数器的增量，下单与撤单计入交易计数器
+func krakenPrivateCost(req Request) float64 {
+	switch strings.TrimPrefix(req.Path, "/0/private/") {
+	case "Ledgers", "QueryLedgers", "TradesHistory", "QueryTrades":
+		return 2
+	case "AddOrder", "AddOrderBatch", "EditOrder", "CancelOrder", "CancelOrderBatch":
+		return 0
+	}
+	return 1
+}
+
+// Kraken 的限频规则，按 Starter 等级：公共接口每秒约 1 次；私有接口计数器上限 15、每秒衰减 0.33；
+// 交易计数器按交易对计数，上限 60、每秒衰减 1；其他等级用 NewLimiter 创建后通过 Registry.Register 替换
+func Kraken() *Limiter {
+	return NewLimiter(common.Kraken,
+		Rule{Name: "public", Limit: Every(1, time.Second), Match: PathPrefix("/0/public/")},
+		Rule{Name: "private", Limit: Decay(15, 0.33), Match: PathPrefix("/0/private/"), Cost: krakenPrivateCost},
+		Rule{
+			Name:  "trading",
+			Limit: Decay(60, 1),
+			Scope: PerInstrument,
+			Match: func(req Request) bool {
+				switch req.Path {
+				case "/0/private/AddOrder", "/0/private/AddOrderBatch", "/0/private/EditOrder":
+					return req.Kind == HTTP
+				}
+				return false
+			},
+		},
+	)
+}
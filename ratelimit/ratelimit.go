@@ -0,0 +1,350 @@
+// Package ratelimit 按交易所的限频规则在本地限速，避免请求与订阅消息的突发被交易所返回 429 或断开连接
+// 每条规则是一个令牌桶，按全局、品种或连接分别计数；一个请求同时受多条规则约束时，在全部规则都有足够令牌后才发出
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// Mode 令牌不足时的处理方式
+type Mode int
+
+const (
+	// Wait 等待令牌补充；ctx 在令牌补充之前结束时返回错误，不会占用令牌
+	Wait Mode = iota
+	// FailFast 令牌不足时立即返回 *common.RateLimitError，RetryAfter 为需要等待的时间
+	FailFast
+)
+
+func (m Mode) String() string {
+	if m == FailFast {
+		return "fail_fast"
+	}
+	return "wait"
+}
+
+// Kind 请求的类型
+type Kind int
+
+const (
+	// HTTP REST 请求
+	HTTP Kind = iota
+	// WS 通过 WebSocket 发送的消息
+	WS
+)
+
+// Scope 规则的计数范围
+type Scope int
+
+const (
+	// Global 同一个 Limiter 上的请求共用一个桶，对应交易所按 IP 或账户的限频
+	Global Scope = iota
+	// PerInstrument 按品种分别计数，请求中的每个品种各消耗一次令牌
+	PerInstrument
+	// PerConnection 按 WebSocket 连接分别计数
+	PerConnection
+)
+
+// Limit 令牌桶的容量与补充速度
+type Limit struct {
+	// Burst 桶容量，即允许的最大突发
+	Burst float64
+	// Rate 每秒补充的令牌数
+	Rate float64
+}
+
+// Every period 内最多 n 个请求
+func Every(n int, period time.Duration) Limit {
+	return Limit{Burst: float64(n), Rate: float64(n) / period.Seconds()}
+}
+
+// Decay 计数器模型的限频：每个请求使计数器增加，计数器按 perSecond 衰减，超过 max 时被拒绝
+func Decay(max, perSecond float64) Limit {
+	return Limit{Burst: max, Rate: perSecond}
+}
+
+// Request 待限速的请求
+type Request struct {
+	Kind   Kind
+	Method string
+	// Path 不含查询参数的路径
+	Path  string
+	Query url.Values
+	// Instruments 请求涉及的品种，批量下单时每个订单一项，可以重复
+	Instruments []string
+	// ConnID WebSocket 连接的编号
+	ConnID string
+	// Data WebSocket 消息内容
+	Data []byte
+}
+
+// HTTPRequest 由 URL 生成 REST 请求
+func HTTPRequest(method, rawURL string, instruments ...string) Request {
+	req := Request{Kind: HTTP, Method: method, Instruments: instruments}
+	if u, err := url.Parse(rawURL); err == nil {
+		req.Path, req.Query = u.Path, u.Query()
+	}
+	return req
+}
+
+// Message 生成 WebSocket 消息请求
+func Message(connID uint64, data []byte) Request {
+	return Request{Kind: WS, ConnID: strconv.FormatUint(connID, 10), Data: data}
+}
+
+// Rule 一条限频规则
+type Rule struct {
+	// Name 规则名，出现在错误信息中
+	Name  string
+	Limit Limit
+	Scope Scope
+	// Match 判断请求是否受该规则约束，为空时匹配全部请求
+	Match func(Request) bool
+	// Cost 请求消耗的令牌数（权重），为空时为 1；返回 0 时不受该规则约束
+	Cost func(Request) float64
+}
+
+func (r Rule) cost(req Request) float64 {
+	if r.Cost == nil {
+		return 1
+	}
+	return r.Cost(req)
+}
+
+// maxIdleBuckets 桶的数量超过该值时清理已经补满的桶，避免按品种、连接计数的桶无限增长
+const maxIdleBuckets = 4096
+
+type bucketKey struct {
+	rule int
+	key  string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 一个交易所的限频规则，并发安全；nil 的 Limiter 不限速
+type Limiter struct {
+	exchange string
+	rules    []Rule
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	now     func() time.Time
+}
+
+// NewLimiter 创建限速器，Burst 或 Rate 不为正数的规则被忽略
+func NewLimiter(exchange string, rules ...Rule) *Limiter {
+	l := &Limiter{
+		exchange: exchange,
+		buckets:  make(map[bucketKey]*bucket),
+		now:      time.Now,
+	}
+	for _, rule := range rules {
+		if rule.Limit.Burst > 0 && rule.Limit.Rate > 0 {
+			l.rules = append(l.rules, rule)
+		}
+	}
+	return l
+}
+
+// Exchange 交易所名称
+func (l *Limiter) Exchange() string {
+	if l == nil {
+		return ""
+	}
+	return l.exchange
+}
+
+// Rules 生效的规则
+func (l *Limiter) Rules() []Rule {
+	if l == nil {
+		return nil
+	}
+	return l.rules
+}
+
+// Wait 等待令牌，ctx 结束时返回 ctx 的错误；等待时间超过 ctx 的截止时间时立即返回 *common.RateLimitError
+func (l *Limiter) Wait(ctx context.Context, req Request) error {
+	return l.Acquire(ctx, Wait, req)
+}
+
+// Allow 令牌足够时消耗令牌并返回 nil，否则返回 *common.RateLimitError
+func (l *Limiter) Allow(req Request) error {
+	return l.Acquire(context.Background(), FailFast, req)
+}
+
+type charge struct {
+	key  bucketKey
+	b    *bucket
+	cost float64
+}
+
+// Acquire 按 mode 获取请求需要的全部令牌
+func (l *Limiter) Acquire(ctx context.Context, mode Mode, req Request) error {
+	if l == nil || len(l.rules) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	charges := l.charges(req, now)
+
+	var (
+		wait    time.Duration
+		blocked = -1
+	)
+	for _, c := range charges {
+		if need := c.cost - c.b.tokens; need > 0 {
+			if d := time.Duration(need / l.rules[c.key.rule].Limit.Rate * float64(time.Second)); d > wait {
+				wait, blocked = d, c.key.rule
+			}
+		}
+	}
+	if wait > 0 {
+		deadline, ok := ctx.Deadline()
+		if mode == FailFast || (ok && now.Add(wait).After(deadline)) {
+			l.mu.Unlock()
+			return &common.RateLimitError{
+				Exchange:   l.exchange,
+				Msg:        fmt.Sprintf("local limit %s", l.rules[blocked].Name),
+				RetryAfter: wait,
+			}
+		}
+	}
+	// 先占用令牌（可以为负），等待期间后来的请求排在后面
+	for _, c := range charges {
+		c.b.tokens -= c.cost
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.refund(charges)
+		return ctx.Err()
+	}
+}
+
+// charges 请求在各个桶上的消耗，同一个桶的消耗合并；调用方持有 l.mu
+func (l *Limiter) charges(req Request, now time.Time) []charge {
+	if len(l.buckets) > maxIdleBuckets {
+		l.evict(now)
+	}
+	var charges []charge
+	add := func(rule int, key string, cost float64) {
+		k := bucketKey{rule: rule, key: key}
+		for i := range charges {
+			if charges[i].key == k {
+				charges[i].cost += cost
+				return
+			}
+		}
+		charges = append(charges, charge{key: k, b: l.bucket(k, now), cost: cost})
+	}
+
+	for i, rule := range l.rules {
+		if rule.Match != nil && !rule.Match(req) {
+			continue
+		}
+		cost := rule.cost(req)
+		if cost <= 0 {
+			continue
+		}
+		switch rule.Scope {
+		case PerInstrument:
+			for _, inst := range req.Instruments {
+				add(i, inst, cost)
+			}
+		case PerConnection:
+			add(i, req.ConnID, cost)
+		default:
+			add(i, "", cost)
+		}
+	}
+	return charges
+}
+
+// bucket 取出桶并按经过的时间补充令牌；调用方持有 l.mu
+func (l *Limiter) bucket(k bucketKey, now time.Time) *bucket {
+	limit := l.rules[k.rule].Limit
+	b, ok := l.buckets[k]
+	if !ok {
+		b = &bucket{tokens: limit.Burst, last: now}
+		l.buckets[k] = b
+		return b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(limit.Burst, b.tokens+elapsed.Seconds()*limit.Rate)
+		b.last = now
+	}
+	return b
+}
+
+func (l *Limiter) evict(now time.Time) {
+	for k, b := range l.buckets {
+		limit := l.rules[k.rule].Limit
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= limit.Burst {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+func (l *Limiter) refund(charges []charge) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range charges {
+		c.b.tokens = min(l.rules[c.key.rule].Limit.Burst, c.b.tokens+c.cost)
+	}
+}
+
+// Registry 按交易所保存 Limiter，同一个交易所的客户端共用限速器，对应交易所按 IP 或账户计数的限频
+type Registry struct {
+	mu       sync.RWMutex
+	limiters map[string]*Limiter
+}
+
+// DefaultRegistry 内置 OKX、币安与 Kraken 的限频规则，适配器默认使用
+var DefaultRegistry = NewRegistry(OKX(), Binance(), Kraken())
+
+func NewRegistry(limiters ...*Limiter) *Registry {
+	r := &Registry{limiters: make(map[string]*Limiter)}
+	for _, l := range limiters {
+		r.Register(l)
+	}
+	return r
+}
+
+// Register 注册或替换交易所的限速器
+func (r *Registry) Register(l *Limiter) {
+	if l == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limiters[l.exchange] = l
+}
+
+// Get 交易所的限速器，未注册时返回 nil（不限速）
+func (r *Registry) Get(exchange string) *Limiter {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.limiters[exchange]
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// fakeNow 固定的时间，advance 推进
+func fakeNow(l *Limiter) func(time.Duration) {
+	now := time.Unix(1_700_000_000, 0)
+	l.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestFailFast(t *testing.T) {
+	order := Endpoint("POST", "/order")
+	l := NewLimiter("test",
+		Rule{Name: "global", Limit: Every(2, time.Second)},
+		Rule{Name: "order", Limit: Every(2, time.Second), Scope: PerInstrument, Match: order},
+	)
+	advance := fakeNow(l)
+	req := func(insts ...string) Request { return HTTPRequest("POST", "https://x/order?a=1", insts...) }
+
+	if err := l.Allow(req("BTC", "BTC")); err != nil {
+		t.Fatal(err)
+	}
+	// BTC 的桶已经用完，全局桶还剩 1 个
+	err := l.Allow(req("BTC"))
+	var rl *common.RateLimitError
+	if !errors.As(err, &rl) || rl.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("err = %v, want RateLimitError retry after 500ms", err)
+	}
+	// 被拒绝的请求不占用令牌
+	if err := l.Allow(req("ETH")); err != nil {
+		t.Fatalf("ETH: %v", err)
+	}
+	if err := l.Allow(req("ETH")); err == nil {
+		t.Fatal("global bucket should be empty")
+	}
+
+	advance(500 * time.Millisecond)
+	if err := l.Allow(req("BTC")); err != nil {
+		t.Fatalf("after refill: %v", err)
+	}
+}
+
+func TestWaitRefundOnCancel(t *testing.T) {
+	l := NewLimiter("test", Rule{Name: "ws", Limit: Every(1, 50*time.Millisecond), Scope: PerConnection, Match: Messages})
+	msg := Message(1, []byte("{}"))
+
+	if err := l.Wait(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := l.Wait(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Fatalf("waited %s, want about 50ms", d)
+	}
+	// 其他连接单独计数
+	if err := l.Allow(Message(2, []byte("{}"))); err != nil {
+		t.Fatalf("conn 2: %v", err)
+	}
+
+	// 截止时间早于令牌补充时立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	var rl *common.RateLimitError
+	if err := l.Wait(ctx, msg); !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want RateLimitError", err)
+	}
+
+	// 等待中取消时归还令牌
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx, msg) }()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := l.Allow(msg); err != nil {
+		t.Fatalf("token not refunded: %v", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	depth := HTTPRequest("GET", "https://api.binance.com/api/v3/depth?symbol=BTCUSDT&limit=1000")
+	if w := binanceWeight(depth); w != 50 {
+		t.Fatalf("depth weight = %v, want 50", w)
+	}
+	if !okxOps("subscribe")(Message(1, []byte(`{"id":"1","op":"subscribe","args":[]}`))) {
+		t.Fatal("subscribe message not matched")
+	}
+	for _, name := range []string{common.OKX, common.Binance, common.Kraken} {
+		if DefaultRegistry.Get(name) == nil {
+			t.Fatalf("no default limiter for %s", name)
+		}
+	}
+	var nilLimiter *Limiter
+	if err := nilLimiter.Allow(depth); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,51 @@
+// Package recording 定义 WebSocket 原始帧的录制格式
+//
+// 文件由 5 字节文件头（"DXRC" + 版本号）和连续的帧记录组成，只追加写入。
+// 每条记录依次为：方向(1 字节)、接收时间(varint，Unix 纳秒)、连接编号(uvarint)、
+// 数据长度(uvarint)、原始数据。
+package recording
+
+import (
+	"errors"
+	"time"
+)
+
+type Direction uint8
+
+const (
+	Inbound  Direction = 1 // 交易所 -> 本地
+	Outbound Direction = 2 // 本地 -> 交易所
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "in"
+	case Outbound:
+		return "out"
+	default:
+		return "unknown"
+	}
+}
+
+// Frame 一条原始帧
+type Frame struct {
+	Time      time.Time
+	ConnID    uint64 // 每次建立连接递增，用于区分重连前后的数据
+	Direction Direction
+	Data      []byte
+}
+
+// Recorder 帧录制器，WsClient 会在读写协程中调用，实现需要并发安全
+type Recorder interface {
+	Record(frame Frame) error
+}
+
+const version byte = 1
+
+var (
+	magic = []byte("DXRC")
+
+	ErrBadHeader = errors.New("recording: not a recording file")
+	ErrVersion   = errors.New("recording: unsupported version")
+)
@@ -0,0 +1,103 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// maxFrameSize 防止损坏的文件导致分配过大的内存
+const maxFrameSize = 64 << 20
+
+// Reader 按顺序读取录制文件中的帧
+type Reader struct {
+	r      *bufio.Reader
+	closer io.Closer
+}
+
+// NewReader 校验文件头并返回 Reader
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, ErrBadHeader
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, ErrBadHeader
+	}
+	if header[len(magic)] != version {
+		return nil, ErrVersion
+	}
+	return &Reader{r: br}, nil
+}
+
+// Open 打开录制文件
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Next 读取下一帧，读完返回 io.EOF；文件末尾不完整的记录（如进程崩溃时写了一半）返回 io.ErrUnexpectedEOF
+func (r *Reader) Next() (Frame, error) {
+	dir, err := r.r.ReadByte()
+	if err != nil {
+		return Frame{}, err
+	}
+
+	ts, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return Frame{}, unexpected(err)
+	}
+	connID, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return Frame{}, unexpected(err)
+	}
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return Frame{}, unexpected(err)
+	}
+	if size > maxFrameSize {
+		return Frame{}, fmt.Errorf("recording: frame too large (%d bytes)", size)
+	}
+
+	data := make([]byte, size)
+	if _, err = io.ReadFull(r.r, data); err != nil {
+		return Frame{}, unexpected(err)
+	}
+
+	return Frame{
+		Time:      time.Unix(0, ts),
+		ConnID:    connID,
+		Direction: Direction(dir),
+		Data:      data,
+	}, nil
+}
+
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "okx.rec")
+	start := time.Unix(1700000000, 123456789)
+
+	frames := []Frame{
+		{Time: start, ConnID: 1, Direction: Outbound, Data: []byte(`{"op":"subscribe"}`)},
+		{Time: start.Add(time.Millisecond), ConnID: 1, Direction: Inbound, Data: []byte(`{"event":"subscribe"}`)},
+		{Time: start.Add(time.Second), ConnID: 2, Direction: Inbound, Data: []byte{}},
+	}
+
+	// 分两次写入，第二次追加不能重复写文件头
+	for _, batch := range [][]Frame{frames[:1], frames[1:]} {
+		w, err := Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range batch {
+			if err = w.Record(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i, want := range frames {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if !got.Time.Equal(want.Time) || got.ConnID != want.ConnID ||
+			got.Direction != want.Direction || string(got.Data) != string(want.Data) {
+			t.Fatalf("frame %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err = r.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer 将帧写入 io.Writer，带缓冲，使用完需要 Close 或 Flush
+type Writer struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+	buf    [binary.MaxVarintLen64*3 + 1]byte
+	err    error
+}
+
+// NewWriter 写入文件头并返回 Writer
+func NewWriter(w io.Writer) (*Writer, error) {
+	rw := newWriter(w, nil)
+	if err := rw.writeHeader(); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+// Create 以追加方式打开录制文件，文件为空时写入文件头
+func Create(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	rw := newWriter(f, f)
+	if stat.Size() == 0 {
+		if err = rw.writeHeader(); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return rw, nil
+}
+
+func newWriter(w io.Writer, closer io.Closer) *Writer {
+	return &Writer{w: bufio.NewWriterSize(w, 64<<10), closer: closer}
+}
+
+func (w *Writer) writeHeader() error {
+	if _, err := w.w.Write(magic); err != nil {
+		return err
+	}
+	if err := w.w.WriteByte(version); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// Record 写入一帧，出错后后续写入都会返回同一个错误
+func (w *Writer) Record(frame Frame) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+
+	n := 0
+	w.buf[n] = byte(frame.Direction)
+	n++
+	n += binary.PutVarint(w.buf[n:], frame.Time.UnixNano())
+	n += binary.PutUvarint(w.buf[n:], frame.ConnID)
+	n += binary.PutUvarint(w.buf[n:], uint64(len(frame.Data)))
+
+	if _, err := w.w.Write(w.buf[:n]); err != nil {
+		w.err = err
+		return err
+	}
+	if _, err := w.w.Write(frame.Data); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// Flush 将缓冲区写入底层
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Flush()
+}
+
+// Close 刷新缓冲区，由 Create 打开的文件会被关闭
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.w.Flush()
+	if w.closer != nil {
+		if cErr := w.closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+// Package stream 把回调式订阅转换为 channel 与 iter.Seq2，方便在 select 循环中消费、控制背压与编写测试
+// 回调在协程池中执行，Stream 把回调的数据写入带缓冲的 channel，缓冲区满时按 Overflow 策略阻塞或丢弃
+package stream
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"sync/atomic"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+// Overflow 缓冲区满时的处理策略
+type Overflow int
+
+const (
+	// Block 阻塞回调协程直到消费者读取，背压传递到连接的读队列
+	Block Overflow = iota
+	// DropNewest 丢弃新到的消息
+	DropNewest
+	// DropOldest 丢弃缓冲区中最旧的消息，保留最新的
+	DropOldest
+)
+
+func (o Overflow) String() string {
+	switch o {
+	case DropNewest:
+		return "drop_newest"
+	case DropOldest:
+		return "drop_oldest"
+	default:
+		return "block"
+	}
+}
+
+// DefaultBufferSize 默认的缓冲区大小
+const DefaultBufferSize = 256
+
+// Config 缓冲区与溢出策略
+type Config struct {
+	// BufferSize channel 的缓冲区大小，默认 DefaultBufferSize
+	BufferSize int
+	// Overflow 缓冲区满时的处理策略，默认 Block
+	Overflow Overflow
+	// OnDrop 丢弃消息时回调，参数为累计丢弃的数量；在回调协程中同步执行，不能阻塞
+	OnDrop func(dropped uint64)
+}
+
+type Option func(*Config)
+
+// WithBufferSize 设置缓冲区大小
+func WithBufferSize(size int) Option {
+	return func(cfg *Config) {
+		cfg.BufferSize = size
+	}
+}
+
+// WithOverflow 设置缓冲区满时的处理策略
+func WithOverflow(overflow Overflow) Option {
+	return func(cfg *Config) {
+		cfg.Overflow = overflow
+	}
+}
+
+// WithOnDrop 丢弃消息时回调
+func WithOnDrop(fn func(dropped uint64)) Option {
+	return func(cfg *Config) {
+		cfg.OnDrop = fn
+	}
+}
+
+// Stream 一个订阅的 channel，关闭后 C() 在缓冲区中的消息读完之后关闭
+type Stream[T any] struct {
+	cfg     Config
+	ch      chan T
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu      sync.Mutex
+	closed  bool
+	senders sync.WaitGroup // 正在写入 ch 的回调，关闭 ch 之前等待它们退出
+	once    sync.Once
+}
+
+// New 创建 Stream，通常通过 Open 创建并跟随客户端关闭
+func New[T any](opts ...Option) *Stream[T] {
+	cfg := Config{BufferSize: DefaultBufferSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	return &Stream[T]{
+		cfg:  cfg,
+		ch:   make(chan T, cfg.BufferSize),
+		done: make(chan struct{}),
+	}
+}
+
+// C 消费端的 channel
+func (s *Stream[T]) C() <-chan T {
+	return s.ch
+}
+
+// Dropped 累计丢弃的消息数量
+func (s *Stream[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Push 写入一条消息，作为订阅回调使用；关闭后直接返回
+func (s *Stream[T]) Push(v T) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.senders.Add(1)
+	s.mu.Unlock()
+	defer s.senders.Done()
+
+	switch s.cfg.Overflow {
+	case DropNewest:
+		select {
+		case s.ch <- v:
+		default:
+			s.drop()
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- v:
+				return nil
+			default:
+			}
+			// 缓冲区满，丢掉最旧的一条再写入；与消费者竞争时重试
+			select {
+			case <-s.ch:
+				s.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- v:
+		case <-s.done:
+		}
+	}
+	return nil
+}
+
+// Close 停止写入，等待正在阻塞的回调退出后关闭 channel，可以重复调用
+func (s *Stream[T]) Close() {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		close(s.done)
+		s.mu.Unlock()
+		s.senders.Wait()
+		close(s.ch)
+	})
+}
+
+// Done 关闭时关闭
+func (s *Stream[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Stream[T]) drop() {
+	n := s.dropped.Add(1)
+	if s.cfg.OnDrop != nil {
+		s.cfg.OnDrop(n)
+	}
+}
+
+// Item 带品种的消息，用于回调同时传入品种与数据的订阅
+type Item[T any] struct {
+	Symbol string
+	Data   T
+}
+
+// Group 一个客户端打开的全部 Stream，客户端关闭时一起关闭；零值可以直接使用
+type Group struct {
+	mu      sync.Mutex
+	closed  bool
+	streams map[closer]struct{}
+}
+
+type closer interface {
+	Close()
+	Done() <-chan struct{}
+}
+
+// Open 创建 Stream 并加入 g，ctx 结束或 g 关闭时关闭；g 已经关闭时返回 common.ErrClosed
+func Open[T any](ctx context.Context, g *Group, opts ...Option) (*Stream[T], error) {
+	s := New[T](opts...)
+	if !g.add(s) {
+		return nil, common.ErrClosed
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.Done():
+		}
+		g.remove(s)
+	}()
+	return s, nil
+}
+
+func (g *Group) add(s closer) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	if g.streams == nil {
+		g.streams = make(map[closer]struct{})
+	}
+	g.streams[s] = struct{}{}
+	return true
+}
+
+func (g *Group) remove(s closer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.streams, s)
+}
+
+// Close 关闭全部 Stream，之后 Open 返回 common.ErrClosed
+func (g *Group) Close() {
+	g.mu.Lock()
+	g.closed = true
+	streams := make([]closer, 0, len(g.streams))
+	for s := range g.streams {
+		streams = append(streams, s)
+	}
+	g.mu.Unlock()
+	for _, s := range streams {
+		s.Close()
+	}
+}
+
+// Subscribe 打开 Stream 并通过 subscribe 注册写入 Stream 的回调，subscribe 返回错误时关闭 Stream
+// ctx 结束后交易所侧的订阅仍然保留，不再需要时由调用方取消订阅
+func Subscribe[T any](ctx context.Context, g *Group, subscribe func(push func(T) error) error, opts ...Option) (<-chan T, error) {
+	s, err := Open[T](ctx, g, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := subscribe(s.Push); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s.C(), nil
+}
+
+// SubscribeItems 同 Subscribe，用于回调同时传入品种与数据的订阅
+func SubscribeItems[T any](ctx context.Context, g *Group, subscribe func(push func(string, T) error) error, opts ...Option) (<-chan Item[T], error) {
+	return Subscribe(ctx, g, func(push func(Item[T]) error) error {
+		return subscribe(func(symbol string, data T) error {
+			return push(Item[T]{Symbol: symbol, Data: data})
+		})
+	}, opts...)
+}
+
+// Seq 把 open 返回的 channel 转换为 iter.Seq2
+// open 失败时产出一次错误；ctx 结束时产出 ctx 的错误；客户端关闭时正常结束；提前退出循环会关闭 Stream
+func Seq[T any](ctx context.Context, open func(ctx context.Context) (<-chan T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		sctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		ch, err := open(sctx)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for v := range ch {
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
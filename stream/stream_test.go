@@ -0,0 +1,129 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/simonks2016/dex_plus/common"
+)
+
+func drain[T any](ch <-chan T) []T {
+	var out []T
+	for v := range ch {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestOverflow(t *testing.T) {
+	cases := []struct {
+		overflow Overflow
+		want     []int
+	}{
+		{DropNewest, []int{0, 1}},
+		{DropOldest, []int{3, 4}},
+	}
+	for _, c := range cases {
+		var dropped uint64
+		s := New[int](WithBufferSize(2), WithOverflow(c.overflow), WithOnDrop(func(n uint64) { dropped = n }))
+		for i := 0; i < 5; i++ {
+			_ = s.Push(i)
+		}
+		s.Close()
+		if got := drain(s.C()); !slices.Equal(got, c.want) {
+			t.Fatalf("%s: got %v, want %v", c.overflow, got, c.want)
+		}
+		if dropped != 3 || s.Dropped() != 3 {
+			t.Fatalf("%s: dropped %d/%d, want 3", c.overflow, dropped, s.Dropped())
+		}
+	}
+}
+
+func TestBlockReleasedOnClose(t *testing.T) {
+	var g Group
+	s, err := Open[int](context.Background(), &g, WithBufferSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Push(1)
+
+	pushed := make(chan struct{})
+	go func() {
+		_ = s.Push(2) // 缓冲区已满，阻塞到关闭
+		close(pushed)
+	}()
+
+	g.Close()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatal("blocked Push not released by Close")
+	}
+	if got := drain(s.C()); !slices.Equal(got, []int{1}) {
+		t.Fatalf("got %v, want [1]", got)
+	}
+	if _, err := Open[int](context.Background(), &g); !errors.Is(err, common.ErrClosed) {
+		t.Fatalf("Open after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestSeq(t *testing.T) {
+	var g Group
+	pushCh := make(chan func(int) error, 1)
+	open := func(ctx context.Context) (<-chan int, error) {
+		return Subscribe(ctx, &g, func(push func(int) error) error {
+			pushCh <- push
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan []int)
+	go func() {
+		var got []int
+		for v, err := range Seq(ctx, open) {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				break
+			}
+			if got = append(got, v); len(got) == 2 {
+				break
+			}
+		}
+		done <- got
+	}()
+
+	push := <-pushCh
+	for i := 0; i < 3; i++ {
+		_ = push(i)
+	}
+	if got := <-done; !slices.Equal(got, []int{0, 1}) {
+		t.Fatalf("got %v, want [0 1]", got)
+	}
+
+	// 退出循环后 Stream 关闭，之后的推送直接返回
+	deadline := time.Now().Add(time.Second)
+	for {
+		g.mu.Lock()
+		n := len(g.streams)
+		g.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream not closed after break")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	failed := errors.New("subscribe failed")
+	for _, err := range Seq(ctx, func(context.Context) (<-chan int, error) { return nil, failed }) {
+		if !errors.Is(err, failed) {
+			t.Fatalf("err = %v, want %v", err, failed)
+		}
+	}
+}
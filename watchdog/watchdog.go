@@ -0,0 +1,259 @@
+// Package watchdog 按 (channel, symbol) 记录最后一条推送的时间，长时间没有推送时回调并可自动重订阅或重连
+// 连接层的 ping/pong 只能说明连接还活着，单个订阅静默失效（如重订阅没有 ack、频道被服务端下线）需要在这里发现
+package watchdog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Action 判定为静默后自动执行的动作
+type Action int
+
+const (
+	// ActionNone 只回调 OnStale
+	ActionNone Action = iota
+	// ActionResubscribe 重新订阅静默的 (channel, symbol)
+	ActionResubscribe
+	// ActionReconnect 重连整个连接，同一轮检查中多个静默的订阅只重连一次
+	ActionReconnect
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionResubscribe:
+		return "resubscribe"
+	case ActionReconnect:
+		return "reconnect"
+	default:
+		return "none"
+	}
+}
+
+// StaleEvent 一个订阅被判定为静默
+type StaleEvent struct {
+	Channel     string
+	Symbol      string
+	LastMessage time.Time     // 最后一条推送的时间，从未收到推送时为开始监视的时间
+	Silence     time.Duration // 已经静默的时长
+	Threshold   time.Duration
+	Action      Action
+}
+
+// Config 静默检测配置
+type Config struct {
+	// Threshold 默认的静默阈值，为 0 时只检查 Thresholds 中列出的频道
+	Threshold time.Duration
+	// Thresholds 按频道（或 "channel:symbol"）覆盖阈值，"channel:symbol" 优先；值为 0 表示不检查
+	// 频道名与交易所推送中的名称一致，如 OKX "books5"、Kraken "book"、Coinbase "level2"
+	Thresholds map[string]time.Duration
+	// CheckInterval 检查间隔，默认为最小阈值的 1/4，不小于 100ms
+	CheckInterval time.Duration
+	// Action 判定为静默后自动执行的动作，默认只回调
+	Action Action
+	// OnStale 判定为静默时回调，在检查协程中执行，不能阻塞
+	OnStale func(event StaleEvent)
+}
+
+// threshold 返回 (channel, symbol) 的阈值，0 表示不检查
+func (c *Config) threshold(channel, symbol string) time.Duration {
+	if d, ok := c.Thresholds[channel+":"+symbol]; ok {
+		return d
+	}
+	if d, ok := c.Thresholds[channel]; ok {
+		return d
+	}
+	return c.Threshold
+}
+
+func (c *Config) checkInterval() time.Duration {
+	if c.CheckInterval > 0 {
+		return c.CheckInterval
+	}
+	least := c.Threshold
+	for _, d := range c.Thresholds {
+		if d > 0 && (least <= 0 || d < least) {
+			least = d
+		}
+	}
+	if interval := least / 4; interval > 100*time.Millisecond {
+		return interval
+	}
+	return 100 * time.Millisecond
+}
+
+// Target 执行自动动作的适配器
+type Target interface {
+	// ResubscribeFeed 重新订阅一个 (channel, symbol)
+	ResubscribeFeed(channel, symbol string) error
+	// Reconnect 重连
+	Reconnect(reason string)
+}
+
+type feed struct {
+	channel string
+	symbol  string
+}
+
+// Watchdog 静默检测器；nil 的 *Watchdog 所有方法都是空操作，适配器无需判空
+type Watchdog struct {
+	cfg    Config
+	target Target
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	last   map[feed]time.Time
+	active bool
+
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
+// New 创建静默检测器，需要调用 Start 开始检查
+func New(cfg Config, target Target, logger *slog.Logger) *Watchdog {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Watchdog{
+		cfg:    cfg,
+		target: target,
+		logger: logger,
+		last:   make(map[feed]time.Time),
+	}
+}
+
+// Watch 开始监视一个 (channel, symbol)，从未收到推送的订阅同样会被判定为静默
+func (w *Watchdog) Watch(channel, symbol string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.last[feed{channel, symbol}]; !ok {
+		w.last[feed{channel, symbol}] = time.Now()
+	}
+}
+
+// Forget 不再监视一个 (channel, symbol)，取消订阅后调用
+func (w *Watchdog) Forget(channel, symbol string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.last, feed{channel, symbol})
+}
+
+// Touch 记录收到一条推送
+func (w *Watchdog) Touch(channel, symbol string) {
+	if w == nil {
+		return
+	}
+	now := time.Now()
+	w.mu.Lock()
+	w.last[feed{channel, symbol}] = now
+	w.mu.Unlock()
+}
+
+// Resume 连接建立后恢复检查，所有订阅从现在开始重新计时
+func (w *Watchdog) Resume() {
+	if w == nil {
+		return
+	}
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for f := range w.last {
+		w.last[f] = now
+	}
+	w.active = true
+}
+
+// Pause 连接断开时暂停检查，断线由连接层负责重连
+func (w *Watchdog) Pause() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.active = false
+}
+
+// Start 启动检查协程，重复调用只启动一次
+func (w *Watchdog) Start(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	w.once.Do(func() {
+		ctx, w.cancel = context.WithCancel(ctx)
+		go w.run(ctx)
+	})
+}
+
+// Stop 停止检查协程
+func (w *Watchdog) Stop() {
+	if w == nil || w.cancel == nil {
+		return
+	}
+	w.cancel()
+}
+
+func (w *Watchdog) run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check 找出静默的订阅并执行回调与动作
+func (w *Watchdog) check(now time.Time) {
+	var stale []StaleEvent
+
+	w.mu.Lock()
+	if !w.active {
+		w.mu.Unlock()
+		return
+	}
+	for f, last := range w.last {
+		threshold := w.cfg.threshold(f.channel, f.symbol)
+		if threshold <= 0 || now.Sub(last) < threshold {
+			continue
+		}
+		stale = append(stale, StaleEvent{
+			Channel:     f.channel,
+			Symbol:      f.symbol,
+			LastMessage: last,
+			Silence:     now.Sub(last),
+			Threshold:   threshold,
+			Action:      w.cfg.Action,
+		})
+		// 重新计时，仍然静默时下一个阈值周期再次触发
+		w.last[f] = now
+	}
+	w.mu.Unlock()
+
+	for _, e := range stale {
+		w.logger.Warn("subscription is stale", "channel", e.Channel, "symbol", e.Symbol,
+			"silence", e.Silence, "action", e.Action)
+		if w.cfg.OnStale != nil {
+			w.cfg.OnStale(e)
+		}
+		if w.cfg.Action == ActionResubscribe && w.target != nil {
+			if err := w.target.ResubscribeFeed(e.Channel, e.Symbol); err != nil {
+				w.logger.Error("failed to resubscribe stale subscription", "channel", e.Channel, "symbol", e.Symbol, "error", err)
+			}
+		}
+	}
+	if len(stale) > 0 && w.cfg.Action == ActionReconnect && w.target != nil {
+		w.target.Reconnect("stale subscription: " + stale[0].Channel + " " + stale[0].Symbol)
+	}
+}
@@ -0,0 +1,81 @@
+package watchdog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type recordTarget struct {
+	resubscribed []string
+	reconnects   int
+}
+
+func (t *recordTarget) ResubscribeFeed(channel, symbol string) error {
+	t.resubscribed = append(t.resubscribed, channel+":"+symbol)
+	return nil
+}
+
+func (t *recordTarget) Reconnect(string) { t.reconnects++ }
+
+func TestWatchdogCheck(t *testing.T) {
+	var events []StaleEvent
+	target := &recordTarget{}
+	w := New(Config{
+		Threshold:  time.Second,
+		Thresholds: map[string]time.Duration{"trades": 0, "book:ETH-USD": 5 * time.Second},
+		Action:     ActionResubscribe,
+		OnStale:    func(e StaleEvent) { events = append(events, e) },
+	}, target, slog.New(slog.DiscardHandler))
+
+	w.Watch("book", "BTC-USD")
+	w.Watch("book", "ETH-USD")
+	w.Watch("trades", "BTC-USD")
+
+	// 未 Resume 时不检查
+	w.check(time.Now().Add(time.Minute))
+	if len(events) != 0 {
+		t.Fatalf("checked while paused: %v", events)
+	}
+
+	w.Resume()
+	start := time.Now()
+	w.Touch("book", "BTC-USD")
+	w.check(start.Add(2 * time.Second))
+	if len(events) != 1 || events[0].Channel != "book" || events[0].Symbol != "BTC-USD" {
+		t.Fatalf("events = %+v, want book BTC-USD", events)
+	}
+	if len(target.resubscribed) != 1 || target.resubscribed[0] != "book:BTC-USD" {
+		t.Fatalf("resubscribed = %v", target.resubscribed)
+	}
+
+	// 触发后重新计时，同一轮阈值内不会重复触发
+	w.check(start.Add(2500 * time.Millisecond))
+	if len(events) != 1 {
+		t.Fatalf("fired twice within threshold: %+v", events)
+	}
+
+	w.check(start.Add(6 * time.Second))
+	if len(events) != 3 {
+		t.Fatalf("events = %+v, want BTC-USD again and ETH-USD", events)
+	}
+}
+
+func TestWatchdogReconnectOnce(t *testing.T) {
+	target := &recordTarget{}
+	w := New(Config{Threshold: time.Second, Action: ActionReconnect}, target, slog.New(slog.DiscardHandler))
+	w.Watch("book", "BTC-USD")
+	w.Watch("book", "ETH-USD")
+	w.Resume()
+
+	w.check(time.Now().Add(2 * time.Second))
+	if target.reconnects != 1 {
+		t.Fatalf("reconnects = %d, want 1", target.reconnects)
+	}
+
+	var nilWatchdog *Watchdog
+	nilWatchdog.Touch("book", "BTC-USD")
+	nilWatchdog.Start(context.Background())
+	nilWatchdog.Stop()
+}